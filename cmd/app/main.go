@@ -6,16 +6,24 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/gorilla/mux"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"rest_api/internal/api/application"
 	"rest_api/internal/api/config"
+	"rest_api/internal/api/graph"
 	"rest_api/internal/api/handler"
 	"rest_api/internal/api/kafka"
+	"rest_api/internal/api/minio"
+	"rest_api/internal/api/negotiate"
+	"rest_api/internal/api/openapi"
+	"rest_api/internal/api/rpc"
 	"rest_api/internal/api/service"
+	"rest_api/internal/api/similarity"
 	"rest_api/internal/api/tmdb"
 	"rest_api/internal/data"
+	"rest_api/internal/queue"
 	"rest_api/internal/scheduler"
 	"sync"
 	"syscall"
@@ -23,55 +31,243 @@ import (
 )
 
 func main() {
-	// create kafka topic
+	// create kafka topics: the created movies keep being published to the movies topic as they always were, the
+	// change events go to their own topic
 	admin, err := sarama.NewClusterAdmin([]string{config.BrokerLink}, sarama.NewConfig())
 	if err != nil {
 		log.Fatalf("Could not create kafka admin: %v", err)
 	}
-	err = admin.CreateTopic(config.Topic, &sarama.TopicDetail{
-		NumPartitions:     1,
-		ReplicationFactor: 1,
-	}, false)
-	var tErr *sarama.TopicError
-	if err != nil {
-		if !errors.As(err, &tErr) || !errors.Is(tErr.Unwrap(), sarama.ErrTopicAlreadyExists) {
-			log.Fatalf("Could not create topic: %v", err)
+	for _, topic := range []string{config.Topic, config.EventTopic} {
+		err = admin.CreateTopic(topic, &sarama.TopicDetail{
+			NumPartitions:     1,
+			ReplicationFactor: 1,
+		}, false)
+		var tErr *sarama.TopicError
+		if err != nil {
+			if !errors.As(err, &tErr) || !errors.Is(tErr.Unwrap(), sarama.ErrTopicAlreadyExists) {
+				log.Fatalf("Could not create topic: %v", err)
+			}
 		}
 	}
-	// create kafka publisher
-	var publisher kafka.Publisher
+	// create kafka publishers
+	var publisher, eventPublisher kafka.Publisher
 	if config.SyncPublish {
-		publisher = &kafka.SyncPublisher{}
+		publisher, eventPublisher = &kafka.SyncPublisher{}, &kafka.SyncPublisher{}
 	} else {
-		publisher = &kafka.AsyncPublisher{}
+		publisher, eventPublisher = &kafka.AsyncPublisher{}, &kafka.AsyncPublisher{}
 	}
 	publisher.Configure(config.Topic)
+	eventPublisher.Configure(config.EventTopic)
 
 	//create db and service layer
 	db := application.CreateDB()
 
 	userRepository := &data.UserRepository{DB: db}
 	movieRepository := &data.MovieRepository{DB: db}
+	assetRepository := &data.AssetRepository{DB: db}
+	refreshRunRepository := &data.RefreshRunRepository{DB: db}
+	leaseRepository := &data.LeaseRepository{DB: db}
+	importRepository := &data.ImportRepository{DB: db}
+	queueRepository := &data.QueueRepository{DB: db}
+	auditRepository := &data.AuditRepository{DB: db}
+	revisionRepository := &data.RevisionRepository{DB: db}
+	watchlistRepository := &data.WatchlistRepository{DB: db}
+	ratingRepository := &data.RatingRepository{DB: db}
+	reviewRepository := &data.ReviewRepository{DB: db}
+	genreRepository := &data.GenreRepository{DB: db}
+	tagRepository := &data.TagRepository{DB: db}
+	creditRepository := &data.CreditRepository{DB: db}
+	collectionRepository := &data.CollectionRepository{DB: db}
+	recommendationRepository := &data.RecommendationRepository{DB: db}
+	idempotencyRepository := &data.IdempotencyRepository{DB: db}
+
+	// create object storage for movie assets
+	storage := minio.NewService(application.CreateMinioClient())
+	if err = storage.EnsureBucket(context.Background()); err != nil {
+		log.Fatalf("Could not create bucket: %v", err)
+	}
 
-	movieService := service.NewMovieService(movieRepository)
+	// movies are read through a cache, invalidated by the change events of every replica
+	movieCache := service.NewMovieCache(movieRepository, config.MovieCacheSize, config.MovieCacheTTL, config.MovieCacheNegativeTTL)
+	movieService := service.NewMovieService(movieCache, eventPublisher)
+	movieService.Subscribe(service.PublishCreatedMovies(publisher))
+	userService := service.NewUserService(userRepository, movieService)
+	auditService := service.NewAuditService(auditRepository)
+	revisionService := service.NewRevisionService(revisionRepository, movieService)
+	watchlistService := service.NewWatchlistService(watchlistRepository, movieService)
+	ratingService := service.NewRatingService(ratingRepository, reviewRepository, movieService)
+	ratingService.Subscribe(func(_ string, movieId int) { movieService.Invalidate(movieId) })
+	genreService := service.NewGenreService(genreRepository)
+	tagService := service.NewTagService(tagRepository, movieService)
+	assetService := service.NewAssetService(assetRepository, movieRepository, storage, config.AssetURLExpiry)
 	tmdbService := tmdb.NewService(tmdb.ApiUrl)
+	jobQueue := queue.NewQueue(queueRepository, application.ReplicaID(), config.QueueWorkers, config.QueuePollInterval,
+		config.QueueBackoffBase, config.QueueBackoffMax)
+	exportService := service.NewExportService(movieRepository, storage, config.ExportFlushRows, config.ExportFormat, config.ExportPrefix, config.ExportRetention)
+	importService := service.NewImportService(importRepository, movieService, tmdbService, jobQueue, config.ImportBatchSize, config.ImportConcurrency)
+	creditService := service.NewCreditService(creditRepository, movieService, tmdbService)
+	collectionService := service.NewCollectionService(collectionRepository, movieService)
+	similarityService := service.NewSimilarityService(movieService, similarity.NewIndex(config.SimilarityGenreWeight))
+	recommendationService := service.NewRecommendationService(recommendationRepository, ratingService, watchlistService, config.RecommendationNeighborhood,
+		config.RecommendationMinOverlap, config.RecommendationMinRatings, config.RecommendationCacheTTL)
+	refreshService := service.NewRefreshService(movieRepository, refreshRunRepository, movieService, tmdbService, creditService,
+		collectionService, jobQueue, config.RefreshMaxAge, config.RefreshBatchSize, config.RefreshRequestsPerSecond)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepository, config.IdempotencyKeyTTL, config.IdempotencyLockTimeout)
+
+	// queued jobs run on every replica, each job being claimed by a single worker
+	err = queue.Handle(jobQueue, service.EnrichMovieJob, queue.Options{MaxAttempts: config.EnrichMaxAttempts, Timeout: config.EnrichTimeout}, refreshService.Enrich)
+	if err != nil {
+		log.Fatalf("Could not register job handler: %v", err)
+	}
+	err = queue.Handle(jobQueue, service.SyncCreditsJob, queue.Options{MaxAttempts: config.EnrichMaxAttempts, Timeout: config.EnrichTimeout}, creditService.SyncJob)
+	if err != nil {
+		log.Fatalf("Could not register job handler: %v", err)
+	}
+	err = queue.Handle(jobQueue, service.ImportMoviesJob, queue.Options{MaxAttempts: config.ImportMaxAttempts, Timeout: config.ImportTimeout}, importService.Process)
+	if err != nil {
+		log.Fatalf("Could not register job handler: %v", err)
+	}
+
+	// background jobs are registered here and started once the server is up. Only the elected leader
+	// among the replicas runs scheduled jobs.
+	elector := scheduler.NewElector(leaseRepository, config.LeaderLease, application.ReplicaID(), config.LeaderLeaseTTL)
+	sch := scheduler.NewScheduler(elector, &data.AdvisoryLocker{DB: db})
+	err = sch.Register(scheduler.Job{
+		Name:     "metadata-refresh",
+		Schedule: scheduler.MustParseCron(config.RefreshSchedule),
+		Timeout:  config.RefreshTimeout,
+		Jitter:   config.RefreshJitter,
+		Run:      refreshService.Run,
+	})
+	if err != nil {
+		log.Fatalf("Could not register job: %v", err)
+	}
+	err = sch.Register(scheduler.Job{
+		Name:     "catalog-export",
+		Schedule: scheduler.MustParseCron(config.ExportSchedule),
+		Timeout:  config.ExportTimeout,
+		Run:      exportService.RunScheduled,
+	})
+	if err != nil {
+		log.Fatalf("Could not register job: %v", err)
+	}
+	err = sch.Register(scheduler.Job{
+		Name:     "trash-purge",
+		Schedule: scheduler.MustParseCron(config.TrashPurgeSchedule),
+		Run: func(ctx context.Context) error {
+			purged, err := movieService.PurgeTrash(ctx, config.TrashRetention)
+			if err != nil {
+				return err
+			}
+			return assetService.RemoveObjects(ctx, purged)
+		},
+	})
+	if err != nil {
+		log.Fatalf("Could not register job: %v", err)
+	}
+
+	err = sch.Register(scheduler.Job{
+		Name:     "idempotency-purge",
+		Schedule: scheduler.MustParseCron(config.IdempotencyPurgeSchedule),
+		Run:      idempotencyService.Purge,
+	})
+	if err != nil {
+		log.Fatalf("Could not register job: %v", err)
+	}
+
+	err = sch.Register(scheduler.Job{
+		Name:     "recommendation-matrix",
+		Schedule: scheduler.MustParseCron(config.RecommendationSchedule),
+		Timeout:  config.RecommendationTimeout,
+		Run:      recommendationService.ComputeMatrix,
+	})
+	if err != nil {
+		log.Fatalf("Could not register job: %v", err)
+	}
+	// the similarity index lives in every replica, so every replica rebuilds its own
+	err = sch.Register(scheduler.Job{
+		Name:     "similarity-rebuild",
+		Schedule: scheduler.MustParseCron(config.SimilaritySchedule),
+		Timeout:  config.SimilarityTimeout,
+		Local:    true,
+		Run:      similarityService.Rebuild,
+	})
+	if err != nil {
+		log.Fatalf("Could not register job: %v", err)
+	}
+
+	// the gRPC catalog is served on its own port, WatchChanges following the movie events of all the replicas
+	consumer, err := kafka.NewConsumer([]string{config.BrokerLink}, config.EventTopic)
+	if err != nil {
+		log.Fatalf("Could not create kafka consumer: %v", err)
+	}
+	changeFeed := rpc.NewChangeFeed(config.GRPCWatchBuffer)
+	rpcMetrics := rpc.NewMetrics()
+	grpcServer, grpcHealth := rpc.NewServer(rpc.NewCatalogServer(movieService, tmdbService, refreshService, changeFeed),
+		userRepository, rpcMetrics)
 
 	r := mux.NewRouter()
 
 	h := &handler.Handler{
-		UserRepository: userRepository,
-		MovieService:   movieService,
-		TmdbService:    tmdbService,
-		Publisher:      publisher,
+		UserRepository:        userRepository,
+		MovieService:          movieService,
+		MovieCache:            movieCache,
+		TmdbService:           tmdbService,
+		AssetService:          assetService,
+		Scheduler:             sch,
+		Elector:               elector,
+		RefreshService:        refreshService,
+		ImportService:         importService,
+		ExportService:         exportService,
+		Queue:                 jobQueue,
+		UserService:           userService,
+		AuditService:          auditService,
+		RevisionService:       revisionService,
+		WatchlistService:      watchlistService,
+		RatingService:         ratingService,
+		GenreService:          genreService,
+		TagService:            tagService,
+		CreditService:         creditService,
+		CollectionService:     collectionService,
+		SimilarityService:     similarityService,
+		RecommendationService: recommendationService,
+		IdempotencyService:    idempotencyService,
+		RPCMetrics:            rpcMetrics,
 	}
 
-	r.HandleFunc("/ping", h.PingHandler).Methods(http.MethodGet)
-	//r.HandleFunc("/movies", h.BasicAuth(h.GetMovies)).Methods(http.MethodGet)
-	r.HandleFunc("/movies", h.GetMovies).Methods(http.MethodGet)
-	r.HandleFunc("/movies/{movieId}", h.GetMovie).Methods(http.MethodGet)
-	r.HandleFunc("/movies", h.AddMovie).Methods(http.MethodPost)
-	r.HandleFunc("/movies/{movieId}", h.UpdateMovie).Methods(http.MethodPut)
-	r.HandleFunc("/movies/{movieId}", h.DeleteMovie).Methods(http.MethodDelete)
+	graphHandler, err := graph.NewHandler(graph.Services{
+		Movies:     movieService,
+		Genres:     genreService,
+		Credits:    creditService,
+		Ratings:    ratingService,
+		Watchlists: watchlistService,
+		Refresh:    refreshService,
+	}, config.GraphQLMaxDepth, config.GraphQLMaxComplexity)
+	if err != nil {
+		log.Fatalf("Could not build GraphQL schema: %v", err)
+	}
+
+	// responses are compressed last, after being validated
+	r.Use(negotiate.Compress(config.CompressMinSize))
+	// every request gets an id and, when it carries valid credentials, an actor for the audit log
+	r.Use(h.RequestContext)
+
+	// v1 is served at the unversioned paths too, for the clients predating the versioning
+	routes := handler.V1Routes(h.Routes())
+	routes = append(routes, h.V2Routes()...)
+	routes = append(routes, handler.GraphQLRoutes(graphHandler)...)
+	// the routes are documented in the OpenAPI document, which requests are validated against, and responses too
+	// when testing
+	doc := openapi.NewDocument("go-rest-api", "2.0.0", "Movie catalog enriched from The Movie Database")
+	if err = handler.Register(r, doc, routes); err != nil {
+		log.Fatalf("Could not register routes: %v", err)
+	}
+	r.Handle("/openapi.json", doc).Methods(http.MethodGet)
+	r.Handle("/docs", openapi.DocsHandler(doc.Info.Title, "/openapi.json")).Methods(http.MethodGet)
+	r.Use(handler.Deprecation(doc, config.V1Deprecation, config.V1Sunset))
+	r.Use(openapi.NewValidator(doc, config.ValidateResponses).Middleware)
+	// retries of unsafe requests with an Idempotency-Key are answered with the stored response, once validated
+	r.Use(h.Idempotency(doc))
 
 	server := http.Server{
 		Addr:         ":3000",
@@ -80,8 +276,8 @@ func main() {
 		WriteTimeout: 30 * time.Second,
 	}
 
-	done := make(chan bool)
-	defer close(done)
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
 	quit := make(chan os.Signal, 1)
 
 	wg := sync.WaitGroup{}
@@ -89,10 +285,22 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	terminationDelay := 5 * time.Second
 
+	grpcListener, err := net.Listen("tcp", config.GRPCAddr)
+	if err != nil {
+		log.Fatalf("Could not listen on %s: %v", config.GRPCAddr, err)
+	}
+	// grpcErr is set when the gRPC server fails, which shuts the replica down like a signal; it is read once wg is done
+	var grpcErr error
+	grpcFailed := make(chan struct{})
+
 	go func() {
-		sig := <-quit
+		select {
+		case sig := <-quit:
+			log.Printf("Signal %v caught. Shutting down in %vs", sig, terminationDelay)
+		case <-grpcFailed:
+			log.Printf("gRPC server failed. Shutting down in %vs", terminationDelay)
+		}
 		server.SetKeepAlivesEnabled(false)
-		log.Printf("Signal %v caught. Shutting down in %vs", sig, terminationDelay)
 
 		delay := time.NewTicker(terminationDelay)
 		defer delay.Stop()
@@ -106,17 +314,52 @@ func main() {
 		// termination delay in both context and signal listening?
 		ctx, cancel := context.WithTimeout(context.Background(), terminationDelay)
 		defer cancel()
+		grpcHealth.Shutdown()
 		if err := server.Shutdown(ctx); err != nil {
 			log.Fatalf("could not shutdown server: %v", err)
 		}
-		done <- true
+		changeFeed.Close()
+		grpcServer.GracefulStop()
+		stopScheduler()
 		close(quit)
 	}()
-	sch := scheduler.NewScheduler(5, done, &wg)
-	// run scheduler in background
-	wg.Add(1)
+	// run leader election, scheduler and job queue in background, and build the similarity index without waiting
+	// for its schedule
+	go func() {
+		if err := similarityService.Rebuild(schedulerCtx); err != nil {
+			log.Printf("Could not build similarity index: %v", err)
+		}
+	}()
+	wg.Add(5)
 	go func() {
-		sch.Run()
+		defer wg.Done()
+		err := consumer.Run(schedulerCtx, func(msg []byte) {
+			movieCache.HandleMessage(msg)
+			changeFeed.HandleMessage(msg)
+			similarityService.HandleMessage(msg)
+		})
+		if err != nil {
+			log.Printf("Could not consume movie events: %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		log.Printf("Started gRPC server on %s", config.GRPCAddr)
+		if grpcErr = grpcServer.Serve(grpcListener); grpcErr != nil {
+			close(grpcFailed)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		elector.Run(schedulerCtx)
+	}()
+	go func() {
+		defer wg.Done()
+		sch.Run(schedulerCtx)
+	}()
+	go func() {
+		defer wg.Done()
+		jobQueue.Run(schedulerCtx)
 	}()
 
 	listenAddr := ":3000"
@@ -126,6 +369,9 @@ func main() {
 	}
 	log.Printf("Started server on %s", listenAddr)
 	wg.Wait()
+	if grpcErr != nil {
+		log.Fatalf("Could not serve gRPC: %v", grpcErr)
+	}
 
 	log.Println("Server stopped")
 
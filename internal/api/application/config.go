@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 func CreateDB() *sql.DB {
@@ -33,3 +36,30 @@ func GetEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func CreateMinioClient() *minio.Client {
+	endpoint := GetEnv("MINIO_HOST", "localhost:9000")
+	accessKey := GetEnv("MINIO_ACCESS_KEY", "test")
+	secretKey := GetEnv("MINIO_SECRET_KEY", "test")
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: GetEnv("MINIO_SECURE", "false") == "true",
+	})
+	if err != nil {
+		log.Fatalf("minio init failed: %s", err)
+	}
+
+	return client
+}
+
+// ReplicaID identifies this instance of the application among its replicas.
+func ReplicaID() string {
+	if id, ok := os.LookupEnv("REPLICA_ID"); ok {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
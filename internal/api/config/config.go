@@ -1,12 +1,95 @@
 package config
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 const (
 	BucketName  = "default"
 	BrokerLink  = "localhost:29092"
 	Topic       = "movies"
+	EventTopic  = "movie-events"
 	SyncPublish = false
+
+	AssetURLExpiry = 15 * time.Minute
+
+	LeaderLease    = "scheduler"
+	LeaderLeaseTTL = 15 * time.Second
+
+	RefreshSchedule          = "0 * * * *"
+	RefreshTimeout           = 30 * time.Minute
+	RefreshJitter            = 2 * time.Minute
+	RefreshMaxAge            = 7 * 24 * time.Hour
+	RefreshBatchSize         = 200
+	RefreshRequestsPerSecond = 4
+
+	SimilaritySchedule    = "*/30 * * * *"
+	SimilarityTimeout     = 5 * time.Minute
+	SimilarityGenreWeight = 0.3
+
+	RecommendationSchedule     = "45 * * * *"
+	RecommendationTimeout      = 15 * time.Minute
+	RecommendationNeighborhood = 30
+	RecommendationMinOverlap   = 2
+	RecommendationMinRatings   = 3
+	RecommendationCacheTTL     = 10 * time.Minute
+
+	GraphQLMaxDepth      = 8
+	GraphQLMaxComplexity = 5000
+
+	GRPCAddr        = ":3001"
+	GRPCWatchBuffer = 64
+
+	CompressMinSize = 1024
+
+	MovieCacheSize        = 10000
+	MovieCacheTTL         = 5 * time.Minute
+	MovieCacheNegativeTTL = 30 * time.Second
+
+	ImportMaxBytes    = 32 << 20
+	ImportBatchSize   = 500
+	ImportConcurrency = 8
+
+	ExportFlushRows = 1000
+	ExportSchedule  = "0 3 * * *"
+	ExportTimeout   = time.Hour
+	ExportFormat    = "parquet"
+	ExportPrefix    = "exports/"
+	ExportRetention = 30 * 24 * time.Hour
+
+	TrashPurgeSchedule = "30 4 * * *"
+	TrashRetention     = 30 * 24 * time.Hour
+
+	IdempotencyKeyTTL        = 24 * time.Hour
+	IdempotencyLockTimeout   = time.Minute
+	IdempotencyPurgeSchedule = "15 * * * *"
+
+	QueueWorkers      = 4
+	QueuePollInterval = time.Second
+	QueueBackoffBase  = 10 * time.Second
+	QueueBackoffMax   = 30 * time.Minute
+	EnrichMaxAttempts = 5
+	EnrichTimeout     = time.Minute
+	ImportMaxAttempts = 3
+	ImportTimeout     = time.Hour
 )
 
 var ApiKey = os.Getenv("API_KEY")
+
+// V1Deprecation is when the v1 API was deprecated in favor of v2, and V1Sunset when it will be removed.
+var (
+	V1Deprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	V1Sunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// Cache-Control policies of the cacheable routes. Movies are revalidated with If-Modified-Since once stale.
+var (
+	MoviesCacheControl = "public, max-age=30, must-revalidate"
+	MovieCacheControl  = "public, max-age=300, must-revalidate"
+	GenresCacheControl = "public, max-age=3600"
+)
+
+// ValidateResponses makes the responses be checked against the OpenAPI document too, replacing the ones not
+// matching it by an error. It buffers every response, so it is meant for tests and development.
+var ValidateResponses = os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true"
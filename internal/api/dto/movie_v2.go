@@ -0,0 +1,305 @@
+// Package dto holds the representations of the resources which differ from the model in some version of the API,
+// along with their mappers.
+package dto
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"rest_api/internal/api/model"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTitleLength is the maximum length of the title of a movie.
+const MaxTitleLength = 50
+
+// MovieV2 is the representation of a movie in the v2 API. Unlike v1, the fields which can be unknown are null
+// rather than omitted, the lists are always present and the related details are grouped.
+type MovieV2 struct {
+	XMLName        xml.Name      `json:"-" xml:"movie"`
+	ID             int           `json:"id" xml:"id"`
+	Title          string        `json:"title" xml:"title"`
+	Overview       string        `json:"overview" xml:"overview"`
+	RuntimeMinutes *int          `json:"runtimeMinutes" xml:"runtimeMinutes"`
+	Release        ReleaseV2     `json:"release" xml:"release"`
+	Genres         []LabelV2     `json:"genres" xml:"genres>genre"`
+	Tags           []LabelV2     `json:"tags" xml:"tags>tag"`
+	Ratings        RatingsV2     `json:"ratings" xml:"ratings"`
+	ExternalIDs    ExternalIDsV2 `json:"externalIds" xml:"externalIds"`
+	DeletedAt      *time.Time    `json:"deletedAt" xml:"deletedAt"`
+	Links          MovieLinksV2  `json:"links" xml:"links"`
+}
+
+type ReleaseV2 struct {
+	// Date is formatted as YYYY-MM-DD.
+	Date *string `json:"date" xml:"date"`
+	Year *int    `json:"year" xml:"year"`
+}
+
+// LabelV2 is a genre or a tag.
+type LabelV2 struct {
+	ID   int    `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+// RatingsV2 are the vote average of the movie on TMDB, if it has one, and the aggregate of the ratings of our
+// users, whose average is null until the movie is rated.
+type RatingsV2 struct {
+	Tmdb  *TmdbRatingV2 `json:"tmdb" xml:"tmdb"`
+	Users UserRatingsV2 `json:"users" xml:"users"`
+}
+
+type TmdbRatingV2 struct {
+	Average float64 `json:"average" xml:"average"`
+}
+
+type UserRatingsV2 struct {
+	Average *float64 `json:"average" xml:"average"`
+	Count   int      `json:"count" xml:"count"`
+}
+
+type ExternalIDsV2 struct {
+	Tmdb *int `json:"tmdb" xml:"tmdb"`
+}
+
+// MovieLinksV2 are the paths of the movie and of its related resources.
+type MovieLinksV2 struct {
+	Self    string `json:"self" xml:"self"`
+	Credits string `json:"credits" xml:"credits"`
+	Similar string `json:"similar" xml:"similar"`
+	Reviews string `json:"reviews" xml:"reviews"`
+	Assets  string `json:"assets" xml:"assets"`
+}
+
+// MovieV2Create is the body of the creation of a movie, whose details are taken from the TMDB movie with the
+// title.
+type MovieV2Create struct {
+	ID    int    `json:"id" xml:"id"`
+	Title string `json:"title" xml:"title"`
+}
+
+// MovieV2Update is the body of the update of a movie, identified by the path, which replaces its title, overview,
+// runtime and release date. Nil genres keep the current ones.
+type MovieV2Update struct {
+	Title          string           `json:"title" xml:"title"`
+	Overview       string           `json:"overview" xml:"overview"`
+	RuntimeMinutes *int             `json:"runtimeMinutes" xml:"runtimeMinutes"`
+	Release        *ReleaseV2Update `json:"release" xml:"release"`
+	Genres         []GenreV2Update  `json:"genres" xml:"genres>genre"`
+}
+
+type ReleaseV2Update struct {
+	Date string `json:"date" xml:"date"`
+}
+
+type GenreV2Update struct {
+	ID int `json:"id" xml:"id"`
+}
+
+// ToMovieV2 maps the movie to its v2 representation.
+func ToMovieV2(movie *model.Movie) MovieV2 {
+	self := "/v2/movies/" + strconv.Itoa(movie.MovieId)
+	dto := MovieV2{
+		ID:             movie.MovieId,
+		Title:          movie.MovieName,
+		Overview:       movie.Overview,
+		RuntimeMinutes: positive(movie.Runtime),
+		Genres:         make([]LabelV2, 0, len(movie.Genres)),
+		Tags:           make([]LabelV2, 0, len(movie.Tags)),
+		Ratings:        RatingsV2{Users: UserRatingsV2{Count: movie.RatingCount}},
+		ExternalIDs:    ExternalIDsV2{Tmdb: positive(movie.TmdbId)},
+		DeletedAt:      movie.DeletedAt,
+		Links: MovieLinksV2{
+			Self:    self,
+			Credits: self + "/credits",
+			Similar: self + "/similar",
+			Reviews: self + "/reviews",
+			Assets:  self + "/assets",
+		},
+	}
+	if releaseDate, err := time.Parse(time.DateOnly, movie.ReleaseDate); err == nil {
+		year := releaseDate.Year()
+		dto.Release = ReleaseV2{Date: &movie.ReleaseDate, Year: &year}
+	}
+	for _, genre := range movie.Genres {
+		dto.Genres = append(dto.Genres, LabelV2{ID: genre.ID, Name: genre.Name})
+	}
+	for _, tag := range movie.Tags {
+		dto.Tags = append(dto.Tags, LabelV2{ID: tag.ID, Name: tag.Name})
+	}
+	if movie.TmdbVoteAverage > 0 {
+		dto.Ratings.Tmdb = &TmdbRatingV2{Average: movie.TmdbVoteAverage}
+	}
+	if movie.RatingCount > 0 {
+		average := movie.RatingAverage
+		dto.Ratings.Users.Average = &average
+	}
+	return dto
+}
+
+// ToMoviesV2 maps the movies to their v2 representation, an empty list for no movies.
+func ToMoviesV2(movies []*model.Movie) []MovieV2 {
+	dtos := make([]MovieV2, len(movies))
+	for i, movie := range movies {
+		dtos[i] = ToMovieV2(movie)
+	}
+	return dtos
+}
+
+// ToModel returns the movie to create, without the details from TMDB.
+func (c MovieV2Create) ToModel() *model.Movie {
+	return &model.Movie{MovieId: c.ID, MovieName: c.Title}
+}
+
+// ToModel returns the movie with the id of the update.
+func (u MovieV2Update) ToModel(movieId int) *model.Movie {
+	movie := &model.Movie{MovieId: movieId, MovieName: u.Title, Overview: u.Overview}
+	if u.RuntimeMinutes != nil {
+		movie.Runtime = *u.RuntimeMinutes
+	}
+	if u.Release != nil {
+		movie.ReleaseDate = u.Release.Date
+	}
+	if u.Genres != nil {
+		movie.Genres = make([]model.Genre, len(u.Genres))
+		for i, genre := range u.Genres {
+			movie.Genres[i] = model.Genre{ID: genre.ID}
+		}
+	}
+	return movie
+}
+
+// Validate checks the constraints documented for the body, which the validator only enforces on JSON bodies.
+func (c MovieV2Create) Validate() error {
+	return validateTitle(c.Title)
+}
+
+// Validate checks the constraints documented for the body, which the validator only enforces on JSON bodies.
+func (u MovieV2Update) Validate() error {
+	if err := validateTitle(u.Title); err != nil {
+		return err
+	}
+	if u.RuntimeMinutes != nil && *u.RuntimeMinutes < 0 {
+		return errors.New("runtimeMinutes should be at least 0")
+	}
+	if u.Release != nil && u.Release.Date != "" {
+		if _, err := time.Parse(time.DateOnly, u.Release.Date); err != nil {
+			return errors.New("release.date should be formatted as YYYY-MM-DD")
+		}
+	}
+	return nil
+}
+
+func validateTitle(title string) error {
+	length := utf8.RuneCountInString(title)
+	switch {
+	case length == 0:
+		return errors.New("title should be present")
+	case length > MaxTitleLength:
+		return fmt.Errorf("title should be at most %d characters", MaxTitleLength)
+	}
+	return nil
+}
+
+// positive returns nil for unknown values, stored as zero.
+func positive(value int) *int {
+	if value <= 0 {
+		return nil
+	}
+	return &value
+}
+
+// MarshalCSV returns the columns of the movie, the ones of the nested fields being named by their path. Genres and
+// tags are the list of their names separated by |, and the links are left out.
+func (m MovieV2) MarshalCSV() ([]string, []string) {
+	header := []string{"id", "title", "overview", "runtimeMinutes", "release.date", "release.year", "genres", "tags",
+		"ratings.tmdb.average", "ratings.users.average", "ratings.users.count", "externalIds.tmdb", "deletedAt"}
+	genres := make([]string, len(m.Genres))
+	for i, genre := range m.Genres {
+		genres[i] = genre.Name
+	}
+	tags := make([]string, len(m.Tags))
+	for i, tag := range m.Tags {
+		tags[i] = tag.Name
+	}
+	record := []string{
+		strconv.Itoa(m.ID),
+		m.Title,
+		m.Overview,
+		csvInt(m.RuntimeMinutes),
+		"",
+		csvInt(m.Release.Year),
+		strings.Join(genres, "|"),
+		strings.Join(tags, "|"),
+		"",
+		"",
+		strconv.Itoa(m.Ratings.Users.Count),
+		csvInt(m.ExternalIDs.Tmdb),
+		"",
+	}
+	if m.Release.Date != nil {
+		record[4] = *m.Release.Date
+	}
+	if m.Ratings.Tmdb != nil {
+		record[8] = strconv.FormatFloat(m.Ratings.Tmdb.Average, 'f', -1, 64)
+	}
+	if m.Ratings.Users.Average != nil {
+		record[9] = strconv.FormatFloat(*m.Ratings.Users.Average, 'f', -1, 64)
+	}
+	if m.DeletedAt != nil {
+		record[12] = m.DeletedAt.UTC().Format(time.RFC3339)
+	}
+	return header, record
+}
+
+// UnmarshalCSV sets the id and title of the movie to create from the columns of the same name.
+func (c *MovieV2Create) UnmarshalCSV(fields map[string]string) error {
+	id, err := parseCSVInt(fields, "id")
+	if err != nil {
+		return err
+	}
+	if id != nil {
+		c.ID = *id
+	}
+	c.Title = strings.TrimSpace(fields["title"])
+	return nil
+}
+
+// UnmarshalCSV sets the update from the columns named like the fields of MovieV2, empty columns being null. The
+// genres cannot be updated from CSV.
+func (u *MovieV2Update) UnmarshalCSV(fields map[string]string) error {
+	runtime, err := parseCSVInt(fields, "runtimeMinutes")
+	if err != nil {
+		return err
+	}
+	u.RuntimeMinutes = runtime
+	u.Title = strings.TrimSpace(fields["title"])
+	u.Overview = fields["overview"]
+	if date := strings.TrimSpace(fields["release.date"]); date != "" {
+		u.Release = &ReleaseV2Update{Date: date}
+	}
+	return nil
+}
+
+// parseCSVInt returns the integer of the column, nil if it is missing or empty.
+func parseCSVInt(fields map[string]string, column string) (*int, error) {
+	value := strings.TrimSpace(fields[column])
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s should be a number", column)
+	}
+	return &parsed, nil
+}
+
+func csvInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"encoding/json"
+	"rest_api/internal/api/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMovieV2(t *testing.T) {
+	deletedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		movie *model.Movie
+		want  string
+	}{
+		{
+			name: "enriched movie",
+			movie: &model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear", TmdbId: 42, Runtime: 95,
+				ReleaseDate: "2023-06-22", TmdbVoteAverage: 8.1, RatingAverage: 7.5, RatingCount: 2,
+				Genres: []model.Genre{{ID: 18, Name: "Drama"}}, Tags: []model.Tag{{ID: 3, Name: "kitchen"}}},
+			want: `{"id":1,"title":"The bear","overview":"bear","runtimeMinutes":95,
+				"release":{"date":"2023-06-22","year":2023},
+				"genres":[{"id":18,"name":"Drama"}],"tags":[{"id":3,"name":"kitchen"}],
+				"ratings":{"tmdb":{"average":8.1},"users":{"average":7.5,"count":2}},
+				"externalIds":{"tmdb":42},"deletedAt":null,
+				"links":{"self":"/v2/movies/1","credits":"/v2/movies/1/credits","similar":"/v2/movies/1/similar",
+					"reviews":"/v2/movies/1/reviews","assets":"/v2/movies/1/assets"}}`,
+		},
+		{
+			name:  "deleted movie without details",
+			movie: &model.Movie{MovieId: 2, MovieName: "Gone", DeletedAt: &deletedAt},
+			want: `{"id":2,"title":"Gone","overview":"","runtimeMinutes":null,"release":{"date":null,"year":null},
+				"genres":[],"tags":[],"ratings":{"tmdb":null,"users":{"average":null,"count":0}},
+				"externalIds":{"tmdb":null},"deletedAt":"2024-05-01T10:00:00Z",
+				"links":{"self":"/v2/movies/2","credits":"/v2/movies/2/credits","similar":"/v2/movies/2/similar",
+					"reviews":"/v2/movies/2/reviews","assets":"/v2/movies/2/assets"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movieJSON, err := json.Marshal(ToMovieV2(tt.movie))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(movieJSON))
+		})
+	}
+}
+
+func TestToMoviesV2(t *testing.T) {
+	moviesJSON, err := json.Marshal(ToMoviesV2(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(moviesJSON))
+}
+
+func TestMovieV2Update_ToModel(t *testing.T) {
+	runtime := 95
+	update := MovieV2Update{Title: "The bear", Overview: "bear", RuntimeMinutes: &runtime,
+		Release: &ReleaseV2Update{Date: "2023-06-22"}, Genres: []GenreV2Update{{ID: 18}}}
+
+	assert.Equal(t, &model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear", Runtime: 95,
+		ReleaseDate: "2023-06-22", Genres: []model.Genre{{ID: 18}}}, update.ToModel(1))
+	assert.Equal(t, &model.Movie{MovieId: 1, MovieName: "The bear"}, MovieV2Update{Title: "The bear"}.ToModel(1))
+}
+
+func TestMovieV2_Validate(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name    string
+		body    interface{ Validate() error }
+		wantErr string
+	}{
+		{name: "create", body: MovieV2Create{Title: "The bear"}},
+		{name: "create without title", body: MovieV2Create{ID: 1}, wantErr: "title should be present"},
+		{name: "create with long title", body: MovieV2Create{Title: strings.Repeat("é", MaxTitleLength+1)},
+			wantErr: "title should be at most 50 characters"},
+		{name: "update", body: MovieV2Update{Title: "The bear", Release: &ReleaseV2Update{Date: "2023-06-22"}}},
+		{name: "update without title", body: MovieV2Update{Overview: "bear"}, wantErr: "title should be present"},
+		{name: "update with long title", body: MovieV2Update{Title: strings.Repeat("b", MaxTitleLength+1)},
+			wantErr: "title should be at most 50 characters"},
+		{name: "update with negative runtime", body: MovieV2Update{Title: "The bear", RuntimeMinutes: &negative},
+			wantErr: "runtimeMinutes should be at least 0"},
+		{name: "update with invalid date", body: MovieV2Update{Title: "The bear", Release: &ReleaseV2Update{Date: "June 2023"}},
+			wantErr: "release.date should be formatted as YYYY-MM-DD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.body.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"rest_api/internal/api/model"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Writer encodes movies in one of the export formats.
+type Writer interface {
+	Write(movie *model.Movie) error
+	// Flush writes the buffered movies to the underlying writer, and flushes it too if it supports it.
+	Flush() error
+	// Close flushes the remaining movies and writes the trailer of the format, if any.
+	Close() error
+}
+
+// NewWriter returns a Writer for the format. An unknown format results in ErrUnsupportedFormat.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := &csvWriter{output: w, writer: csv.NewWriter(w)}
+		header, _ := model.Movie{}.MarshalCSV()
+		return writer, writer.writer.Write(header)
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{output: w, buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case FormatParquet:
+		return &parquetWriter{output: w, writer: parquet.NewGenericWriter[parquetMovie](w)}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// ContentType returns the media type of the format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	}
+	return "application/octet-stream"
+}
+
+// flusher is implemented by http.ResponseWriter and buffered writers.
+type flusher interface {
+	Flush()
+}
+
+func flush(w io.Writer) {
+	if f, ok := w.(flusher); ok {
+		f.Flush()
+	}
+}
+
+type csvWriter struct {
+	output io.Writer
+	writer *csv.Writer
+}
+
+// Write writes the columns of Movie.MarshalCSV, as GET /movies does for text/csv.
+func (w *csvWriter) Write(movie *model.Movie) error {
+	_, record := movie.MarshalCSV()
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	flush(w.output)
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	return w.Flush()
+}
+
+type ndjsonWriter struct {
+	output   io.Writer
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(movie *model.Movie) error {
+	return w.encoder.Encode(movie)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	if err := w.buffered.Flush(); err != nil {
+		return err
+	}
+	flush(w.output)
+	return nil
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.Flush()
+}
+
+type parquetMovie struct {
+	ID       int64  `parquet:"id"`
+	Title    string `parquet:"title"`
+	Overview string `parquet:"overview,optional"`
+	TmdbID   int64  `parquet:"tmdb_id,optional"`
+	Runtime  int32  `parquet:"runtime,optional"`
+}
+
+// parquetWriter writes a row group on every flush, the footer is written on close.
+type parquetWriter struct {
+	output io.Writer
+	writer *parquet.GenericWriter[parquetMovie]
+}
+
+func (w *parquetWriter) Write(movie *model.Movie) error {
+	_, err := w.writer.Write([]parquetMovie{{
+		ID:       int64(movie.MovieId),
+		Title:    movie.MovieName,
+		Overview: movie.Overview,
+		TmdbID:   int64(movie.TmdbId),
+		Runtime:  int32(movie.Runtime),
+	}})
+	return err
+}
+
+func (w *parquetWriter) Flush() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	flush(w.output)
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+	flush(w.output)
+	return nil
+}
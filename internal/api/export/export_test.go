@@ -0,0 +1,34 @@
+package export
+
+import (
+	"bytes"
+	"rest_api/internal/api/model"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer, err := NewWriter(FormatParquet, buf)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(&model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear", TmdbId: 11, Runtime: 95}))
+	require.NoError(t, writer.Flush())
+	require.NoError(t, writer.Write(&model.Movie{MovieId: 2, MovieName: "Heat"}))
+	require.NoError(t, writer.Close())
+
+	rows, err := parquet.Read[parquetMovie](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, []parquetMovie{
+		{ID: 1, Title: "The bear", Overview: "bear", TmdbID: 11, Runtime: 95},
+		{ID: 2, Title: "Heat"},
+	}, rows)
+}
+
+func TestNewWriter_UnsupportedFormat(t *testing.T) {
+	_, err := NewWriter("xml", &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,183 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/utils"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const maxRequestBytes = 1 << 20
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler serves GraphQL requests, as a JSON body on POST or as query parameters on GET. GET requests can only
+// run queries. The user is the one identified by the RequestContext middleware; requests with invalid credentials
+// are refused rather than run anonymously.
+type Handler struct {
+	schema        graphql.Schema
+	services      Services
+	maxDepth      int
+	maxComplexity int
+}
+
+func NewHandler(services Services, maxDepth, maxComplexity int) (*Handler, error) {
+	schema, err := NewSchema(services)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: schema, services: services, maxDepth: maxDepth, maxComplexity: maxComplexity}, nil
+}
+
+func (h *Handler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received graphql request")
+	if _, _, ok := req.BasicAuth(); ok && reqctx.User(req.Context()) == nil {
+		utils.ReturnUnauthorizedResponse(res)
+		return
+	}
+	var gqlReq request
+	switch req.Method {
+	case http.MethodGet:
+		query := req.URL.Query()
+		gqlReq.Query, gqlReq.OperationName = query.Get("query"), query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &gqlReq.Variables); err != nil {
+				returnErrors(res, http.StatusBadRequest, errors.New("variables should be a JSON object"))
+				return
+			}
+		}
+	case http.MethodPost:
+		req.Body = http.MaxBytesReader(res, req.Body, maxRequestBytes)
+		if err := json.NewDecoder(req.Body).Decode(&gqlReq); err != nil {
+			returnErrors(res, http.StatusBadRequest, errors.New("request body should be a GraphQL request in JSON"))
+			return
+		}
+	default:
+		res.Header().Set("Allow", "GET, POST")
+		returnErrors(res, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if gqlReq.Query == "" {
+		returnErrors(res, http.StatusBadRequest, errors.New("query should be present"))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(gqlReq.Query), Name: "GraphQL request"})})
+	if err != nil {
+		returnErrors(res, http.StatusBadRequest, err)
+		return
+	}
+	if validation := graphql.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		returnResult(res, http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+	operation := findOperation(doc, gqlReq.OperationName)
+	if operation == nil {
+		returnErrors(res, http.StatusBadRequest, errors.New("operation not found"))
+		return
+	}
+	if req.Method == http.MethodGet && operation.Operation != ast.OperationTypeQuery {
+		res.Header().Set("Allow", "POST")
+		returnErrors(res, http.StatusMethodNotAllowed, fmt.Errorf("%s operations should be sent with POST", operation.Operation))
+		return
+	}
+	if err = checkLimits(&h.schema, doc, operation, gqlReq.Variables, h.maxDepth, h.maxComplexity); err != nil {
+		returnErrors(res, http.StatusBadRequest, err)
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: gqlReq.OperationName,
+		Args:          gqlReq.Variables,
+		Context:       withLoaders(req.Context(), h.newLoaders()),
+	})
+	returnResult(res, http.StatusOK, result)
+}
+
+// newLoaders returns the loaders of a request, which batch the loads of its fields.
+func (h *Handler) newLoaders() *loaders {
+	return &loaders{
+		movies: newLoader(func(ctx context.Context, movieIds []int) (map[int]*model.Movie, error) {
+			movies, err := h.services.Movies.GetMany(ctx, movieIds)
+			if err != nil {
+				return nil, resolverError(err)
+			}
+			byId := make(map[int]*model.Movie, len(movies))
+			for _, movie := range movies {
+				byId[movie.MovieId] = movie
+			}
+			return byId, nil
+		}),
+		credits: newLoader(func(ctx context.Context, movieIds []int) (map[int]*model.MovieCredits, error) {
+			credits, err := h.services.Credits.CreditsOfMovies(ctx, movieIds)
+			return credits, resolverError(err)
+		}),
+		ratings: newLoader(func(ctx context.Context, movieIds []int) (map[int]*model.Rating, error) {
+			ratings, err := h.services.Ratings.UserRatings(ctx, reqctx.User(ctx).Username, movieIds)
+			if err != nil {
+				return nil, resolverError(err)
+			}
+			byMovie := make(map[int]*model.Rating, len(ratings))
+			for _, rating := range ratings {
+				byMovie[rating.MovieId] = rating
+			}
+			return byMovie, nil
+		}),
+		people: newLoader(func(ctx context.Context, personIds []int) (map[int]*model.Person, error) {
+			people, err := h.services.Credits.GetPeople(ctx, personIds)
+			return people, resolverError(err)
+		}),
+	}
+}
+
+// findOperation returns the operation of the document with the given name, or its only operation if no name is
+// given.
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+func returnErrors(res http.ResponseWriter, status int, err error) {
+	returnResult(res, status, &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}})
+}
+
+func returnResult(res http.ResponseWriter, status int, result *graphql.Result) {
+	responseBytes, err := json.Marshal(result)
+	if err != nil {
+		slog.Error("Error when marshalling graphql result", "error", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	utils.ReturnJsonResponse(res, status, responseBytes)
+}
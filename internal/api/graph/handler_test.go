@@ -0,0 +1,255 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/service"
+	"rest_api/internal/data/datatest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCreditRepository struct {
+	mock.Mock
+}
+
+func (r *mockCreditRepository) Replace(_ context.Context, movieId int, credits []*model.Credit) error {
+	args := r.Called(movieId, credits)
+	return args.Error(0)
+}
+
+func (r *mockCreditRepository) ListForMovie(_ context.Context, movieId int) ([]*model.Credit, error) {
+	args := r.Called(movieId)
+	return args.Get(0).([]*model.Credit), args.Error(1)
+}
+
+func (r *mockCreditRepository) ListForMovies(_ context.Context, movieIds []int) (map[int][]*model.Credit, error) {
+	args := r.Called(movieIds)
+	return args.Get(0).(map[int][]*model.Credit), args.Error(1)
+}
+
+func (r *mockCreditRepository) GetPerson(_ context.Context, personId int) (*model.Person, error) {
+	args := r.Called(personId)
+	return args.Get(0).(*model.Person), args.Error(1)
+}
+
+func (r *mockCreditRepository) GetPeople(_ context.Context, personIds []int) (map[int]*model.Person, error) {
+	args := r.Called(personIds)
+	return args.Get(0).(map[int]*model.Person), args.Error(1)
+}
+
+type response struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func newTestHandler(t *testing.T, movieRepository *datatest.MovieStore, creditRepository *mockCreditRepository) (*Handler, *service.MovieService) {
+	movieService := service.NewMovieService(movieRepository, nil)
+	h, err := NewHandler(Services{
+		Movies:  movieService,
+		Credits: service.NewCreditService(creditRepository, movieService, nil),
+	}, 8, 5000)
+	require.NoError(t, err)
+	return h, movieService
+}
+
+func serve(t *testing.T, h *Handler, req *http.Request) (int, response) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	var body response
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&body))
+	return w.Result().StatusCode, body
+}
+
+func post(query string, user *model.User) *http.Request {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	if user != nil {
+		req = req.WithContext(reqctx.WithUser(req.Context(), user))
+	}
+	return req
+}
+
+// sameIds matches the batches of the given ids, in any order since the fields of a level are resolved in no
+// particular order.
+func sameIds(ids ...int) any {
+	return mock.MatchedBy(func(batch []int) bool {
+		return assert.ElementsMatch(new(testing.T), ids, batch)
+	})
+}
+
+func TestHandler_BatchesLoads(t *testing.T) {
+	movieRepository := new(datatest.MovieStore)
+	movieRepository.On("GetMany", sameIds(1, 2)).Return([]*model.Movie{{MovieId: 1, MovieName: "Alien"}, {MovieId: 2, MovieName: "Aliens"}}, nil).Once()
+	creditRepository := new(mockCreditRepository)
+	creditRepository.On("ListForMovies", sameIds(1, 2)).Return(map[int][]*model.Credit{
+		1: {{Person: &model.Person{ID: 10, Name: "Sigourney Weaver"}, Role: model.CreditCast, Character: "Ripley"}},
+		2: {{Person: &model.Person{ID: 10, Name: "Sigourney Weaver"}, Role: model.CreditCast, Character: "Ripley"}},
+	}, nil).Once()
+	h, _ := newTestHandler(t, movieRepository, creditRepository)
+
+	status, body := serve(t, h, post(`{
+		first: movie(id: 1) { title cast { character person { name } } }
+		second: movie(id: 2) { title cast { character } }
+		again: movie(id: 1) { title }
+	}`, nil))
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, map[string]any{"title": "Alien", "cast": []any{map[string]any{"character": "Ripley", "person": map[string]any{"name": "Sigourney Weaver"}}}}, body.Data["first"])
+	assert.Equal(t, "Aliens", body.Data["second"].(map[string]any)["title"])
+	assert.Equal(t, "Alien", body.Data["again"].(map[string]any)["title"])
+	movieRepository.AssertExpectations(t)
+	creditRepository.AssertExpectations(t)
+}
+
+func TestHandler_BatchesPeople(t *testing.T) {
+	creditRepository := new(mockCreditRepository)
+	creditRepository.On("GetPeople", sameIds(10, 11)).Return(map[int]*model.Person{
+		10: {ID: 10, Name: "Sigourney Weaver", Filmography: []*model.FilmographyEntry{}},
+	}, nil).Once()
+	h, _ := newTestHandler(t, new(datatest.MovieStore), creditRepository)
+
+	status, body := serve(t, h, post(`{ weaver: person(id: 10) { name } unknown: person(id: 11) { name } }`, nil))
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]any{"name": "Sigourney Weaver"}, body.Data["weaver"])
+	assert.Nil(t, body.Data["unknown"])
+	creditRepository.AssertExpectations(t)
+}
+
+func TestHandler_PagesMoviesInTheDatabase(t *testing.T) {
+	movieRepository := new(datatest.MovieStore)
+	movieRepository.On("List", model.MovieFilter{Title: "alien", Limit: 2, Offset: 4}).
+		Return([]*model.Movie{{MovieId: 5, MovieName: "Alien"}, {MovieId: 6, MovieName: "Aliens"}}, nil).Once()
+	h, _ := newTestHandler(t, movieRepository, new(mockCreditRepository))
+
+	status, body := serve(t, h, post(`{ movies(title: "alien", limit: 2, offset: 4) { title } }`, nil))
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, []any{map[string]any{"title": "Alien"}, map[string]any{"title": "Aliens"}}, body.Data["movies"])
+	movieRepository.AssertExpectations(t)
+}
+
+func TestHandler_RejectsInvalidCredentials(t *testing.T) {
+	h, _ := newTestHandler(t, new(datatest.MovieStore), new(mockCreditRepository))
+	req := post(`{ movies { title } }`, nil)
+	// the RequestContext middleware did not identify a user from the credentials
+	req.SetBasicAuth("alice", "wrong")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestHandler_Mutations(t *testing.T) {
+	user := &model.User{Username: "alice"}
+
+	tests := []struct {
+		name       string
+		user       *model.User
+		query      string
+		setup      func(repository *datatest.MovieStore)
+		wantEvent  string
+		wantErrors []string
+	}{
+		{
+			name:       "requires a user",
+			query:      `mutation { deleteMovie(id: 1) }`,
+			wantErrors: []string{"authentication required"},
+		},
+		{
+			name:  "updates given fields",
+			user:  user,
+			query: `mutation { updateMovie(id: 1, input: {runtime: 117}) { title runtime } }`,
+			setup: func(repository *datatest.MovieStore) {
+				repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "Alien", Runtime: 100}, nil)
+				repository.On("Update", &model.Movie{MovieId: 1, MovieName: "Alien", Runtime: 117}).
+					Return(&model.Movie{MovieId: 1, MovieName: "Alien", Runtime: 117}, nil)
+			},
+			wantEvent: model.MovieUpdated,
+		},
+		{
+			name:  "rejects invalid release dates",
+			user:  user,
+			query: `mutation { updateMovie(id: 1, input: {releaseDate: "24/05/1979"}) { title } }`,
+			setup: func(repository *datatest.MovieStore) {
+				repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "Alien"}, nil)
+			},
+			wantErrors: []string{"releaseDate should be formatted as YYYY-MM-DD"},
+		},
+		{
+			name:      "deletes",
+			user:      user,
+			query:     `mutation { deleteMovie(id: 1) }`,
+			setup:     func(repository *datatest.MovieStore) { repository.On("Delete", 1).Return(nil) },
+			wantEvent: model.MovieDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movieRepository := new(datatest.MovieStore)
+			if tt.setup != nil {
+				tt.setup(movieRepository)
+			}
+			h, movieService := newTestHandler(t, movieRepository, new(mockCreditRepository))
+			var events []string
+			movieService.Subscribe(func(eventType string, _ *model.Movie) { events = append(events, eventType) })
+
+			status, body := serve(t, h, post(tt.query, tt.user))
+
+			assert.Equal(t, http.StatusOK, status)
+			var messages []string
+			for _, err := range body.Errors {
+				messages = append(messages, err.Message)
+			}
+			assert.Equal(t, tt.wantErrors, messages)
+			if tt.wantEvent != "" {
+				assert.Equal(t, []string{tt.wantEvent}, events)
+			} else {
+				assert.Empty(t, events)
+			}
+			movieRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_RejectsInvalidRequests(t *testing.T) {
+	h, _ := newTestHandler(t, new(datatest.MovieStore), new(mockCreditRepository))
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{"mutation over GET", httptest.NewRequest(http.MethodGet, "/graphql?query=mutation%7BdeleteMovie(id%3A1)%7D", nil), http.StatusMethodNotAllowed},
+		{"syntax error", post(`{ movie(id: 1) { title }`, nil), http.StatusBadRequest},
+		{"unknown field", post(`{ movie(id: 1) { budget } }`, nil), http.StatusBadRequest},
+		{"too deep", post(`{ person(id: 1) { filmography { movie { cast { person { filmography { movie { cast { person { name } } } } } } } } } }`, nil), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, h, tt.req)
+
+			assert.Equal(t, tt.wantStatus, status)
+			assert.NotEmpty(t, body.Errors)
+			assert.Nil(t, body.Data)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// defaultListSize is the assumed length of list fields without a limit argument when computing the complexity.
+const defaultListSize = 10
+
+// analysis computes the depth and the complexity of an operation. Every field costs 1, and the selections of a list
+// field are counted as many times as its limit argument, or defaultListSize. Introspection fields are free, so that
+// tools can always load the schema.
+type analysis struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// checkLimits returns an error if the operation is deeper or more complex than allowed.
+func checkLimits(schema *graphql.Schema, doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{},
+	maxDepth, maxComplexity int) error {
+	a := &analysis{schema: schema, fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			a.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	root := schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+
+	depth, complexity := a.selectionSet(operation.SelectionSet, root, 1, map[string]bool{})
+	if depth > maxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, maxDepth)
+	}
+	if complexity > maxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, maxComplexity)
+	}
+	return nil
+}
+
+// selectionSet returns the deepest level and the complexity of the selections, whose fields are at the given depth.
+func (a *analysis) selectionSet(set *ast.SelectionSet, parent *graphql.Object, depth int, spread map[string]bool) (int, int) {
+	if set == nil || parent == nil {
+		return depth - 1, 0
+	}
+	maxDepth, complexity := depth-1, 0
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = a.field(selection, parent, depth, spread)
+		case *ast.InlineFragment:
+			object := parent
+			if selection.TypeCondition != nil {
+				object, _ = a.schema.Type(selection.TypeCondition.Name.Value).(*graphql.Object)
+			}
+			d, c = a.selectionSet(selection.SelectionSet, object, depth, spread)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := a.fragments[name]
+			if !ok || spread[name] {
+				continue
+			}
+			spread[name] = true
+			object, _ := a.schema.Type(fragment.TypeCondition.Name.Value).(*graphql.Object)
+			d, c = a.selectionSet(fragment.SelectionSet, object, depth, spread)
+			delete(spread, name)
+		}
+		maxDepth = max(maxDepth, d)
+		complexity += c
+	}
+	return maxDepth, complexity
+}
+
+func (a *analysis) field(field *ast.Field, parent *graphql.Object, depth int, spread map[string]bool) (int, int) {
+	name := field.Name.Value
+	definition, ok := parent.Fields()[name]
+	if strings.HasPrefix(name, "__") || !ok {
+		return 0, 0
+	}
+
+	fieldType, list := graphql.Type(definition.Type), false
+	for {
+		if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+			fieldType = nonNull.OfType
+		} else if l, ok := fieldType.(*graphql.List); ok {
+			fieldType, list = l.OfType, true
+		} else {
+			break
+		}
+	}
+	object, _ := fieldType.(*graphql.Object)
+	childDepth, childComplexity := a.selectionSet(field.SelectionSet, object, depth+1, spread)
+	if list {
+		childComplexity *= a.listSize(field, definition)
+	}
+	return max(depth, childDepth), 1 + childComplexity
+}
+
+// listSize returns the limit argument of the field, or its default value.
+func (a *analysis) listSize(field *ast.Field, definition *graphql.FieldDefinition) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				return size
+			}
+		case *ast.Variable:
+			switch size := a.variables[value.Name.Value].(type) {
+			case int:
+				return size
+			case float64:
+				return int(size)
+			}
+		}
+	}
+	for _, argument := range definition.Args {
+		if size, ok := argument.DefaultValue.(int); ok && argument.Name() == "limit" {
+			return size
+		}
+	}
+	return defaultListSize
+}
@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLimits(t *testing.T) {
+	schema, err := NewSchema(Services{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		query          string
+		variables      map[string]interface{}
+		wantDepth      int
+		wantComplexity int
+	}{
+		{"list without limit", `{ genres { name } }`, nil, 2, 11},
+		{"default limit", `{ movies { title } }`, nil, 2, 51},
+		{"nested lists", `{ movies(limit: 5) { title cast { character } } }`, nil, 3, 61},
+		{"variable limit", `query($n: Int) { movies(limit: $n) { title } }`, map[string]interface{}{"n": float64(20)}, 2, 21},
+		{"fragments", `{ movie(id: 1) { ...f } } fragment f on Movie { title genres { name } }`, nil, 3, 13},
+		{"introspection", `{ __schema { types { name } } }`, nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			require.NoError(t, err)
+			operation := findOperation(doc, "")
+			require.NotNil(t, operation)
+
+			assert.NoError(t, checkLimits(&schema, doc, operation, tt.variables, tt.wantDepth, tt.wantComplexity))
+			if tt.wantDepth > 0 {
+				assert.EqualError(t, checkLimits(&schema, doc, operation, tt.variables, tt.wantDepth-1, tt.wantComplexity),
+					fmt.Sprintf("query depth %d exceeds the limit of %d", tt.wantDepth, tt.wantDepth-1))
+				assert.EqualError(t, checkLimits(&schema, doc, operation, tt.variables, tt.wantDepth, tt.wantComplexity-1),
+					fmt.Sprintf("query complexity %d exceeds the limit of %d", tt.wantComplexity, tt.wantComplexity-1))
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"sync"
+)
+
+// loader batches the loads of a request. graphql-go resolves the thunks returned by resolvers level by level, so
+// the keys loaded by all the fields of a level are fetched together when the first of their thunks is called.
+// Loaded keys are cached for the rest of the request.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+	batches map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	values map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, batches: map[K]*batch[K, V]{}}
+}
+
+// load returns a thunk resolving to the value of the key, or to nil if the fetch did not return the key.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (interface{}, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &batch[K, V]{}
+		}
+		b = l.pending
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		b.once.Do(func() {
+			l.mu.Lock()
+			if l.pending == b {
+				l.pending = nil
+			}
+			l.mu.Unlock()
+			b.values, b.err = l.fetch(ctx, b.keys)
+		})
+		if b.err != nil {
+			return nil, b.err
+		}
+		value, ok := b.values[key]
+		if !ok {
+			return nil, nil
+		}
+		return value, nil
+	}
+}
+
+type loadersKey struct{}
+
+// loaders are the loaders of a request.
+type loaders struct {
+	movies  *loader[int, *model.Movie]
+	credits *loader[int, *model.MovieCredits]
+	ratings *loader[int, *model.Rating]
+	people  *loader[int, *model.Person]
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersOf(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
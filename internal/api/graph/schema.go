@@ -0,0 +1,538 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/service"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultMoviesLimit = 50
+	maxMoviesLimit     = 100
+)
+
+var (
+	errAuthRequired = errors.New("authentication required")
+	errInternal     = errors.New("error when retrieving data")
+)
+
+// Services are the services the schema resolves fields with. Refresh may be nil, in which case created movies are
+// not queued for enrichment.
+type Services struct {
+	Movies     *service.MovieService
+	Genres     *service.GenreService
+	Credits    *service.CreditService
+	Ratings    *service.RatingService
+	Watchlists *service.WatchlistService
+	Refresh    *service.RefreshService
+}
+
+// NewSchema builds the schema of the catalog. Queries are public except for me, mutations need an authenticated
+// user. Movie mutations go through MovieService, so that they publish events and are audited like REST changes.
+func NewSchema(services Services) (graphql.Schema, error) {
+	b := &schemaBuilder{services: services}
+	return b.build()
+}
+
+type schemaBuilder struct {
+	services Services
+
+	genre, tag, movie, person, credit, filmographyEntry, rating, watchlist, watchlistItem, user *graphql.Object
+}
+
+func (b *schemaBuilder) build() (graphql.Schema, error) {
+	b.genre = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Genre",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return genreOf(p.Source).ID, nil
+			}},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return genreOf(p.Source).Name, nil
+			}},
+			"movieCount": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if genre, ok := p.Source.(*model.GenreCount); ok {
+					return genre.MovieCount, nil
+				}
+				return nil, nil
+			}},
+		},
+	})
+	b.tag = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Tag",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	b.person = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Person",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":                 &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"name":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"profilePath":        &graphql.Field{Type: graphql.String},
+				"knownForDepartment": &graphql.Field{Type: graphql.String},
+				"filmography":        &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(b.filmographyEntry)), Resolve: b.filmography},
+			}
+		}),
+	})
+	b.credit = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Credit",
+		Fields: graphql.Fields{
+			"person":     &graphql.Field{Type: graphql.NewNonNull(b.person)},
+			"character":  &graphql.Field{Type: graphql.String},
+			"job":        &graphql.Field{Type: graphql.String},
+			"department": &graphql.Field{Type: graphql.String},
+			"order":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	b.movie = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Movie",
+		Fields: graphql.Fields{
+			"id":              &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"overview":        &graphql.Field{Type: graphql.String},
+			"tmdbId":          &graphql.Field{Type: graphql.Int},
+			"runtime":         &graphql.Field{Type: graphql.Int},
+			"releaseDate":     &graphql.Field{Type: graphql.String},
+			"tmdbVoteAverage": &graphql.Field{Type: graphql.Float},
+			"ratingAverage":   &graphql.Field{Type: graphql.Float},
+			"ratingCount":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"genres":          &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(b.genre))), Resolve: movieGenres},
+			"tags":            &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(b.tag))), Resolve: movieTags},
+			"cast":            &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(b.credit)), Resolve: b.movieCredits(model.CreditCast)},
+			"crew":            &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(b.credit)), Resolve: b.movieCredits(model.CreditCrew)},
+			"myRating": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "The rating of the authenticated user, null for anonymous requests.",
+				Resolve:     b.myRating,
+			},
+		},
+	})
+	b.filmographyEntry = graphql.NewObject(graphql.ObjectConfig{
+		Name: "FilmographyEntry",
+		Fields: graphql.Fields{
+			"movie": &graphql.Field{Type: b.movie, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersOf(p.Context).movies.load(p.Context, p.Source.(*model.FilmographyEntry).Movie.MovieId), nil
+			}},
+			"role":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"character":  &graphql.Field{Type: graphql.String},
+			"job":        &graphql.Field{Type: graphql.String},
+			"department": &graphql.Field{Type: graphql.String},
+		},
+	})
+	b.rating = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Rating",
+		Fields: graphql.Fields{
+			"movie": &graphql.Field{Type: b.movie, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersOf(p.Context).movies.load(p.Context, p.Source.(*model.Rating).MovieId), nil
+			}},
+			"rating":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+	b.watchlistItem = graphql.NewObject(graphql.ObjectConfig{
+		Name: "WatchlistItem",
+		Fields: graphql.Fields{
+			"movie": &graphql.Field{Type: b.movie, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersOf(p.Context).movies.load(p.Context, p.Source.(*model.WatchlistItem).Movie.MovieId), nil
+			}},
+			"position":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"addedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"watched":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"watchedAt": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+	b.watchlist = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Watchlist",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"default":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"items":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(b.watchlistItem)))},
+		},
+	})
+	b.user = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"username": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"admin":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"curator":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"ratings": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(b.rating))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					ratings, err := b.services.Ratings.UserRatings(p.Context, p.Source.(*model.User).Username, nil)
+					return ratings, resolverError(err)
+				}},
+			"watchlists": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(b.watchlist))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					watchlists, err := b.services.Watchlists.List(p.Context, p.Source.(*model.User).Username)
+					return watchlists, resolverError(err)
+				}},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: b.query(), Mutation: b.mutation()})
+}
+
+func (b *schemaBuilder) query() *graphql.Object {
+	matchMode := graphql.NewEnum(graphql.EnumConfig{
+		Name: "MatchMode",
+		Values: graphql.EnumValueConfigMap{
+			"ANY": &graphql.EnumValueConfig{Value: false},
+			"ALL": &graphql.EnumValueConfig{Value: true},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"movies": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(b.movie))),
+				Args: graphql.FieldConfigArgument{
+					"title":      &graphql.ArgumentConfig{Type: graphql.String},
+					"minRuntime": &graphql.ArgumentConfig{Type: graphql.Int},
+					"maxRuntime": &graphql.ArgumentConfig{Type: graphql.Int},
+					"genres":     &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"genreMatch": &graphql.ArgumentConfig{Type: matchMode, DefaultValue: false},
+					"tags":       &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"tagMatch":   &graphql.ArgumentConfig{Type: matchMode, DefaultValue: false},
+					"person":     &graphql.ArgumentConfig{Type: graphql.Int},
+					"limit":      &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultMoviesLimit},
+					"offset":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: b.movies,
+			},
+			"movie": &graphql.Field{
+				Type: b.movie,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersOf(p.Context).movies.load(p.Context, p.Args["id"].(int)), nil
+				},
+			},
+			"genres": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(b.genre))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					genres, err := b.services.Genres.List(p.Context)
+					if err != nil {
+						return nil, resolverError(err)
+					}
+					return genres, nil
+				},
+			},
+			"person": &graphql.Field{
+				Type: b.person,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersOf(p.Context).people.load(p.Context, p.Args["id"].(int)), nil
+				},
+			},
+			"me": &graphql.Field{
+				Type:        b.user,
+				Description: "The authenticated user, with their ratings and watchlists.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return currentUser(p.Context)
+				},
+			},
+		},
+	})
+}
+
+func (b *schemaBuilder) mutation() *graphql.Object {
+	movieInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "MovieInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"overview":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"runtime":     &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"releaseDate": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Formatted as YYYY-MM-DD."},
+			"genreIds":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createMovie": &graphql.Field{
+				Type:        graphql.NewNonNull(b.movie),
+				Description: "Creates a movie, whose metadata is then fetched from TMDB in the background. Retries are not deduplicated.",
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.Int},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieInput)},
+				},
+				Resolve: b.createMovie,
+			},
+			"updateMovie": &graphql.Field{
+				Type:        graphql.NewNonNull(b.movie),
+				Description: "Changes the given fields of a movie.",
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieInput)},
+				},
+				Resolve: b.updateMovie,
+			},
+			"deleteMovie": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Moves a movie to the trash.",
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if _, err := currentUser(p.Context); err != nil {
+						return nil, err
+					}
+					if err := b.services.Movies.Delete(p.Context, p.Args["id"].(int)); err != nil {
+						return nil, resolverError(err)
+					}
+					return true, nil
+				},
+			},
+			"rateMovie": &graphql.Field{
+				Type:        graphql.NewNonNull(b.rating),
+				Description: "Rates a movie, or changes the rating the user already gave it.",
+				Args: graphql.FieldConfigArgument{
+					"movieId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"rating":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: b.rateMovie,
+			},
+		},
+	})
+}
+
+func (b *schemaBuilder) movies(p graphql.ResolveParams) (interface{}, error) {
+	limit, offset := p.Args["limit"].(int), p.Args["offset"].(int)
+	if limit < 1 || limit > maxMoviesLimit {
+		return nil, fmt.Errorf("limit should be between 1 and %d", maxMoviesLimit)
+	}
+	if offset < 0 {
+		return nil, errors.New("offset should not be negative")
+	}
+	filter := model.MovieFilter{
+		Genres:         labels(p.Args["genres"]),
+		MatchAllGenres: p.Args["genreMatch"].(bool),
+		Tags:           labels(p.Args["tags"]),
+		MatchAllTags:   p.Args["tagMatch"].(bool),
+		Limit:          limit,
+		Offset:         offset,
+	}
+	filter.Title, _ = p.Args["title"].(string)
+	filter.Title = strings.TrimSpace(filter.Title)
+	filter.MinRuntime, _ = p.Args["minRuntime"].(int)
+	filter.MaxRuntime, _ = p.Args["maxRuntime"].(int)
+	filter.PersonId, _ = p.Args["person"].(int)
+
+	movies, err := b.services.Movies.List(filter)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return movies, nil
+}
+
+func (b *schemaBuilder) movieCredits(role string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		thunk := loadersOf(p.Context).credits.load(p.Context, p.Source.(*model.Movie).MovieId)
+		return func() (interface{}, error) {
+			credits, err := thunk()
+			if credits == nil || err != nil {
+				return nil, err
+			}
+			if role == model.CreditCast {
+				return credits.(*model.MovieCredits).Cast, nil
+			}
+			return credits.(*model.MovieCredits).Crew, nil
+		}, nil
+	}
+}
+
+func (b *schemaBuilder) myRating(p graphql.ResolveParams) (interface{}, error) {
+	if reqctx.User(p.Context) == nil {
+		return nil, nil
+	}
+	thunk := loadersOf(p.Context).ratings.load(p.Context, p.Source.(*model.Movie).MovieId)
+	return func() (interface{}, error) {
+		rating, err := thunk()
+		if rating == nil || err != nil {
+			return nil, err
+		}
+		return rating.(*model.Rating).Rating, nil
+	}, nil
+}
+
+// filmography returns the filmography of the person, loading it for people listed in credits.
+func (b *schemaBuilder) filmography(p graphql.ResolveParams) (interface{}, error) {
+	person := p.Source.(*model.Person)
+	if person.Filmography != nil {
+		return person.Filmography, nil
+	}
+	thunk := loadersOf(p.Context).people.load(p.Context, person.ID)
+	return func() (interface{}, error) {
+		loaded, err := thunk()
+		if loaded == nil || err != nil {
+			return nil, err
+		}
+		return loaded.(*model.Person).Filmography, nil
+	}, nil
+}
+
+func (b *schemaBuilder) createMovie(p graphql.ResolveParams) (interface{}, error) {
+	if _, err := currentUser(p.Context); err != nil {
+		return nil, err
+	}
+	movie := &model.Movie{}
+	movie.MovieId, _ = p.Args["id"].(int)
+	if err := applyMovieInput(movie, p.Args["input"].(map[string]interface{})); err != nil {
+		return nil, err
+	}
+	created, err := b.services.Movies.Create(p.Context, movie)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if b.services.Refresh != nil {
+		if _, err = b.services.Refresh.EnqueueEnrichment(p.Context, created.MovieId); err != nil {
+			slog.Warn("Unable to enqueue movie enrichment", "movieId", created.MovieId, "error", err)
+		}
+	}
+	return created, nil
+}
+
+func (b *schemaBuilder) updateMovie(p graphql.ResolveParams) (interface{}, error) {
+	if _, err := currentUser(p.Context); err != nil {
+		return nil, err
+	}
+	current, err := b.services.Movies.Get(p.Args["id"].(int))
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	movie := *current
+	if err = applyMovieInput(&movie, p.Args["input"].(map[string]interface{})); err != nil {
+		return nil, err
+	}
+	updated, err := b.services.Movies.Update(p.Context, &movie)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return updated, nil
+}
+
+func (b *schemaBuilder) rateMovie(p graphql.ResolveParams) (interface{}, error) {
+	user, err := currentUser(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	rating := &model.Rating{MovieId: p.Args["movieId"].(int), Username: user.Username, Rating: p.Args["rating"].(int)}
+	rated, err := b.services.Ratings.Rate(p.Context, rating)
+	var cErr model.ConflictError
+	if errors.As(err, &cErr) {
+		rated, err = b.services.Ratings.UpdateRating(p.Context, rating)
+	}
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return rated, nil
+}
+
+// applyMovieInput sets the fields given in the input on the movie, and checks that it has a title and a valid
+// release date.
+func applyMovieInput(movie *model.Movie, input map[string]interface{}) error {
+	if title, ok := input["title"].(string); ok {
+		movie.MovieName = strings.TrimSpace(title)
+	}
+	if overview, ok := input["overview"].(string); ok {
+		movie.Overview = overview
+	}
+	if runtime, ok := input["runtime"].(int); ok {
+		movie.Runtime = runtime
+	}
+	if releaseDate, ok := input["releaseDate"].(string); ok {
+		movie.ReleaseDate = releaseDate
+	}
+	if genreIds, ok := input["genreIds"].([]interface{}); ok {
+		movie.Genres = []model.Genre{}
+		for _, id := range genreIds {
+			movie.Genres = append(movie.Genres, model.Genre{ID: id.(int)})
+		}
+	}
+
+	if movie.MovieName == "" {
+		return errors.New("title should be present")
+	}
+	if movie.Runtime < 0 {
+		return errors.New("runtime should not be negative")
+	}
+	if _, err := time.Parse(time.DateOnly, movie.ReleaseDate); movie.ReleaseDate != "" && err != nil {
+		return errors.New("releaseDate should be formatted as YYYY-MM-DD")
+	}
+	return nil
+}
+
+func movieGenres(p graphql.ResolveParams) (interface{}, error) {
+	genres := p.Source.(*model.Movie).Genres
+	result := make([]*model.Genre, len(genres))
+	for i := range genres {
+		result[i] = &genres[i]
+	}
+	return result, nil
+}
+
+// genreOf returns the genre of a movie or of the genre list.
+func genreOf(source interface{}) *model.Genre {
+	if genre, ok := source.(*model.GenreCount); ok {
+		return &genre.Genre
+	}
+	return source.(*model.Genre)
+}
+
+func movieTags(p graphql.ResolveParams) (interface{}, error) {
+	tags := p.Source.(*model.Movie).Tags
+	result := make([]*model.Tag, len(tags))
+	for i := range tags {
+		result[i] = &tags[i]
+	}
+	return result, nil
+}
+
+// labels returns the lower-cased names of a list argument.
+func labels(arg interface{}) []string {
+	values, _ := arg.([]interface{})
+	var names []string
+	for _, value := range values {
+		if name := strings.ToLower(strings.TrimSpace(value.(string))); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func currentUser(ctx context.Context) (*model.User, error) {
+	user := reqctx.User(ctx)
+	if user == nil {
+		return nil, errAuthRequired
+	}
+	return user, nil
+}
+
+// resolverError maps the errors of the services to the errors reported to clients. Unexpected errors are already
+// logged by the services and reported without details.
+func resolverError(err error) error {
+	var nfErr model.NotFoundError
+	var cErr model.ConflictError
+	var vErr model.ValidationError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &nfErr):
+		return errors.New("not found")
+	case errors.As(err, &cErr):
+		return errors.New("already exists")
+	case errors.As(err, &vErr):
+		return errors.New(vErr.Message)
+	}
+	return errInternal
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetAssetUploadURL(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST asset upload url request")
+	vars := mux.Vars(req)
+
+	movieId := validateIDParam(vars["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	presigned, err := h.AssetService.UploadURL(req.Context(), movieId, vars["assetType"])
+	if err != nil {
+		returnAssetErrorResponse(err, "No movie with provided id exists", res)
+		return
+	}
+
+	responseJSON, err := json.Marshal(presigned)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, responseJSON)
+}
+
+func (h *Handler) ConfirmAssetUpload(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST asset confirmation request")
+	vars := mux.Vars(req)
+
+	movieId := validateIDParam(vars["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	var asset *model.MovieAsset
+	defer req.Body.Close()
+	err := json.NewDecoder(req.Body).Decode(&asset)
+	if err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+	if asset.ObjectKey == "" {
+		returnErrorResponse("key parameter should be present", http.StatusBadRequest, res)
+		return
+	}
+	asset.MovieId = movieId
+
+	createdAsset, err := h.AssetService.Confirm(req.Context(), asset)
+	if err != nil {
+		var cErr model.ConflictError
+		if errors.As(err, &cErr) {
+			returnErrorResponse("An asset with the provided key already exists", http.StatusConflict, res)
+			return
+		}
+		returnAssetErrorResponse(err, "No movie with provided id exists", res)
+		return
+	}
+
+	assetJSON, err := json.Marshal(createdAsset)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusCreated, assetJSON)
+}
+
+func (h *Handler) GetAssets(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET assets request")
+	vars := mux.Vars(req)
+
+	movieId := validateIDParam(vars["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	assets, err := h.AssetService.GetAll(movieId)
+	if err != nil {
+		returnAssetErrorResponse(err, "No movie with provided id exists", res)
+		return
+	}
+
+	assetsJSON, err := json.Marshal(assets)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, assetsJSON)
+}
+
+func (h *Handler) GetAssetDownloadURL(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET asset download url request")
+	vars := mux.Vars(req)
+
+	movieId := validateIDParam(vars["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	assetId := validateIDParam(vars["assetId"], res)
+	if assetId == 0 {
+		return
+	}
+
+	presigned, err := h.AssetService.DownloadURL(req.Context(), movieId, assetId)
+	if err != nil {
+		returnAssetErrorResponse(err, "No asset with provided id exists", res)
+		return
+	}
+
+	responseJSON, err := json.Marshal(presigned)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, responseJSON)
+}
+
+func returnAssetErrorResponse(err error, notFoundMessage string, res http.ResponseWriter) {
+	var nfErr model.NotFoundError
+	if errors.As(err, &nfErr) {
+		returnErrorResponse(notFoundMessage, http.StatusNotFound, res)
+		return
+	}
+	var vErr model.ValidationError
+	if errors.As(err, &vErr) {
+		returnErrorResponse(vErr.Message, http.StatusBadRequest, res)
+		return
+	}
+	returnErrorResponse("Unexpected error when handling movie assets", http.StatusInternalServerError, res)
+}
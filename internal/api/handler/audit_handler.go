@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+func (h *Handler) GetAuditLog(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET audit log request")
+
+	filter, err := parseAuditFilter(req)
+	if err != nil {
+		returnErrorResponse(err.Error(), http.StatusBadRequest, res)
+		return
+	}
+
+	entries, err := h.AuditService.List(req.Context(), filter)
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	entriesJSON, err := json.Marshal(entries)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, entriesJSON)
+}
+
+// parseAuditFilter reads the entity, entityId, actor, from, to and limit query parameters. The time range
+// bounds are RFC 3339 timestamps, from being inclusive and to exclusive.
+func parseAuditFilter(req *http.Request) (model.AuditFilter, error) {
+	query := req.URL.Query()
+	filter := model.AuditFilter{
+		Entity:   query.Get("entity"),
+		EntityID: query.Get("entityId"),
+		Actor:    query.Get("actor"),
+		Limit:    defaultAuditLimit,
+	}
+	if filter.Entity != "" && filter.Entity != model.AuditEntityMovie && filter.Entity != model.AuditEntityUser {
+		return filter, fmt.Errorf("entity should be %s or %s", model.AuditEntityMovie, model.AuditEntityUser)
+	}
+	for param, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if !query.Has(param) {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, query.Get(param))
+		if err != nil {
+			return filter, fmt.Errorf("%s should be an RFC 3339 timestamp", param)
+		}
+		*bound = value
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, fmt.Errorf("from should be before to")
+	}
+	if query.Has("limit") {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			return filter, fmt.Errorf("limit should be a number between 1 and %d", maxAuditLimit)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/utils"
+)
+
+// GetMovieCacheStats returns the counters of the movie cache of this replica.
+func (h *Handler) GetMovieCacheStats(res http.ResponseWriter, _ *http.Request) {
+	slog.Info("Received GET movie cache stats request")
+
+	responseJSON, err := json.Marshal(h.MovieCache.Stats())
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, responseJSON)
+}
+
+// FlushMovieCache drops the movies cached by this replica, which are read from the database again.
+func (h *Handler) FlushMovieCache(res http.ResponseWriter, _ *http.Request) {
+	slog.Info("Received POST movie cache flush request")
+
+	h.MovieCache.Flush()
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// collectionRequest is the body of the creation and update of a manual collection. The movies are listed in
+// the order of the collection.
+type collectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MovieIds    []int  `json:"movieIds"`
+}
+
+func (h *Handler) GetCollections(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET collections request")
+
+	collections, err := h.CollectionService.List(req.Context())
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	returnCollectionResponse(collections, http.StatusOK, res)
+}
+
+func (h *Handler) GetCollection(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET collection request")
+
+	collectionId := validateIDParam(mux.Vars(req)["collectionId"], res)
+	if collectionId == 0 {
+		return
+	}
+
+	collection, err := h.CollectionService.Get(req.Context(), collectionId)
+	if err != nil {
+		returnCollectionErrorResponse(err, res)
+		return
+	}
+
+	returnCollectionResponse(collection, http.StatusOK, res)
+}
+
+func (h *Handler) CreateCollection(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST collection request")
+
+	body, ok := decodeCollection(res, req)
+	if !ok {
+		return
+	}
+
+	collection := &model.Collection{Name: body.Name, Description: body.Description, CreatedBy: reqctx.User(req.Context()).Username}
+	created, err := h.CollectionService.Create(req.Context(), collection, body.MovieIds)
+	if err != nil {
+		returnCollectionErrorResponse(err, res)
+		return
+	}
+
+	returnCollectionResponse(created, http.StatusCreated, res)
+}
+
+func (h *Handler) UpdateCollection(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT collection request")
+
+	collectionId := validateIDParam(mux.Vars(req)["collectionId"], res)
+	if collectionId == 0 {
+		return
+	}
+	body, ok := decodeCollection(res, req)
+	if !ok {
+		return
+	}
+
+	collection := &model.Collection{ID: collectionId, Name: body.Name, Description: body.Description}
+	updated, err := h.CollectionService.Update(req.Context(), collection, body.MovieIds)
+	if err != nil {
+		returnCollectionErrorResponse(err, res)
+		return
+	}
+
+	returnCollectionResponse(updated, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteCollection(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE collection request")
+
+	collectionId := validateIDParam(mux.Vars(req)["collectionId"], res)
+	if collectionId == 0 {
+		return
+	}
+
+	if err := h.CollectionService.Delete(req.Context(), collectionId); err != nil {
+		returnCollectionErrorResponse(err, res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+func decodeCollection(res http.ResponseWriter, req *http.Request) (*collectionRequest, bool) {
+	body := &collectionRequest{}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(body); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return nil, false
+	}
+	return body, true
+}
+
+func returnCollectionResponse(value any, status int, res http.ResponseWriter) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, status, valueJSON)
+}
+
+func returnCollectionErrorResponse(err error, res http.ResponseWriter) {
+	var vErr model.ValidationError
+	var nfErr model.NotFoundError
+	switch {
+	case errors.As(err, &vErr):
+		returnErrorResponse(vErr.Message, http.StatusBadRequest, res)
+	case errors.As(err, &nfErr):
+		returnErrorResponse("No collection with provided id exists", http.StatusNotFound, res)
+	default:
+		returnErrorResponse("Unexpected error when accessing collections", http.StatusInternalServerError, res)
+	}
+}
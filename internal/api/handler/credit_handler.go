@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetMovieCredits(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie credits request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	credits, err := h.CreditService.MovieCredits(req.Context(), movieId)
+	if err != nil {
+		returnCreditErrorResponse(err, "No movie with provided id exists", res)
+		return
+	}
+
+	returnCreditResponse(credits, res)
+}
+
+// GetPerson returns a person with the movies they are credited in.
+func (h *Handler) GetPerson(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET person request")
+
+	personId := validateIDParam(mux.Vars(req)["personId"], res)
+	if personId == 0 {
+		return
+	}
+
+	person, err := h.CreditService.GetPerson(req.Context(), personId)
+	if err != nil {
+		returnCreditErrorResponse(err, "No person with provided id exists", res)
+		return
+	}
+
+	returnCreditResponse(person, res)
+}
+
+func returnCreditResponse(value any, res http.ResponseWriter) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, valueJSON)
+}
+
+func returnCreditErrorResponse(err error, notFoundMessage string, res http.ResponseWriter) {
+	var nfErr model.NotFoundError
+	if errors.As(err, &nfErr) {
+		returnErrorResponse(notFoundMessage, http.StatusNotFound, res)
+		return
+	}
+	returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+}
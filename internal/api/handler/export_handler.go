@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/export"
+	"time"
+)
+
+// ExportMovies streams the movies matching the list filters in the requested format. Once streaming has started
+// the status cannot change anymore, so a failure aborts the connection to let the client know the export is
+// incomplete. The write timeout of the server does not apply, as a large export takes longer to stream.
+func (h *Handler) ExportMovies(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie export request")
+
+	err := http.NewResponseController(res).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("Unable to clear the write deadline of the movie export", "error", err)
+	}
+
+	filter, err := parseMovieFilter(req)
+	if err != nil {
+		returnErrorResponse(err.Error(), http.StatusBadRequest, res)
+		return
+	}
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	writer, err := export.NewWriter(format, res)
+	if err != nil {
+		if errors.Is(err, export.ErrUnsupportedFormat) {
+			returnErrorResponse("format should be csv, ndjson or parquet", http.StatusBadRequest, res)
+			return
+		}
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+	res.Header().Set("Content-Type", export.ContentType(format))
+	res.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="movies.%s"`, format))
+
+	count, err := h.ExportService.Export(req.Context(), filter, writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		slog.Error("Aborting movie export", "exported", count, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	slog.Info("Finished movie export", "format", format, "exported", count)
+}
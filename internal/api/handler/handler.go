@@ -1,30 +1,59 @@
 package handler
 
 import (
-	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
-	"rest_api/internal/api/kafka"
 	"rest_api/internal/api/model"
+	"rest_api/internal/api/negotiate"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/rpc"
 	"rest_api/internal/api/service"
 	"rest_api/internal/api/tmdb"
 	"rest_api/internal/api/utils"
 	"rest_api/internal/data"
+	"rest_api/internal/queue"
+	"rest_api/internal/scheduler"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
 
+const requestIDHeader = "X-Request-ID"
+
 type Handler struct {
-	UserRepository *data.UserRepository
-	MovieService   *service.MovieService
-	TmdbService    *tmdb.Service
-	Publisher      kafka.Publisher
+	UserRepository        *data.UserRepository
+	MovieService          *service.MovieService
+	MovieCache            *service.MovieCache
+	TmdbService           *tmdb.Service
+	AssetService          *service.AssetService
+	Scheduler             *scheduler.Scheduler
+	Elector               *scheduler.Elector
+	RefreshService        *service.RefreshService
+	ImportService         *service.ImportService
+	ExportService         *service.ExportService
+	Queue                 *queue.Queue
+	UserService           *service.UserService
+	AuditService          *service.AuditService
+	RevisionService       *service.RevisionService
+	WatchlistService      *service.WatchlistService
+	RatingService         *service.RatingService
+	GenreService          *service.GenreService
+	TagService            *service.TagService
+	CreditService         *service.CreditService
+	CollectionService     *service.CollectionService
+	SimilarityService     *service.SimilarityService
+	RecommendationService *service.RecommendationService
+	IdempotencyService    *service.IdempotencyService
+	RPCMetrics            *rpc.Metrics
 }
 
 func (h *Handler) PingHandler(res http.ResponseWriter, _ *http.Request) {
@@ -32,53 +61,183 @@ func (h *Handler) PingHandler(res http.ResponseWriter, _ *http.Request) {
 	utils.ReturnJsonResponse(res, http.StatusOK, responseBytes)
 }
 
+// RequestContext assigns an id to every request, echoed in the X-Request-ID header, and identifies the user of
+// the basic auth credentials so that changes can be attributed in the audit log. Requests without valid
+// credentials are not rejected here, only by BasicAuth and AdminAuth.
+func (h *Handler) RequestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requestId := req.Header.Get(requestIDHeader)
+		if !reqctx.ValidRequestID(requestId) {
+			requestId = uuid.NewString()
+		}
+		res.Header().Set(requestIDHeader, requestId)
+		ctx := reqctx.WithRequestID(req.Context(), requestId)
+
+		user, err := h.verifyCredentials(req)
+		if err != nil {
+			slog.Error("Error when getting user from db", "error", err)
+		}
+		if user != nil {
+			ctx = reqctx.WithUser(ctx, user)
+		}
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
 func (h *Handler) BasicAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
-		username, password, ok := req.BasicAuth()
-		if ok {
-			//usernameHash := sha256.Sum256([]byte(username))
-			user, err := h.UserRepository.GetUser(username)
-			if err != nil {
-				var nferr *model.NotFoundError
-				if errors.As(err, &nferr) {
-					utils.ReturnUnauthorizedResponse(res)
-					return
-				}
-				log.Printf("Error when getting user from db: %s\n", err)
-				responseBytes := createResponse(false, "Error when accessing user list. Please try again")
-				utils.ReturnJsonResponse(res, http.StatusInternalServerError, responseBytes)
-				return
-			}
-			passwordHash := sha256.Sum256([]byte(password))
-			//expectedUsernameHash := sha256.Sum256([]byte(app.auth.username))
-			expectedPasswordHash := sha256.Sum256([]byte(user.Password))
+		if user, ok := h.authenticate(res, req); ok {
+			next.ServeHTTP(res, req.WithContext(reqctx.WithUser(req.Context(), user)))
+		}
+	}
+}
 
-			//usernameMatch := (subtle.ConstantTimeCompare(usernameHash[:], expectedUsernameHash[:]) == 1)
-			passwordMatch := subtle.ConstantTimeCompare(passwordHash[:], expectedPasswordHash[:]) == 1
+// AdminAuth is like BasicAuth but additionally requires the user to be an admin.
+func (h *Handler) AdminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		user, ok := h.authenticate(res, req)
+		if !ok {
+			return
+		}
+		if !user.Admin {
+			returnErrorResponse("Admin access required", http.StatusForbidden, res)
+			return
+		}
+		next.ServeHTTP(res, req.WithContext(reqctx.WithUser(req.Context(), user)))
+	}
+}
 
-			if passwordMatch {
-				next.ServeHTTP(res, req)
-				return
-			}
+// CuratorAuth is like BasicAuth but additionally requires the user to be a curator or an admin.
+func (h *Handler) CuratorAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		user, ok := h.authenticate(res, req)
+		if !ok {
+			return
+		}
+		if !user.Curator && !user.Admin {
+			returnErrorResponse("Curator access required", http.StatusForbidden, res)
+			return
 		}
+		next.ServeHTTP(res, req.WithContext(reqctx.WithUser(req.Context(), user)))
+	}
+}
+
+// authenticate returns the user of the request, already identified by RequestContext or checked from the basic
+// auth credentials. If they are not valid, an error response is written and false is returned.
+func (h *Handler) authenticate(res http.ResponseWriter, req *http.Request) (*model.User, bool) {
+	if user := reqctx.User(req.Context()); user != nil {
+		return user, true
+	}
+	user, err := h.verifyCredentials(req)
+	if err != nil {
+		log.Printf("Error when getting user from db: %s\n", err)
+		responseBytes := createResponse(false, "Error when accessing user list. Please try again")
+		utils.ReturnJsonResponse(res, http.StatusInternalServerError, responseBytes)
+		return nil, false
+	}
+	if user == nil {
 		utils.ReturnUnauthorizedResponse(res)
+		return nil, false
 	}
+	return user, true
 }
 
-func (h *Handler) GetMovies(res http.ResponseWriter, _ *http.Request) {
+// verifyCredentials returns the user of the basic auth credentials of the request, or nil if they are missing
+// or invalid.
+func (h *Handler) verifyCredentials(req *http.Request) (*model.User, error) {
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	//usernameHash := sha256.Sum256([]byte(username))
+	user, err := h.UserRepository.GetUser(username)
+	if err != nil {
+		var nferr *model.NotFoundError
+		if errors.As(err, &nferr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !utils.PasswordMatches(password, user.Password) {
+		return nil, nil
+	}
+	return user, nil
+}
+
+func (h *Handler) GetMovies(res http.ResponseWriter, req *http.Request) {
 	slog.Info("Received GET movies request")
 
-	movies, err := h.MovieService.GetAll()
+	filter, err := parseMovieFilter(req)
+	if err != nil {
+		returnErrorResponse(err.Error(), http.StatusBadRequest, res)
+		return
+	}
+
+	lastModified, err := h.MovieService.LastModified(req.Context())
 	if err != nil {
 		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+	if notModified(res, req, lastModified) {
+		return
 	}
-	movieJSON, err := json.Marshal(&movies)
+	movies, err := h.MovieService.List(filter)
 	if err != nil {
-		slog.Error("Error when marshalling the response data: %s\n", err)
-		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+	returnMovieResponse(movies, http.StatusOK, res, req)
+}
+
+// parseMovieFilter reads the movie filter from the query parameters.
+func parseMovieFilter(req *http.Request) (model.MovieFilter, error) {
+	query := req.URL.Query()
+	filter := model.MovieFilter{Title: strings.TrimSpace(query.Get("title"))}
+	numberParams := []struct {
+		name  string
+		value *int
+	}{{"minRuntime", &filter.MinRuntime}, {"maxRuntime", &filter.MaxRuntime}, {"person", &filter.PersonId}}
+	for _, param := range numberParams {
+		if !query.Has(param.name) {
+			continue
+		}
+		parsed, err := strconv.Atoi(query.Get(param.name))
+		if err != nil || parsed < 0 {
+			return model.MovieFilter{}, fmt.Errorf("%s should be a positive number", param.name)
+		}
+		*param.value = parsed
 	}
 
-	utils.ReturnJsonResponse(res, http.StatusOK, movieJSON)
+	labelParams := []struct {
+		name     string
+		names    *[]string
+		matchAll *bool
+	}{{"genre", &filter.Genres, &filter.MatchAllGenres}, {"tag", &filter.Tags, &filter.MatchAllTags}}
+	for _, param := range labelParams {
+		*param.names = parseLabels(query[param.name])
+		switch match := query.Get(param.name + "Match"); match {
+		case "", "any":
+		case "all":
+			*param.matchAll = true
+		default:
+			return model.MovieFilter{}, fmt.Errorf("%sMatch should be any or all", param.name)
+		}
+	}
+	return filter, nil
+}
+
+// parseLabels returns the distinct lower-cased names of the values, each of which can be a comma separated list.
+func parseLabels(values []string) []string {
+	var labels []string
+	for _, value := range values {
+		for _, label := range strings.Split(value, ",") {
+			label = strings.ToLower(strings.TrimSpace(label))
+			if label != "" && !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels
 }
 
 func (h *Handler) GetMovie(res http.ResponseWriter, req *http.Request) {
@@ -102,27 +261,25 @@ func (h *Handler) GetMovie(res http.ResponseWriter, req *http.Request) {
 		utils.ReturnJsonResponse(res, http.StatusInternalServerError, responseBytes)
 		return
 	}
-
-	movieJSON, err := json.Marshal(&movie)
-	if err != nil {
-		slog.Error("Error when marshalling the response data: %s\n", err)
-		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+	if notModified(res, req, movie.UpdatedAt) {
 		return
 	}
 
-	utils.ReturnJsonResponse(res, http.StatusOK, movieJSON)
+	returnMovieResponse(movie, http.StatusOK, res, req)
 }
 
 func (h *Handler) AddMovie(res http.ResponseWriter, req *http.Request) {
 	slog.Info("Received POST movie request")
-	var movie *model.Movie
-
-	payload := req.Body
+	movie := &model.Movie{}
 
 	defer req.Body.Close()
-	err := json.NewDecoder(payload).Decode(&movie)
+	err := negotiate.Decode(req, movie)
+	if errors.Is(err, negotiate.ErrUnsupportedMediaType) {
+		returnUnsupportedMediaType(res)
+		return
+	}
 	if err != nil {
-		slog.Error("Error when unmarshalling the request body: %s\n", err)
+		slog.Error("Error when unmarshalling the request body", "error", err)
 		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
 		return
 	}
@@ -134,31 +291,13 @@ func (h *Handler) AddMovie(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	var movieInfo *tmdb.Movie
-	//if movie.MovieName != "" {
-	response, err := h.TmdbService.GetMovieByTitle(movie.MovieName)
+	movieToPersist, err := h.movieFromTmdb(movie)
 	if err != nil {
 		returnErrorResponse("Could not create movie. A movie with the provided name does not exist", http.StatusBadRequest, res)
 		return
 	}
-	movieInfo = response
-	/*} else if movie.MovieId != 0 {
-		response, err := h.TmdbService.GetMovieByID(movie.MovieId)
-		if err != nil {
-			returnErrorResponse("Could not create movie. A movie with the provided name does not exist", http.StatusBadRequest, res)
-			return
-		}
-		movieInfo = response
-	}
-	*/
-	// todo check models etc
-	movieToPersist := &model.Movie{
-		MovieId:   movie.MovieId,
-		MovieName: movie.MovieName,
-		Overview:  movieInfo.Overview,
-	}
 	// handle by id as well
-	createdMovie, err := h.MovieService.Create(movieToPersist)
+	createdMovie, err := h.MovieService.Create(req.Context(), movieToPersist)
 
 	if err != nil {
 		var cErr model.ConflictError
@@ -169,22 +308,36 @@ func (h *Handler) AddMovie(res http.ResponseWriter, req *http.Request) {
 		returnErrorResponse("Unexpected error when creating data", http.StatusConflict, res)
 		return
 	}
+	h.enqueueEnrichment(req, createdMovie)
 
-	movieJSON, err := json.Marshal(createdMovie)
-	if err != nil {
-		slog.Error("Error when marshalling the response data: %s\n", err)
-		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
-		return
-	}
+	returnMovieResponse(createdMovie, http.StatusCreated, res, req)
+}
 
-	//publish event
-	err = h.Publisher.Publish(string(movieJSON))
+// movieFromTmdb returns the movie to create, with the details of the TMDB movie with its title.
+func (h *Handler) movieFromTmdb(movie *model.Movie) (*model.Movie, error) {
+	movieInfo, err := h.TmdbService.GetMovieByTitle(movie.MovieName)
 	if err != nil {
-		slog.Error("Error when publishing movie", "error", err)
-		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return nil, err
 	}
+	// todo check models etc
+	return &model.Movie{
+		MovieId:         movie.MovieId,
+		MovieName:       movie.MovieName,
+		Overview:        movieInfo.Overview,
+		TmdbId:          movieInfo.ID,
+		Runtime:         int(movieInfo.Runtime),
+		TmdbVoteAverage: movieInfo.RoundedVoteAverage(),
+		ReleaseDate:     movieInfo.ReleaseDate,
+		Genres:          model.GenresOf(movieInfo.GenreIDs()),
+	}, nil
+}
 
-	utils.ReturnJsonResponse(res, http.StatusCreated, movieJSON)
+// enqueueEnrichment enqueues the fetch of the details and credits of the created movie, which is returned without
+// them.
+func (h *Handler) enqueueEnrichment(req *http.Request, movie *model.Movie) {
+	if _, err := h.RefreshService.EnqueueEnrichment(req.Context(), movie.MovieId); err != nil {
+		slog.Warn("Unable to enqueue movie enrichment", "movieId", movie.MovieId, "error", err)
+	}
 }
 
 func (h *Handler) UpdateMovie(res http.ResponseWriter, req *http.Request) {
@@ -196,14 +349,16 @@ func (h *Handler) UpdateMovie(res http.ResponseWriter, req *http.Request) {
 	if movieId == 0 {
 		return
 	}
-	var movie *model.Movie
-
-	payload := req.Body
+	movie := &model.Movie{}
 
 	defer req.Body.Close()
-	err := json.NewDecoder(payload).Decode(&movie)
+	err := negotiate.Decode(req, movie)
+	if errors.Is(err, negotiate.ErrUnsupportedMediaType) {
+		returnUnsupportedMediaType(res)
+		return
+	}
 	if err != nil {
-		slog.Error("Error when unmarshalling the request body: %s\n", err)
+		slog.Error("Error when unmarshalling the request body", "error", err)
 		returnErrorResponse("Could not parse request body", http.StatusInternalServerError, res)
 		return
 	}
@@ -212,10 +367,19 @@ func (h *Handler) UpdateMovie(res http.ResponseWriter, req *http.Request) {
 		returnErrorResponse("Mismatch between movieId in query parameter and request body", http.StatusBadRequest, res)
 		return
 	}
-	updatedMovie, err := h.MovieService.Update(movie)
+	// the validator only checks JSON bodies against the schema
+	if movie.Runtime < 0 {
+		returnErrorResponse("runtime should be at least 0", http.StatusBadRequest, res)
+		return
+	}
+	if _, err = time.Parse(time.DateOnly, movie.ReleaseDate); movie.ReleaseDate != "" && err != nil {
+		returnErrorResponse("releaseDate should be formatted as YYYY-MM-DD", http.StatusBadRequest, res)
+		return
+	}
+	updatedMovie, err := h.MovieService.Update(req.Context(), movie)
 
 	if err != nil {
-		var nfErr *model.NotFoundError
+		var nfErr model.NotFoundError
 		if errors.As(err, &nfErr) {
 			responseBytes := createResponse(false, "No movie with provided id exists")
 			utils.ReturnJsonResponse(res, http.StatusNotFound, responseBytes)
@@ -226,14 +390,7 @@ func (h *Handler) UpdateMovie(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	movieJSON, err := json.Marshal(updatedMovie)
-	if err != nil {
-		slog.Error("Error when marshalling the response data: %s\n", err)
-		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
-		return
-	}
-
-	utils.ReturnJsonResponse(res, http.StatusOK, movieJSON)
+	returnMovieResponse(updatedMovie, http.StatusOK, res, req)
 }
 
 func (h *Handler) DeleteMovie(res http.ResponseWriter, req *http.Request) {
@@ -242,13 +399,18 @@ func (h *Handler) DeleteMovie(res http.ResponseWriter, req *http.Request) {
 	idParam := vars["movieId"]
 	movieId, err := strconv.Atoi(idParam)
 	if err != nil {
-		slog.Error("Error when converting id to int: %s\n", err)
+		slog.Error("Error when converting id to int", "error", err)
 		returnErrorResponse("ID should be a number", http.StatusBadRequest, res)
 		return
 	}
 
-	err = h.MovieService.Delete(movieId)
+	err = h.MovieService.Delete(req.Context(), movieId)
 	if err != nil {
+		var nfErr model.NotFoundError
+		if errors.As(err, &nfErr) {
+			returnErrorResponse("No movie with provided id exists", http.StatusNotFound, res)
+			return
+		}
 		returnErrorResponse("Error when deleting requested movie", http.StatusInternalServerError, res)
 		return
 	}
@@ -256,6 +418,69 @@ func (h *Handler) DeleteMovie(res http.ResponseWriter, req *http.Request) {
 	utils.ReturnEmptyResponse(res, http.StatusNoContent)
 }
 
+func (h *Handler) GetTrash(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie trash request")
+
+	movies, err := h.MovieService.GetTrash()
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+	returnMovieResponse(movies, http.StatusOK, res, req)
+}
+
+func (h *Handler) RestoreMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST movie restore request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	movie, err := h.MovieService.Restore(req.Context(), movieId)
+	if err != nil {
+		var nfErr model.NotFoundError
+		if errors.As(err, &nfErr) {
+			returnErrorResponse("No deleted movie with provided id exists", http.StatusNotFound, res)
+			return
+		}
+		returnErrorResponse("Error when restoring requested movie", http.StatusInternalServerError, res)
+		return
+	}
+	returnMovieResponse(movie, http.StatusOK, res, req)
+}
+
+// returnMovieResponse writes the movies in the format negotiated with the client, the routes making sure that one
+// of the formats is acceptable.
+func returnMovieResponse(value any, status int, res http.ResponseWriter, req *http.Request) {
+	if err := negotiate.Write(res, req, status, value); err != nil {
+		slog.Error("Error when encoding the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+	}
+}
+
+// notModified sets the Last-Modified header of the response to when the resource was last modified and, if it was
+// not modified since the If-Modified-Since header of the request, answers it with a 304 response. Dates are only
+// precise to the second in the headers. Nothing is done for a zero time.
+func notModified(res http.ResponseWriter, req *http.Request, lastModified time.Time) bool {
+	if lastModified.IsZero() {
+		return false
+	}
+	res.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.Truncate(time.Second).After(since) {
+		return false
+	}
+	// the response would have depended on the format too
+	res.Header().Add("Vary", "Accept")
+	res.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+func returnUnsupportedMediaType(res http.ResponseWriter) {
+	returnErrorResponse("Content-Type should be "+strings.Join(negotiate.MediaTypes(), ", "), http.StatusUnsupportedMediaType, res)
+}
+
 func returnErrorResponse(message string, status int, res http.ResponseWriter) {
 	responseBytes := createResponse(false, message)
 	utils.ReturnJsonResponse(res, status, responseBytes)
@@ -270,7 +495,7 @@ func createResponse(success bool, message string) []byte {
 func validateIDParam(id string, res http.ResponseWriter) int {
 	movieId, err := strconv.Atoi(id)
 	if err != nil {
-		slog.Error("Error when converting id to int: %s\n", err)
+		slog.Error("Error when converting id to int", "error", err)
 		returnErrorResponse("ID should be a number", http.StatusBadRequest, res)
 		return 0
 	}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -9,64 +10,72 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"rest_api/internal/api/model"
+	"rest_api/internal/api/openapi"
+	"rest_api/internal/api/reqctx"
 	"rest_api/internal/api/service"
 	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data/datatest"
 	"strings"
 	"testing"
+	"time"
 )
 
-type mockMovieRepository struct {
+type mockPublisher struct {
 	mock.Mock
 }
 
-func (r *mockMovieRepository) GetAll() ([]*model.Movie, error) {
-	args := r.Called()
-	return args.Get(0).([]*model.Movie), args.Error(1)
+func (p *mockPublisher) Publish(msg string) error {
+	args := p.Called(msg)
+	return args.Error(0)
 }
 
-func (r *mockMovieRepository) Get(movieId int) (*model.Movie, error) {
-	args := r.Called(movieId)
-	return args.Get(0).(*model.Movie), args.Error(1)
+func (p *mockPublisher) Configure(_ string) {}
+
+type mockJobQueue struct {
+	mock.Mock
 }
 
-func (r *mockMovieRepository) Create(movie *model.Movie) (*model.Movie, error) {
-	args := r.Called(movie)
-	return args.Get(0).(*model.Movie), args.Error(1)
+func (q *mockJobQueue) Enqueue(_ context.Context, kind string, payload any) (*model.QueueJob, error) {
+	args := q.Called(kind, payload)
+	return &model.QueueJob{ID: 1, Kind: kind, Status: model.QueueJobPending}, args.Error(0)
 }
 
-func (r *mockMovieRepository) Update(movie *model.Movie) (*model.Movie, error) {
-	args := r.Called(movie)
-	return args.Get(0).(*model.Movie), args.Error(1)
+type mockIdempotencyRepository struct {
+	mock.Mock
 }
 
-func (r *mockMovieRepository) Delete(movieId int) error {
-	args := r.Called(movieId)
-	return args.Error(0)
+func (r *mockIdempotencyRepository) Claim(_ context.Context, key *model.IdempotencyKey, _, _ time.Duration) (*model.IdempotencyKey, bool, error) {
+	args := r.Called(key)
+	claimed, _ := args.Get(0).(*model.IdempotencyKey)
+	return claimed, args.Bool(1), args.Error(2)
 }
 
-type mockPublisher struct {
-	mock.Mock
+func (r *mockIdempotencyRepository) Complete(_ context.Context, key *model.IdempotencyKey) error {
+	args := r.Called(key)
+	return args.Error(0)
 }
 
-func (p *mockPublisher) Publish(msg string) error {
-	args := p.Called(msg)
+func (r *mockIdempotencyRepository) Release(_ context.Context, key *model.IdempotencyKey) error {
+	args := r.Called(key)
 	return args.Error(0)
 }
 
-func (p *mockPublisher) Configure(_ string) {}
+func (r *mockIdempotencyRepository) Purge(_ context.Context) (int64, error) {
+	args := r.Called()
+	return int64(args.Int(0)), args.Error(1)
+}
 
 func TestHandler_GetMovie(t *testing.T) {
 	w := httptest.NewRecorder()
 
-	repository := new(mockMovieRepository)
+	repository := new(datatest.MovieStore)
 	// TODO change anytnhing
-	repository.On("Get", mock.Anything).Return(&model.Movie{1, "foo", "bar"}, nil)
+	repository.On("Get", mock.Anything).Return(&model.Movie{MovieId: 1, MovieName: "foo", Overview: "bar"}, nil)
 
 	h := Handler{
 		UserRepository: nil,
-		MovieService:   service.NewMovieService(repository),
+		MovieService:   service.NewMovieService(repository, nil),
 		TmdbService:    nil,
-		Publisher:      nil,
 	}
 
 	req, err := http.NewRequest(http.MethodGet, "http://localhost:3000/movies/", nil)
@@ -85,6 +94,32 @@ func TestHandler_GetMovie(t *testing.T) {
 	assert.Equal(t, `{"id":1,"title":"foo","overview":"bar"}`, string(bytes))
 }
 
+func TestHandler_MovieCache(t *testing.T) {
+	repository := new(datatest.MovieStore)
+	repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "foo", Overview: "bar"}, nil)
+	cache := service.NewMovieCache(repository, 10, time.Minute, time.Minute)
+	h := Handler{MovieCache: cache}
+
+	_, err := cache.Get(1)
+	require.NoError(t, err)
+	_, err = cache.Get(1)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	h.GetMovieCacheStats(w, httptest.NewRequest(http.MethodGet, "/admin/cache/movies", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"size":1,"capacity":10,"hits":1,"negativeHits":0,"misses":1,"hitRatio":0.5,"evictions":0,
+		"invalidations":0,"flushes":0}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.FlushMovieCache(w, httptest.NewRequest(http.MethodPost, "/admin/cache/movies/flush", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	stats := cache.Stats()
+	assert.Equal(t, 0, stats.Size)
+	assert.Equal(t, int64(1), stats.Flushes)
+}
+
 func TestHandler_CreateMovie(t *testing.T) {
 	tmdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -94,17 +129,21 @@ func TestHandler_CreateMovie(t *testing.T) {
 
 	w := httptest.NewRecorder()
 
-	mockRepository := new(mockMovieRepository)
-	mockRepository.On("Create", mock.Anything).Return(&model.Movie{1, "The bear", "bear"}, nil)
+	mockRepository := new(datatest.MovieStore)
+	mockRepository.On("Create", mock.Anything).Return(&model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear"}, nil)
+	mockRepository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear"}, nil)
 
 	publisher := new(mockPublisher)
 	publisher.On("Publish", mock.Anything).Return(nil)
+	jobs := new(mockJobQueue)
+	jobs.On("Enqueue", service.EnrichMovieJob, service.EnrichMovie{MovieId: 1}).Return(nil)
 
+	movieService := service.NewMovieService(mockRepository, publisher)
 	h := Handler{
 		UserRepository: nil,
-		MovieService:   service.NewMovieService(mockRepository),
+		MovieService:   movieService,
 		TmdbService:    tmdb.NewService(tmdbServer.URL),
-		Publisher:      publisher,
+		RefreshService: service.NewRefreshService(nil, nil, movieService, nil, nil, nil, jobs, time.Hour, 10, 1),
 	}
 
 	req, err := http.NewRequest(http.MethodPost, "http://localhost:3000/movies/", strings.NewReader(`{"id":45,"title":"The bear"}`))
@@ -120,4 +159,329 @@ func TestHandler_CreateMovie(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, `{"id":1,"title":"The bear","overview":"bear"}`, string(bytes))
+	mockRepository.AssertCalled(t, "Create", &model.Movie{MovieId: 45, MovieName: "The bear", Overview: "bear", TmdbId: 1, Runtime: 123})
+	publisher.AssertCalled(t, "Publish", `{"type":"movie.created","movie":{"id":1,"title":"The bear","overview":"bear"}}`)
+	jobs.AssertCalled(t, "Enqueue", service.EnrichMovieJob, service.EnrichMovie{MovieId: 1})
+}
+
+func TestHandler_ExportMovies(t *testing.T) {
+	repository := new(datatest.MovieStore)
+	repository.On("Stream", model.MovieFilter{Title: "bear", MinRuntime: 90}).Return([]*model.Movie{
+		{MovieId: 1, MovieName: "The bear", Overview: "bear, again", TmdbId: 11, Runtime: 95},
+		{MovieId: 2, MovieName: "Bear story"},
+	}, nil)
+	h := Handler{ExportService: service.NewExportService(repository, nil, 1, "", "", 0)}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		body   string
+	}{
+		{
+			name:   "csv",
+			query:  "?title=bear&minRuntime=90",
+			status: http.StatusOK,
+			body: "id,title,overview,tmdbId,runtime,releaseDate,tmdbVoteAverage,ratingAverage,ratingCount,genres,tags,deletedAt\n" +
+				"1,The bear,\"bear, again\",11,95,,,,,,,\n2,Bear story,,,,,,,,,,\n",
+		},
+		{
+			name:   "ndjson",
+			query:  "?format=ndjson&title=bear&minRuntime=90",
+			status: http.StatusOK,
+			body:   "{\"id\":1,\"title\":\"The bear\",\"overview\":\"bear, again\",\"tmdbId\":11,\"runtime\":95}\n{\"id\":2,\"title\":\"Bear story\",\"overview\":\"\"}\n",
+		},
+		{
+			name:   "unsupported format",
+			query:  "?format=xml",
+			status: http.StatusBadRequest,
+			body:   `{"success":false,"message":"format should be csv, ndjson or parquet"}`,
+		},
+		{
+			name:   "invalid filter",
+			query:  "?minRuntime=long",
+			status: http.StatusBadRequest,
+			body:   `{"success":false,"message":"minRuntime should be a positive number"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:3000/movies/export"+tt.query, nil)
+
+			h.ExportMovies(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.status, res.StatusCode)
+			bytes, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(bytes))
+		})
+	}
+}
+
+func TestHandler_ExportMoviesOutlivesWriteTimeout(t *testing.T) {
+	repository := new(datatest.MovieStore)
+	// the export takes longer than the write timeout of the server
+	repository.On("Stream", model.MovieFilter{}).After(100*time.Millisecond).
+		Return([]*model.Movie{{MovieId: 1, MovieName: "The bear"}}, nil)
+	h := Handler{ExportService: service.NewExportService(repository, nil, 1, "", "", 0)}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(h.ExportMovies))
+	server.Config.WriteTimeout = 20 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/movies/export?format=ndjson")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	bytes, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":1,\"title\":\"The bear\",\"overview\":\"\"}\n", string(bytes))
+}
+
+func TestHandler_RequestContext(t *testing.T) {
+	h := Handler{}
+	var requestId, actor string
+	next := h.RequestContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId = reqctx.RequestID(r.Context())
+		actor = reqctx.Actor(r.Context())
+	}))
+
+	tests := []struct {
+		name      string
+		requestId string
+		generated bool
+	}{
+		{name: "provided id", requestId: "abc-123"},
+		{name: "missing id", generated: true},
+		{name: "invalid id", requestId: "abc 123", generated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:3000/movies", nil)
+			if tt.requestId != "" {
+				req.Header.Set("X-Request-ID", tt.requestId)
+			}
+
+			next.ServeHTTP(w, req)
+
+			if tt.generated {
+				assert.NotEqual(t, tt.requestId, requestId)
+				assert.Len(t, requestId, 36)
+			} else {
+				assert.Equal(t, tt.requestId, requestId)
+			}
+			assert.Equal(t, requestId, w.Header().Get("X-Request-ID"))
+			assert.Equal(t, reqctx.ActorAnonymous, actor)
+		})
+	}
+}
+
+func TestHandler_Idempotency(t *testing.T) {
+	body := `{"title":"The bear"}`
+	first := httptest.NewRequest(http.MethodPost, "/movies", nil)
+	key := &model.IdempotencyKey{Actor: reqctx.ActorAnonymous, Key: "k1", Fingerprint: fingerprint(first, []byte(body))}
+	stored := &model.IdempotencyKey{Actor: reqctx.ActorAnonymous, Key: "k1", Fingerprint: key.Fingerprint, Status: http.StatusCreated,
+		Headers: map[string]string{"Content-Type": "application/json", "Location": "/movies/1"}, Body: []byte(`{"id":1}`)}
+
+	tests := []struct {
+		name        string
+		key         string
+		body        string
+		contentType string
+		// claimed is the key returned when claiming it, and claimedNow whether it was claimed for this request
+		claimed       *model.IdempotencyKey
+		claimedNow    bool
+		status        int
+		wantStatus    int
+		wantBody      string
+		wantProcessed bool
+		wantReplayed  bool
+		// wantStored is the response stored for the key, if any, and wantReleased whether the key was released
+		wantStored   *model.IdempotencyKey
+		wantReleased bool
+	}{
+		{name: "without key", body: body, status: http.StatusCreated, wantStatus: http.StatusCreated, wantBody: `{"id":1}`,
+			wantProcessed: true},
+		{name: "first request", key: "k1", body: body, claimed: key, claimedNow: true, status: http.StatusCreated,
+			wantStatus: http.StatusCreated, wantBody: `{"id":1}`, wantProcessed: true, wantStored: stored},
+		{name: "retry", key: "k1", body: body, claimed: stored, wantStatus: http.StatusCreated, wantBody: `{"id":1}`,
+			wantReplayed: true},
+		{name: "retry while processing", key: "k1", body: body, claimed: &model.IdempotencyKey{Fingerprint: key.Fingerprint},
+			wantStatus: http.StatusConflict, wantBody: `{"success":false,"message":"A request with the Idempotency-Key is still being processed"}`},
+		{name: "key of another request", key: "k1", body: `{"title":"Another bear"}`, claimed: stored,
+			wantStatus: http.StatusUnprocessableEntity, wantBody: `{"success":false,"message":"The Idempotency-Key was already used for another request"}`},
+		{name: "server error", key: "k1", body: body, claimed: key, claimedNow: true, status: http.StatusInternalServerError,
+			wantStatus: http.StatusInternalServerError, wantBody: `{"id":1}`, wantProcessed: true, wantReleased: true},
+		{name: "key of another content type", key: "k1", body: body, contentType: "application/xml", claimed: stored,
+			wantStatus: http.StatusUnprocessableEntity, wantBody: `{"success":false,"message":"The Idempotency-Key was already used for another request"}`},
+		{name: "forbidden", key: "k1", body: body, claimed: key, claimedNow: true, status: http.StatusForbidden,
+			wantStatus: http.StatusForbidden, wantBody: `{"id":1}`, wantProcessed: true, wantReleased: true},
+		{name: "unsupported media type", key: "k1", body: body, claimed: key, claimedNow: true,
+			status: http.StatusUnsupportedMediaType, wantStatus: http.StatusUnsupportedMediaType, wantBody: `{"id":1}`,
+			wantProcessed: true, wantReleased: true},
+		{name: "client error", key: "k1", body: body, claimed: key, claimedNow: true, status: http.StatusNotFound,
+			wantStatus: http.StatusNotFound, wantBody: `{"id":1}`, wantProcessed: true,
+			wantStored: &model.IdempotencyKey{Actor: reqctx.ActorAnonymous, Key: "k1", Fingerprint: key.Fingerprint,
+				Status: http.StatusNotFound, Headers: stored.Headers, Body: []byte(`{"id":1}`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := new(mockIdempotencyRepository)
+			if tt.claimed != nil {
+				claimed := *tt.claimed
+				repository.On("Claim", mock.Anything).Return(&claimed, tt.claimedNow, nil)
+			}
+			repository.On("Complete", mock.Anything).Return(nil)
+			repository.On("Release", mock.Anything).Return(nil)
+			h := &Handler{IdempotencyService: service.NewIdempotencyService(repository, time.Hour, time.Minute)}
+			processed := false
+			addMovie := func(res http.ResponseWriter, req *http.Request) {
+				processed = true
+				requestBody, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.body, string(requestBody))
+				res.Header().Set("Content-Type", "application/json")
+				res.Header().Set("Location", "/movies/1")
+				res.WriteHeader(tt.status)
+				res.Write([]byte(`{"id":1}`))
+			}
+			r := mux.NewRouter()
+			doc := openapi.NewDocument("test", "1.0.0", "")
+			require.NoError(t, Register(r, doc, []Route{route(http.MethodPost, "/movies", addMovie,
+				idempotent(operation("createMovie", "Create a movie", "movies")))}))
+			r.Use(h.RequestContext)
+			r.Use(h.Idempotency(doc))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/movies", strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantProcessed, processed)
+			if tt.wantReplayed {
+				assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+				assert.Equal(t, "/movies/1", w.Header().Get("Location"))
+			} else {
+				assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+			}
+			if tt.wantStored != nil {
+				repository.AssertCalled(t, "Complete", tt.wantStored)
+			} else {
+				repository.AssertNotCalled(t, "Complete", mock.Anything)
+			}
+			if tt.wantReleased {
+				repository.AssertCalled(t, "Release", key)
+			} else {
+				repository.AssertNotCalled(t, "Release", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestParseAuditFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		filter model.AuditFilter
+		err    string
+	}{
+		{
+			name:   "defaults",
+			filter: model.AuditFilter{Limit: 100},
+		},
+		{
+			name:  "all filters",
+			query: "?entity=movie&entityId=1&actor=alice&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z&limit=10",
+			filter: model.AuditFilter{Entity: "movie", EntityID: "1", Actor: "alice", Limit: 10,
+				From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "unknown entity",
+			query: "?entity=asset",
+			err:   "entity should be movie or user",
+		},
+		{
+			name:  "invalid time",
+			query: "?from=yesterday",
+			err:   "from should be an RFC 3339 timestamp",
+		},
+		{
+			name:  "empty range",
+			query: "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z",
+			err:   "from should be before to",
+		},
+		{
+			name:  "invalid limit",
+			query: "?limit=5000",
+			err:   "limit should be a number between 1 and 1000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:3000/audit"+tt.query, nil)
+			filter, err := parseAuditFilter(req)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.filter, filter)
+		})
+	}
+}
+
+func TestParseMovieFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		filter model.MovieFilter
+		err    string
+	}{
+		{
+			name: "no filter",
+		},
+		{
+			name:   "genres and tags",
+			query:  "?genre=Drama,%20comedy&genre=drama&tag=cult&tagMatch=all",
+			filter: model.MovieFilter{Genres: []string{"drama", "comedy"}, Tags: []string{"cult"}, MatchAllTags: true},
+		},
+		{
+			name:   "any match",
+			query:  "?genre=horror&genreMatch=any",
+			filter: model.MovieFilter{Genres: []string{"horror"}},
+		},
+		{
+			name:  "invalid match",
+			query: "?genre=horror&genreMatch=both",
+			err:   "genreMatch should be any or all",
+		},
+		{
+			name:  "invalid runtime",
+			query: "?minRuntime=-1",
+			err:   "minRuntime should be a positive number",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:3000/movies"+tt.query, nil)
+			filter, err := parseMovieFilter(req)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.filter, filter)
+		})
+	}
 }
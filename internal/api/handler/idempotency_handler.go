@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/openapi"
+	"rest_api/internal/api/reqctx"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// replayedHeaders are the headers stored along with the responses, the other ones being set anew for every request.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// idempotent documents the Idempotency-Key header of the operations whose retries are answered with the response of
+// the first request, and the errors of the keys.
+func idempotent(operation *openapi.Operation) *openapi.Operation {
+	return operation.
+		Header(idempotencyKeyHeader, openapi.String().Length(1, 255),
+			"Unique key of the request, whose retries with the same key are answered with the response of the first one").
+		Errors(http.StatusConflict, http.StatusUnprocessableEntity)
+}
+
+// Idempotency answers the retries of the requests sent with an Idempotency-Key header with the stored response of
+// the first request, for the operations documenting the header. Keys are scoped to the user and expire with their
+// response. A key sent again with another method, path, body or format gets a 422 response, and a 409 one while the
+// first request is still being processed. Server errors, and the errors of the credentials and of the formats, are not
+// stored, so that the request can be retried once fixed. It should be used after the validator, for invalid requests
+// not to use up keys.
+func (h *Handler) Idempotency(doc *openapi.Document) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			key := req.Header.Get(idempotencyKeyHeader)
+			if key == "" || !documentsIdempotency(doc, req) {
+				next.ServeHTTP(res, req)
+				return
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				returnErrorResponse("Could not read request body", http.StatusBadRequest, res)
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			claimed, err := h.IdempotencyService.Begin(req.Context(), reqctx.Actor(req.Context()), key, fingerprint(req, body))
+			if err != nil {
+				var vErr model.ValidationError
+				var cErr model.ConflictError
+				switch {
+				case errors.As(err, &vErr):
+					returnErrorResponse(vErr.Message, http.StatusUnprocessableEntity, res)
+				case errors.As(err, &cErr):
+					returnErrorResponse("A request with the Idempotency-Key is still being processed", http.StatusConflict, res)
+				default:
+					returnErrorResponse("Unexpected error when checking the Idempotency-Key", http.StatusInternalServerError, res)
+				}
+				return
+			}
+			if claimed.Status != 0 {
+				slog.Info("Replaying idempotent response", "status", claimed.Status)
+				replay(res, claimed)
+				return
+			}
+
+			recorder := &idempotencyRecorder{ResponseWriter: res, status: http.StatusOK}
+			next.ServeHTTP(recorder, req)
+			// the response is stored even if the client went away, since it will retry
+			ctx := context.WithoutCancel(req.Context())
+			if retriable(recorder.status) {
+				h.IdempotencyService.Release(ctx, claimed)
+				return
+			}
+			claimed.Status = recorder.status
+			claimed.Body = recorder.body.Bytes()
+			claimed.Headers = map[string]string{}
+			for _, name := range replayedHeaders {
+				if value := recorder.Header().Get(name); value != "" {
+					claimed.Headers[name] = value
+				}
+			}
+			h.IdempotencyService.Complete(ctx, claimed)
+		})
+	}
+}
+
+// documentsIdempotency returns whether the operation of the route matched by the request documents the
+// Idempotency-Key header.
+func documentsIdempotency(doc *openapi.Document, req *http.Request) bool {
+	route := mux.CurrentRoute(req)
+	if route == nil {
+		return false
+	}
+	path, err := route.GetPathTemplate()
+	operation := doc.Operation(req.Method, path)
+	if err != nil || operation == nil {
+		return false
+	}
+	for _, param := range operation.Parameters {
+		if param.In == "header" && param.Name == idempotencyKeyHeader {
+			return true
+		}
+	}
+	return false
+}
+
+// retriable returns whether the response of the request should not be stored for its key, the request being expected
+// to be sent again with the same key once the server recovered or the client fixed its credentials or formats.
+func retriable(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotAcceptable, http.StatusUnsupportedMediaType:
+		return true
+	}
+	return status >= http.StatusInternalServerError
+}
+
+// fingerprint identifies the request sent with a key by its method, path, query, formats and body. The formats are
+// part of it since the same body can be decoded differently, and the response encoded differently.
+func fingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+" "+req.URL.RequestURI()+"\n")
+	io.WriteString(hash, "Accept: "+req.Header.Get("Accept")+"\n")
+	io.WriteString(hash, "Content-Type: "+req.Header.Get("Content-Type")+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replay sends the stored response of the key, with a header telling it is a replay.
+func replay(res http.ResponseWriter, key *model.IdempotencyKey) {
+	for name, value := range key.Headers {
+		res.Header().Set(name, value)
+	}
+	res.Header().Set(idempotentReplayedHeader, "true")
+	res.WriteHeader(key.Status)
+	res.Write(key.Body)
+}
+
+// idempotencyRecorder sends the response while keeping a copy of it.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *idempotencyRecorder) WriteHeader(status int) {
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.wroteHeader = true
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idempotencyRecorder) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"rest_api/internal/api/config"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/service"
+	"rest_api/internal/api/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) ImportMovies(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST movie import request")
+
+	req.Body = http.MaxBytesReader(res, req.Body, config.ImportMaxBytes)
+	defer req.Body.Close()
+
+	body, format, err := importFile(req)
+	if err != nil {
+		returnImportFileErrorResponse(err, res)
+		return
+	}
+
+	rows, rowErrors, err := service.ParseImport(format, body)
+	if err != nil {
+		returnImportFileErrorResponse(err, res)
+		return
+	}
+	if len(rows)+len(rowErrors) == 0 {
+		returnErrorResponse("Import file should contain at least one movie", http.StatusBadRequest, res)
+		return
+	}
+
+	imp, err := h.ImportService.Start(req.Context(), format, rows, rowErrors)
+	if err != nil {
+		returnErrorResponse("Could not start import", http.StatusInternalServerError, res)
+		return
+	}
+
+	importJSON, err := json.Marshal(imp)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Header().Set("Location", fmt.Sprintf("/imports/%d", imp.ID))
+	utils.ReturnJsonResponse(res, http.StatusAccepted, importJSON)
+}
+
+// importFile returns the uploaded file and its format. The file is either the raw request body or the "file"
+// field of a multipart form; the format comes from the format query parameter, the content type or the file
+// extension, in that order.
+func importFile(req *http.Request) (io.Reader, string, error) {
+	format := strings.ToLower(req.URL.Query().Get("format"))
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	body := io.Reader(req.Body)
+	if mediaType == "multipart/form-data" {
+		file, fileHeader, err := req.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, "", err
+			}
+			return nil, "", model.ValidationError{Message: "file field should be present"}
+		}
+		body = file
+		mediaType = fileHeader.Header.Get("Content-Type")
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+	}
+	if format == "" {
+		switch mediaType {
+		case "text/csv":
+			format = model.ImportFormatCSV
+		case "application/x-ndjson", "application/ndjson":
+			format = model.ImportFormatNDJSON
+		}
+	}
+	if format != model.ImportFormatCSV && format != model.ImportFormatNDJSON {
+		return nil, "", model.ValidationError{Message: "import format should be csv or ndjson"}
+	}
+	return body, format, nil
+}
+
+// returnImportFileErrorResponse answers the errors of reading the import file, the body having been read up to the
+// import limit.
+func returnImportFileErrorResponse(err error, res http.ResponseWriter) {
+	var maxBytesErr *http.MaxBytesError
+	var vErr model.ValidationError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		returnErrorResponse(fmt.Sprintf("Import file should be at most %d bytes", config.ImportMaxBytes), http.StatusRequestEntityTooLarge, res)
+	case errors.As(err, &vErr):
+		returnErrorResponse(vErr.Message, http.StatusBadRequest, res)
+	default:
+		slog.Error("Error when reading the import file", "error", err)
+		returnErrorResponse("Could not read import file", http.StatusBadRequest, res)
+	}
+}
+
+func (h *Handler) GetImport(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie import request")
+
+	importId := validateIDParam(mux.Vars(req)["importId"], res)
+	if importId == 0 {
+		return
+	}
+
+	imp, err := h.ImportService.Get(importId)
+	if err != nil {
+		returnImportErrorResponse(err, res)
+		return
+	}
+	if imp.FailedRows > 0 {
+		imp.ErrorReport = fmt.Sprintf("/imports/%d/errors", imp.ID)
+	}
+
+	importJSON, err := json.Marshal(imp)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, importJSON)
+}
+
+// GetImportErrors downloads every row error of the import as a CSV report.
+func (h *Handler) GetImportErrors(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie import errors request")
+
+	importId := validateIDParam(mux.Vars(req)["importId"], res)
+	if importId == 0 {
+		return
+	}
+
+	rowErrors, err := h.ImportService.GetErrors(importId)
+	if err != nil {
+		returnImportErrorResponse(err, res)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/csv")
+	res.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%d-errors.csv"`, importId))
+	res.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(res)
+	writer.Write([]string{"line", "id", "title", "error"})
+	for _, rowErr := range rowErrors {
+		movieId := ""
+		if rowErr.MovieId != 0 {
+			movieId = strconv.Itoa(rowErr.MovieId)
+		}
+		writer.Write([]string{strconv.Itoa(rowErr.Line), movieId, rowErr.Title, rowErr.Message})
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		slog.Error("Error when writing the import error report", "importId", importId, "error", err)
+	}
+}
+
+func returnImportErrorResponse(err error, res http.ResponseWriter) {
+	var nfErr model.NotFoundError
+	if errors.As(err, &nfErr) {
+		returnErrorResponse("No import with provided id exists", http.StatusNotFound, res)
+		return
+	}
+	returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+	"rest_api/internal/data"
+	"rest_api/internal/scheduler"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetJobs(res http.ResponseWriter, _ *http.Request) {
+	slog.Info("Received GET jobs request")
+
+	jobsJSON, err := json.Marshal(h.Scheduler.Jobs())
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, jobsJSON)
+}
+
+func (h *Handler) TriggerJob(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	slog.Info("Received POST job trigger request", "job", name)
+
+	err := h.Scheduler.Trigger(name)
+	if err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrJobNotFound):
+			returnErrorResponse("No job with provided name exists", http.StatusNotFound, res)
+		case errors.Is(err, scheduler.ErrJobRunning):
+			returnErrorResponse("Job is already running", http.StatusConflict, res)
+		default:
+			returnErrorResponse("Could not trigger job", http.StatusServiceUnavailable, res)
+		}
+		return
+	}
+
+	responseBytes := createResponse(true, "Job triggered")
+	utils.ReturnJsonResponse(res, http.StatusAccepted, responseBytes)
+}
+
+type leaderResponse struct {
+	Replica  string       `json:"replica"`
+	IsLeader bool         `json:"isLeader"`
+	Lease    *model.Lease `json:"lease"`
+}
+
+func (h *Handler) GetLeader(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET leader request")
+
+	lease, err := h.Elector.Current(req.Context())
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		slog.Error("Error when getting leader lease", "error", err)
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	responseJSON, err := json.Marshal(leaderResponse{
+		Replica:  h.Elector.ID(),
+		IsLeader: h.Elector.IsLeader(),
+		Lease:    lease,
+	})
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, responseJSON)
+}
+
+// GetRPCMetrics returns the gRPC calls served by this replica, by method and status code.
+func (h *Handler) GetRPCMetrics(res http.ResponseWriter, _ *http.Request) {
+	slog.Info("Received GET gRPC metrics request")
+
+	responseJSON, err := json.Marshal(h.RPCMetrics.Snapshot())
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, responseJSON)
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/dto"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/negotiate"
+
+	"github.com/gorilla/mux"
+)
+
+// The v2 movie endpoints work like the v1 ones, with the movies in their v2 representation.
+
+func (h *Handler) GetMoviesV2(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET v2 movies request")
+
+	filter, err := parseMovieFilter(req)
+	if err != nil {
+		returnErrorResponse(err.Error(), http.StatusBadRequest, res)
+		return
+	}
+
+	lastModified, err := h.MovieService.LastModified(req.Context())
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+	if notModified(res, req, lastModified) {
+		return
+	}
+	movies, err := h.MovieService.List(filter)
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	returnMovieResponse(dto.ToMoviesV2(movies), http.StatusOK, res, req)
+}
+
+func (h *Handler) GetTrashV2(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET v2 movie trash request")
+
+	movies, err := h.MovieService.GetTrash()
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	returnMovieResponse(dto.ToMoviesV2(movies), http.StatusOK, res, req)
+}
+
+func (h *Handler) GetMovieV2(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET v2 movie request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	movie, err := h.MovieService.Get(movieId)
+	if err != nil {
+		returnMovieV2ErrorResponse(err, "No movie with provided id exists", res)
+		return
+	}
+	if notModified(res, req, movie.UpdatedAt) {
+		return
+	}
+
+	returnMovieResponse(dto.ToMovieV2(movie), http.StatusOK, res, req)
+}
+
+func (h *Handler) AddMovieV2(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST v2 movie request")
+
+	var body dto.MovieV2Create
+	if !decodeMovieV2(res, req, &body) {
+		return
+	}
+	if err := body.Validate(); err != nil {
+		returnErrorResponse(err.Error(), http.StatusBadRequest, res)
+		return
+	}
+
+	movie, err := h.movieFromTmdb(body.ToModel())
+	if err != nil {
+		returnErrorResponse("Could not create movie. A movie with the provided title does not exist", http.StatusBadRequest, res)
+		return
+	}
+	created, err := h.MovieService.Create(req.Context(), movie)
+	if err != nil {
+		returnMovieV2ErrorResponse(err, "", res)
+		return
+	}
+	h.enqueueEnrichment(req, created)
+
+	returnMovieResponse(dto.ToMovieV2(created), http.StatusCreated, res, req)
+}
+
+// UpdateMovieV2 replaces the editable fields of the movie of the path, which is not repeated in the body.
+func (h *Handler) UpdateMovieV2(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT v2 movie request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	var body dto.MovieV2Update
+	if !decodeMovieV2(res, req, &body) {
+		return
+	}
+
+	if err := body.Validate(); err != nil {
+		returnErrorResponse(err.Error(), http.StatusBadRequest, res)
+		return
+	}
+	updated, err := h.MovieService.Update(req.Context(), body.ToModel(movieId))
+	if err != nil {
+		returnMovieV2ErrorResponse(err, "No movie with provided id exists", res)
+		return
+	}
+
+	returnMovieResponse(dto.ToMovieV2(updated), http.StatusOK, res, req)
+}
+
+func (h *Handler) RestoreMovieV2(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST v2 movie restore request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	movie, err := h.MovieService.Restore(req.Context(), movieId)
+	if err != nil {
+		returnMovieV2ErrorResponse(err, "No deleted movie with provided id exists", res)
+		return
+	}
+
+	returnMovieResponse(dto.ToMovieV2(movie), http.StatusOK, res, req)
+}
+
+func decodeMovieV2(res http.ResponseWriter, req *http.Request, body any) bool {
+	defer req.Body.Close()
+	err := negotiate.Decode(req, body)
+	if errors.Is(err, negotiate.ErrUnsupportedMediaType) {
+		returnUnsupportedMediaType(res)
+		return false
+	}
+	if err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return false
+	}
+	return true
+}
+
+func returnMovieV2ErrorResponse(err error, notFoundMessage string, res http.ResponseWriter) {
+	var nfErr model.NotFoundError
+	var cErr model.ConflictError
+	switch {
+	case errors.As(err, &nfErr):
+		returnErrorResponse(notFoundMessage, http.StatusNotFound, res)
+	case errors.As(err, &cErr):
+		returnErrorResponse("A movie with the provided id already exists", http.StatusConflict, res)
+	default:
+		returnErrorResponse("Unexpected error when accessing movies", http.StatusInternalServerError, res)
+	}
+}
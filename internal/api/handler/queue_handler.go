@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+	"rest_api/internal/data"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultQueueJobsLimit = 50
+	maxQueueJobsLimit     = 500
+)
+
+var queueJobStatuses = []string{model.QueueJobPending, model.QueueJobRunning, model.QueueJobCompleted, model.QueueJobDead, model.QueueJobCancelled}
+
+func (h *Handler) EnrichMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST movie enrichment request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	job, err := h.RefreshService.EnqueueEnrichment(req.Context(), movieId)
+	if err != nil {
+		var nfErr model.NotFoundError
+		if errors.As(err, &nfErr) {
+			returnErrorResponse("No movie with provided id exists", http.StatusNotFound, res)
+			return
+		}
+		returnErrorResponse("Could not enqueue enrichment", http.StatusInternalServerError, res)
+		return
+	}
+
+	returnQueueJobResponse(job, http.StatusAccepted, res)
+}
+
+func (h *Handler) GetQueueJobs(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET queue jobs request")
+
+	query := req.URL.Query()
+	filter := model.QueueJobFilter{Status: query.Get("status"), Kind: query.Get("kind"), Limit: defaultQueueJobsLimit}
+	if filter.Status != "" && !slices.Contains(queueJobStatuses, filter.Status) {
+		returnErrorResponse("status should be pending, running, completed, dead or cancelled", http.StatusBadRequest, res)
+		return
+	}
+	if query.Has("limit") {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxQueueJobsLimit {
+			returnErrorResponse("limit should be a number between 1 and 500", http.StatusBadRequest, res)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	jobs, err := h.Queue.List(req.Context(), filter)
+	if err != nil {
+		slog.Error("Error when getting queue jobs from db", "error", err)
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	jobsJSON, err := json.Marshal(jobs)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, jobsJSON)
+}
+
+func (h *Handler) GetQueueJob(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET queue job request")
+	h.queueJobAction(res, req, http.StatusOK, h.Queue.Get)
+}
+
+func (h *Handler) RetryQueueJob(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST queue job retry request")
+	h.queueJobAction(res, req, http.StatusAccepted, h.Queue.Retry)
+}
+
+func (h *Handler) CancelQueueJob(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST queue job cancel request")
+	h.queueJobAction(res, req, http.StatusOK, h.Queue.Cancel)
+}
+
+// queueJobAction runs the action on the job of the jobId parameter and returns the resulting job.
+func (h *Handler) queueJobAction(res http.ResponseWriter, req *http.Request, status int,
+	action func(ctx context.Context, id int64) (*model.QueueJob, error)) {
+	jobId := validateIDParam(mux.Vars(req)["jobId"], res)
+	if jobId == 0 {
+		return
+	}
+
+	job, err := action(req.Context(), int64(jobId))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			returnErrorResponse("No job with provided id exists", http.StatusNotFound, res)
+		case errors.Is(err, data.ErrInvalidState):
+			returnErrorResponse("Job is not in a state allowing this action", http.StatusConflict, res)
+		default:
+			slog.Error("Error when updating queue job", "jobId", jobId, "error", err)
+			returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		}
+		return
+	}
+
+	returnQueueJobResponse(job, status, res)
+}
+
+func returnQueueJobResponse(job *model.QueueJob, status int, res http.ResponseWriter) {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, status, jobJSON)
+}
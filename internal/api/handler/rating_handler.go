@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) RateMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST movie rating request")
+	h.saveRating(res, req, true)
+}
+
+func (h *Handler) UpdateRating(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT movie rating request")
+	h.saveRating(res, req, false)
+}
+
+// saveRating creates or updates the rating of the authenticated user from the rating field of the request body.
+func (h *Handler) saveRating(res http.ResponseWriter, req *http.Request, create bool) {
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	rating := &model.Rating{}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(rating); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+	rating.MovieId = movieId
+	rating.Username = reqctx.User(req.Context()).Username
+
+	var err error
+	status := http.StatusOK
+	if create {
+		rating, err = h.RatingService.Rate(req.Context(), rating)
+		status = http.StatusCreated
+	} else {
+		rating, err = h.RatingService.UpdateRating(req.Context(), rating)
+	}
+	if err != nil {
+		returnRatingErrorResponse(err, "The movie is already rated, update the rating instead", res)
+		return
+	}
+
+	returnRatingResponse(rating, status, res)
+}
+
+func (h *Handler) DeleteRating(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE movie rating request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	if err := h.RatingService.DeleteRating(req.Context(), movieId, reqctx.User(req.Context()).Username); err != nil {
+		returnRatingErrorResponse(err, "", res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+// GetReviews lists the reviews of the movie, including the hidden ones for admins.
+func (h *Handler) GetReviews(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie reviews request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	user := reqctx.User(req.Context())
+
+	reviews, err := h.RatingService.GetReviews(req.Context(), movieId, user != nil && user.Admin)
+	if err != nil {
+		returnRatingErrorResponse(err, "", res)
+		return
+	}
+
+	returnRatingResponse(reviews, http.StatusOK, res)
+}
+
+func (h *Handler) CreateReview(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST movie review request")
+	h.saveReview(res, req, true)
+}
+
+func (h *Handler) UpdateReview(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT movie review request")
+	h.saveReview(res, req, false)
+}
+
+// saveReview creates or updates the review of the authenticated user from the text and spoiler fields of the
+// request body.
+func (h *Handler) saveReview(res http.ResponseWriter, req *http.Request, create bool) {
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	review := &model.Review{}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(review); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+	review = &model.Review{MovieId: movieId, Username: reqctx.User(req.Context()).Username, Text: review.Text, Spoiler: review.Spoiler}
+
+	var err error
+	status := http.StatusOK
+	if create {
+		review, err = h.RatingService.CreateReview(req.Context(), review)
+		status = http.StatusCreated
+	} else {
+		review, err = h.RatingService.UpdateReview(req.Context(), review)
+	}
+	if err != nil {
+		returnRatingErrorResponse(err, "The movie is already reviewed, update the review instead", res)
+		return
+	}
+
+	returnRatingResponse(review, status, res)
+}
+
+func (h *Handler) DeleteReview(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE movie review request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	if err := h.RatingService.DeleteReview(req.Context(), movieId, reqctx.User(req.Context()).Username); err != nil {
+		returnRatingErrorResponse(err, "", res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+func (h *Handler) HideReview(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST review hide request")
+	h.moderateReview(res, req, true)
+}
+
+func (h *Handler) UnhideReview(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST review unhide request")
+	h.moderateReview(res, req, false)
+}
+
+func (h *Handler) moderateReview(res http.ResponseWriter, req *http.Request, hidden bool) {
+	reviewId := validateIDParam(mux.Vars(req)["reviewId"], res)
+	if reviewId == 0 {
+		return
+	}
+
+	review, err := h.RatingService.SetReviewHidden(req.Context(), reviewId, reqctx.User(req.Context()).Username, hidden)
+	if err != nil {
+		returnRatingErrorResponse(err, "", res)
+		return
+	}
+
+	returnRatingResponse(review, http.StatusOK, res)
+}
+
+func returnRatingResponse(value any, status int, res http.ResponseWriter) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, status, valueJSON)
+}
+
+func returnRatingErrorResponse(err error, conflictMessage string, res http.ResponseWriter) {
+	var vErr model.ValidationError
+	var nfErr model.NotFoundError
+	var cErr model.ConflictError
+	switch {
+	case errors.As(err, &vErr):
+		returnErrorResponse(vErr.Message, http.StatusBadRequest, res)
+	case errors.As(err, &nfErr):
+		returnErrorResponse("No movie, rating or review with provided id exists", http.StatusNotFound, res)
+	case errors.As(err, &cErr):
+		returnErrorResponse(conflictMessage, http.StatusConflict, res)
+	default:
+		returnErrorResponse("Unexpected error when accessing ratings", http.StatusInternalServerError, res)
+	}
+}
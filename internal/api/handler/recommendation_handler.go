@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/utils"
+	"strconv"
+)
+
+const (
+	defaultRecommendationLimit = 20
+	maxRecommendationLimit     = 50
+)
+
+// GetRecommendations returns the movies recommended to the user, based on their ratings or, without enough of them,
+// on popularity and the genres they like.
+func (h *Handler) GetRecommendations(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET recommendations request")
+
+	limit := defaultRecommendationLimit
+	if query := req.URL.Query(); query.Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxRecommendationLimit {
+			returnErrorResponse(fmt.Sprintf("limit should be a number between 1 and %d", maxRecommendationLimit), http.StatusBadRequest, res)
+			return
+		}
+	}
+
+	recommendations, err := h.RecommendationService.Recommend(req.Context(), reqctx.User(req.Context()).Username, limit)
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	recommendationsJSON, err := json.Marshal(recommendations)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, recommendationsJSON)
+}
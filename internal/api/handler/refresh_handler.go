@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/utils"
+)
+
+const refreshRunsLimit = 50
+
+func (h *Handler) GetRefreshRuns(res http.ResponseWriter, _ *http.Request) {
+	slog.Info("Received GET refresh runs request")
+
+	runs, err := h.RefreshService.GetRecentRuns(refreshRunsLimit)
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	runsJSON, err := json.Marshal(runs)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, runsJSON)
+}
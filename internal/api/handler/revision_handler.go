@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetRevisions(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie revisions request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	revisions, err := h.RevisionService.List(req.Context(), movieId)
+	if err != nil {
+		returnRevisionErrorResponse(err, "No movie with provided id exists", res)
+		return
+	}
+
+	returnRevisionResponse(revisions, res)
+}
+
+func (h *Handler) GetRevision(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie revision request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	revision := validateIDParam(mux.Vars(req)["revision"], res)
+	if revision == 0 {
+		return
+	}
+
+	rev, err := h.RevisionService.Get(req.Context(), movieId, revision)
+	if err != nil {
+		returnRevisionErrorResponse(err, "No revision with provided number exists", res)
+		return
+	}
+
+	returnRevisionResponse(rev, res)
+}
+
+// DiffRevisions compares the revisions given by the from and to query parameters.
+func (h *Handler) DiffRevisions(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET movie revision diff request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	from, fromErr := strconv.Atoi(req.URL.Query().Get("from"))
+	to, toErr := strconv.Atoi(req.URL.Query().Get("to"))
+	if fromErr != nil || toErr != nil || from < 1 || to < 1 {
+		returnErrorResponse("from and to should be revision numbers", http.StatusBadRequest, res)
+		return
+	}
+
+	diff, err := h.RevisionService.Diff(req.Context(), movieId, from, to)
+	if err != nil {
+		returnRevisionErrorResponse(err, "No revision with provided number exists", res)
+		return
+	}
+
+	returnRevisionResponse(diff, res)
+}
+
+func (h *Handler) RevertRevision(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST movie revision revert request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	revision := validateIDParam(mux.Vars(req)["revision"], res)
+	if revision == 0 {
+		return
+	}
+
+	movie, err := h.RevisionService.Revert(req.Context(), movieId, revision)
+	if err != nil {
+		returnRevisionErrorResponse(err, "No revision with provided number exists", res)
+		return
+	}
+
+	returnRevisionResponse(movie, res)
+}
+
+func returnRevisionResponse(value any, res http.ResponseWriter) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, valueJSON)
+}
+
+func returnRevisionErrorResponse(err error, notFoundMessage string, res http.ResponseWriter) {
+	var nfErr model.NotFoundError
+	if errors.As(err, &nfErr) {
+		returnErrorResponse(notFoundMessage, http.StatusNotFound, res)
+		return
+	}
+	returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+}
@@ -0,0 +1,726 @@
+package handler
+
+import (
+	"net/http"
+	"rest_api/internal/api/config"
+	"rest_api/internal/api/dto"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/negotiate"
+	"rest_api/internal/api/openapi"
+	"rest_api/internal/api/rpc"
+	"rest_api/internal/api/service"
+	"rest_api/internal/scheduler"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Route is an endpoint of the API, along with the operation documenting it.
+type Route struct {
+	Method    string
+	Path      string
+	Handler   http.Handler
+	Operation *openapi.Operation
+	// CacheControl is the Cache-Control header of the successful responses, if any.
+	CacheControl string
+}
+
+func route(method, path string, handler http.HandlerFunc, operation *openapi.Operation) Route {
+	return Route{Method: method, Path: path, Handler: handler, Operation: operation}
+}
+
+// Cached returns the route with the Cache-Control policy of its successful responses, which is documented.
+func (rt Route) Cached(policy string) Route {
+	rt.CacheControl = policy
+	rt.Operation.WithHeader(http.StatusOK, "Cache-Control", "Caching policy of the response")
+	return rt
+}
+
+// Register adds the routes to the router, in order, and documents them in the API document.
+func Register(r *mux.Router, doc *openapi.Document, routes []Route) error {
+	for _, rt := range routes {
+		if err := doc.Add(rt.Method, rt.Path, rt.Operation); err != nil {
+			return err
+		}
+		handler := rt.Handler
+		if rt.CacheControl != "" {
+			handler = cacheControl(rt.CacheControl, handler)
+		}
+		r.Handle(rt.Path, handler).Methods(rt.Method)
+	}
+	return nil
+}
+
+// cacheControl sets the Cache-Control header of the 200 and 304 responses of the handler, errors not being cached.
+func cacheControl(policy string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: res, policy: policy}, req)
+	})
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.wroteHeader = true
+		if status == http.StatusOK || status == http.StatusNotModified {
+			w.Header().Set("Cache-Control", w.policy)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// V1Routes returns the routes of the first version of the API under /v1, and at their unversioned paths for the
+// clients predating the versioning. Their operations are documented as deprecated.
+func V1Routes(routes []Route) []Route {
+	versioned := make([]Route, len(routes))
+	unversioned := make([]Route, len(routes))
+	for i, rt := range routes {
+		rt.Operation.Deprecate()
+		versioned[i], unversioned[i] = rt, rt
+		versioned[i].Path = "/v1" + rt.Path
+		unversioned[i].Operation = rt.Operation.Alias(rt.Operation.OperationID + "Unversioned")
+	}
+	return append(versioned, unversioned...)
+}
+
+// Deprecation sets the Deprecation header (RFC 9745), the Sunset header (RFC 8594) and a link to the documentation
+// on the responses of the routes whose operations are deprecated in the document. It should be used before the
+// validator, for the rejected requests to get them too.
+func Deprecation(doc *openapi.Document, deprecation, sunset time.Time) mux.MiddlewareFunc {
+	deprecationHeader := "@" + strconv.FormatInt(deprecation.Unix(), 10)
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if route := mux.CurrentRoute(req); route != nil {
+				path, err := route.GetPathTemplate()
+				if operation := doc.Operation(req.Method, path); err == nil && operation != nil && operation.Deprecated {
+					res.Header().Set("Deprecation", deprecationHeader)
+					res.Header().Set("Sunset", sunsetHeader)
+					res.Header().Add("Link", `</docs>; rel="deprecation"; type="text/html"`)
+				}
+			}
+			next.ServeHTTP(res, req)
+		})
+	}
+}
+
+// V2Routes returns the routes of the second version of the API, under /v2, which only differs from the first one
+// by the representation of the movies so far. The resources linked from the v2 movies are served under /v2 too.
+func (h *Handler) V2Routes() []Route {
+	movie := openapi.SchemaOf(dto.MovieV2{})
+	movies := openapi.SchemaOf([]dto.MovieV2{})
+	return []Route{
+		route(http.MethodGet, "/v2/movies", negotiate.Acceptable(h.GetMoviesV2),
+			conditional(movieFilter(operation("listMoviesV2", "List the movies matching the filters", "movies")).
+				ReturnsContents(http.StatusOK, "The matching movies", formats(movies)).
+				Errors(http.StatusNotAcceptable))).
+			Cached(config.MoviesCacheControl),
+		route(http.MethodGet, "/v2/movies/trash", negotiate.Acceptable(h.GetTrashV2),
+			operation("listTrashV2", "List the deleted movies, until they are purged", "movies").
+				ReturnsContents(http.StatusOK, "The deleted movies", formats(movies)).
+				Errors(http.StatusNotAcceptable)),
+		route(http.MethodGet, "/v2/movies/{movieId}", negotiate.Acceptable(h.GetMovieV2),
+			conditional(movieOperation("getMovieV2", "Get a movie", "movies").
+				ReturnsContents(http.StatusOK, "The movie", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusNotFound))).
+			Cached(config.MovieCacheControl),
+		route(http.MethodPost, "/v2/movies", h.BasicAuth(negotiate.Acceptable(h.AddMovieV2)),
+			idempotent(operation("createMovieV2", "Create a movie from the TMDB movie with the title", "movies").Authenticated().
+				BodyContents(formats(openapi.Object(map[string]*openapi.Schema{
+					"id":    openapi.Integer(),
+					"title": openapi.String().Length(1, dto.MaxTitleLength),
+				}, "title"))).
+				ReturnsContents(http.StatusCreated, "The created movie, enriched in the background", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusConflict))),
+		route(http.MethodPut, "/v2/movies/{movieId}", h.BasicAuth(negotiate.Acceptable(h.UpdateMovieV2)),
+			idempotent(movieOperation("updateMovieV2", "Update a movie", "movies").Authenticated().
+				BodyContents(formats(openapi.Object(map[string]*openapi.Schema{
+					"title":          openapi.String().Length(1, dto.MaxTitleLength),
+					"overview":       openapi.String(),
+					"runtimeMinutes": openapi.Integer().AtLeast(0),
+					"release": openapi.Object(map[string]*openapi.Schema{
+						"date": openapi.String().WithFormat("date"),
+					}),
+					"genres": openapi.Array(openapi.Object(map[string]*openapi.Schema{"id": openapi.Integer()}, "id")).
+						Describe("Genres of the movie, the current ones being kept if missing"),
+				}, "title"))).
+				ReturnsContents(http.StatusOK, "The updated movie", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusNotFound))),
+		route(http.MethodDelete, "/v2/movies/{movieId}", h.BasicAuth(h.DeleteMovie),
+			idempotent(movieOperation("deleteMovieV2", "Move a movie to the trash", "movies").Authenticated().
+				Returns(http.StatusNoContent, "The movie was deleted", nil).
+				Errors(http.StatusNotFound))),
+		route(http.MethodPost, "/v2/movies/{movieId}/restore", h.BasicAuth(negotiate.Acceptable(h.RestoreMovieV2)),
+			idempotent(movieOperation("restoreMovieV2", "Restore a movie from the trash", "movies").Authenticated().
+				ReturnsContents(http.StatusOK, "The restored movie", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusNotFound))),
+		route(http.MethodGet, "/v2/movies/{movieId}/credits", h.GetMovieCredits,
+			movieOperation("getMovieCreditsV2", "Get the cast and crew of a movie", "credits").
+				Returns(http.StatusOK, "The credits of the movie", openapi.SchemaOf(model.MovieCredits{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/v2/movies/{movieId}/similar", h.GetSimilarMovies,
+			movieOperation("listSimilarMoviesV2", "List the movies with the most similar overview and genres", "movies").
+				Query("limit", limit(maxSimilarLimit), "Number of movies").
+				Returns(http.StatusOK, "The similar movies", openapi.SchemaOf([]model.SimilarMovie{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/v2/movies/{movieId}/reviews", h.GetReviews,
+			movieOperation("listReviewsV2", "List the reviews of a movie, including the hidden ones for admins", "ratings").
+				Returns(http.StatusOK, "The reviews of the movie", openapi.SchemaOf([]model.Review{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/v2/movies/{movieId}/assets", h.GetAssets,
+			movieOperation("listAssetsV2", "List the assets of a movie", "assets").
+				Returns(http.StatusOK, "The assets of the movie", openapi.SchemaOf([]model.MovieAsset{})).
+				Errors(http.StatusNotFound)),
+	}
+}
+
+// Routes returns the REST endpoints, the fixed paths before the ones with a variable at the same position.
+func (h *Handler) Routes() []Route {
+	var (
+		message  = openapi.SchemaOf(model.ResponseMessage{})
+		movie    = openapi.SchemaOf(model.Movie{})
+		movies   = openapi.SchemaOf([]model.Movie{})
+		queueJob = openapi.SchemaOf(model.QueueJob{})
+		rating   = openapi.SchemaOf(model.Rating{})
+		review   = openapi.SchemaOf(model.Review{})
+	)
+	return []Route{
+		route(http.MethodGet, "/ping", h.PingHandler,
+			operation("ping", "Check that the server is running", "health").
+				Returns(http.StatusOK, "The server is running", message)),
+
+		route(http.MethodGet, "/movies", negotiate.Acceptable(h.GetMovies),
+			conditional(movieFilter(operation("listMovies", "List the movies matching the filters", "movies")).
+				ReturnsContents(http.StatusOK, "The matching movies", formats(movies)).
+				Errors(http.StatusNotAcceptable))).
+			Cached(config.MoviesCacheControl),
+		route(http.MethodGet, "/movies/export", h.ExportMovies,
+			movieFilter(operation("exportMovies", "Export the movies matching the filters", "movies")).
+				Query("format", openapi.String().OneOf("csv", "ndjson", "parquet"), "Format of the export, csv by default").
+				ReturnsContent(http.StatusOK, "The exported movies", "text/csv", file()).
+				ReturnsContent(http.StatusOK, "The exported movies", "application/x-ndjson", file()).
+				ReturnsContent(http.StatusOK, "The exported movies", "application/vnd.apache.parquet", file()).
+				WithHeader(http.StatusOK, "Content-Disposition", "Name of the exported file")),
+		route(http.MethodGet, "/movies/trash", negotiate.Acceptable(h.GetTrash),
+			operation("listTrash", "List the deleted movies, until they are purged", "movies").
+				ReturnsContents(http.StatusOK, "The deleted movies", formats(movies)).
+				Errors(http.StatusNotAcceptable)),
+		route(http.MethodGet, "/movies/{movieId}", negotiate.Acceptable(h.GetMovie),
+			conditional(movieOperation("getMovie", "Get a movie", "movies").
+				ReturnsContents(http.StatusOK, "The movie", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusNotFound))).
+			Cached(config.MovieCacheControl),
+		route(http.MethodPost, "/movies", h.BasicAuth(negotiate.Acceptable(h.AddMovie)),
+			idempotent(operation("createMovie", "Create a movie from the TMDB movie with the title", "movies").Authenticated().
+				BodyContents(formats(openapi.Object(map[string]*openapi.Schema{
+					"id":    openapi.Integer(),
+					"title": openapi.String(),
+				}, "title"))).
+				ReturnsContents(http.StatusCreated, "The created movie, enriched in the background", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusConflict))),
+		route(http.MethodPost, "/movies/import", h.BasicAuth(h.ImportMovies),
+			operation("importMovies", "Import movies from a CSV or NDJSON file", "imports").Authenticated().
+				Query("format", openapi.String().OneOf(model.ImportFormatCSV, model.ImportFormatNDJSON),
+					"Format of the file, by default the one of the content type or of the file name").
+				Body("multipart/form-data", openapi.Object(map[string]*openapi.Schema{"file": file()}, "file")).
+				Body("text/csv", file()).
+				Body("application/x-ndjson", file()).
+				Body("application/ndjson", file()).
+				Body("application/octet-stream", file()).
+				BodyLimit(config.ImportMaxBytes).
+				Returns(http.StatusAccepted, "The started import", openapi.SchemaOf(model.MovieImport{})).
+				WithHeader(http.StatusAccepted, "Location", "Path of the import").
+				Errors(http.StatusUnsupportedMediaType)),
+		route(http.MethodPut, "/movies/{movieId}", h.BasicAuth(negotiate.Acceptable(h.UpdateMovie)),
+			idempotent(movieOperation("updateMovie", "Update a movie", "movies").Authenticated().
+				BodyContents(formats(openapi.Object(map[string]*openapi.Schema{
+					"id":          id(),
+					"title":       openapi.String(),
+					"overview":    openapi.String(),
+					"runtime":     openapi.Integer().AtLeast(0),
+					"releaseDate": openapi.String().Describe("Formatted as YYYY-MM-DD"),
+					"genres": openapi.Array(openapi.Object(map[string]*openapi.Schema{"id": openapi.Integer()}, "id")).
+						Describe("Genres of the movie, the current ones being kept if missing"),
+				}, "id"))).
+				ReturnsContents(http.StatusOK, "The updated movie", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusNotFound))),
+		route(http.MethodDelete, "/movies/{movieId}", h.BasicAuth(h.DeleteMovie),
+			idempotent(movieOperation("deleteMovie", "Move a movie to the trash", "movies").Authenticated().
+				Returns(http.StatusNoContent, "The movie was deleted", nil).
+				Errors(http.StatusNotFound))),
+		route(http.MethodPost, "/movies/{movieId}/restore", h.BasicAuth(negotiate.Acceptable(h.RestoreMovie)),
+			idempotent(movieOperation("restoreMovie", "Restore a movie from the trash", "movies").Authenticated().
+				ReturnsContents(http.StatusOK, "The restored movie", formats(movie)).
+				Errors(http.StatusNotAcceptable, http.StatusNotFound))),
+		route(http.MethodPost, "/movies/{movieId}/enrich", h.BasicAuth(h.EnrichMovie),
+			idempotent(movieOperation("enrichMovie", "Enqueue the refresh of the movie details and credits from TMDB", "movies").Authenticated().
+				Returns(http.StatusAccepted, "The enqueued job", queueJob).
+				Errors(http.StatusNotFound))),
+		route(http.MethodGet, "/movies/{movieId}/revisions", h.GetRevisions,
+			movieOperation("listRevisions", "List the revisions of a movie", "revisions").
+				Returns(http.StatusOK, "The revisions of the movie", openapi.SchemaOf([]model.MovieRevision{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/movies/{movieId}/revisions/diff", h.DiffRevisions,
+			movieOperation("diffRevisions", "Compare two revisions of a movie", "revisions").
+				RequiredQuery("from", id(), "Revision to compare from").
+				RequiredQuery("to", id(), "Revision to compare to").
+				Returns(http.StatusOK, "The changed fields", openapi.SchemaOf(model.RevisionDiff{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/movies/{movieId}/revisions/{revision}", h.GetRevision,
+			movieOperation("getRevision", "Get a revision of a movie", "revisions").
+				PathParam("revision", id()).
+				Returns(http.StatusOK, "The revision", openapi.SchemaOf(model.MovieRevision{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/movies/{movieId}/revisions/{revision}/revert", h.BasicAuth(h.RevertRevision),
+			movieOperation("revertRevision", "Revert a movie to a revision", "revisions").Authenticated().
+				PathParam("revision", id()).
+				Returns(http.StatusOK, "The reverted movie", movie).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/movies/{movieId}/assets", h.GetAssets,
+			movieOperation("listAssets", "List the assets of a movie", "assets").
+				Returns(http.StatusOK, "The assets of the movie", openapi.SchemaOf([]model.MovieAsset{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/movies/{movieId}/assets", h.BasicAuth(h.ConfirmAssetUpload),
+			movieOperation("confirmAssetUpload", "Register an asset uploaded to its presigned URL", "assets").Authenticated().
+				Body("application/json", openapi.Object(map[string]*openapi.Schema{
+					"key":         openapi.String(),
+					"type":        assetType(),
+					"size":        openapi.Integer().AtLeast(0),
+					"checksum":    openapi.String().Describe("ETag or upload checksum of the object, checked against the stored one"),
+					"contentType": openapi.String(),
+				}, "key")).
+				Returns(http.StatusCreated, "The registered asset", openapi.SchemaOf(model.MovieAsset{})).
+				Errors(http.StatusNotFound, http.StatusConflict)),
+		route(http.MethodPost, "/movies/{movieId}/assets/{assetType}/upload-url", h.BasicAuth(h.GetAssetUploadURL),
+			movieOperation("getAssetUploadURL", "Get a presigned URL to upload an asset to", "assets").Authenticated().
+				PathParam("assetType", assetType()).
+				Returns(http.StatusOK, "The upload URL", openapi.SchemaOf(model.PresignedURL{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/movies/{movieId}/assets/{assetId}/download-url", h.GetAssetDownloadURL,
+			movieOperation("getAssetDownloadURL", "Get a presigned URL to download an asset from", "assets").
+				PathParam("assetId", id()).
+				Returns(http.StatusOK, "The download URL", openapi.SchemaOf(model.PresignedURL{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/movies/{movieId}/ratings", h.BasicAuth(h.RateMovie),
+			idempotent(movieOperation("rateMovie", "Rate a movie", "ratings").Authenticated().
+				Body("application/json", ratingBody()).
+				Returns(http.StatusCreated, "The rating", rating).
+				Errors(http.StatusNotFound, http.StatusConflict))),
+		route(http.MethodPut, "/movies/{movieId}/ratings", h.BasicAuth(h.UpdateRating),
+			movieOperation("updateRating", "Change the rating of a movie", "ratings").Authenticated().
+				Body("application/json", ratingBody()).
+				Returns(http.StatusOK, "The rating", rating).
+				Errors(http.StatusNotFound)),
+		route(http.MethodDelete, "/movies/{movieId}/ratings", h.BasicAuth(h.DeleteRating),
+			movieOperation("deleteRating", "Delete the rating of a movie", "ratings").Authenticated().
+				Returns(http.StatusNoContent, "The rating was deleted", nil).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/movies/{movieId}/reviews", h.GetReviews,
+			movieOperation("listReviews", "List the reviews of a movie, including the hidden ones for admins", "ratings").
+				Returns(http.StatusOK, "The reviews of the movie", openapi.SchemaOf([]model.Review{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/movies/{movieId}/reviews", h.BasicAuth(h.CreateReview),
+			idempotent(movieOperation("createReview", "Review a movie", "ratings").Authenticated().
+				Body("application/json", reviewBody()).
+				Returns(http.StatusCreated, "The review", review).
+				Errors(http.StatusNotFound, http.StatusConflict))),
+		route(http.MethodPut, "/movies/{movieId}/reviews", h.BasicAuth(h.UpdateReview),
+			movieOperation("updateReview", "Change the review of a movie", "ratings").Authenticated().
+				Body("application/json", reviewBody()).
+				Returns(http.StatusOK, "The review", review).
+				Errors(http.StatusNotFound)),
+		route(http.MethodDelete, "/movies/{movieId}/reviews", h.BasicAuth(h.DeleteReview),
+			movieOperation("deleteReview", "Delete the review of a movie", "ratings").Authenticated().
+				Returns(http.StatusNoContent, "The review was deleted", nil).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPut, "/movies/{movieId}/tags/{tagId}", h.BasicAuth(h.TagMovie),
+			movieOperation("tagMovie", "Give a tag to a movie", "tags").Authenticated().
+				PathParam("tagId", id()).
+				Returns(http.StatusOK, "The movie with its tags", movie).
+				Errors(http.StatusNotFound)),
+		route(http.MethodDelete, "/movies/{movieId}/tags/{tagId}", h.BasicAuth(h.UntagMovie),
+			movieOperation("untagMovie", "Take a tag away from a movie", "tags").Authenticated().
+				PathParam("tagId", id()).
+				Returns(http.StatusNoContent, "The tag was taken away", nil).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/movies/{movieId}/credits", h.GetMovieCredits,
+			movieOperation("getMovieCredits", "Get the cast and crew of a movie", "credits").
+				Returns(http.StatusOK, "The credits of the movie", openapi.SchemaOf(model.MovieCredits{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/movies/{movieId}/similar", h.GetSimilarMovies,
+			movieOperation("listSimilarMovies", "List the movies with the most similar overview and genres", "movies").
+				Query("limit", limit(maxSimilarLimit), "Number of movies").
+				Returns(http.StatusOK, "The similar movies", openapi.SchemaOf([]model.SimilarMovie{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/people/{personId}", h.GetPerson,
+			operation("getPerson", "Get a person with their filmography", "credits").
+				PathParam("personId", id()).
+				Returns(http.StatusOK, "The person", openapi.SchemaOf(model.Person{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/genres", h.GetGenres,
+			operation("listGenres", "List the genres with their number of movies", "tags").
+				Returns(http.StatusOK, "The genres", openapi.SchemaOf([]model.GenreCount{}))).
+			Cached(config.GenresCacheControl),
+
+		route(http.MethodGet, "/collections", h.GetCollections,
+			operation("listCollections", "List the collections", "collections").
+				Returns(http.StatusOK, "The collections, without their movies", openapi.SchemaOf([]model.Collection{}))),
+		route(http.MethodPost, "/collections", h.CuratorAuth(h.CreateCollection),
+			idempotent(curatorOperation("createCollection", "Create a manual collection", "collections").
+				Body("application/json", collectionBody()).
+				Returns(http.StatusCreated, "The created collection", openapi.SchemaOf(model.Collection{})).
+				Errors(http.StatusNotFound))),
+		route(http.MethodGet, "/collections/{collectionId}", h.GetCollection,
+			operation("getCollection", "Get a collection with its movies", "collections").
+				PathParam("collectionId", id()).
+				Returns(http.StatusOK, "The collection", openapi.SchemaOf(model.Collection{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPut, "/collections/{collectionId}", h.CuratorAuth(h.UpdateCollection),
+			curatorOperation("updateCollection", "Change a manual collection", "collections").
+				PathParam("collectionId", id()).
+				Body("application/json", collectionBody()).
+				Returns(http.StatusOK, "The updated collection", openapi.SchemaOf(model.Collection{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodDelete, "/collections/{collectionId}", h.CuratorAuth(h.DeleteCollection),
+			curatorOperation("deleteCollection", "Delete a manual collection", "collections").
+				PathParam("collectionId", id()).
+				Returns(http.StatusNoContent, "The collection was deleted", nil).
+				Errors(http.StatusNotFound)),
+
+		route(http.MethodGet, "/tags", h.GetTags,
+			operation("listTags", "List the tags with their number of movies", "tags").
+				Returns(http.StatusOK, "The tags", openapi.SchemaOf([]model.TagCount{}))),
+		route(http.MethodPost, "/tags", h.BasicAuth(h.CreateTag),
+			idempotent(operation("createTag", "Create a tag", "tags").Authenticated().
+				Body("application/json", tagBody()).
+				Returns(http.StatusCreated, "The created tag", openapi.SchemaOf(model.Tag{})).
+				Errors(http.StatusConflict))),
+		route(http.MethodPut, "/tags/{tagId}", h.AdminAuth(h.RenameTag),
+			adminOperation("renameTag", "Rename a tag", "tags").
+				PathParam("tagId", id()).
+				Body("application/json", tagBody()).
+				Returns(http.StatusOK, "The renamed tag", openapi.SchemaOf(model.Tag{})).
+				Errors(http.StatusNotFound, http.StatusConflict)),
+		route(http.MethodDelete, "/tags/{tagId}", h.AdminAuth(h.DeleteTag),
+			adminOperation("deleteTag", "Delete a tag", "tags").
+				PathParam("tagId", id()).
+				Returns(http.StatusNoContent, "The tag was deleted", nil).
+				Errors(http.StatusNotFound)),
+
+		route(http.MethodGet, "/me/recommendations", h.BasicAuth(h.GetRecommendations),
+			operation("listRecommendations", "List the movies recommended to the user", "ratings").Authenticated().
+				Query("limit", limit(maxRecommendationLimit), "Number of movies").
+				Returns(http.StatusOK, "The recommended movies", openapi.SchemaOf([]model.Recommendation{}))),
+		route(http.MethodGet, "/me/watchlists", h.BasicAuth(h.GetWatchlists),
+			operation("listWatchlists", "List the watchlists of the user", "watchlists").Authenticated().
+				Returns(http.StatusOK, "The watchlists", openapi.SchemaOf([]model.Watchlist{}))),
+		route(http.MethodPost, "/me/watchlists", h.BasicAuth(h.CreateWatchlist),
+			idempotent(operation("createWatchlist", "Create a watchlist", "watchlists").Authenticated().
+				Body("application/json", openapi.Object(map[string]*openapi.Schema{"name": openapi.String()}, "name")).
+				Returns(http.StatusCreated, "The created watchlist", openapi.SchemaOf(model.Watchlist{})).
+				Errors(http.StatusConflict))),
+		route(http.MethodGet, "/me/watchlists/{watchlistId}", h.BasicAuth(h.GetWatchlist),
+			watchlistOperation("getWatchlist", "Get a watchlist with its movies").
+				Returns(http.StatusOK, "The watchlist", openapi.SchemaOf(model.Watchlist{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodDelete, "/me/watchlists/{watchlistId}", h.BasicAuth(h.DeleteWatchlist),
+			watchlistOperation("deleteWatchlist", "Delete a watchlist other than the default one").
+				Returns(http.StatusNoContent, "The watchlist was deleted", nil).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPut, "/me/watchlists/{watchlistId}/order", h.BasicAuth(h.ReorderWatchlist),
+			watchlistOperation("reorderWatchlist", "Reorder the movies of a watchlist").
+				Body("application/json", openapi.Object(map[string]*openapi.Schema{
+					"movieIds": openapi.Array(id()).Describe("Every movie of the watchlist, in the new order"),
+				}, "movieIds")).
+				Returns(http.StatusOK, "The reordered watchlist", openapi.SchemaOf(model.Watchlist{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/me/watchlists/{watchlistId}/movies", h.BasicAuth(h.AddWatchlistMovie),
+			idempotent(watchlistOperation("addWatchlistMovie", "Add a movie to a watchlist").
+				Body("application/json", openapi.Object(map[string]*openapi.Schema{"movieId": id()}, "movieId")).
+				Returns(http.StatusOK, "The watchlist", openapi.SchemaOf(model.Watchlist{})).
+				Errors(http.StatusNotFound, http.StatusConflict))),
+		route(http.MethodPut, "/me/watchlists/{watchlistId}/movies/{movieId}", h.BasicAuth(h.UpdateWatchlistMovie),
+			watchlistOperation("updateWatchlistMovie", "Mark a movie of a watchlist as watched or not").
+				PathParam("movieId", id()).
+				Body("application/json", openapi.Object(map[string]*openapi.Schema{"watched": openapi.Boolean()}, "watched")).
+				Returns(http.StatusOK, "The watchlist", openapi.SchemaOf(model.Watchlist{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodDelete, "/me/watchlists/{watchlistId}/movies/{movieId}", h.BasicAuth(h.RemoveWatchlistMovie),
+			watchlistOperation("removeWatchlistMovie", "Remove a movie from a watchlist").
+				PathParam("movieId", id()).
+				Returns(http.StatusNoContent, "The movie was removed", nil).
+				Errors(http.StatusNotFound)),
+
+		route(http.MethodGet, "/imports/{importId}", h.GetImport,
+			operation("getImport", "Get the progress of an import", "imports").
+				PathParam("importId", id()).
+				Returns(http.StatusOK, "The import", openapi.SchemaOf(model.MovieImport{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/imports/{importId}/errors", h.GetImportErrors,
+			operation("getImportErrors", "Download the rows of an import which failed", "imports").
+				PathParam("importId", id()).
+				ReturnsContent(http.StatusOK, "The failed rows with their error", "text/csv", file()).
+				Errors(http.StatusNotFound)),
+
+		route(http.MethodGet, "/admin/jobs", h.AdminAuth(h.GetJobs),
+			adminOperation("listJobs", "List the scheduled jobs", "admin").
+				Returns(http.StatusOK, "The jobs", openapi.SchemaOf([]scheduler.JobStatus{}))),
+		route(http.MethodPost, "/admin/jobs/{name}/run", h.AdminAuth(h.TriggerJob),
+			adminOperation("triggerJob", "Run a scheduled job now", "admin").
+				PathParam("name", jobName()).
+				Returns(http.StatusAccepted, "The job was triggered", message).
+				Errors(http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable)),
+		route(http.MethodGet, "/admin/leader", h.AdminAuth(h.GetLeader),
+			adminOperation("getLeader", "Get the replica leading the scheduled jobs", "admin").
+				Returns(http.StatusOK, "The leader lease", openapi.SchemaOf(leaderResponse{}))),
+		route(http.MethodGet, "/admin/grpc/metrics", h.AdminAuth(h.GetRPCMetrics),
+			adminOperation("getRPCMetrics", "List the gRPC calls served by the replica", "admin").
+				Returns(http.StatusOK, "The calls by method", openapi.SchemaOf([]rpc.MethodStats{}))),
+		route(http.MethodGet, "/admin/cache/movies", h.AdminAuth(h.GetMovieCacheStats),
+			adminOperation("getMovieCacheStats", "Get the counters of the movie cache of the replica", "admin").
+				Returns(http.StatusOK, "The counters of the cache", openapi.SchemaOf(service.MovieCacheStats{}))),
+		route(http.MethodPost, "/admin/cache/movies/flush", h.AdminAuth(h.FlushMovieCache),
+			adminOperation("flushMovieCache", "Drop the movies cached by the replica", "admin").
+				Returns(http.StatusNoContent, "The cache was flushed", nil)),
+		route(http.MethodGet, "/admin/refresh-runs", h.AdminAuth(h.GetRefreshRuns),
+			adminOperation("listRefreshRuns", "List the recent runs of the metadata refresh", "admin").
+				Returns(http.StatusOK, "The refresh runs", openapi.SchemaOf([]model.RefreshRun{}))),
+		route(http.MethodGet, "/admin/queue/jobs", h.AdminAuth(h.GetQueueJobs),
+			adminOperation("listQueueJobs", "List the jobs of the queue", "admin").
+				Query("status", openapi.String().OneOf(model.QueueJobPending, model.QueueJobRunning, model.QueueJobCompleted,
+					model.QueueJobDead, model.QueueJobCancelled), "Status of the jobs").
+				Query("kind", openapi.String(), "Kind of the jobs").
+				Query("limit", limit(maxQueueJobsLimit), "Number of jobs").
+				Returns(http.StatusOK, "The jobs", openapi.SchemaOf([]model.QueueJob{}))),
+		route(http.MethodGet, "/admin/queue/jobs/{jobId}", h.AdminAuth(h.GetQueueJob),
+			queueJobOperation("getQueueJob", "Get a job of the queue").
+				Returns(http.StatusOK, "The job", queueJob).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/admin/queue/jobs/{jobId}/retry", h.AdminAuth(h.RetryQueueJob),
+			queueJobOperation("retryQueueJob", "Retry a dead or cancelled job").
+				Returns(http.StatusAccepted, "The job, pending again", queueJob).
+				Errors(http.StatusNotFound, http.StatusConflict)),
+		route(http.MethodPost, "/admin/queue/jobs/{jobId}/cancel", h.AdminAuth(h.CancelQueueJob),
+			queueJobOperation("cancelQueueJob", "Cancel a pending job").
+				Returns(http.StatusOK, "The cancelled job", queueJob).
+				Errors(http.StatusNotFound, http.StatusConflict)),
+		route(http.MethodGet, "/admin/users", h.AdminAuth(h.GetUsers),
+			adminOperation("listUsers", "List the users", "admin").
+				Returns(http.StatusOK, "The users", openapi.SchemaOf([]model.User{}))),
+		route(http.MethodPost, "/admin/users", h.AdminAuth(h.CreateUser),
+			idempotent(adminOperation("createUser", "Create a user", "admin").
+				Body("application/json", userBody("username", "password")).
+				Returns(http.StatusCreated, "The created user", openapi.SchemaOf(model.User{})).
+				Errors(http.StatusConflict))),
+		route(http.MethodPut, "/admin/users/{username}", h.AdminAuth(h.UpdateUser),
+			adminOperation("updateUser", "Change the roles of a user, and their password if one is given", "admin").
+				PathParam("username", username()).
+				Body("application/json", userBody()).
+				Returns(http.StatusOK, "The updated user", openapi.SchemaOf(model.User{})).
+				Errors(http.StatusNotFound)),
+		route(http.MethodDelete, "/admin/users/{username}", h.AdminAuth(h.DeleteUser),
+			adminOperation("deleteUser", "Delete a user", "admin").
+				PathParam("username", username()).
+				Returns(http.StatusNoContent, "The user was deleted", nil).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/admin/reviews/{reviewId}/hide", h.AdminAuth(h.HideReview),
+			adminOperation("hideReview", "Hide a review from the users", "ratings").
+				PathParam("reviewId", id()).
+				Returns(http.StatusOK, "The hidden review", review).
+				Errors(http.StatusNotFound)),
+		route(http.MethodPost, "/admin/reviews/{reviewId}/unhide", h.AdminAuth(h.UnhideReview),
+			adminOperation("unhideReview", "Show a hidden review to the users again", "ratings").
+				PathParam("reviewId", id()).
+				Returns(http.StatusOK, "The review", review).
+				Errors(http.StatusNotFound)),
+		route(http.MethodGet, "/audit", h.AdminAuth(h.GetAuditLog),
+			adminOperation("listAuditEntries", "List the changes of movies and users", "admin").
+				Query("entity", openapi.String().OneOf(model.AuditEntityMovie, model.AuditEntityUser), "Kind of the changed entities").
+				Query("entityId", openapi.String(), "Id of the changed entity").
+				Query("actor", openapi.String(), "User who made the changes").
+				Query("from", openapi.String().WithFormat("date-time"), "Start of the changes, inclusive").
+				Query("to", openapi.String().WithFormat("date-time"), "End of the changes, exclusive").
+				Query("limit", limit(maxAuditLimit), "Number of changes").
+				Returns(http.StatusOK, "The changes, the latest first", openapi.SchemaOf([]model.AuditEntry{}))),
+	}
+}
+
+// GraphQLRoutes returns the endpoints of the GraphQL handler, which reads queries from the query parameters of GET
+// requests and from the JSON body of POST ones.
+func GraphQLRoutes(graphql http.Handler) []Route {
+	result := openapi.Object(map[string]*openapi.Schema{
+		"data":   openapi.Any(),
+		"errors": openapi.Array(openapi.Any()),
+	})
+	graphqlOperation := func(operationId, summary string) *openapi.Operation {
+		return operation(operationId, summary, "graphql").
+			Returns(http.StatusOK, "The result of the operation", result).
+			Returns(http.StatusBadRequest, "The request is not a valid operation", result).
+			Returns(http.StatusMethodNotAllowed, "Mutations should be sent with POST", result).
+			ReturnsContent(http.StatusUnauthorized, "Invalid credentials", "text/plain", openapi.String())
+	}
+	return []Route{
+		{
+			Method:  http.MethodGet,
+			Path:    "/graphql",
+			Handler: graphql,
+			Operation: graphqlOperation("queryGraphQL", "Run a GraphQL query").
+				RequiredQuery("query", openapi.String(), "The GraphQL document").
+				Query("operationName", openapi.String(), "Operation of the document to run").
+				Query("variables", openapi.String(), "Variables of the operation, as a JSON object"),
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/graphql",
+			Handler: graphql,
+			Operation: graphqlOperation("runGraphQL", "Run a GraphQL query or mutation").
+				Body("application/json", openapi.Object(map[string]*openapi.Schema{
+					"query":         openapi.String(),
+					"operationName": openapi.String(),
+					"variables":     openapi.Object(nil),
+				}, "query")),
+		},
+	}
+}
+
+func operation(operationId, summary, tag string) *openapi.Operation {
+	return openapi.NewOperation(operationId, summary, tag)
+}
+
+func movieOperation(operationId, summary, tag string) *openapi.Operation {
+	return operation(operationId, summary, tag).PathParam("movieId", id())
+}
+
+func watchlistOperation(operationId, summary string) *openapi.Operation {
+	return operation(operationId, summary, "watchlists").Authenticated().
+		PathParam("watchlistId", openapi.String().WithPattern(`^([1-9][0-9]*|`+defaultWatchlistParam+`)$`).
+			Describe("Id of the watchlist, or default for the default watchlist of the user"))
+}
+
+func queueJobOperation(operationId, summary string) *openapi.Operation {
+	return adminOperation(operationId, summary, "admin").PathParam("jobId", id())
+}
+
+func adminOperation(operationId, summary, tag string) *openapi.Operation {
+	return operation(operationId, summary+", for admins", tag).Authenticated().Errors(http.StatusForbidden)
+}
+
+func curatorOperation(operationId, summary, tag string) *openapi.Operation {
+	return operation(operationId, summary+", for curators", tag).Authenticated().Errors(http.StatusForbidden)
+}
+
+// formats returns the contents of a request or response in the formats of the negotiate package, described by the
+// schema of their JSON encoding but for CSV.
+func formats(schema *openapi.Schema) map[string]*openapi.Schema {
+	contents := map[string]*openapi.Schema{}
+	for _, codec := range negotiate.Codecs {
+		for _, mediaType := range codec.MediaTypes {
+			contents[mediaType] = schema
+			if codec == negotiate.CSV {
+				contents[mediaType] = openapi.String()
+			}
+		}
+	}
+	return contents
+}
+
+// conditional documents the If-Modified-Since header of the operations answered with a 304 response when the
+// resource was not modified since, and the Last-Modified header of their successful response.
+func conditional(operation *openapi.Operation) *openapi.Operation {
+	return operation.
+		Header("If-Modified-Since", openapi.String(), "HTTP date of the Last-Modified header of a previous response").
+		WithHeader(http.StatusOK, "Last-Modified", "When the resource was last modified").
+		Returns(http.StatusNotModified, "The resource was not modified since If-Modified-Since", nil)
+}
+
+// movieFilter documents the query parameters read by parseMovieFilter.
+func movieFilter(operation *openapi.Operation) *openapi.Operation {
+	labels := openapi.Array(openapi.String())
+	match := openapi.String().OneOf("any", "all")
+	return operation.
+		Query("title", openapi.String(), "Part of the title").
+		Query("minRuntime", openapi.Integer().AtLeast(0), "Minimum runtime in minutes").
+		Query("maxRuntime", openapi.Integer().AtLeast(0), "Maximum runtime in minutes").
+		Query("person", openapi.Integer().AtLeast(0), "Id of a person credited in the movies").
+		Query("genre", labels, "Genre names, repeated or comma separated").
+		Query("genreMatch", match, "Whether the movies should have any or all of the genres").
+		Query("tag", labels, "Tag names, repeated or comma separated").
+		Query("tagMatch", match, "Whether the movies should have any or all of the tags")
+}
+
+func id() *openapi.Schema {
+	return openapi.Integer().AtLeast(1)
+}
+
+func limit(maximum int) *openapi.Schema {
+	return openapi.Integer().Between(1, float64(maximum))
+}
+
+func file() *openapi.Schema {
+	return openapi.String().WithFormat("binary")
+}
+
+func assetType() *openapi.Schema {
+	return openapi.String().OneOf(model.AssetTypePoster, model.AssetTypeTrailer, model.AssetTypeSubtitle)
+}
+
+// jobName documents the names of the scheduled jobs, lowercase words separated by dashes.
+func jobName() *openapi.Schema {
+	return openapi.String().WithPattern(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+}
+
+// username documents the usernames, as long as the users table allows.
+func username() *openapi.Schema {
+	return openapi.String().Length(1, 50)
+}
+
+func ratingBody() *openapi.Schema {
+	return openapi.Object(map[string]*openapi.Schema{
+		"rating": openapi.Integer().Between(model.MinRating, model.MaxRating),
+	}, "rating")
+}
+
+func reviewBody() *openapi.Schema {
+	return openapi.Object(map[string]*openapi.Schema{
+		"text":    openapi.String(),
+		"spoiler": openapi.Boolean(),
+	}, "text")
+}
+
+func collectionBody() *openapi.Schema {
+	return openapi.Object(map[string]*openapi.Schema{
+		"name":        openapi.String(),
+		"description": openapi.String(),
+		"movieIds":    openapi.Array(id()).Describe("Movies of the collection, in order"),
+	}, "name")
+}
+
+func tagBody() *openapi.Schema {
+	return openapi.Object(map[string]*openapi.Schema{"name": openapi.String()}, "name")
+}
+
+func userBody(required ...string) *openapi.Schema {
+	return openapi.Object(map[string]*openapi.Schema{
+		"username": openapi.String(),
+		"password": openapi.String(),
+		"admin":    openapi.Boolean(),
+		"curator":  openapi.Boolean(),
+	}, required...)
+}
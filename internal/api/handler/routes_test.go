@@ -0,0 +1,1215 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"rest_api/internal/api/config"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/openapi"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/service"
+	"rest_api/internal/api/similarity"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"rest_api/internal/queue"
+	"rest_api/internal/scheduler"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	miniogo "github.com/minio/minio-go/v7"
+)
+
+func TestRegister_DocumentsEveryRoute(t *testing.T) {
+	h := &Handler{}
+	r := mux.NewRouter()
+	doc := openapi.NewDocument("test", "1.0.0", "")
+
+	routes := V1Routes(h.Routes())
+	routes = append(routes, h.V2Routes()...)
+	routes = append(routes, GraphQLRoutes(http.NotFoundHandler())...)
+	err := Register(r, doc, routes)
+	require.NoError(t, err)
+
+	count := 0
+	operationIds := map[string]bool{}
+	err = r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		methods, err := route.GetMethods()
+		require.NoError(t, err)
+		for _, method := range methods {
+			operation := doc.Operation(method, path)
+			if assert.NotNil(t, operation, "%s %s is not documented", method, path) {
+				assert.False(t, operationIds[operation.OperationID], "%s is not unique", operation.OperationID)
+				operationIds[operation.OperationID] = true
+			}
+			count++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2*70+11+2, count)
+
+	documentJSON, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(documentJSON, &document))
+	assert.Equal(t, "3.1.0", document["openapi"])
+	assert.Contains(t, document["components"].(map[string]any)["schemas"], "Movie")
+	assert.Contains(t, document["components"].(map[string]any)["schemas"], "MovieV2")
+}
+
+// TestRoutes_Contract runs requests through the router with the responses validated against the API document, so
+// that a handler drifting from its documentation fails with a 500 response.
+func TestRoutes_Contract(t *testing.T) {
+	tmdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results":[{"id":1,"original_title":"The bear","overview":"bear","runtime":123}],"total_results": 1}`))
+	}))
+	defer tmdbServer.Close()
+
+	deletedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, 6, 1, 8, 30, 15, 500, time.UTC)
+	bear := &model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear", Runtime: 95, Genres: []model.Genre{{ID: 18, Name: "Drama"}},
+		UpdatedAt: updatedAt}
+	repository := new(datatest.MovieStore)
+	repository.On("LastModified").Return(updatedAt, nil)
+	repository.On("List", mock.Anything).Return([]*model.Movie{bear}, nil)
+	repository.On("Stream", mock.Anything).Return([]*model.Movie{bear}, nil)
+	repository.On("GetTrash").Return([]*model.Movie{{MovieId: 2, MovieName: "Gone", DeletedAt: &deletedAt}}, nil)
+	repository.On("Get", 1).Return(bear, nil)
+	repository.On("Get", 2).Return((*model.Movie)(nil), data.ErrRecordNotFound)
+	repository.On("GetMany", []int{1}).Return([]*model.Movie{bear}, nil)
+	repository.On("Create", mock.Anything).Return(bear, nil)
+	repository.On("Update", mock.MatchedBy(func(movie *model.Movie) bool { return movie.MovieId == 2 })).
+		Return((*model.Movie)(nil), data.ErrRecordNotFound)
+	repository.On("Update", mock.Anything).Return(bear, nil)
+	repository.On("Delete", 1).Return(nil)
+	repository.On("Delete", 2).Return(data.ErrRecordNotFound)
+	repository.On("Restore", 2).Return(nil, data.ErrRecordNotFound)
+	publisher := new(mockPublisher)
+	publisher.On("Publish", mock.Anything).Return(nil)
+	jobs := new(mockJobQueue)
+	jobs.On("Enqueue", mock.Anything, mock.Anything).Return(nil)
+
+	revisions := new(mockRevisionRepository)
+	revisions.On("Get", 1, mock.Anything).Return(nil, data.ErrRecordNotFound)
+	assets := new(mockAssetRepository)
+	assets.On("Get", 1, 9).Return(nil, data.ErrRecordNotFound)
+	assets.On("Create", mock.Anything).Return(nil, data.ErrRecordExists)
+	storage := new(mockStorage)
+	storage.On("StatObject", "movies/1/poster/bear.jpg").Return(miniogo.ObjectInfo{Size: 1024}, nil)
+	ratings := new(mockRatingRepository)
+	ratings.On("Create", mock.Anything).Return(nil, data.ErrRecordExists)
+	ratings.On("Delete", 2, "alice").Return(data.ErrRecordNotFound)
+	reviews := new(mockReviewRepository)
+	reviews.On("Create", mock.Anything).Return(nil, data.ErrRecordExists)
+	reviews.On("Delete", 2, "alice").Return(data.ErrRecordNotFound)
+	reviews.On("SetHidden", 9, mock.Anything).Return(nil, data.ErrRecordNotFound)
+	tags := new(mockTagRepository)
+	tags.On("Create", "Noir", "alice").Return(nil, data.ErrRecordExists)
+	tags.On("Update", mock.MatchedBy(func(tag *model.Tag) bool { return tag.ID == 9 })).Return(nil, data.ErrRecordNotFound)
+	tags.On("Update", mock.Anything).Return(nil, data.ErrRecordExists)
+	tags.On("Delete", 9).Return(data.ErrRecordNotFound)
+	tags.On("RemoveFromMovie", 1, 9).Return(data.ErrRecordNotFound)
+	credits := new(mockCreditRepository)
+	credits.On("GetPerson", 9).Return(nil, data.ErrRecordNotFound)
+	collections := new(mockCollectionRepository)
+	collections.On("Get", 9).Return(nil, data.ErrRecordNotFound)
+	collections.On("Create", mock.Anything, mock.Anything).Return(nil, data.ErrRecordNotFound)
+	collections.On("Update", mock.Anything, mock.Anything).Return(nil, data.ErrRecordNotFound)
+	collections.On("Delete", 9).Return(data.ErrRecordNotFound)
+	watchlists := new(mockWatchlistRepository)
+	watchlists.On("Get", "alice", 1).Return(&model.Watchlist{ID: 1, Name: "Weekend"}, nil)
+	watchlists.On("Get", "alice", 9).Return(nil, data.ErrRecordNotFound)
+	watchlists.On("Create", "alice", "Weekend").Return(nil, data.ErrRecordExists)
+	watchlists.On("Delete", "alice", 9).Return(data.ErrRecordNotFound)
+	watchlists.On("AddMovie", 1, 1).Return(data.ErrRecordExists)
+	users := new(mockUserStore)
+	users.On("Update", mock.Anything).Return(nil, data.ErrRecordNotFound)
+	users.On("Delete", "bob").Return(nil, data.ErrRecordNotFound)
+	imports := new(mockImportRepository)
+	imports.On("Get", 9).Return(nil, data.ErrRecordNotFound)
+	queueStore := new(mockQueueStore)
+	queueStore.On("Get", int64(9)).Return(nil, data.ErrRecordNotFound)
+	queueStore.On("Retry", int64(9)).Return(nil, data.ErrRecordNotFound)
+	queueStore.On("Retry", int64(1)).Return(nil, data.ErrInvalidState)
+	queueStore.On("Cancel", int64(9)).Return(nil, data.ErrRecordNotFound)
+	queueStore.On("Cancel", int64(1)).Return(nil, data.ErrInvalidState)
+	keys := new(mockIdempotencyRepository)
+	inFlight := &model.IdempotencyKey{}
+	keys.On("Claim", mock.MatchedBy(func(key *model.IdempotencyKey) bool { return key.Key == "in-flight" })).
+		Run(func(args mock.Arguments) { inFlight.Fingerprint = args.Get(0).(*model.IdempotencyKey).Fingerprint }).
+		Return(inFlight, false, nil)
+	keys.On("Claim", mock.Anything).Return(&model.IdempotencyKey{Fingerprint: "another request"}, false, nil)
+
+	// the metadata refresh keeps running until the end of the test, the export returns at once
+	release := make(chan struct{})
+	defer close(release)
+	running := scheduler.NewScheduler(nil, nil)
+	require.NoError(t, running.Register(scheduler.Job{Name: "metadata-refresh", Schedule: scheduler.Every(time.Hour),
+		Run: func(ctx context.Context) error {
+			<-release
+			return nil
+		}}))
+	require.NoError(t, running.Register(scheduler.Job{Name: "catalog-export", Schedule: scheduler.Every(time.Hour),
+		Run: func(ctx context.Context) error { return nil }}))
+	require.NoError(t, running.Trigger("metadata-refresh"))
+	// stopped stands for the scheduler of a replica shutting down
+	stopped := scheduler.NewScheduler(nil, nil)
+	require.NoError(t, stopped.Register(scheduler.Job{Name: "catalog-export", Schedule: scheduler.Every(time.Hour),
+		Run: func(ctx context.Context) error { return nil }}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stopped.Run(ctx)
+
+	movieService := service.NewMovieService(repository, publisher)
+	ratingService := service.NewRatingService(ratings, reviews, movieService)
+	h := &Handler{
+		MovieService:       movieService,
+		TmdbService:        tmdb.NewService(tmdbServer.URL),
+		AssetService:       service.NewAssetService(assets, repository, storage, time.Hour),
+		Scheduler:          running,
+		RefreshService:     service.NewRefreshService(nil, nil, movieService, nil, nil, nil, jobs, time.Hour, 10, 1),
+		ImportService:      service.NewImportService(imports, movieService, nil, jobs, 10, 1),
+		ExportService:      service.NewExportService(repository, nil, 1, "", "", 0),
+		Queue:              queue.NewQueue(queueStore, "test", 1, time.Second, time.Second, time.Minute),
+		UserService:        service.NewUserService(users, movieService),
+		RevisionService:    service.NewRevisionService(revisions, movieService),
+		WatchlistService:   service.NewWatchlistService(watchlists, movieService),
+		RatingService:      ratingService,
+		TagService:         service.NewTagService(tags, movieService),
+		CreditService:      service.NewCreditService(credits, movieService, nil),
+		CollectionService:  service.NewCollectionService(collections, movieService),
+		SimilarityService:  service.NewSimilarityService(movieService, similarity.NewIndex(0.5)),
+		IdempotencyService: service.NewIdempotencyService(keys, time.Hour, time.Minute),
+		MovieCache:         service.NewMovieCache(repository, 10, time.Minute, time.Minute),
+	}
+	r := mux.NewRouter()
+	doc := openapi.NewDocument("test", "1.0.0", "")
+	deprecation := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, Register(r, doc, append(V1Routes(h.Routes()), h.V2Routes()...)))
+	// stands for RequestContext, which checks the credentials against the database
+	accounts := map[string]*model.User{
+		"alice": {Username: "alice"},
+		"carol": {Username: "carol", Curator: true},
+		"root":  {Username: "root", Admin: true},
+	}
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if username, _, ok := req.BasicAuth(); ok && accounts[username] != nil {
+				req = req.WithContext(reqctx.WithUser(req.Context(), accounts[username]))
+			}
+			next.ServeHTTP(res, req)
+		})
+	})
+	r.Use(Deprecation(doc, deprecation, sunset))
+	r.Use(openapi.NewValidator(doc, true).Middleware)
+	r.Use(h.Idempotency(doc))
+
+	type contractCase struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		accept      string
+		body        string
+		// user sends the request with the basic auth credentials of alice, carol the curator or root the admin
+		user           string
+		idempotencyKey string
+		status         int
+		message        string
+		// responseType is the content type of successful responses, JSON by default
+		responseType    string
+		ifModifiedSince string
+		cacheControl    string
+		// scheduler replaces the scheduler of the handler for the request
+		scheduler *scheduler.Scheduler
+	}
+	tests := []contractCase{
+		{name: "ping", method: http.MethodGet, target: "/ping", status: http.StatusOK},
+		{name: "v1 ping", method: http.MethodGet, target: "/v1/ping", status: http.StatusOK},
+		{name: "list movies", method: http.MethodGet, target: "/movies?title=bear&genre=drama,comedy&genreMatch=all", status: http.StatusOK},
+		{name: "invalid filter", method: http.MethodGet, target: "/movies?minRuntime=-5", status: http.StatusBadRequest,
+			message: "minRuntime should be at least 0"},
+		{name: "invalid match", method: http.MethodGet, target: "/movies?tagMatch=some", status: http.StatusBadRequest,
+			message: "tagMatch should be one of any, all"},
+		{name: "list trash", method: http.MethodGet, target: "/movies/trash", status: http.StatusOK},
+		{name: "export", method: http.MethodGet, target: "/movies/export?format=ndjson", status: http.StatusOK},
+		{name: "get movie", method: http.MethodGet, target: "/movies/1", status: http.StatusOK},
+		{name: "missing movie", method: http.MethodGet, target: "/movies/2", status: http.StatusNotFound},
+		{name: "invalid id", method: http.MethodGet, target: "/movies/abc", status: http.StatusBadRequest,
+			message: "movieId should be an integer"},
+		{name: "create movie", method: http.MethodPost, target: "/movies", user: "alice",
+			body: `{"title":"The bear"}`, status: http.StatusCreated},
+		{name: "create movie without title", method: http.MethodPost, target: "/movies", user: "alice", body: `{"id":45}`,
+			status: http.StatusBadRequest, message: "body.title should be present"},
+		{name: "create movie with invalid title", method: http.MethodPost, target: "/movies", user: "alice", body: `{"title":5}`,
+			status: http.StatusBadRequest, message: "body.title should be a string"},
+		{name: "create movie from xml", method: http.MethodPost, target: "/movies", user: "alice", contentType: "application/xml",
+			body: `<movie><title>The bear</title></movie>`, status: http.StatusCreated},
+		{name: "create movie from csv", method: http.MethodPost, target: "/movies", user: "alice", contentType: "text/csv",
+			body: "id,title\n1,The bear\n", accept: "text/csv", status: http.StatusCreated, responseType: "text/csv"},
+		{name: "create movie from text", method: http.MethodPost, target: "/movies", user: "alice", contentType: "text/plain",
+			body: `The bear`, status: http.StatusUnsupportedMediaType},
+		{name: "create movie for unacceptable type", method: http.MethodPost, target: "/movies", user: "alice", accept: "image/png",
+			body: `{"title":"The bear"}`, status: http.StatusNotAcceptable,
+			message: "Accept should allow application/json, application/xml, text/csv or application/msgpack"},
+		{name: "get movie as xml", method: http.MethodGet, target: "/movies/1", accept: "application/xml",
+			status: http.StatusOK, responseType: "application/xml"},
+		{name: "get movie as msgpack", method: http.MethodGet, target: "/movies/1", accept: "application/x-msgpack",
+			status: http.StatusOK, responseType: "application/x-msgpack"},
+		{name: "list movies by preference", method: http.MethodGet, target: "/movies",
+			accept: "application/json;q=0.5, text/csv;q=0.8, */*;q=0.1", status: http.StatusOK, responseType: "text/csv"},
+		{name: "update movie", method: http.MethodPut, target: "/movies/1", user: "alice",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"id":1,"title":"The bear","runtime":95}`, status: http.StatusOK},
+		{name: "update movie with negative runtime", method: http.MethodPut, target: "/movies/1", user: "alice",
+			body: `{"id":1,"runtime":-1}`, status: http.StatusBadRequest, message: "body.runtime should be at least 0"},
+		{name: "delete movie", method: http.MethodDelete, target: "/movies/1", user: "alice", status: http.StatusNoContent},
+		{name: "missing diff bounds", method: http.MethodGet, target: "/movies/1/revisions/diff?from=1", status: http.StatusBadRequest,
+			message: "to parameter should be present"},
+		{name: "v1 get movie", method: http.MethodGet, target: "/v1/movies/1", status: http.StatusOK},
+		{name: "v2 list movies", method: http.MethodGet, target: "/v2/movies?genre=drama", status: http.StatusOK},
+		{name: "v2 list trash", method: http.MethodGet, target: "/v2/movies/trash", status: http.StatusOK},
+		{name: "v2 get movie", method: http.MethodGet, target: "/v2/movies/1", status: http.StatusOK},
+		{name: "v2 missing movie", method: http.MethodGet, target: "/v2/movies/2", status: http.StatusNotFound,
+			message: "No movie with provided id exists"},
+		{name: "v2 create movie", method: http.MethodPost, target: "/v2/movies", user: "alice",
+			body: `{"title":"The bear"}`, status: http.StatusCreated},
+		{name: "v2 create movie with empty title", method: http.MethodPost, target: "/v2/movies", user: "alice", body: `{"title":""}`,
+			status: http.StatusBadRequest, message: "body.title should be at least 1 characters"},
+		{name: "v2 update movie", method: http.MethodPut, target: "/v2/movies/1", user: "alice",
+			body: `{"title":"The bear","runtimeMinutes":95,"release":{"date":"2024-05-01"}}`, status: http.StatusOK},
+		{name: "v2 update movie with invalid date", method: http.MethodPut, target: "/v2/movies/1", user: "alice",
+			body: `{"title":"The bear","release":{"date":"May 2024"}}`, status: http.StatusBadRequest,
+			message: "body.release.date should be formatted as YYYY-MM-DD"},
+		{name: "v2 delete movie", method: http.MethodDelete, target: "/v2/movies/1", user: "alice", status: http.StatusNoContent},
+		{name: "list movies not modified", method: http.MethodGet, target: "/movies?genre=drama",
+			ifModifiedSince: "Sat, 01 Jun 2024 08:30:15 GMT", status: http.StatusNotModified,
+			cacheControl: "public, max-age=30, must-revalidate"},
+		{name: "get movie modified", method: http.MethodGet, target: "/movies/1",
+			ifModifiedSince: "Sat, 01 Jun 2024 08:30:14 GMT", status: http.StatusOK,
+			cacheControl: "public, max-age=300, must-revalidate"},
+		{name: "get movie with invalid If-Modified-Since", method: http.MethodGet, target: "/movies/1",
+			ifModifiedSince: "yesterday", status: http.StatusOK},
+		{name: "v2 get movie not modified", method: http.MethodGet, target: "/v2/movies/1",
+			ifModifiedSince: "Sun, 02 Jun 2024 00:00:00 GMT", status: http.StatusNotModified,
+			cacheControl: "public, max-age=300, must-revalidate"},
+		{name: "v2 list movies as csv", method: http.MethodGet, target: "/v2/movies", accept: "text/*",
+			status: http.StatusOK, responseType: "text/csv"},
+		{name: "v2 update movie from xml", method: http.MethodPut, target: "/v2/movies/1", user: "alice", contentType: "text/xml",
+			body:   `<movieV2Update><title>The bear</title><release><date>2024-05-01</date></release></movieV2Update>`,
+			accept: "application/xml", status: http.StatusOK, responseType: "application/xml"},
+
+		{name: "update movie from xml with negative runtime", method: http.MethodPut, target: "/movies/1", user: "alice",
+			contentType: "application/xml", body: `<movie><id>1</id><title>The bear</title><runtime>-1</runtime></movie>`,
+			status: http.StatusBadRequest, message: "runtime should be at least 0"},
+		{name: "v2 create movie from xml with long title", method: http.MethodPost, target: "/v2/movies", user: "alice",
+			contentType: "application/xml", body: `<movieV2Create><title>` + strings.Repeat("b", 51) + `</title></movieV2Create>`,
+			status: http.StatusBadRequest, message: "title should be at most 50 characters"},
+		{name: "v2 update movie from csv with negative runtime", method: http.MethodPut, target: "/v2/movies/1", user: "alice",
+			contentType: "text/csv", body: "title,runtimeMinutes\nThe bear,-1\n", status: http.StatusBadRequest,
+			message: "runtimeMinutes should be at least 0"},
+
+		{name: "list movies for unacceptable type", method: http.MethodGet, target: "/movies", accept: "image/png",
+			status: http.StatusNotAcceptable},
+		{name: "invalid export format", method: http.MethodGet, target: "/movies/export?format=xml", status: http.StatusBadRequest,
+			message: "format should be one of csv, ndjson, parquet"},
+		{name: "list trash for unacceptable type", method: http.MethodGet, target: "/movies/trash", accept: "image/png",
+			status: http.StatusNotAcceptable},
+		{name: "get movie for unacceptable type", method: http.MethodGet, target: "/movies/1", accept: "image/png",
+			status: http.StatusNotAcceptable},
+		{name: "create movie in flight", method: http.MethodPost, target: "/movies", user: "alice", body: `{"title":"The bear"}`,
+			idempotencyKey: "in-flight", status: http.StatusConflict,
+			message: "A request with the Idempotency-Key is still being processed"},
+		{name: "create movie with reused key", method: http.MethodPost, target: "/movies", user: "alice", body: `{"title":"The bear"}`,
+			idempotencyKey: "reused", status: http.StatusUnprocessableEntity,
+			message: "The Idempotency-Key was already used for another request"},
+		{name: "update missing movie", method: http.MethodPut, target: "/movies/2", user: "alice", body: `{"id":2,"title":"Gone"}`,
+			status: http.StatusNotFound, message: "No movie with provided id exists"},
+		{name: "update movie for unacceptable type", method: http.MethodPut, target: "/movies/1", user: "alice", accept: "image/png",
+			body: `{"id":1,"title":"The bear"}`, status: http.StatusNotAcceptable},
+		{name: "update movie in flight", method: http.MethodPut, target: "/movies/1", user: "alice",
+			body: `{"id":1,"title":"The bear"}`, idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "update movie with reused key", method: http.MethodPut, target: "/movies/1", user: "alice",
+			body: `{"id":1,"title":"The bear"}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "delete movie with invalid id", method: http.MethodDelete, target: "/movies/abc", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "delete missing movie", method: http.MethodDelete, target: "/movies/2", user: "alice", status: http.StatusNotFound},
+		{name: "delete movie in flight", method: http.MethodDelete, target: "/movies/1", user: "alice", idempotencyKey: "in-flight",
+			status: http.StatusConflict},
+		{name: "delete movie with reused key", method: http.MethodDelete, target: "/movies/1", user: "alice", idempotencyKey: "reused",
+			status: http.StatusUnprocessableEntity},
+		{name: "restore movie with invalid id", method: http.MethodPost, target: "/movies/abc/restore", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "restore missing movie", method: http.MethodPost, target: "/movies/2/restore", user: "alice",
+			status: http.StatusNotFound},
+		{name: "restore movie for unacceptable type", method: http.MethodPost, target: "/movies/1/restore", user: "alice",
+			accept: "image/png", status: http.StatusNotAcceptable},
+		{name: "restore movie in flight", method: http.MethodPost, target: "/movies/1/restore", user: "alice",
+			idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "restore movie with reused key", method: http.MethodPost, target: "/movies/1/restore", user: "alice",
+			idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "enrich movie with invalid id", method: http.MethodPost, target: "/movies/abc/enrich", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "enrich missing movie", method: http.MethodPost, target: "/movies/2/enrich", user: "alice", status: http.StatusNotFound},
+		{name: "enrich movie in flight", method: http.MethodPost, target: "/movies/1/enrich", user: "alice",
+			idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "enrich movie with reused key", method: http.MethodPost, target: "/movies/1/enrich", user: "alice",
+			idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "import in invalid format", method: http.MethodPost, target: "/movies/import?format=xml", user: "alice",
+			contentType: "text/csv", body: "id,title\n1,The bear\n", status: http.StatusBadRequest},
+		{name: "import too large file", method: http.MethodPost, target: "/movies/import", user: "alice", contentType: "text/csv",
+			body: strings.Repeat("a", config.ImportMaxBytes+1), status: http.StatusRequestEntityTooLarge},
+		{name: "import too large form", method: http.MethodPost, target: "/movies/import", user: "alice",
+			contentType: "multipart/form-data; boundary=movies",
+			body: "--movies\r\nContent-Disposition: form-data; name=\"file\"; filename=\"movies.csv\"\r\n\r\n" +
+				strings.Repeat("a", config.ImportMaxBytes) + "\r\n--movies--\r\n",
+			status: http.StatusRequestEntityTooLarge, message: "Import file should be at most 33554432 bytes"},
+		{name: "import form without file", method: http.MethodPost, target: "/movies/import", user: "alice",
+			contentType: "multipart/form-data; boundary=movies",
+			body:        "--movies\r\nContent-Disposition: form-data; name=\"other\"\r\n\r\nbear\r\n--movies--\r\n",
+			status:      http.StatusBadRequest, message: "file field should be present"},
+		{name: "import unsupported type", method: http.MethodPost, target: "/movies/import", user: "alice", contentType: "image/png",
+			body: "png", status: http.StatusUnsupportedMediaType},
+
+		{name: "v2 invalid filter", method: http.MethodGet, target: "/v2/movies?minRuntime=-5", status: http.StatusBadRequest,
+			message: "minRuntime should be at least 0"},
+		{name: "v2 list movies for unacceptable type", method: http.MethodGet, target: "/v2/movies", accept: "image/png",
+			status: http.StatusNotAcceptable},
+		{name: "v2 list trash for unacceptable type", method: http.MethodGet, target: "/v2/movies/trash", accept: "image/png",
+			status: http.StatusNotAcceptable},
+		{name: "v2 get movie with invalid id", method: http.MethodGet, target: "/v2/movies/abc", status: http.StatusBadRequest},
+		{name: "v2 get movie for unacceptable type", method: http.MethodGet, target: "/v2/movies/1", accept: "image/png",
+			status: http.StatusNotAcceptable},
+		{name: "v2 create movie for unacceptable type", method: http.MethodPost, target: "/v2/movies", user: "alice",
+			accept: "image/png", body: `{"title":"The bear"}`, status: http.StatusNotAcceptable},
+		{name: "v2 create movie in flight", method: http.MethodPost, target: "/v2/movies", user: "alice", body: `{"title":"The bear"}`,
+			idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "v2 create movie with reused key", method: http.MethodPost, target: "/v2/movies", user: "alice",
+			body: `{"title":"The bear"}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "v2 update missing movie", method: http.MethodPut, target: "/v2/movies/2", user: "alice", body: `{"title":"Gone"}`,
+			status: http.StatusNotFound},
+		{name: "v2 update movie for unacceptable type", method: http.MethodPut, target: "/v2/movies/1", user: "alice",
+			accept: "image/png", body: `{"title":"The bear"}`, status: http.StatusNotAcceptable},
+		{name: "v2 update movie in flight", method: http.MethodPut, target: "/v2/movies/1", user: "alice", body: `{"title":"The bear"}`,
+			idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "v2 update movie with reused key", method: http.MethodPut, target: "/v2/movies/1", user: "alice",
+			body: `{"title":"The bear"}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "v2 delete movie with invalid id", method: http.MethodDelete, target: "/v2/movies/abc", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "v2 delete missing movie", method: http.MethodDelete, target: "/v2/movies/2", user: "alice",
+			status: http.StatusNotFound},
+		{name: "v2 delete movie in flight", method: http.MethodDelete, target: "/v2/movies/1", user: "alice",
+			idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "v2 delete movie with reused key", method: http.MethodDelete, target: "/v2/movies/1", user: "alice",
+			idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "v2 restore movie with invalid id", method: http.MethodPost, target: "/v2/movies/abc/restore", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "v2 restore missing movie", method: http.MethodPost, target: "/v2/movies/2/restore", user: "alice",
+			status: http.StatusNotFound},
+		{name: "v2 restore movie for unacceptable type", method: http.MethodPost, target: "/v2/movies/1/restore", user: "alice",
+			accept: "image/png", status: http.StatusNotAcceptable},
+		{name: "v2 restore movie in flight", method: http.MethodPost, target: "/v2/movies/1/restore", user: "alice",
+			idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "v2 restore movie with reused key", method: http.MethodPost, target: "/v2/movies/1/restore", user: "alice",
+			idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "v2 credits with invalid id", method: http.MethodGet, target: "/v2/movies/abc/credits", status: http.StatusBadRequest},
+		{name: "v2 credits of missing movie", method: http.MethodGet, target: "/v2/movies/2/credits", status: http.StatusNotFound},
+		{name: "v2 similar movies with invalid limit", method: http.MethodGet, target: "/v2/movies/1/similar?limit=0",
+			status: http.StatusBadRequest},
+		{name: "v2 similar movies of missing movie", method: http.MethodGet, target: "/v2/movies/2/similar",
+			status: http.StatusNotFound},
+		{name: "v2 list reviews with invalid id", method: http.MethodGet, target: "/v2/movies/abc/reviews",
+			status: http.StatusBadRequest},
+		{name: "v2 list reviews of missing movie", method: http.MethodGet, target: "/v2/movies/2/reviews",
+			status: http.StatusNotFound},
+		{name: "v2 list assets with invalid id", method: http.MethodGet, target: "/v2/movies/abc/assets",
+			status: http.StatusBadRequest},
+		{name: "v2 list assets of missing movie", method: http.MethodGet, target: "/v2/movies/2/assets",
+			status: http.StatusNotFound},
+		{name: "v2 update movie without title", method: http.MethodPut, target: "/v2/movies/1", user: "alice",
+			body: `{"overview":"bear"}`, status: http.StatusBadRequest, message: "body.title should be present"},
+
+		{name: "list revisions with invalid id", method: http.MethodGet, target: "/movies/abc/revisions", status: http.StatusBadRequest},
+		{name: "list revisions of missing movie", method: http.MethodGet, target: "/movies/2/revisions", status: http.StatusNotFound},
+		{name: "diff missing revisions", method: http.MethodGet, target: "/movies/1/revisions/diff?from=1&to=2",
+			status: http.StatusNotFound},
+		{name: "get revision with invalid number", method: http.MethodGet, target: "/movies/1/revisions/0", status: http.StatusBadRequest},
+		{name: "get missing revision", method: http.MethodGet, target: "/movies/1/revisions/9", status: http.StatusNotFound},
+		{name: "revert revision with invalid number", method: http.MethodPost, target: "/movies/1/revisions/abc/revert", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "revert missing revision", method: http.MethodPost, target: "/movies/1/revisions/9/revert", user: "alice",
+			status: http.StatusNotFound},
+
+		{name: "list assets with invalid id", method: http.MethodGet, target: "/movies/abc/assets", status: http.StatusBadRequest},
+		{name: "list assets of missing movie", method: http.MethodGet, target: "/movies/2/assets", status: http.StatusNotFound},
+		{name: "confirm asset without key", method: http.MethodPost, target: "/movies/1/assets", user: "alice",
+			body: `{"type":"poster"}`, status: http.StatusBadRequest, message: "body.key should be present"},
+		{name: "confirm asset of missing movie", method: http.MethodPost, target: "/movies/2/assets", user: "alice",
+			body: `{"key":"movies/2/poster/bear.jpg","type":"poster"}`, status: http.StatusNotFound},
+		{name: "confirm registered asset", method: http.MethodPost, target: "/movies/1/assets", user: "alice",
+			body: `{"key":"movies/1/poster/bear.jpg","type":"poster"}`, status: http.StatusConflict},
+		{name: "upload url of invalid type", method: http.MethodPost, target: "/movies/1/assets/cover/upload-url", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "upload url of missing movie", method: http.MethodPost, target: "/movies/2/assets/poster/upload-url", user: "alice",
+			status: http.StatusNotFound},
+		{name: "download url with invalid id", method: http.MethodGet, target: "/movies/1/assets/abc/download-url",
+			status: http.StatusBadRequest},
+		{name: "download url of missing asset", method: http.MethodGet, target: "/movies/1/assets/9/download-url",
+			status: http.StatusNotFound},
+
+		{name: "credits with invalid id", method: http.MethodGet, target: "/movies/abc/credits", status: http.StatusBadRequest},
+		{name: "credits of missing movie", method: http.MethodGet, target: "/movies/2/credits", status: http.StatusNotFound},
+		{name: "similar movies with invalid limit", method: http.MethodGet, target: "/movies/1/similar?limit=0",
+			status: http.StatusBadRequest},
+		{name: "similar movies of missing movie", method: http.MethodGet, target: "/movies/2/similar", status: http.StatusNotFound},
+		{name: "person with invalid id", method: http.MethodGet, target: "/people/abc", status: http.StatusBadRequest},
+		{name: "missing person", method: http.MethodGet, target: "/people/9", status: http.StatusNotFound},
+
+		{name: "rate movie out of range", method: http.MethodPost, target: "/movies/1/ratings", user: "alice",
+			body: `{"rating":0}`, status: http.StatusBadRequest},
+		{name: "rate missing movie", method: http.MethodPost, target: "/movies/2/ratings", user: "alice",
+			body: `{"rating":4}`, status: http.StatusNotFound},
+		{name: "rate movie again", method: http.MethodPost, target: "/movies/1/ratings", user: "alice",
+			body: `{"rating":4}`, status: http.StatusConflict},
+		{name: "rate movie with reused key", method: http.MethodPost, target: "/movies/1/ratings", user: "alice",
+			body: `{"rating":4}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "update rating with invalid id", method: http.MethodPut, target: "/movies/abc/ratings", user: "alice",
+			body: `{"rating":4}`, status: http.StatusBadRequest},
+		{name: "update rating of missing movie", method: http.MethodPut, target: "/movies/2/ratings", user: "alice",
+			body: `{"rating":4}`, status: http.StatusNotFound},
+		{name: "delete rating with invalid id", method: http.MethodDelete, target: "/movies/abc/ratings", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "delete missing rating", method: http.MethodDelete, target: "/movies/2/ratings", user: "alice",
+			status: http.StatusNotFound},
+		{name: "list reviews with invalid id", method: http.MethodGet, target: "/movies/abc/reviews", status: http.StatusBadRequest},
+		{name: "list reviews of missing movie", method: http.MethodGet, target: "/movies/2/reviews", status: http.StatusNotFound},
+		{name: "review without text", method: http.MethodPost, target: "/movies/1/reviews", user: "alice", body: `{}`,
+			status: http.StatusBadRequest},
+		{name: "review missing movie", method: http.MethodPost, target: "/movies/2/reviews", user: "alice",
+			body: `{"text":"Great"}`, status: http.StatusNotFound},
+		{name: "review movie again", method: http.MethodPost, target: "/movies/1/reviews", user: "alice",
+			body: `{"text":"Great"}`, status: http.StatusConflict},
+		{name: "review movie with reused key", method: http.MethodPost, target: "/movies/1/reviews", user: "alice",
+			body: `{"text":"Great"}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "update review with invalid id", method: http.MethodPut, target: "/movies/abc/reviews", user: "alice",
+			body: `{"text":"Great"}`, status: http.StatusBadRequest},
+		{name: "update review of missing movie", method: http.MethodPut, target: "/movies/2/reviews", user: "alice",
+			body: `{"text":"Great"}`, status: http.StatusNotFound},
+		{name: "delete review with invalid id", method: http.MethodDelete, target: "/movies/abc/reviews", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "delete missing review", method: http.MethodDelete, target: "/movies/2/reviews", user: "alice",
+			status: http.StatusNotFound},
+		{name: "hide review with invalid id", method: http.MethodPost, target: "/admin/reviews/abc/hide", user: "root",
+			status: http.StatusBadRequest},
+		{name: "hide review as user", method: http.MethodPost, target: "/admin/reviews/1/hide", user: "alice",
+			status: http.StatusForbidden, message: "Admin access required"},
+		{name: "hide missing review", method: http.MethodPost, target: "/admin/reviews/9/hide", user: "root",
+			status: http.StatusNotFound},
+		{name: "unhide review with invalid id", method: http.MethodPost, target: "/admin/reviews/abc/unhide", user: "root",
+			status: http.StatusBadRequest},
+		{name: "unhide review as user", method: http.MethodPost, target: "/admin/reviews/1/unhide", user: "alice",
+			status: http.StatusForbidden},
+		{name: "unhide missing review", method: http.MethodPost, target: "/admin/reviews/9/unhide", user: "root",
+			status: http.StatusNotFound},
+
+		{name: "tag movie with invalid tag", method: http.MethodPut, target: "/movies/1/tags/abc", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "tag missing movie", method: http.MethodPut, target: "/movies/2/tags/1", user: "alice", status: http.StatusNotFound},
+		{name: "untag movie with invalid tag", method: http.MethodDelete, target: "/movies/1/tags/abc", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "untag missing tag", method: http.MethodDelete, target: "/movies/1/tags/9", user: "alice", status: http.StatusNotFound},
+		{name: "create tag without name", method: http.MethodPost, target: "/tags", user: "alice", body: `{}`,
+			status: http.StatusBadRequest},
+		{name: "create existing tag", method: http.MethodPost, target: "/tags", user: "alice", body: `{"name":"Noir"}`,
+			status: http.StatusConflict},
+		{name: "create tag with reused key", method: http.MethodPost, target: "/tags", user: "alice", body: `{"name":"Noir"}`,
+			idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "rename tag with invalid id", method: http.MethodPut, target: "/tags/abc", user: "root", body: `{"name":"Noir"}`,
+			status: http.StatusBadRequest},
+		{name: "rename tag as user", method: http.MethodPut, target: "/tags/1", user: "alice", body: `{"name":"Noir"}`,
+			status: http.StatusForbidden},
+		{name: "rename missing tag", method: http.MethodPut, target: "/tags/9", user: "root", body: `{"name":"Noir"}`,
+			status: http.StatusNotFound},
+		{name: "rename tag to existing name", method: http.MethodPut, target: "/tags/1", user: "root", body: `{"name":"Noir"}`,
+			status: http.StatusConflict},
+		{name: "delete tag with invalid id", method: http.MethodDelete, target: "/tags/abc", user: "root",
+			status: http.StatusBadRequest},
+		{name: "delete tag as user", method: http.MethodDelete, target: "/tags/1", user: "alice", status: http.StatusForbidden},
+		{name: "delete missing tag", method: http.MethodDelete, target: "/tags/9", user: "root", status: http.StatusNotFound},
+
+		{name: "get collection with invalid id", method: http.MethodGet, target: "/collections/abc", status: http.StatusBadRequest},
+		{name: "get missing collection", method: http.MethodGet, target: "/collections/9", status: http.StatusNotFound},
+		{name: "create collection without name", method: http.MethodPost, target: "/collections", user: "carol", body: `{}`,
+			status: http.StatusBadRequest},
+		{name: "create collection as user", method: http.MethodPost, target: "/collections", user: "alice",
+			body: `{"name":"Bears"}`, status: http.StatusForbidden, message: "Curator access required"},
+		{name: "create collection of movie deleted meanwhile", method: http.MethodPost, target: "/collections", user: "carol",
+			body: `{"name":"Bears","movieIds":[1]}`, status: http.StatusNotFound},
+		{name: "create collection in flight", method: http.MethodPost, target: "/collections", user: "carol",
+			body: `{"name":"Bears"}`, idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "create collection with reused key", method: http.MethodPost, target: "/collections", user: "carol",
+			body: `{"name":"Bears"}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "update collection with invalid id", method: http.MethodPut, target: "/collections/abc", user: "carol",
+			body: `{"name":"Bears"}`, status: http.StatusBadRequest},
+		{name: "update collection as user", method: http.MethodPut, target: "/collections/9", user: "alice",
+			body: `{"name":"Bears"}`, status: http.StatusForbidden},
+		{name: "update missing collection", method: http.MethodPut, target: "/collections/9", user: "carol",
+			body: `{"name":"Bears","movieIds":[1]}`, status: http.StatusNotFound},
+		{name: "delete collection with invalid id", method: http.MethodDelete, target: "/collections/abc", user: "carol",
+			status: http.StatusBadRequest},
+		{name: "delete collection as user", method: http.MethodDelete, target: "/collections/9", user: "alice",
+			status: http.StatusForbidden},
+		{name: "delete missing collection", method: http.MethodDelete, target: "/collections/9", user: "carol",
+			status: http.StatusNotFound},
+
+		{name: "recommendations with invalid limit", method: http.MethodGet, target: "/me/recommendations?limit=0", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "create watchlist without name", method: http.MethodPost, target: "/me/watchlists", user: "alice", body: `{}`,
+			status: http.StatusBadRequest},
+		{name: "create existing watchlist", method: http.MethodPost, target: "/me/watchlists", user: "alice",
+			body: `{"name":"Weekend"}`, status: http.StatusConflict, message: "A watchlist with the provided name already exists"},
+		{name: "create watchlist with reused key", method: http.MethodPost, target: "/me/watchlists", user: "alice",
+			body: `{"name":"Weekend"}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "get watchlist with invalid id", method: http.MethodGet, target: "/me/watchlists/abc", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "get missing watchlist", method: http.MethodGet, target: "/me/watchlists/9", user: "alice",
+			status: http.StatusNotFound},
+		{name: "delete watchlist with invalid id", method: http.MethodDelete, target: "/me/watchlists/abc", user: "alice",
+			status: http.StatusBadRequest},
+		{name: "delete missing watchlist", method: http.MethodDelete, target: "/me/watchlists/9", user: "alice",
+			status: http.StatusNotFound},
+		{name: "reorder watchlist without movies", method: http.MethodPut, target: "/me/watchlists/1/order", user: "alice",
+			body: `{}`, status: http.StatusBadRequest},
+		{name: "reorder missing watchlist", method: http.MethodPut, target: "/me/watchlists/9/order", user: "alice",
+			body: `{"movieIds":[1]}`, status: http.StatusNotFound},
+		{name: "add watchlist movie without id", method: http.MethodPost, target: "/me/watchlists/1/movies", user: "alice",
+			body: `{}`, status: http.StatusBadRequest},
+		{name: "add movie to missing watchlist", method: http.MethodPost, target: "/me/watchlists/9/movies", user: "alice",
+			body: `{"movieId":1}`, status: http.StatusNotFound},
+		{name: "add watchlist movie again", method: http.MethodPost, target: "/me/watchlists/1/movies", user: "alice",
+			body: `{"movieId":1}`, status: http.StatusConflict, message: "The movie is already in the watchlist"},
+		{name: "add watchlist movie with reused key", method: http.MethodPost, target: "/me/watchlists/1/movies", user: "alice",
+			body: `{"movieId":1}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "mark watchlist movie without flag", method: http.MethodPut, target: "/me/watchlists/1/movies/1", user: "alice",
+			body: `{}`, status: http.StatusBadRequest},
+		{name: "mark movie of missing watchlist", method: http.MethodPut, target: "/me/watchlists/9/movies/1", user: "alice",
+			body: `{"watched":true}`, status: http.StatusNotFound},
+		{name: "remove watchlist movie with invalid id", method: http.MethodDelete, target: "/me/watchlists/1/movies/abc",
+			user: "alice", status: http.StatusBadRequest},
+		{name: "remove movie of missing watchlist", method: http.MethodDelete, target: "/me/watchlists/9/movies/1",
+			user: "alice", status: http.StatusNotFound},
+
+		{name: "get import with invalid id", method: http.MethodGet, target: "/imports/abc", status: http.StatusBadRequest},
+		{name: "get missing import", method: http.MethodGet, target: "/imports/9", status: http.StatusNotFound,
+			message: "No import with provided id exists"},
+		{name: "import errors with invalid id", method: http.MethodGet, target: "/imports/abc/errors", status: http.StatusBadRequest},
+		{name: "errors of missing import", method: http.MethodGet, target: "/imports/9/errors", status: http.StatusNotFound},
+
+		{name: "list jobs as user", method: http.MethodGet, target: "/admin/jobs", user: "alice", status: http.StatusForbidden},
+		{name: "trigger job with invalid name", method: http.MethodPost, target: "/admin/jobs/Refresh_All/run", user: "root",
+			status: http.StatusBadRequest},
+		{name: "trigger job as user", method: http.MethodPost, target: "/admin/jobs/catalog-export/run", user: "alice",
+			status: http.StatusForbidden},
+		{name: "trigger missing job", method: http.MethodPost, target: "/admin/jobs/unknown/run", user: "root",
+			status: http.StatusNotFound, message: "No job with provided name exists"},
+		{name: "trigger running job", method: http.MethodPost, target: "/admin/jobs/metadata-refresh/run", user: "root",
+			status: http.StatusConflict, message: "Job is already running"},
+		{name: "trigger job on stopped scheduler", method: http.MethodPost, target: "/admin/jobs/catalog-export/run",
+			user: "root", scheduler: stopped, status: http.StatusServiceUnavailable, message: "Could not trigger job"},
+		{name: "trigger job", method: http.MethodPost, target: "/admin/jobs/catalog-export/run", user: "root",
+			status: http.StatusAccepted},
+		{name: "get leader as user", method: http.MethodGet, target: "/admin/leader", user: "alice", status: http.StatusForbidden},
+		{name: "grpc metrics as user", method: http.MethodGet, target: "/admin/grpc/metrics", user: "alice",
+			status: http.StatusForbidden},
+		{name: "movie cache stats as user", method: http.MethodGet, target: "/admin/cache/movies", user: "alice",
+			status: http.StatusForbidden},
+		{name: "movie cache stats", method: http.MethodGet, target: "/admin/cache/movies", user: "root", status: http.StatusOK},
+		{name: "flush movie cache as user", method: http.MethodPost, target: "/admin/cache/movies/flush", user: "alice",
+			status: http.StatusForbidden},
+		{name: "flush movie cache", method: http.MethodPost, target: "/admin/cache/movies/flush", user: "root",
+			status: http.StatusNoContent},
+		{name: "refresh runs as user", method: http.MethodGet, target: "/admin/refresh-runs", user: "alice",
+			status: http.StatusForbidden},
+		{name: "list queue jobs with invalid status", method: http.MethodGet, target: "/admin/queue/jobs?status=lost", user: "root",
+			status: http.StatusBadRequest},
+		{name: "list queue jobs as user", method: http.MethodGet, target: "/admin/queue/jobs", user: "alice",
+			status: http.StatusForbidden},
+		{name: "get queue job with invalid id", method: http.MethodGet, target: "/admin/queue/jobs/abc", user: "root",
+			status: http.StatusBadRequest},
+		{name: "get queue job as user", method: http.MethodGet, target: "/admin/queue/jobs/1", user: "alice",
+			status: http.StatusForbidden},
+		{name: "get missing queue job", method: http.MethodGet, target: "/admin/queue/jobs/9", user: "root",
+			status: http.StatusNotFound, message: "No job with provided id exists"},
+		{name: "retry queue job with invalid id", method: http.MethodPost, target: "/admin/queue/jobs/abc/retry", user: "root",
+			status: http.StatusBadRequest},
+		{name: "retry queue job as user", method: http.MethodPost, target: "/admin/queue/jobs/1/retry", user: "alice",
+			status: http.StatusForbidden},
+		{name: "retry missing queue job", method: http.MethodPost, target: "/admin/queue/jobs/9/retry", user: "root",
+			status: http.StatusNotFound},
+		{name: "retry pending queue job", method: http.MethodPost, target: "/admin/queue/jobs/1/retry", user: "root",
+			status: http.StatusConflict, message: "Job is not in a state allowing this action"},
+		{name: "cancel queue job with invalid id", method: http.MethodPost, target: "/admin/queue/jobs/abc/cancel", user: "root",
+			status: http.StatusBadRequest},
+		{name: "cancel queue job as user", method: http.MethodPost, target: "/admin/queue/jobs/1/cancel", user: "alice",
+			status: http.StatusForbidden},
+		{name: "cancel missing queue job", method: http.MethodPost, target: "/admin/queue/jobs/9/cancel", user: "root",
+			status: http.StatusNotFound},
+		{name: "cancel completed queue job", method: http.MethodPost, target: "/admin/queue/jobs/1/cancel", user: "root",
+			status: http.StatusConflict},
+		{name: "list users as user", method: http.MethodGet, target: "/admin/users", user: "alice", status: http.StatusForbidden},
+		{name: "create user without password", method: http.MethodPost, target: "/admin/users", user: "root",
+			body: `{"username":"bob"}`, status: http.StatusBadRequest, message: "body.password should be present"},
+		{name: "create user as user", method: http.MethodPost, target: "/admin/users", user: "alice",
+			body: `{"username":"bob","password":"secret"}`, status: http.StatusForbidden},
+		{name: "create user in flight", method: http.MethodPost, target: "/admin/users", user: "root",
+			body: `{"username":"bob","password":"secret"}`, idempotencyKey: "in-flight", status: http.StatusConflict},
+		{name: "create user with reused key", method: http.MethodPost, target: "/admin/users", user: "root",
+			body: `{"username":"bob","password":"secret"}`, idempotencyKey: "reused", status: http.StatusUnprocessableEntity},
+		{name: "update user with too long name", method: http.MethodPut, target: "/admin/users/" + strings.Repeat("b", 51),
+			user: "root", body: `{"admin":true}`, status: http.StatusBadRequest},
+		{name: "update user as user", method: http.MethodPut, target: "/admin/users/bob", user: "alice", body: `{"admin":true}`,
+			status: http.StatusForbidden},
+		{name: "update missing user", method: http.MethodPut, target: "/admin/users/bob", user: "root", body: `{"admin":true}`,
+			status: http.StatusNotFound, message: "No user with provided username exists"},
+		{name: "delete user with too long name", method: http.MethodDelete, target: "/admin/users/" + strings.Repeat("b", 51),
+			user: "root", status: http.StatusBadRequest},
+		{name: "delete user as user", method: http.MethodDelete, target: "/admin/users/bob", user: "alice",
+			status: http.StatusForbidden},
+		{name: "delete missing user", method: http.MethodDelete, target: "/admin/users/bob", user: "root",
+			status: http.StatusNotFound},
+		{name: "audit log of invalid entity", method: http.MethodGet, target: "/audit?entity=tag", user: "root",
+			status: http.StatusBadRequest},
+		{name: "audit log as user", method: http.MethodGet, target: "/audit", user: "alice", status: http.StatusForbidden},
+	}
+	// every authenticated operation rejects missing credentials, and every operation with a body too large bodies
+	pathParams := strings.NewReplacer("{assetType}", "poster", "{name}", "catalog-export", "{username}", "bob")
+	paramValue := regexp.MustCompile(`\{[a-zA-Z]+\}`)
+	for _, path := range sortedPaths(doc) {
+		if strings.HasPrefix(path, "/v1/") {
+			continue
+		}
+		target := paramValue.ReplaceAllString(pathParams.Replace(path), "1")
+		for method, operation := range doc.Paths[path] {
+			method = strings.ToUpper(method)
+			if operation.Security != nil {
+				tests = append(tests, contractCase{name: operation.OperationID + " without credentials", method: method,
+					target: target, status: http.StatusUnauthorized})
+			}
+			if operation.RequestBody != nil && operation.RequestBody.Content["application/json"] != nil {
+				tests = append(tests, contractCase{name: operation.OperationID + " with too large body", method: method,
+					target: target, user: "root", body: `"` + strings.Repeat("a", openapi.DefaultMaxBodyBytes) + `"`,
+					status: http.StatusRequestEntityTooLarge})
+			}
+		}
+	}
+
+	covered := map[string]map[int]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "http://localhost:3000"+tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.ifModifiedSince != "" {
+				req.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, "secret")
+			}
+			if tt.idempotencyKey != "" {
+				req.Header.Set(idempotencyKeyHeader, tt.idempotencyKey)
+			}
+			if tt.scheduler != nil {
+				h.Scheduler = tt.scheduler
+				defer func() { h.Scheduler = running }()
+			}
+
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			bytes, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.StatusCode, string(bytes))
+			if tt.responseType != "" {
+				assert.Equal(t, tt.responseType, res.Header.Get("Content-Type"))
+			}
+			if tt.message != "" {
+				assert.JSONEq(t, `{"success":false,"message":"`+tt.message+`"}`, string(bytes))
+			}
+			if tt.cacheControl != "" {
+				assert.Equal(t, tt.cacheControl, res.Header.Get("Cache-Control"))
+				assert.Equal(t, "Sat, 01 Jun 2024 08:30:15 GMT", res.Header.Get("Last-Modified"))
+			}
+			if res.StatusCode >= http.StatusBadRequest {
+				assert.Empty(t, res.Header.Get("Cache-Control"))
+			}
+			// v1 is deprecated, at its unversioned paths too
+			if !strings.HasPrefix(tt.target, "/v2/") {
+				assert.Equal(t, "@1792368000", res.Header.Get("Deprecation"))
+				assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", res.Header.Get("Sunset"))
+				assert.Equal(t, `</docs>; rel="deprecation"; type="text/html"`, res.Header.Get("Link"))
+			} else {
+				assert.Empty(t, res.Header.Get("Deprecation"))
+			}
+
+			var match mux.RouteMatch
+			if r.Match(req, &match) && res.StatusCode == tt.status {
+				path, err := match.Route.GetPathTemplate()
+				require.NoError(t, err)
+				if covered[tt.method+" "+path] == nil {
+					covered[tt.method+" "+path] = map[int]bool{}
+				}
+				covered[tt.method+" "+path][tt.status] = true
+			}
+		})
+	}
+
+	t.Run("documented errors", func(t *testing.T) {
+		for _, path := range sortedPaths(doc) {
+			if strings.HasPrefix(path, "/v1/") {
+				continue
+			}
+			for method, operation := range doc.Paths[path] {
+				method = strings.ToUpper(method)
+				for response := range operation.Responses {
+					status, err := strconv.Atoi(response)
+					require.NoError(t, err)
+					if status >= http.StatusBadRequest && status != http.StatusInternalServerError {
+						assert.True(t, covered[method+" "+path][status], "%s %s: %d is not covered", method, path, status)
+					}
+				}
+			}
+		}
+	})
+}
+
+func sortedPaths(doc *openapi.Document) []string {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+type mockRevisionRepository struct {
+	mock.Mock
+}
+
+func (r *mockRevisionRepository) List(_ context.Context, movieId int) ([]*model.MovieRevision, error) {
+	args := r.Called(movieId)
+	revisions, _ := args.Get(0).([]*model.MovieRevision)
+	return revisions, args.Error(1)
+}
+
+func (r *mockRevisionRepository) Get(_ context.Context, movieId, revision int) (*model.MovieRevision, error) {
+	args := r.Called(movieId, revision)
+	rev, _ := args.Get(0).(*model.MovieRevision)
+	return rev, args.Error(1)
+}
+
+type mockAssetRepository struct {
+	mock.Mock
+}
+
+func (r *mockAssetRepository) GetByMovie(movieId int) ([]*model.MovieAsset, error) {
+	args := r.Called(movieId)
+	assets, _ := args.Get(0).([]*model.MovieAsset)
+	return assets, args.Error(1)
+}
+
+func (r *mockAssetRepository) Get(movieId, assetId int) (*model.MovieAsset, error) {
+	args := r.Called(movieId, assetId)
+	asset, _ := args.Get(0).(*model.MovieAsset)
+	return asset, args.Error(1)
+}
+
+func (r *mockAssetRepository) Create(asset *model.MovieAsset) (*model.MovieAsset, error) {
+	args := r.Called(asset)
+	created, _ := args.Get(0).(*model.MovieAsset)
+	return created, args.Error(1)
+}
+
+type mockStorage struct {
+	mock.Mock
+}
+
+func (m *mockStorage) StatObject(_ context.Context, id string) (miniogo.ObjectInfo, error) {
+	args := m.Called(id)
+	return args.Get(0).(miniogo.ObjectInfo), args.Error(1)
+}
+
+func (m *mockStorage) PresignedPutURL(_ context.Context, id string, _ time.Duration) (*url.URL, error) {
+	args := m.Called(id)
+	u, _ := args.Get(0).(*url.URL)
+	return u, args.Error(1)
+}
+
+func (m *mockStorage) PresignedGetURL(_ context.Context, id string, _ time.Duration) (*url.URL, error) {
+	args := m.Called(id)
+	u, _ := args.Get(0).(*url.URL)
+	return u, args.Error(1)
+}
+
+func (m *mockStorage) ListObjects(_ context.Context, prefix string) ([]miniogo.ObjectInfo, error) {
+	args := m.Called(prefix)
+	return args.Get(0).([]miniogo.ObjectInfo), args.Error(1)
+}
+
+func (m *mockStorage) RemoveObject(_ context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type mockRatingRepository struct {
+	mock.Mock
+}
+
+func (r *mockRatingRepository) ListForUser(_ context.Context, username string, movieIds []int) ([]*model.Rating, error) {
+	args := r.Called(username, movieIds)
+	ratings, _ := args.Get(0).([]*model.Rating)
+	return ratings, args.Error(1)
+}
+
+func (r *mockRatingRepository) Create(_ context.Context, rating *model.Rating) (*model.Rating, error) {
+	args := r.Called(rating)
+	created, _ := args.Get(0).(*model.Rating)
+	return created, args.Error(1)
+}
+
+func (r *mockRatingRepository) Update(_ context.Context, rating *model.Rating) (*model.Rating, error) {
+	args := r.Called(rating)
+	updated, _ := args.Get(0).(*model.Rating)
+	return updated, args.Error(1)
+}
+
+func (r *mockRatingRepository) Delete(_ context.Context, movieId int, username string) error {
+	args := r.Called(movieId, username)
+	return args.Error(0)
+}
+
+type mockReviewRepository struct {
+	mock.Mock
+}
+
+func (r *mockReviewRepository) List(_ context.Context, movieId int, includeHidden bool) ([]*model.Review, error) {
+	args := r.Called(movieId, includeHidden)
+	reviews, _ := args.Get(0).([]*model.Review)
+	return reviews, args.Error(1)
+}
+
+func (r *mockReviewRepository) Create(_ context.Context, review *model.Review) (*model.Review, error) {
+	args := r.Called(review)
+	created, _ := args.Get(0).(*model.Review)
+	return created, args.Error(1)
+}
+
+func (r *mockReviewRepository) Update(_ context.Context, review *model.Review) (*model.Review, error) {
+	args := r.Called(review)
+	updated, _ := args.Get(0).(*model.Review)
+	return updated, args.Error(1)
+}
+
+func (r *mockReviewRepository) Delete(_ context.Context, movieId int, username string) error {
+	args := r.Called(movieId, username)
+	return args.Error(0)
+}
+
+func (r *mockReviewRepository) SetHidden(_ context.Context, reviewId int, hiddenBy string) (*model.Review, error) {
+	args := r.Called(reviewId, hiddenBy)
+	review, _ := args.Get(0).(*model.Review)
+	return review, args.Error(1)
+}
+
+type mockTagRepository struct {
+	mock.Mock
+}
+
+func (r *mockTagRepository) List(_ context.Context) ([]*model.TagCount, error) {
+	args := r.Called()
+	tags, _ := args.Get(0).([]*model.TagCount)
+	return tags, args.Error(1)
+}
+
+func (r *mockTagRepository) Create(_ context.Context, name, createdBy string) (*model.Tag, error) {
+	args := r.Called(name, createdBy)
+	tag, _ := args.Get(0).(*model.Tag)
+	return tag, args.Error(1)
+}
+
+func (r *mockTagRepository) Update(_ context.Context, tag *model.Tag) (*model.Tag, error) {
+	args := r.Called(tag)
+	updated, _ := args.Get(0).(*model.Tag)
+	return updated, args.Error(1)
+}
+
+func (r *mockTagRepository) Delete(_ context.Context, tagId int) error {
+	args := r.Called(tagId)
+	return args.Error(0)
+}
+
+func (r *mockTagRepository) AddToMovie(_ context.Context, movieId, tagId int) error {
+	args := r.Called(movieId, tagId)
+	return args.Error(0)
+}
+
+func (r *mockTagRepository) RemoveFromMovie(_ context.Context, movieId, tagId int) error {
+	args := r.Called(movieId, tagId)
+	return args.Error(0)
+}
+
+type mockCreditRepository struct {
+	mock.Mock
+}
+
+func (r *mockCreditRepository) Replace(_ context.Context, movieId int, credits []*model.Credit) error {
+	args := r.Called(movieId, credits)
+	return args.Error(0)
+}
+
+func (r *mockCreditRepository) ListForMovie(_ context.Context, movieId int) ([]*model.Credit, error) {
+	args := r.Called(movieId)
+	credits, _ := args.Get(0).([]*model.Credit)
+	return credits, args.Error(1)
+}
+
+func (r *mockCreditRepository) ListForMovies(_ context.Context, movieIds []int) (map[int][]*model.Credit, error) {
+	args := r.Called(movieIds)
+	credits, _ := args.Get(0).(map[int][]*model.Credit)
+	return credits, args.Error(1)
+}
+
+func (r *mockCreditRepository) GetPerson(_ context.Context, personId int) (*model.Person, error) {
+	args := r.Called(personId)
+	person, _ := args.Get(0).(*model.Person)
+	return person, args.Error(1)
+}
+
+func (r *mockCreditRepository) GetPeople(_ context.Context, personIds []int) (map[int]*model.Person, error) {
+	args := r.Called(personIds)
+	people, _ := args.Get(0).(map[int]*model.Person)
+	return people, args.Error(1)
+}
+
+type mockCollectionRepository struct {
+	mock.Mock
+}
+
+func (r *mockCollectionRepository) List(_ context.Context) ([]*model.Collection, error) {
+	args := r.Called()
+	collections, _ := args.Get(0).([]*model.Collection)
+	return collections, args.Error(1)
+}
+
+func (r *mockCollectionRepository) Get(_ context.Context, collectionId int) (*model.Collection, error) {
+	args := r.Called(collectionId)
+	collection, _ := args.Get(0).(*model.Collection)
+	return collection, args.Error(1)
+}
+
+func (r *mockCollectionRepository) Create(_ context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	args := r.Called(collection, movieIds)
+	created, _ := args.Get(0).(*model.Collection)
+	return created, args.Error(1)
+}
+
+func (r *mockCollectionRepository) Update(_ context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	args := r.Called(collection, movieIds)
+	updated, _ := args.Get(0).(*model.Collection)
+	return updated, args.Error(1)
+}
+
+func (r *mockCollectionRepository) Delete(_ context.Context, collectionId int) error {
+	args := r.Called(collectionId)
+	return args.Error(0)
+}
+
+func (r *mockCollectionRepository) LinkTmdb(_ context.Context, movieId, tmdbId int, name string) error {
+	args := r.Called(movieId, tmdbId, name)
+	return args.Error(0)
+}
+
+type mockWatchlistRepository struct {
+	mock.Mock
+}
+
+func (r *mockWatchlistRepository) List(_ context.Context, username string) ([]*model.Watchlist, error) {
+	args := r.Called(username)
+	watchlists, _ := args.Get(0).([]*model.Watchlist)
+	return watchlists, args.Error(1)
+}
+
+func (r *mockWatchlistRepository) Get(_ context.Context, username string, watchlistId int) (*model.Watchlist, error) {
+	args := r.Called(username, watchlistId)
+	watchlist, _ := args.Get(0).(*model.Watchlist)
+	return watchlist, args.Error(1)
+}
+
+func (r *mockWatchlistRepository) GetDefault(_ context.Context, username string) (*model.Watchlist, error) {
+	args := r.Called(username)
+	watchlist, _ := args.Get(0).(*model.Watchlist)
+	return watchlist, args.Error(1)
+}
+
+func (r *mockWatchlistRepository) Create(_ context.Context, username, name string) (*model.Watchlist, error) {
+	args := r.Called(username, name)
+	watchlist, _ := args.Get(0).(*model.Watchlist)
+	return watchlist, args.Error(1)
+}
+
+func (r *mockWatchlistRepository) Delete(_ context.Context, username string, watchlistId int) error {
+	args := r.Called(username, watchlistId)
+	return args.Error(0)
+}
+
+func (r *mockWatchlistRepository) AddMovie(_ context.Context, watchlistId, movieId int) error {
+	args := r.Called(watchlistId, movieId)
+	return args.Error(0)
+}
+
+func (r *mockWatchlistRepository) RemoveMovie(_ context.Context, watchlistId, movieId int) error {
+	args := r.Called(watchlistId, movieId)
+	return args.Error(0)
+}
+
+func (r *mockWatchlistRepository) SetWatched(_ context.Context, watchlistId, movieId int, watched bool) error {
+	args := r.Called(watchlistId, movieId, watched)
+	return args.Error(0)
+}
+
+func (r *mockWatchlistRepository) Reorder(_ context.Context, watchlistId int, movieIds []int) error {
+	args := r.Called(watchlistId, movieIds)
+	return args.Error(0)
+}
+
+type mockUserStore struct {
+	mock.Mock
+}
+
+func (r *mockUserStore) List(_ context.Context) ([]*model.User, error) {
+	args := r.Called()
+	users, _ := args.Get(0).([]*model.User)
+	return users, args.Error(1)
+}
+
+func (r *mockUserStore) Create(_ context.Context, user *model.User) (*model.User, error) {
+	args := r.Called(user)
+	created, _ := args.Get(0).(*model.User)
+	return created, args.Error(1)
+}
+
+func (r *mockUserStore) Update(_ context.Context, user *model.User) (*model.User, error) {
+	args := r.Called(user)
+	updated, _ := args.Get(0).(*model.User)
+	return updated, args.Error(1)
+}
+
+func (r *mockUserStore) Delete(_ context.Context, username string) ([]int, error) {
+	args := r.Called(username)
+	movieIds, _ := args.Get(0).([]int)
+	return movieIds, args.Error(1)
+}
+
+type mockImportRepository struct {
+	mock.Mock
+}
+
+func (r *mockImportRepository) Create(imp *model.MovieImport) (*model.MovieImport, error) {
+	args := r.Called(imp)
+	created, _ := args.Get(0).(*model.MovieImport)
+	return created, args.Error(1)
+}
+
+func (r *mockImportRepository) Get(id int) (*model.MovieImport, error) {
+	args := r.Called(id)
+	imp, _ := args.Get(0).(*model.MovieImport)
+	return imp, args.Error(1)
+}
+
+func (r *mockImportRepository) UpdateProgress(imp *model.MovieImport) error {
+	args := r.Called(imp)
+	return args.Error(0)
+}
+
+func (r *mockImportRepository) AddErrors(importId int, rowErrors []*model.ImportRowError) error {
+	args := r.Called(importId, rowErrors)
+	return args.Error(0)
+}
+
+func (r *mockImportRepository) GetErrors(importId, limit int) ([]*model.ImportRowError, error) {
+	args := r.Called(importId, limit)
+	rowErrors, _ := args.Get(0).([]*model.ImportRowError)
+	return rowErrors, args.Error(1)
+}
+
+type mockQueueStore struct {
+	mock.Mock
+}
+
+func (s *mockQueueStore) Enqueue(_ context.Context, job *model.QueueJob) (*model.QueueJob, error) {
+	args := s.Called(job)
+	enqueued, _ := args.Get(0).(*model.QueueJob)
+	return enqueued, args.Error(1)
+}
+
+func (s *mockQueueStore) Claim(_ context.Context, kinds []string, worker string, _ time.Duration) (*model.QueueJob, error) {
+	args := s.Called(kinds, worker)
+	job, _ := args.Get(0).(*model.QueueJob)
+	return job, args.Error(1)
+}
+
+func (s *mockQueueStore) Finish(_ context.Context, job *model.QueueJob) error {
+	args := s.Called(job)
+	return args.Error(0)
+}
+
+func (s *mockQueueStore) Release(_ context.Context, job *model.QueueJob) error {
+	args := s.Called(job)
+	return args.Error(0)
+}
+
+func (s *mockQueueStore) Get(_ context.Context, id int64) (*model.QueueJob, error) {
+	args := s.Called(id)
+	job, _ := args.Get(0).(*model.QueueJob)
+	return job, args.Error(1)
+}
+
+func (s *mockQueueStore) List(_ context.Context, filter model.QueueJobFilter) ([]*model.QueueJob, error) {
+	args := s.Called(filter)
+	jobs, _ := args.Get(0).([]*model.QueueJob)
+	return jobs, args.Error(1)
+}
+
+func (s *mockQueueStore) Retry(_ context.Context, id int64) (*model.QueueJob, error) {
+	args := s.Called(id)
+	job, _ := args.Get(0).(*model.QueueJob)
+	return job, args.Error(1)
+}
+
+func (s *mockQueueStore) Cancel(_ context.Context, id int64) (*model.QueueJob, error) {
+	args := s.Called(id)
+	job, _ := args.Get(0).(*model.QueueJob)
+	return job, args.Error(1)
+}
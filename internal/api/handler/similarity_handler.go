@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultSimilarLimit = 10
+	maxSimilarLimit     = 50
+)
+
+// GetSimilarMovies returns the movies with the most similar overview and genres, with the terms and genres they share.
+func (h *Handler) GetSimilarMovies(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET similar movies request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	limit := defaultSimilarLimit
+	if query := req.URL.Query(); query.Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxSimilarLimit {
+			returnErrorResponse(fmt.Sprintf("limit should be a number between 1 and %d", maxSimilarLimit), http.StatusBadRequest, res)
+			return
+		}
+	}
+
+	similar, err := h.SimilarityService.Similar(req.Context(), movieId, limit)
+	if err != nil {
+		var nfErr model.NotFoundError
+		if errors.As(err, &nfErr) {
+			returnErrorResponse("No movie with provided id exists", http.StatusNotFound, res)
+			return
+		}
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	similarJSON, err := json.Marshal(similar)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, http.StatusOK, similarJSON)
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetGenres(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET genres request")
+
+	genres, err := h.GenreService.List(req.Context())
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	returnTagResponse(genres, http.StatusOK, res)
+}
+
+func (h *Handler) GetTags(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET tags request")
+
+	tags, err := h.TagService.List(req.Context())
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	returnTagResponse(tags, http.StatusOK, res)
+}
+
+func (h *Handler) CreateTag(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST tag request")
+
+	body, ok := decodeTag(res, req)
+	if !ok {
+		return
+	}
+
+	tag, err := h.TagService.Create(req.Context(), body.Name, reqctx.User(req.Context()).Username)
+	if err != nil {
+		returnTagErrorResponse(err, res)
+		return
+	}
+
+	returnTagResponse(tag, http.StatusCreated, res)
+}
+
+func (h *Handler) RenameTag(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT tag request")
+
+	tagId := validateIDParam(mux.Vars(req)["tagId"], res)
+	if tagId == 0 {
+		return
+	}
+	body, ok := decodeTag(res, req)
+	if !ok {
+		return
+	}
+
+	tag, err := h.TagService.Rename(req.Context(), tagId, body.Name)
+	if err != nil {
+		returnTagErrorResponse(err, res)
+		return
+	}
+
+	returnTagResponse(tag, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteTag(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE tag request")
+
+	tagId := validateIDParam(mux.Vars(req)["tagId"], res)
+	if tagId == 0 {
+		return
+	}
+
+	if err := h.TagService.Delete(req.Context(), tagId); err != nil {
+		returnTagErrorResponse(err, res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+// TagMovie gives a tag to a movie and returns the movie with its tags.
+func (h *Handler) TagMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT movie tag request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	tagId := validateIDParam(mux.Vars(req)["tagId"], res)
+	if tagId == 0 {
+		return
+	}
+
+	movie, err := h.TagService.TagMovie(req.Context(), movieId, tagId)
+	if err != nil {
+		returnTagErrorResponse(err, res)
+		return
+	}
+
+	returnTagResponse(movie, http.StatusOK, res)
+}
+
+func (h *Handler) UntagMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE movie tag request")
+
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	tagId := validateIDParam(mux.Vars(req)["tagId"], res)
+	if tagId == 0 {
+		return
+	}
+
+	if err := h.TagService.UntagMovie(req.Context(), movieId, tagId); err != nil {
+		returnTagErrorResponse(err, res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+func decodeTag(res http.ResponseWriter, req *http.Request) (*model.Tag, bool) {
+	tag := &model.Tag{}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(tag); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return nil, false
+	}
+	return tag, true
+}
+
+func returnTagResponse(value any, status int, res http.ResponseWriter) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, status, valueJSON)
+}
+
+func returnTagErrorResponse(err error, res http.ResponseWriter) {
+	var vErr model.ValidationError
+	var nfErr model.NotFoundError
+	var cErr model.ConflictError
+	switch {
+	case errors.As(err, &vErr):
+		returnErrorResponse(vErr.Message, http.StatusBadRequest, res)
+	case errors.As(err, &nfErr):
+		returnErrorResponse("No movie or tag with provided id exists", http.StatusNotFound, res)
+	case errors.As(err, &cErr):
+		returnErrorResponse("A tag with the provided name already exists", http.StatusConflict, res)
+	default:
+		returnErrorResponse("Unexpected error when accessing tags", http.StatusInternalServerError, res)
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetUsers(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET users request")
+
+	users, err := h.UserService.List(req.Context())
+	if err != nil {
+		returnErrorResponse("Error when retrieving data", http.StatusInternalServerError, res)
+		return
+	}
+
+	returnUserResponse(users, http.StatusOK, res)
+}
+
+func (h *Handler) CreateUser(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST user request")
+
+	user := &model.User{}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(user); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+
+	created, err := h.UserService.Create(req.Context(), user)
+	if err != nil {
+		returnUserErrorResponse(err, res)
+		return
+	}
+
+	returnUserResponse(created, http.StatusCreated, res)
+}
+
+// UpdateUser sets the admin flag of the user, and changes its password if one is provided.
+func (h *Handler) UpdateUser(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT user request")
+
+	user := &model.User{}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(user); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+	username := mux.Vars(req)["username"]
+	if user.Username != "" && user.Username != username {
+		returnErrorResponse("Mismatch between username in path and request body", http.StatusBadRequest, res)
+		return
+	}
+	user.Username = username
+
+	updated, err := h.UserService.Update(req.Context(), user)
+	if err != nil {
+		returnUserErrorResponse(err, res)
+		return
+	}
+
+	returnUserResponse(updated, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteUser(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE user request")
+
+	if err := h.UserService.Delete(req.Context(), mux.Vars(req)["username"]); err != nil {
+		returnUserErrorResponse(err, res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+func returnUserResponse(users any, status int, res http.ResponseWriter) {
+	usersJSON, err := json.Marshal(users)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, status, usersJSON)
+}
+
+func returnUserErrorResponse(err error, res http.ResponseWriter) {
+	var vErr model.ValidationError
+	var nfErr model.NotFoundError
+	var cErr model.ConflictError
+	switch {
+	case errors.As(err, &vErr):
+		returnErrorResponse(vErr.Message, http.StatusBadRequest, res)
+	case errors.As(err, &nfErr):
+		returnErrorResponse("No user with provided username exists", http.StatusNotFound, res)
+	case errors.As(err, &cErr):
+		returnErrorResponse("A user with the provided username already exists", http.StatusConflict, res)
+	default:
+		returnErrorResponse("Unexpected error when changing user", http.StatusInternalServerError, res)
+	}
+}
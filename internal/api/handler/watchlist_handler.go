@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultWatchlistParam can be used in place of the id of the default watchlist of the user.
+const defaultWatchlistParam = "default"
+
+func (h *Handler) GetWatchlists(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET watchlists request")
+
+	watchlists, err := h.WatchlistService.List(req.Context(), reqctx.User(req.Context()).Username)
+	if err != nil {
+		returnWatchlistErrorResponse(err, "", res)
+		return
+	}
+
+	returnWatchlistResponse(watchlists, http.StatusOK, res)
+}
+
+func (h *Handler) CreateWatchlist(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST watchlist request")
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+
+	watchlist, err := h.WatchlistService.Create(req.Context(), reqctx.User(req.Context()).Username, body.Name)
+	if err != nil {
+		returnWatchlistErrorResponse(err, "A watchlist with the provided name already exists", res)
+		return
+	}
+
+	returnWatchlistResponse(watchlist, http.StatusCreated, res)
+}
+
+func (h *Handler) GetWatchlist(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received GET watchlist request")
+
+	watchlistId, ok := watchlistIDParam(req, res)
+	if !ok {
+		return
+	}
+
+	watchlist, err := h.WatchlistService.Get(req.Context(), reqctx.User(req.Context()).Username, watchlistId)
+	if err != nil {
+		returnWatchlistErrorResponse(err, "", res)
+		return
+	}
+
+	returnWatchlistResponse(watchlist, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteWatchlist(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE watchlist request")
+
+	watchlistId, ok := watchlistIDParam(req, res)
+	if !ok {
+		return
+	}
+
+	if err := h.WatchlistService.Delete(req.Context(), reqctx.User(req.Context()).Username, watchlistId); err != nil {
+		returnWatchlistErrorResponse(err, "", res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+func (h *Handler) AddWatchlistMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received POST watchlist movie request")
+
+	watchlistId, ok := watchlistIDParam(req, res)
+	if !ok {
+		return
+	}
+	var body struct {
+		MovieId int `json:"movieId"`
+	}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+
+	watchlist, err := h.WatchlistService.AddMovie(req.Context(), reqctx.User(req.Context()).Username, watchlistId, body.MovieId)
+	if err != nil {
+		returnWatchlistErrorResponse(err, "The movie is already in the watchlist", res)
+		return
+	}
+
+	returnWatchlistResponse(watchlist, http.StatusOK, res)
+}
+
+// UpdateWatchlistMovie sets the watched flag of a movie of the watchlist.
+func (h *Handler) UpdateWatchlistMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT watchlist movie request")
+
+	watchlistId, ok := watchlistIDParam(req, res)
+	if !ok {
+		return
+	}
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+	var body struct {
+		Watched *bool `json:"watched"`
+	}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Watched == nil {
+		returnErrorResponse("Request body should contain the watched flag", http.StatusBadRequest, res)
+		return
+	}
+
+	watchlist, err := h.WatchlistService.SetWatched(req.Context(), reqctx.User(req.Context()).Username, watchlistId, movieId, *body.Watched)
+	if err != nil {
+		returnWatchlistErrorResponse(err, "", res)
+		return
+	}
+
+	returnWatchlistResponse(watchlist, http.StatusOK, res)
+}
+
+func (h *Handler) RemoveWatchlistMovie(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received DELETE watchlist movie request")
+
+	watchlistId, ok := watchlistIDParam(req, res)
+	if !ok {
+		return
+	}
+	movieId := validateIDParam(mux.Vars(req)["movieId"], res)
+	if movieId == 0 {
+		return
+	}
+
+	if err := h.WatchlistService.RemoveMovie(req.Context(), reqctx.User(req.Context()).Username, watchlistId, movieId); err != nil {
+		returnWatchlistErrorResponse(err, "", res)
+		return
+	}
+
+	utils.ReturnEmptyResponse(res, http.StatusNoContent)
+}
+
+// ReorderWatchlist sets the order of the watchlist to the movieIds of the request body.
+func (h *Handler) ReorderWatchlist(res http.ResponseWriter, req *http.Request) {
+	slog.Info("Received PUT watchlist order request")
+
+	watchlistId, ok := watchlistIDParam(req, res)
+	if !ok {
+		return
+	}
+	var body struct {
+		MovieIds []int `json:"movieIds"`
+	}
+	defer req.Body.Close()
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		slog.Error("Error when unmarshalling the request body", "error", err)
+		returnErrorResponse("Could not parse request body", http.StatusBadRequest, res)
+		return
+	}
+
+	watchlist, err := h.WatchlistService.Reorder(req.Context(), reqctx.User(req.Context()).Username, watchlistId, body.MovieIds)
+	if err != nil {
+		returnWatchlistErrorResponse(err, "", res)
+		return
+	}
+
+	returnWatchlistResponse(watchlist, http.StatusOK, res)
+}
+
+// watchlistIDParam returns the watchlist id of the path, 0 standing for the default watchlist. If it is not
+// valid, an error response is written and false is returned.
+func watchlistIDParam(req *http.Request, res http.ResponseWriter) (int, bool) {
+	param := mux.Vars(req)["watchlistId"]
+	if param == defaultWatchlistParam {
+		return 0, true
+	}
+	watchlistId := validateIDParam(param, res)
+	return watchlistId, watchlistId != 0
+}
+
+func returnWatchlistResponse(value any, status int, res http.ResponseWriter) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("Error when marshalling the response data", "error", err)
+		returnErrorResponse("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+
+	utils.ReturnJsonResponse(res, status, valueJSON)
+}
+
+// returnWatchlistErrorResponse answers the errors of the watchlist service, a conflict with the message of the
+// operation which can cause it.
+func returnWatchlistErrorResponse(err error, conflictMessage string, res http.ResponseWriter) {
+	var vErr model.ValidationError
+	var nfErr model.NotFoundError
+	var cErr model.ConflictError
+	switch {
+	case errors.As(err, &vErr):
+		returnErrorResponse(vErr.Message, http.StatusBadRequest, res)
+	case errors.As(err, &nfErr):
+		returnErrorResponse("No watchlist or watchlist movie with provided id exists", http.StatusNotFound, res)
+	case errors.As(err, &cErr):
+		returnErrorResponse(conflictMessage, http.StatusConflict, res)
+	default:
+		returnErrorResponse("Unexpected error when accessing watchlists", http.StatusInternalServerError, res)
+	}
+}
@@ -9,9 +9,12 @@ import (
 
 type AsyncPublisher struct {
 	producer sarama.AsyncProducer
+	topic    string
 }
 
 func (p *AsyncPublisher) Configure(topic string) {
+	p.topic = topic
+
 	cfg := sarama.NewConfig()
 	cfg.Version = sarama.DefaultVersion
 	cfg.Producer.RequiredAcks = sarama.WaitForLocal       // Only wait for the leader to ack
@@ -37,7 +40,7 @@ func (p *AsyncPublisher) Configure(topic string) {
 func (p *AsyncPublisher) Publish(msg string) error {
 	// no key, the message with end up in random partition
 	p.producer.Input() <- &sarama.ProducerMessage{
-		Topic: config.Topic,
+		Topic: p.topic,
 		Value: sarama.StringEncoder(msg),
 	}
 	return nil
@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	"log/slog"
+	"sync"
+)
+
+// Consumer reads the messages published to a topic from the moment it starts. It uses no consumer group, so
+// every replica receives every message.
+type Consumer struct {
+	consumer sarama.Consumer
+	topic    string
+}
+
+func NewConsumer(brokers []string, topic string) (*Consumer, error) {
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.DefaultVersion
+	cfg.Consumer.Offsets.Initial = sarama.OffsetNewest
+
+	consumer, err := sarama.NewConsumer(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Consumer{consumer: consumer, topic: topic}, nil
+}
+
+// Run passes the messages of all the partitions of the topic to handle until the context is cancelled. Messages of
+// a partition are handled in order, the ones of different partitions concurrently.
+func (c *Consumer) Run(ctx context.Context, handle func(msg []byte)) error {
+	defer c.consumer.Close()
+
+	partitions, err := c.consumer.Partitions(c.topic)
+	if err != nil {
+		return err
+	}
+	var pcs []sarama.PartitionConsumer
+	wg := sync.WaitGroup{}
+	defer func() {
+		for _, pc := range pcs {
+			pc.AsyncClose()
+		}
+		wg.Wait()
+	}()
+	for _, partition := range partitions {
+		pc, err := c.consumer.ConsumePartition(c.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return err
+		}
+		pcs = append(pcs, pc)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for msg := range pc.Messages() {
+				handle(msg.Value)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for err := range pc.Errors() {
+				slog.Error("Error when consuming topic", "topic", c.topic, "partition", err.Partition, "error", err.Err)
+			}
+		}()
+	}
+	<-ctx.Done()
+	return nil
+}
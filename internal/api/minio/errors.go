@@ -0,0 +1,5 @@
+package minio
+
+import "errors"
+
+var ErrObjectNotFound = errors.New("object not found")
@@ -3,13 +3,32 @@ package minio
 import (
 	"context"
 	"github.com/minio/minio-go/v7"
+	"io"
+	"net/url"
 	"rest_api/internal/api/config"
+	"time"
 )
 
 type Service struct {
 	client *minio.Client
 }
 
+func NewService(client *minio.Client) *Service {
+	return &Service{client: client}
+}
+
+// EnsureBucket creates the configured bucket if it does not exist yet.
+func (s *Service) EnsureBucket(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, config.BucketName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return s.client.MakeBucket(ctx, config.BucketName, minio.MakeBucketOptions{})
+}
+
 func (s *Service) GetObject(ctx context.Context, id string) (*minio.Object, error) {
 	object, err := s.client.GetObject(ctx, config.BucketName, id, minio.GetObjectOptions{})
 	if err != nil {
@@ -19,6 +38,46 @@ func (s *Service) GetObject(ctx context.Context, id string) (*minio.Object, erro
 	return object, nil
 }
 
-func (s *Service) PutObject(ctx context.Context, id string) error {
-	return nil
+// PutObject uploads the content of the reader, whose size does not need to be known in advance.
+func (s *Service) PutObject(ctx context.Context, id string, reader io.Reader, contentType string) error {
+	_, err := s.client.PutObject(ctx, config.BucketName, id, reader, -1, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// ListObjects returns the objects whose key starts with the prefix.
+func (s *Service) ListObjects(ctx context.Context, prefix string) ([]minio.ObjectInfo, error) {
+	var objects []minio.ObjectInfo
+	for object := range s.client.ListObjects(ctx, config.BucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func (s *Service) RemoveObject(ctx context.Context, id string) error {
+	return s.client.RemoveObject(ctx, config.BucketName, id, minio.RemoveObjectOptions{})
+}
+
+// StatObject returns the metadata of a stored object. A missing object results in ErrObjectNotFound.
+func (s *Service) StatObject(ctx context.Context, id string) (minio.ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, config.BucketName, id, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return minio.ObjectInfo{}, ErrObjectNotFound
+		}
+		return minio.ObjectInfo{}, err
+	}
+	return info, nil
+}
+
+// PresignedPutURL returns a URL the client can upload an object to directly, valid for the given expiry.
+func (s *Service) PresignedPutURL(ctx context.Context, id string, expiry time.Duration) (*url.URL, error) {
+	return s.client.PresignedPutObject(ctx, config.BucketName, id, expiry)
+}
+
+// PresignedGetURL returns a URL the client can download an object from directly, valid for the given expiry.
+func (s *Service) PresignedGetURL(ctx context.Context, id string, expiry time.Duration) (*url.URL, error) {
+	return s.client.PresignedGetObject(ctx, config.BucketName, id, expiry, url.Values{})
 }
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarshalCSV returns the columns of the movie, its genres and tags being the list of their names separated by |.
+func (m Movie) MarshalCSV() ([]string, []string) {
+	header := []string{"id", "title", "overview", "tmdbId", "runtime", "releaseDate", "tmdbVoteAverage",
+		"ratingAverage", "ratingCount", "genres", "tags", "deletedAt"}
+	genres := make([]string, len(m.Genres))
+	for i, genre := range m.Genres {
+		genres[i] = genre.Name
+	}
+	tags := make([]string, len(m.Tags))
+	for i, tag := range m.Tags {
+		tags[i] = tag.Name
+	}
+	deletedAt := ""
+	if m.DeletedAt != nil {
+		deletedAt = m.DeletedAt.UTC().Format(time.RFC3339)
+	}
+	return header, []string{
+		strconv.Itoa(m.MovieId),
+		m.MovieName,
+		m.Overview,
+		csvInt(m.TmdbId),
+		csvInt(m.Runtime),
+		m.ReleaseDate,
+		csvFloat(m.TmdbVoteAverage),
+		csvFloat(m.RatingAverage),
+		csvInt(m.RatingCount),
+		strings.Join(genres, "|"),
+		strings.Join(tags, "|"),
+		deletedAt,
+	}
+}
+
+// UnmarshalCSV sets the fields of the movie which can be created or updated, the genres excepted: as they are
+// listed by name, they are kept on update.
+func (m *Movie) UnmarshalCSV(fields map[string]string) error {
+	var err error
+	if m.MovieId, err = parseCSVInt(fields, "id"); err != nil {
+		return err
+	}
+	if m.Runtime, err = parseCSVInt(fields, "runtime"); err != nil {
+		return err
+	}
+	m.MovieName = strings.TrimSpace(fields["title"])
+	m.Overview = fields["overview"]
+	m.ReleaseDate = strings.TrimSpace(fields["releaseDate"])
+	return nil
+}
+
+// parseCSVInt returns the integer of the column, zero if it is missing or empty.
+func parseCSVInt(fields map[string]string, column string) (int, error) {
+	value := strings.TrimSpace(fields[column])
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s should be a number", column)
+	}
+	return parsed, nil
+}
+
+func csvInt(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func csvFloat(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
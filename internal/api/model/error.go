@@ -12,4 +12,12 @@ type NotFoundError struct {
 
 func (c NotFoundError) Error() string {
 	return "Movie not found."
-}
\ No newline at end of file
+}
+
+type ValidationError struct {
+	Message string
+}
+
+func (v ValidationError) Error() string {
+	return v.Message
+}
@@ -1,17 +1,452 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Movie struct {
-	MovieId   int    `json:"id"`
-	MovieName string `json:"title"`
-	Overview  string `json:"overview"`
+	MovieId   int    `json:"id" xml:"id"`
+	MovieName string `json:"title" xml:"title"`
+	Overview  string `json:"overview" xml:"overview"`
+	TmdbId    int    `json:"tmdbId,omitempty" xml:"tmdbId,omitempty"`
+	Runtime   int    `json:"runtime,omitempty" xml:"runtime,omitempty"`
+	// ReleaseDate is formatted as YYYY-MM-DD.
+	ReleaseDate string `json:"releaseDate,omitempty" xml:"releaseDate,omitempty"`
+	// TmdbVoteAverage is the vote average of the movie on TMDB, RatingAverage and RatingCount the aggregate of
+	// the ratings of our users.
+	TmdbVoteAverage float64 `json:"tmdbVoteAverage,omitempty" xml:"tmdbVoteAverage,omitempty"`
+	RatingAverage   float64 `json:"ratingAverage,omitempty" xml:"ratingAverage,omitempty"`
+	RatingCount     int     `json:"ratingCount,omitempty" xml:"ratingCount,omitempty"`
+	// Genres are set from TMDB; on update a nil slice keeps the current genres. Tags are only changed through
+	// the tag endpoints.
+	Genres []Genre `json:"genres,omitempty" xml:"genres>genre,omitempty"`
+	Tags   []Tag   `json:"tags,omitempty" xml:"tags>tag,omitempty"`
+	// DeletedAt is only set for movies in the trash.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" xml:"deletedAt,omitempty"`
+	// UpdatedAt is when the movie, its genres or its tags last changed. It is only sent as the Last-Modified header.
+	UpdatedAt time.Time `json:"-" xml:"-"`
+}
+
+// MovieFilter narrows down the movies returned by the list and export endpoints. Zero values are ignored.
+// Genres and Tags are lower-cased names; a movie matches if it has any of them, or all of them if the
+// corresponding MatchAll flag is set.
+type MovieFilter struct {
+	Title          string
+	MinRuntime     int
+	MaxRuntime     int
+	Genres         []string
+	MatchAllGenres bool
+	Tags           []string
+	MatchAllTags   bool
+	// PersonId restricts the movies to the ones crediting the person.
+	PersonId int
+	// Limit, when positive, pages the movies in the order of their ids, returning at most Limit of them after the
+	// first Offset ones.
+	Limit  int
+	Offset int
+}
+
+// Genre is a genre of TMDB, identified by its TMDB id.
+type Genre struct {
+	ID   int    `json:"id" xml:"id"`
+	Name string `json:"name,omitempty" xml:"name,omitempty"`
+}
+
+// GenresOf returns the genres with the given ids, without their names. It is nil for nil ids.
+func GenresOf(ids []int) []Genre {
+	if ids == nil {
+		return nil
+	}
+	genres := make([]Genre, len(ids))
+	for i, id := range ids {
+		genres[i] = Genre{ID: id}
+	}
+	return genres
+}
+
+// Tag is a free-form label curated by users.
+type Tag struct {
+	ID   int    `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+// GenreCount and TagCount are listed with the number of movies they are given to.
+type GenreCount struct {
+	Genre
+	MovieCount int `json:"movieCount"`
 }
 
+type TagCount struct {
+	Tag
+	MovieCount int `json:"movieCount"`
+}
+
+const (
+	MovieCreated  = "movie.created"
+	MovieUpdated  = "movie.updated"
+	MovieDeleted  = "movie.deleted"
+	MovieRestored = "movie.restored"
+	// MovieInvalidated tells the replicas to drop their cached copy of the movie after a change of its ratings or
+	// tags, or of all the movies when it has no movie. It is not a change of the movie itself.
+	MovieInvalidated = "movie.invalidated"
+)
+
+// MovieEvent is the message published to the movie-events topic on every change of a movie.
+type MovieEvent struct {
+	Type  string `json:"type"`
+	Movie *Movie `json:"movie"`
+}
+
+// User is an account of the API. Curators manage the manual collections, admins can do everything.
 type User struct {
-	Username string
-	Password string
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+	Admin    bool   `json:"admin"`
+	Curator  bool   `json:"curator"`
 }
 
 type ResponseMessage struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 }
+
+const (
+	AssetTypePoster   = "poster"
+	AssetTypeTrailer  = "trailer"
+	AssetTypeSubtitle = "subtitle"
+)
+
+var AssetTypes = []string{AssetTypePoster, AssetTypeTrailer, AssetTypeSubtitle}
+
+type MovieAsset struct {
+	ID          int       `json:"id"`
+	MovieId     int       `json:"movieId"`
+	AssetType   string    `json:"type"`
+	ObjectKey   string    `json:"key"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	ContentType string    `json:"contentType"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type PresignedURL struct {
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type RefreshRun struct {
+	ID         int       `json:"id"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Candidates int       `json:"candidates"`
+	Refreshed  int       `json:"refreshed"`
+	Unchanged  int       `json:"unchanged"`
+	Failed     int       `json:"failed"`
+}
+
+// Lease is a time-limited claim of a named role, held by a single replica.
+type Lease struct {
+	Name       string    `json:"name"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	RenewedAt  time.Time `json:"renewedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// IdempotencyKey is a key sent by a user with an unsafe request, whose retries with the same key are answered with
+// the stored response of the first request instead of being processed again.
+type IdempotencyKey struct {
+	Actor string
+	Key   string
+	// Fingerprint identifies the request the key was sent with, to detect its reuse for another request.
+	Fingerprint string
+	// Status is 0 while the first request is being processed.
+	Status    int
+	Headers   map[string]string
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+type MovieImport struct {
+	ID            int               `json:"id"`
+	Format        string            `json:"format"`
+	Status        string            `json:"status"`
+	TotalRows     int               `json:"totalRows"`
+	ProcessedRows int               `json:"processedRows"`
+	ImportedRows  int               `json:"importedRows"`
+	FailedRows    int               `json:"failedRows"`
+	Error         string            `json:"error,omitempty"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	FinishedAt    *time.Time        `json:"finishedAt,omitempty"`
+	Errors        []*ImportRowError `json:"errors,omitempty"`
+	ErrorReport   string            `json:"errorReport,omitempty"`
+}
+
+// ImportRow is a movie read from an import file, along with the line it was read from.
+type ImportRow struct {
+	Line  int    `json:"line"`
+	Movie *Movie `json:"movie"`
+}
+
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	MovieId int    `json:"movieId,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+const (
+	QueueJobPending   = "pending"
+	QueueJobRunning   = "running"
+	QueueJobCompleted = "completed"
+	QueueJobDead      = "dead"
+	QueueJobCancelled = "cancelled"
+)
+
+// QueueJob is a unit of work of the background job queue. Failed jobs are retried with backoff until
+// MaxAttempts is reached, after which they are dead.
+type QueueJob struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	RunAt       time.Time       `json:"runAt"`
+	LockedBy    string          `json:"lockedBy,omitempty"`
+	LastError   string          `json:"lastError,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// QueueJobFilter narrows down the listed queue jobs. Empty fields are ignored.
+type QueueJobFilter struct {
+	Status string
+	Kind   string
+	Limit  int
+}
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+
+	AuditEntityMovie = "movie"
+	AuditEntityUser  = "user"
+)
+
+// AuditEntry records a change of an entity. Before is empty for creations and After for deletions; for
+// updates both only contain the fields that changed.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"requestId,omitempty"`
+	Action     string          `json:"action"`
+	Entity     string          `json:"entity"`
+	EntityID   string          `json:"entityId"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter narrows down the listed audit entries. Zero values are ignored.
+type AuditFilter struct {
+	Entity   string
+	EntityID string
+	Actor    string
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// MovieRevision is a version of a movie, recorded every time it is updated. The first revision of a movie is its
+// state before its first update, whose author is unknown.
+type MovieRevision struct {
+	Revision  int       `json:"revision"`
+	Movie     *Movie    `json:"movie"`
+	Actor     string    `json:"actor,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// FieldChange is the change of a single field between two versions of an entity. From or To is nil when the
+// field is not set in that version.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type RevisionDiff struct {
+	From    int            `json:"from"`
+	To      int            `json:"to"`
+	Changes []*FieldChange `json:"changes"`
+}
+
+// DefaultWatchlistName is the name of the watchlist every user gets.
+const DefaultWatchlistName = "Watchlist"
+
+// Watchlist is a list of movies of a user, in the user's order.
+type Watchlist struct {
+	ID        int              `json:"id"`
+	Name      string           `json:"name"`
+	Default   bool             `json:"default"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Items     []*WatchlistItem `json:"items"`
+}
+
+type WatchlistItem struct {
+	Movie     *MovieSummary `json:"movie"`
+	Position  int           `json:"position"`
+	AddedAt   time.Time     `json:"addedAt"`
+	Watched   bool          `json:"watched"`
+	WatchedAt *time.Time    `json:"watchedAt,omitempty"`
+}
+
+// MovieSummary is the short form of a movie embedded in other resources.
+type MovieSummary struct {
+	MovieId     int    `json:"id"`
+	MovieName   string `json:"title"`
+	Runtime     int    `json:"runtime,omitempty"`
+	ReleaseDate string `json:"releaseDate,omitempty" xml:"releaseDate,omitempty"`
+}
+
+// SimilarMovie is a movie similar to another one. SharedTerms are the overview terms contributing most to the
+// score, SharedGenres the genres of both movies.
+type SimilarMovie struct {
+	MovieSummary
+	Score        float64  `json:"score"`
+	SharedTerms  []string `json:"sharedTerms"`
+	SharedGenres []Genre  `json:"sharedGenres,omitempty"`
+}
+
+const (
+	RecommendedFromRatings    = "ratings"
+	RecommendedFromPopularity = "popularity"
+)
+
+// Recommendation is a movie recommended to a user. Recommendations from ratings are scored with the predicted
+// rating of the user and list the rated movies they are based on, the others are scored between 0 and 1.
+type Recommendation struct {
+	MovieSummary
+	Score     float64 `json:"score"`
+	Source    string  `json:"source"`
+	BecauseOf []int   `json:"becauseOf,omitempty"`
+}
+
+// MovieNeighbor is an entry of the item-item similarity matrix: a movie similar to the movie with MovieId.
+type MovieNeighbor struct {
+	MovieId    int
+	Neighbor   MovieSummary
+	Similarity float64
+}
+
+// UserHistory is what recommendations are based on. Ratings maps movie ids to the rating of the user,
+// GenreAffinity genre ids to the number of movies the user liked or put on a watchlist with the genre.
+type UserHistory struct {
+	Ratings       map[int]int
+	Watched       []int
+	GenreAffinity map[int]int
+}
+
+const (
+	MinRating = 1
+	MaxRating = 10
+)
+
+// Rating is the score a user gave to a movie. A user rates a movie at most once.
+type Rating struct {
+	MovieId   int       `json:"movieId"`
+	Username  string    `json:"username"`
+	Rating    int       `json:"rating"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Review is the text review of a movie by a user. Hidden reviews were moderated by an admin and are only
+// listed to admins.
+type Review struct {
+	ID        int        `json:"id"`
+	MovieId   int        `json:"movieId"`
+	Username  string     `json:"username"`
+	Text      string     `json:"text"`
+	Spoiler   bool       `json:"spoiler"`
+	HiddenAt  *time.Time `json:"hiddenAt,omitempty"`
+	HiddenBy  string     `json:"hiddenBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+const (
+	CreditCast = "cast"
+	CreditCrew = "crew"
+)
+
+// Person is an actor or crew member, identified by their TMDB person id. Filmography is only set when a single
+// person is requested.
+type Person struct {
+	ID                 int                 `json:"id"`
+	Name               string              `json:"name"`
+	ProfilePath        string              `json:"profilePath,omitempty"`
+	KnownForDepartment string              `json:"knownForDepartment,omitempty"`
+	Filmography        []*FilmographyEntry `json:"filmography,omitempty"`
+}
+
+// Credit is the part of a person in a movie. Character is only set for the cast, Job for the crew.
+type Credit struct {
+	Person     *Person `json:"person"`
+	Role       string  `json:"role"`
+	Character  string  `json:"character,omitempty"`
+	Job        string  `json:"job,omitempty"`
+	Department string  `json:"department,omitempty"`
+	Order      int     `json:"order"`
+}
+
+// MovieCredits is the cast, in billing order, and the crew of a movie.
+type MovieCredits struct {
+	MovieId int       `json:"movieId"`
+	Cast    []*Credit `json:"cast"`
+	Crew    []*Credit `json:"crew"`
+}
+
+// FilmographyEntry is a credit of a person, along with the movie.
+type FilmographyEntry struct {
+	Movie      *MovieSummary `json:"movie"`
+	Role       string        `json:"role"`
+	Character  string        `json:"character,omitempty"`
+	Job        string        `json:"job,omitempty"`
+	Department string        `json:"department,omitempty"`
+}
+
+// Collection groups movies, either a TMDB collection such as a franchise or a manual one created by a curator.
+// The movies of TMDB collections are ordered by release date, the ones of manual collections by the curator.
+// Movies is only set when a single collection is requested.
+type Collection struct {
+	ID          int             `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	TmdbId      int             `json:"tmdbId,omitempty"`
+	Manual      bool            `json:"manual"`
+	CreatedBy   string          `json:"createdBy,omitempty"`
+	MovieCount  int             `json:"movieCount"`
+	Movies      []*MovieSummary `json:"movies,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
@@ -0,0 +1,105 @@
+package negotiate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var JSON = &Codec{
+	MediaTypes: []string{"application/json"},
+	Encode: func(w io.Writer, value any) error {
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(valueJSON)
+		return err
+	},
+	Decode: func(r io.Reader, value any) error {
+		return json.NewDecoder(r).Decode(value)
+	},
+}
+
+// XML encodes structs as elements named by their XMLName field or, without one, by their type name starting in
+// lower case, and lists as an element named by the plural of the one of their elements.
+var XML = &Codec{
+	MediaTypes: []string{"application/xml", "text/xml"},
+	Encode:     encodeXML,
+	Decode: func(r io.Reader, value any) error {
+		return xml.NewDecoder(r).Decode(value)
+	},
+}
+
+// MessagePack encodes values like JSON does, using the names of their JSON fields.
+var MessagePack = &Codec{
+	MediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+	Encode: func(w io.Writer, value any) error {
+		encoder := msgpack.NewEncoder(w)
+		encoder.SetCustomStructTag("json")
+		return encoder.Encode(value)
+	},
+	Decode: func(r io.Reader, value any) error {
+		decoder := msgpack.NewDecoder(r)
+		decoder.SetCustomStructTag("json")
+		return decoder.Decode(value)
+	},
+}
+
+func encodeXML(w io.Writer, value any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice {
+		if err := encoder.EncodeElement(value, xmlStart(elementName(list.Type()))); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	name := elementName(list.Type().Elem())
+	start := xmlStart(name + "s")
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := 0; i < list.Len(); i++ {
+		if err := encoder.EncodeElement(list.Index(i).Interface(), xmlStart(name)); err != nil {
+			return err
+		}
+	}
+	if err := encoder.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func xmlStart(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// elementName returns the name of the XML element of the values of the type.
+func elementName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		if field, ok := t.FieldByName("XMLName"); ok {
+			if name, _, _ := strings.Cut(field.Tag.Get("xml"), ","); name != "" {
+				return name
+			}
+		}
+	}
+	if t.Name() == "" {
+		return "value"
+	}
+	runes := []rune(t.Name())
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,196 @@
+package negotiate
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoding is a content coding responses can be compressed with. Its writers are pooled, as some of them allocate
+// large buffers.
+type Encoding struct {
+	Name    string
+	writers sync.Pool
+}
+
+// compressor is the writer of an encoding, reset for every response.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func newEncoding(name string, newWriter func() compressor) *Encoding {
+	return &Encoding{Name: name, writers: sync.Pool{New: func() any { return newWriter() }}}
+}
+
+var (
+	Zstd = newEncoding("zstd", func() compressor {
+		// the window is kept within the 8MB browsers support
+		writer, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(8<<20))
+		return writer
+	})
+	// Brotli compresses at a quality favoring speed, the higher ones being too slow for dynamic responses.
+	Brotli = newEncoding("br", func() compressor {
+		return brotli.NewWriterLevel(nil, 5)
+	})
+	Gzip = newEncoding("gzip", func() compressor {
+		return gzip.NewWriter(nil)
+	})
+)
+
+// Encodings are the supported content codings, by order of preference when the client accepts several of them
+// equally.
+var Encodings = []*Encoding{Zstd, Brotli, Gzip}
+
+// AcceptedEncoding returns the encoding preferred by the Accept-Encoding header of the request, or nil if the
+// response should not be compressed.
+func AcceptedEncoding(req *http.Request) *Encoding {
+	ranges := parseAccept(req.Header.Get("Accept-Encoding"))
+	var best *Encoding
+	bestQ := 0.0
+	for _, encoding := range Encodings {
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if r.mediaType == encoding.Name && specificity < 1 {
+				q, specificity = r.q, 1
+			} else if r.mediaType == "*" && specificity < 0 {
+				q, specificity = r.q, 0
+			}
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// Compress compresses the responses of at least minSize bytes with the encoding preferred by the client. Smaller
+// responses, responses without content, partial ones and the ones whose content type is already compressed or
+// which already have a content encoding are sent as is.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Add("Vary", "Accept-Encoding")
+			encoding := AcceptedEncoding(req)
+			if encoding == nil || req.Method == http.MethodHead {
+				next.ServeHTTP(res, req)
+				return
+			}
+			writer := &compressWriter{ResponseWriter: res, encoding: encoding, minSize: minSize}
+			defer writer.close()
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
+
+// compressWriter buffers the start of the response until it knows whether it is worth compressing, that is when
+// minSize bytes were written.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding *Encoding
+	minSize  int
+
+	status int
+	buffer bytes.Buffer
+	// passThrough is set once the response is sent as is, compressor once it is being compressed.
+	passThrough bool
+	compressor  compressor
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status != 0 || w.passThrough {
+		return
+	}
+	if status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	if status == http.StatusNoContent || status == http.StatusPartialContent || status == http.StatusNotModified ||
+		w.Header().Get("Content-Encoding") != "" || !compressible(w.Header().Get("Content-Type")) {
+		w.passThrough = true
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.passThrough:
+		return w.ResponseWriter.Write(b)
+	case w.compressor != nil:
+		return w.compressor.Write(b)
+	}
+	w.buffer.Write(b)
+	if w.buffer.Len() >= w.minSize {
+		if err := w.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends what was written so far, compressed whatever its size, the handler streaming its response.
+func (w *compressWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passThrough && w.compressor == nil && w.startCompression() != nil {
+		return
+	}
+	if w.compressor != nil && w.compressor.Flush() != nil {
+		return
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) startCompression() error {
+	w.Header().Set("Content-Encoding", w.encoding.Name)
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.compressor = w.encoding.writers.Get().(compressor)
+	w.compressor.Reset(w.ResponseWriter)
+	_, err := w.compressor.Write(w.buffer.Bytes())
+	w.buffer.Reset()
+	return err
+}
+
+// close ends the compressed stream, or sends the response as is if it stayed below the minimum size.
+func (w *compressWriter) close() {
+	switch {
+	case w.compressor != nil:
+		w.compressor.Close()
+		w.compressor.Reset(nil)
+		w.encoding.writers.Put(w.compressor)
+	case !w.passThrough && w.status != 0:
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buffer.Bytes())
+	}
+}
+
+// compressible returns whether content of the type gains from being compressed: text, JSON, XML and MessagePack.
+// Files such as images, archives or Parquet exports are already compressed.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") {
+		return true
+	}
+	return codecOf(mediaType) == MessagePack || mediaType == "application/javascript"
+}
@@ -0,0 +1,107 @@
+package negotiate
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           *Encoding
+	}{
+		{"", nil},
+		{"identity", nil},
+		{"gzip", Gzip},
+		{"gzip, deflate, br", Brotli},
+		{"gzip, deflate, br, zstd", Zstd},
+		{"br;q=0.8, gzip", Gzip},
+		{"*", Zstd},
+		{"*;q=0.5, zstd;q=0, br;q=0.4", Gzip},
+		{"gzip;q=0", nil},
+		{"deflate, compress", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/movies", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			assert.Equal(t, tt.want, AcceptedEncoding(req))
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"title":"` + strings.Repeat("The bear ", 200) + `"}`
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		wantEncoding   string
+	}{
+		{name: "gzip", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusOK, body: large,
+			wantEncoding: "gzip"},
+		{name: "brotli", acceptEncoding: "gzip, br", contentType: "application/json", status: http.StatusOK, body: large,
+			wantEncoding: "br"},
+		{name: "zstd", acceptEncoding: "zstd", contentType: "text/csv; charset=utf-8", status: http.StatusOK, body: large,
+			wantEncoding: "zstd"},
+		{name: "error", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusBadRequest, body: large,
+			wantEncoding: "gzip"},
+		{name: "small", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusCreated,
+			body: `{"title":"The bear"}`},
+		{name: "not accepted", acceptEncoding: "identity", contentType: "application/json", status: http.StatusOK, body: large},
+		{name: "already compressed", acceptEncoding: "gzip", contentType: "application/vnd.apache.parquet",
+			status: http.StatusOK, body: large},
+		{name: "not modified", acceptEncoding: "gzip", status: http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(1024)(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				if tt.contentType != "" {
+					res.Header().Set("Content-Type", tt.contentType)
+				}
+				res.WriteHeader(tt.status)
+				// written in several parts, the minimum size being reached in the middle of one
+				for i := 0; i < len(tt.body); i += 300 {
+					_, err := io.WriteString(res, tt.body[i:min(i+300, len(tt.body))])
+					require.NoError(t, err)
+				}
+			}))
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/movies", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			assert.Equal(t, tt.wantEncoding, w.Header().Get("Content-Encoding"))
+			body := io.Reader(w.Body)
+			if tt.wantEncoding != "" {
+				assert.Less(t, w.Body.Len(), len(tt.body))
+				var err error
+				body, err = decoders[tt.wantEncoding](w.Body)
+				require.NoError(t, err)
+			}
+			decoded, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(decoded))
+		})
+	}
+}
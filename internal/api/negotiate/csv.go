@@ -0,0 +1,83 @@
+package negotiate
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// CSVMarshaler is implemented by the values which can be encoded as CSV, as a header and a record. A list of them
+// is encoded as a single header followed by the record of every value, so the header should not depend on the
+// value.
+type CSVMarshaler interface {
+	MarshalCSV() (header []string, record []string)
+}
+
+// CSVUnmarshaler is implemented by the values which can be decoded from CSV, given the fields of the record by
+// the name of their column. Columns the value does not know should be ignored.
+type CSVUnmarshaler interface {
+	UnmarshalCSV(fields map[string]string) error
+}
+
+var CSV = &Codec{
+	MediaTypes: []string{"text/csv"},
+	Encode:     encodeCSV,
+	Decode:     decodeCSV,
+}
+
+var csvMarshalerType = reflect.TypeOf((*CSVMarshaler)(nil)).Elem()
+
+func encodeCSV(w io.Writer, value any) error {
+	writer := csv.NewWriter(w)
+	list := reflect.ValueOf(value)
+	if marshaler, ok := value.(CSVMarshaler); ok {
+		header, record := marshaler.MarshalCSV()
+		return writer.WriteAll([][]string{header, record})
+	}
+	if list.Kind() != reflect.Slice || !list.Type().Elem().Implements(csvMarshalerType) {
+		return fmt.Errorf("%T cannot be encoded as CSV", value)
+	}
+
+	// the header is taken from an empty value, for empty lists to have one too
+	elem := list.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	header, _ := reflect.New(elem).Interface().(CSVMarshaler).MarshalCSV()
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for i := 0; i < list.Len(); i++ {
+		_, record := list.Index(i).Interface().(CSVMarshaler).MarshalCSV()
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// decodeCSV decodes a header and a single record.
+func decodeCSV(r io.Reader, value any) error {
+	unmarshaler, ok := value.(CSVUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%T cannot be decoded from CSV", value)
+	}
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) != 2 {
+		return errors.New("csv should have a header and a single record")
+	}
+	fields := make(map[string]string, len(records[0]))
+	for i, column := range records[0] {
+		fields[strings.TrimSpace(column)] = records[1][i]
+	}
+	return unmarshaler.UnmarshalCSV(fields)
+}
@@ -0,0 +1,193 @@
+// Package negotiate selects the format of responses from the Accept header of requests, and the one of request
+// bodies from their Content-Type, among JSON, XML, CSV and MessagePack. Responses are also compressed with the
+// content coding selected from the Accept-Encoding header.
+package negotiate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/utils"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotAcceptable        = errors.New("none of the accepted media types is supported")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// Codec encodes and decodes values in a format.
+type Codec struct {
+	// MediaTypes are the media type of the format, followed by its aliases.
+	MediaTypes []string
+	Encode     func(w io.Writer, value any) error
+	Decode     func(r io.Reader, value any) error
+}
+
+// Codecs are the supported formats, by order of preference when the client accepts several of them equally.
+var Codecs = []*Codec{JSON, XML, CSV, MessagePack}
+
+// MediaTypes returns the media types of the supported formats, aliases included.
+func MediaTypes() []string {
+	var mediaTypes []string
+	for _, codec := range Codecs {
+		mediaTypes = append(mediaTypes, codec.MediaTypes...)
+	}
+	return mediaTypes
+}
+
+// mediaRange is an entry of an Accept header.
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept returns the media ranges of the header, with their quality. Entries with an invalid quality are
+// ignored.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, entry := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// quality returns the quality of the media type given by the most specific range matching it, and whether one
+// does.
+func quality(ranges []mediaRange, mediaType string) (float64, bool) {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.mediaType == mediaType:
+			s = 2
+		case r.mediaType == typ+"/*":
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q, specificity >= 0
+}
+
+// Accepted returns the codec preferred by the Accept header of the request, and the media type to respond with,
+// which is the one of the codec unless the client explicitly asked for one of its aliases. Requests without
+// Accept header get JSON. It returns false if none of the formats is acceptable.
+func Accepted(req *http.Request) (*Codec, string, bool) {
+	header := req.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return JSON, JSON.MediaTypes[0], true
+	}
+	ranges := parseAccept(header)
+	var best *Codec
+	var bestMediaType string
+	bestQ := 0.0
+	for _, codec := range Codecs {
+		for i, mediaType := range codec.MediaTypes {
+			q, ok := quality(ranges, mediaType)
+			// aliases are only used when they are explicitly accepted
+			if !ok || q <= bestQ || (i > 0 && !explicit(ranges, mediaType)) {
+				continue
+			}
+			best, bestMediaType, bestQ = codec, mediaType, q
+		}
+	}
+	return best, bestMediaType, best != nil
+}
+
+func explicit(ranges []mediaRange, mediaType string) bool {
+	for _, r := range ranges {
+		if r.mediaType == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// Acceptable rejects the requests accepting none of the formats with a 406 response, before the handler does
+// anything.
+func Acceptable(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if _, _, ok := Accepted(req); !ok {
+			returnNotAcceptable(res)
+			return
+		}
+		next(res, req)
+	}
+}
+
+// Write writes the value with the status in the format negotiated with the client. Nothing is written if the value
+// cannot be encoded, or if no format is acceptable, in which case ErrNotAcceptable is returned.
+func Write(res http.ResponseWriter, req *http.Request, status int, value any) error {
+	codec, mediaType, ok := Accepted(req)
+	if !ok {
+		return ErrNotAcceptable
+	}
+	var body bytes.Buffer
+	if err := codec.Encode(&body, value); err != nil {
+		return err
+	}
+	res.Header().Add("Vary", "Accept")
+	res.Header().Set("Content-type", mediaType)
+	res.WriteHeader(status)
+	_, err := res.Write(body.Bytes())
+	return err
+}
+
+// Decode decodes the body of the request into the value in the format of its content type, JSON if it has none.
+// An unsupported content type results in ErrUnsupportedMediaType.
+func Decode(req *http.Request, value any) error {
+	codec := JSON
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return ErrUnsupportedMediaType
+		}
+		if codec = codecOf(mediaType); codec == nil {
+			return ErrUnsupportedMediaType
+		}
+	}
+	return codec.Decode(req.Body, value)
+}
+
+func codecOf(mediaType string) *Codec {
+	for _, codec := range Codecs {
+		for _, supported := range codec.MediaTypes {
+			if supported == mediaType {
+				return codec
+			}
+		}
+	}
+	return nil
+}
+
+func returnNotAcceptable(res http.ResponseWriter) {
+	var mediaTypes []string
+	for _, codec := range Codecs {
+		mediaTypes = append(mediaTypes, codec.MediaTypes[0])
+	}
+	message := "Accept should allow " + strings.Join(mediaTypes[:len(mediaTypes)-1], ", ") + " or " + mediaTypes[len(mediaTypes)-1]
+	responseBytes, _ := json.Marshal(model.ResponseMessage{Success: false, Message: message})
+	utils.ReturnJsonResponse(res, http.StatusNotAcceptable, responseBytes)
+}
@@ -0,0 +1,167 @@
+package negotiate
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"rest_api/internal/api/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccepted(t *testing.T) {
+	tests := []struct {
+		accept    string
+		wantType  string
+		wantCodec *Codec
+	}{
+		{"", "application/json", JSON},
+		{"*/*", "application/json", JSON},
+		{"application/xml", "application/xml", XML},
+		{"text/xml", "text/xml", XML},
+		{"text/*", "text/csv", CSV},
+		{"application/json;q=0.5, application/msgpack", "application/msgpack", MessagePack},
+		{"application/vnd.msgpack;q=0.9, application/xml;q=0.4", "application/vnd.msgpack", MessagePack},
+		{"text/csv;q=0.2, */*;q=0.3", "application/json", JSON},
+		{"application/*;q=0.5, application/json;q=0", "application/xml", XML},
+		{"text/html, image/*", "", nil},
+		{"application/json;q=0", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/movies", nil)
+			req.Header.Set("Accept", tt.accept)
+
+			codec, mediaType, ok := Accepted(req)
+
+			assert.Equal(t, tt.wantCodec != nil, ok)
+			assert.Equal(t, tt.wantCodec, codec)
+			assert.Equal(t, tt.wantType, mediaType)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	deletedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	movies := []*model.Movie{
+		{MovieId: 1, MovieName: "The bear", Overview: "bear, in a kitchen", Runtime: 95, ReleaseDate: "2023-06-22",
+			Genres: []model.Genre{{ID: 18, Name: "Drama"}, {ID: 35, Name: "Comedy"}}},
+		{MovieId: 2, MovieName: "Gone", DeletedAt: &deletedAt},
+	}
+	tests := []struct {
+		name   string
+		accept string
+		value  any
+		want   string
+	}{
+		{name: "json", accept: "application/json", value: movies[1],
+			want: `{"id":2,"title":"Gone","overview":"","deletedAt":"2024-05-01T10:00:00Z"}`},
+		{name: "xml movie", accept: "application/xml", value: movies[1],
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<movie><id>2</id><title>Gone</title><overview></overview><genres></genres><tags></tags><deletedAt>2024-05-01T10:00:00Z</deletedAt></movie>`},
+		{name: "xml movies", accept: "application/xml", value: movies,
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<movies><movie><id>1</id><title>The bear</title><overview>bear, in a kitchen</overview><runtime>95</runtime>` +
+				`<releaseDate>2023-06-22</releaseDate><genres><genre><id>18</id><name>Drama</name></genre>` +
+				`<genre><id>35</id><name>Comedy</name></genre></genres><tags></tags></movie>` +
+				`<movie><id>2</id><title>Gone</title><overview></overview><genres></genres><tags></tags><deletedAt>2024-05-01T10:00:00Z</deletedAt></movie></movies>`},
+		{name: "xml empty list", accept: "application/xml", value: []*model.Movie{},
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<movies></movies>`},
+		{name: "csv movies", accept: "text/csv", value: movies,
+			want: "id,title,overview,tmdbId,runtime,releaseDate,tmdbVoteAverage,ratingAverage,ratingCount,genres,tags,deletedAt\n" +
+				"1,The bear,\"bear, in a kitchen\",,95,2023-06-22,,,,Drama|Comedy,,\n" +
+				"2,Gone,,,,,,,,,,2024-05-01T10:00:00Z\n"},
+		{name: "csv empty list", accept: "text/csv", value: []model.Movie{},
+			want: "id,title,overview,tmdbId,runtime,releaseDate,tmdbVoteAverage,ratingAverage,ratingCount,genres,tags,deletedAt\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/movies", nil)
+			req.Header.Set("Accept", tt.accept)
+
+			require.NoError(t, Write(w, req, http.StatusOK, tt.value))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.accept, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
+
+func TestWrite_Errors(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/movies", nil)
+	req.Header.Set("Accept", "text/csv")
+	assert.EqualError(t, Write(w, req, http.StatusOK, model.Tag{ID: 1}), "model.Tag cannot be encoded as CSV")
+
+	req.Header.Set("Accept", "image/png")
+	assert.ErrorIs(t, Write(w, req, http.StatusOK, model.Tag{ID: 1}), ErrNotAcceptable)
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestDecode(t *testing.T) {
+	var msgpackBody bytes.Buffer
+	require.NoError(t, MessagePack.Encode(&msgpackBody, map[string]any{"id": 1, "title": "The bear", "runtime": 95}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        *model.Movie
+		wantErr     string
+	}{
+		{name: "json", body: `{"id":1,"title":"The bear","runtime":95}`,
+			want: &model.Movie{MovieId: 1, MovieName: "The bear", Runtime: 95}},
+		{name: "xml", contentType: "application/xml; charset=utf-8",
+			body: `<movie><id>1</id><title>The bear</title><runtime>95</runtime></movie>`,
+			want: &model.Movie{MovieId: 1, MovieName: "The bear", Runtime: 95}},
+		{name: "csv", contentType: "text/csv", body: "title, id, runtime, genres\nThe bear,1,95,Drama\n",
+			want: &model.Movie{MovieId: 1, MovieName: "The bear", Runtime: 95}},
+		{name: "msgpack", contentType: "application/msgpack", body: msgpackBody.String(),
+			want: &model.Movie{MovieId: 1, MovieName: "The bear", Runtime: 95}},
+		{name: "csv with several records", contentType: "text/csv", body: "id,title\n1,The bear\n2,Gone\n",
+			wantErr: "csv should have a header and a single record"},
+		{name: "csv with invalid number", contentType: "text/csv", body: "id,title\none,The bear\n",
+			wantErr: "id should be a number"},
+		{name: "unsupported", contentType: "text/plain", body: "The bear", wantErr: ErrUnsupportedMediaType.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/movies", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			movie := &model.Movie{}
+			err := Decode(req, movie)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, movie)
+		})
+	}
+}
+
+func TestMessagePack_RoundTrip(t *testing.T) {
+	movie := &model.Movie{MovieId: 1, MovieName: "The bear", TmdbVoteAverage: 8.1, Genres: []model.Genre{{ID: 18, Name: "Drama"}}}
+	var body bytes.Buffer
+	require.NoError(t, MessagePack.Encode(&body, movie))
+
+	var decoded map[string]any
+	require.NoError(t, MessagePack.Decode(bytes.NewReader(body.Bytes()), &decoded))
+	assert.Equal(t, "The bear", decoded["title"])
+	assert.NotContains(t, decoded, "runtime")
+
+	got := &model.Movie{}
+	require.NoError(t, MessagePack.Decode(&body, got))
+	assert.Equal(t, movie, got)
+}
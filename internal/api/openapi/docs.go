@@ -0,0 +1,51 @@
+package openapi
+
+import (
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+)
+
+// ServeHTTP serves the document as JSON.
+func (d *Document) ServeHTTP(res http.ResponseWriter, _ *http.Request) {
+	documentJSON, err := json.Marshal(d)
+	if err != nil {
+		slog.Error("Error when marshalling the API document", "error", err)
+		returnError("Error creating response", http.StatusInternalServerError, res)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	res.Write(documentJSON)
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: {{.URL}}, dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`))
+
+// DocsHandler serves Swagger UI, loaded from a CDN, for the document served at documentURL.
+func DocsHandler(title, documentURL string) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, _ *http.Request) {
+		res.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := docsTemplate.Execute(res, struct{ Title, URL string }{title, documentURL})
+		if err != nil {
+			slog.Error("Error when rendering the API docs", "error", err)
+		}
+	})
+}
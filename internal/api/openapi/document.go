@@ -0,0 +1,327 @@
+// Package openapi documents the REST API as an OpenAPI 3.1 document and validates the requests, and optionally
+// the responses, of the documented operations against it.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"rest_api/internal/api/model"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	Version = "3.1.0"
+
+	// BasicAuth is the security scheme of the operations requiring credentials.
+	BasicAuth = "basicAuth"
+
+	// DefaultMaxBodyBytes is the size limit of the request bodies of the operations not setting one.
+	DefaultMaxBodyBytes = 1 << 20
+)
+
+var pathParamPattern = regexp.MustCompile(`{([^}]+)}`)
+
+// Document is an OpenAPI document. Operations are added with Add, which also adds the schemas of the Go types
+// they refer to to the components.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	componentNames map[reflect.Type]string
+	// operations are the operations by method and path.
+	operations map[string]*Operation
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem are the operations of a path by lower-cased method.
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+	// MaxBytes is the size limit of the body, DefaultMaxBodyBytes if zero.
+	MaxBytes int64 `json:"-"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType is the content of a request or response body. A nil schema leaves the content undocumented, such as
+// files.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+func NewDocument(title, version, description string) *Document {
+	return &Document{
+		OpenAPI:        Version,
+		Info:           Info{Title: title, Version: version, Description: description},
+		Paths:          map[string]PathItem{},
+		Components:     Components{Schemas: map[string]*Schema{}},
+		componentNames: map[reflect.Type]string{},
+		operations:     map[string]*Operation{},
+	}
+}
+
+// NewOperation returns an operation without parameters nor responses.
+func NewOperation(operationId, summary string, tags ...string) *Operation {
+	return &Operation{OperationID: operationId, Summary: summary, Tags: tags, Responses: map[string]*Response{}}
+}
+
+// Alias returns a copy of the operation with another id, for the same operation served at another path. The
+// parameters, request body and responses are shared with the operation.
+func (o *Operation) Alias(operationId string) *Operation {
+	alias := *o
+	alias.OperationID = operationId
+	return &alias
+}
+
+// Deprecate documents that the operation will be removed.
+func (o *Operation) Deprecate() *Operation {
+	o.Deprecated = true
+	return o
+}
+
+// PathParam documents a parameter of the path, which is always required.
+func (o *Operation) PathParam(name string, schema *Schema) *Operation {
+	o.Parameters = append(o.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	return o
+}
+
+// Query documents an optional query parameter. Array parameters can be repeated.
+func (o *Operation) Query(name string, schema *Schema, description string) *Operation {
+	o.Parameters = append(o.Parameters, &Parameter{Name: name, In: "query", Description: description, Schema: schema})
+	return o
+}
+
+// Header documents an optional request header.
+func (o *Operation) Header(name string, schema *Schema, description string) *Operation {
+	o.Parameters = append(o.Parameters, &Parameter{Name: name, In: "header", Description: description, Schema: schema})
+	return o
+}
+
+func (o *Operation) RequiredQuery(name string, schema *Schema, description string) *Operation {
+	o.Query(name, schema, description)
+	o.Parameters[len(o.Parameters)-1].Required = true
+	return o
+}
+
+// Body documents a required request body of the media type. It can be called for each accepted media type.
+func (o *Operation) Body(mediaType string, schema *Schema) *Operation {
+	if o.RequestBody == nil {
+		o.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{}}
+	}
+	o.RequestBody.Content[mediaType] = &MediaType{Schema: schema}
+	return o
+}
+
+// BodyContents documents a required request body in each of the media types, with their schema.
+func (o *Operation) BodyContents(contents map[string]*Schema) *Operation {
+	for mediaType, schema := range contents {
+		o.Body(mediaType, schema)
+	}
+	return o
+}
+
+// BodyLimit sets the size limit of the request body documented by Body, bodies exceeding it getting a 413 response.
+func (o *Operation) BodyLimit(maxBytes int64) *Operation {
+	o.RequestBody.MaxBytes = maxBytes
+	return o
+}
+
+// Returns documents a JSON response. A nil schema documents a response without content.
+func (o *Operation) Returns(status int, description string, schema *Schema) *Operation {
+	if schema == nil {
+		o.response(status, description)
+		return o
+	}
+	return o.ReturnsContent(status, description, "application/json", schema)
+}
+
+// ReturnsContent documents a response of the media type. It can be called for each media type of the status.
+func (o *Operation) ReturnsContent(status int, description, mediaType string, schema *Schema) *Operation {
+	response := o.response(status, description)
+	if response.Content == nil {
+		response.Content = map[string]*MediaType{}
+	}
+	response.Content[mediaType] = &MediaType{Schema: schema}
+	return o
+}
+
+// ReturnsContents documents a response in each of the media types, with their schema.
+func (o *Operation) ReturnsContents(status int, description string, contents map[string]*Schema) *Operation {
+	for mediaType, schema := range contents {
+		o.ReturnsContent(status, description, mediaType, schema)
+	}
+	return o
+}
+
+// WithHeader documents a header of a documented response.
+func (o *Operation) WithHeader(status int, name, description string) *Operation {
+	response := o.Responses[strconv.Itoa(status)]
+	if response.Headers == nil {
+		response.Headers = map[string]*Header{}
+	}
+	response.Headers[name] = &Header{Description: description, Schema: String()}
+	return o
+}
+
+// Errors documents error responses of the given statuses, with the message of the error in the body.
+func (o *Operation) Errors(statuses ...int) *Operation {
+	for _, status := range statuses {
+		if _, ok := o.Responses[strconv.Itoa(status)]; !ok {
+			o.Returns(status, http.StatusText(status), SchemaOf(model.ResponseMessage{}))
+		}
+	}
+	return o
+}
+
+// Authenticated documents that the operation requires basic auth credentials, answered with a plain text 401
+// response if they are missing or invalid.
+func (o *Operation) Authenticated() *Operation {
+	o.Security = []map[string][]string{{BasicAuth: {}}}
+	return o.ReturnsContent(http.StatusUnauthorized, "Missing or invalid credentials", "text/plain", String())
+}
+
+func (o *Operation) response(status int, description string) *Response {
+	key := strconv.Itoa(status)
+	if o.Responses[key] == nil {
+		o.Responses[key] = &Response{Description: description}
+	}
+	return o.Responses[key]
+}
+
+// Add documents the operation of the method and path, whose variables in braces should be documented as path
+// parameters. Every operation can fail with an internal error, with a bad request if it has parameters or a
+// request body to validate, and with a too large request if it has a request body.
+func (d *Document) Add(method, path string, operation *Operation) error {
+	key := method + " " + path
+	if _, ok := d.operations[key]; ok {
+		return fmt.Errorf("operation %s is already documented", key)
+	}
+	var pathParams []string
+	for _, param := range operation.Parameters {
+		if param.In == "path" {
+			pathParams = append(pathParams, param.Name)
+		}
+	}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		if !slices.Contains(pathParams, match[1]) {
+			return fmt.Errorf("path parameter %s of operation %s is not documented", match[1], key)
+		}
+		pathParams = slices.DeleteFunc(pathParams, func(name string) bool { return name == match[1] })
+	}
+	if len(pathParams) > 0 {
+		return fmt.Errorf("operation %s documents path parameters missing from the path: %s", key, strings.Join(pathParams, ", "))
+	}
+
+	if len(operation.Parameters) > 0 || operation.RequestBody != nil {
+		operation.Errors(http.StatusBadRequest)
+	}
+	if operation.RequestBody != nil {
+		operation.Errors(http.StatusRequestEntityTooLarge)
+	}
+	operation.Errors(http.StatusInternalServerError)
+	if operation.Security != nil && d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = map[string]*SecurityScheme{BasicAuth: {Type: "http", Scheme: "basic"}}
+	}
+
+	for _, param := range operation.Parameters {
+		param.Schema = d.resolve(param.Schema)
+	}
+	if operation.RequestBody != nil {
+		for _, media := range operation.RequestBody.Content {
+			media.Schema = d.resolve(media.Schema)
+		}
+	}
+	for _, response := range operation.Responses {
+		for _, media := range response.Content {
+			media.Schema = d.resolve(media.Schema)
+		}
+	}
+
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(method)] = operation
+	d.operations[key] = operation
+	return nil
+}
+
+// Operation returns the operation of the method and path template, or nil if it is not documented.
+func (d *Document) Operation(method, path string) *Operation {
+	return d.operations[method+" "+path]
+}
+
+// resolve replaces the schemas of Go types in the schema by their JSON schemas.
+func (d *Document) resolve(schema *Schema) *Schema {
+	if schema == nil {
+		return nil
+	}
+	if schema.goType != nil {
+		return d.reflectType(indirect(schema.goType))
+	}
+	schema.Items = d.resolve(schema.Items)
+	schema.AdditionalProperties = d.resolve(schema.AdditionalProperties)
+	for name, property := range schema.Properties {
+		schema.Properties[name] = d.resolve(property)
+	}
+	for i, alternative := range schema.AnyOf {
+		schema.AnyOf[i] = d.resolve(alternative)
+	}
+	return schema
+}
+
+// lookup returns the schema of the component the reference points to.
+func (d *Document) lookup(ref string) (*Schema, bool) {
+	schema, ok := d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	return schema, ok && schema != nil
+}
@@ -0,0 +1,253 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is a JSON Schema, limited to the keywords the validator supports. Nullable schemas are documented with
+// the null type as OpenAPI 3.1 does, and Closed objects with additionalProperties set to false.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Nullable             bool               `json:"-"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Closed               bool               `json:"-"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+
+	// goType is the type of the values described by the schema, set by SchemaOf until the schema is added to a
+	// document.
+	goType reflect.Type
+}
+
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	out := struct {
+		*plain
+		Type                 any `json:"type,omitempty"`
+		AdditionalProperties any `json:"additionalProperties,omitempty"`
+	}{plain: (*plain)(s)}
+	if s.Type != "" {
+		out.Type = s.Type
+		if s.Nullable {
+			out.Type = []string{s.Type, "null"}
+		}
+	}
+	if s.AdditionalProperties != nil {
+		out.AdditionalProperties = s.AdditionalProperties
+	} else if s.Closed {
+		out.AdditionalProperties = false
+	}
+	return json.Marshal(out)
+}
+
+func String() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func Integer() *Schema {
+	return &Schema{Type: "integer"}
+}
+
+func Number() *Schema {
+	return &Schema{Type: "number"}
+}
+
+func Boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+func Array(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// Object returns the schema of an object with the given properties, other properties being allowed.
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+// Any returns the schema accepting any value.
+func Any() *Schema {
+	return &Schema{}
+}
+
+// SchemaOf returns the schema of the JSON encoding of the value's type. Named structs are added to the components
+// of the document the schema is added to, their fields being required unless they are omitted when empty. Nil
+// pointers, slices and maps which are not omitted are documented as nullable.
+func SchemaOf(value any) *Schema {
+	return &Schema{goType: reflect.TypeOf(value)}
+}
+
+// Between sets the minimum and maximum of a number.
+func (s *Schema) Between(minimum, maximum float64) *Schema {
+	s.Minimum, s.Maximum = &minimum, &maximum
+	return s
+}
+
+// AtLeast sets the minimum of a number.
+func (s *Schema) AtLeast(minimum float64) *Schema {
+	s.Minimum = &minimum
+	return s
+}
+
+// Length sets the minimum and maximum length of a string.
+func (s *Schema) Length(minimum, maximum int) *Schema {
+	s.MinLength, s.MaxLength = &minimum, &maximum
+	return s
+}
+
+// OneOf restricts the values to the given ones.
+func (s *Schema) OneOf(values ...any) *Schema {
+	s.Enum = values
+	return s
+}
+
+func (s *Schema) WithFormat(format string) *Schema {
+	s.Format = format
+	return s
+}
+
+func (s *Schema) WithPattern(pattern string) *Schema {
+	s.Pattern = pattern
+	return s
+}
+
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// reflectType returns the schema of the type, adding the named structs it refers to to the components. The
+// elements of slices and maps are not expected to be nil.
+func (d *Document) reflectType(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return String().WithFormat("date-time")
+	case t == rawMessageType:
+		return Any()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return Number()
+	case reflect.String:
+		return String()
+	case reflect.Slice, reflect.Array:
+		return Array(d.reflectType(indirect(t.Elem())))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.reflectType(indirect(t.Elem()))}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return &Schema{Ref: d.component(t)}
+	}
+	return Any()
+}
+
+// component returns the reference of the schema of the named struct, added to the components on first use.
+func (d *Document) component(t reflect.Type) string {
+	if name, ok := d.componentNames[t]; ok {
+		return "#/components/schemas/" + name
+	}
+	name := exportedName(t.Name())
+	if _, taken := d.Components.Schemas[name]; taken {
+		name = exportedName(t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]) + name
+	}
+	d.componentNames[t] = name
+	// the name is reserved before the fields are reflected, for the types referring to themselves
+	d.Components.Schemas[name] = nil
+	d.Components.Schemas[name] = d.structSchema(t)
+	return "#/components/schemas/" + name
+}
+
+// structSchema returns the closed object schema of the JSON encoding of the struct, with the fields of embedded
+// structs inlined like encoding/json does.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}, Closed: true}
+	d.addFields(schema, t)
+	return schema
+}
+
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			d.addFields(schema, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		omitEmpty := strings.Contains(options, "omitempty")
+		property := d.reflectType(indirect(field.Type))
+		switch field.Type.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			if !omitEmpty && field.Type != rawMessageType {
+				property = nullableSchema(property)
+			}
+		}
+		schema.Properties[name] = property
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// indirect returns the type pointers point to.
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// nullableSchema returns the schema also accepting null.
+func nullableSchema(schema *Schema) *Schema {
+	switch {
+	case schema.Ref != "":
+		return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	case schema.Type == "":
+		return schema
+	}
+	schema.Nullable = true
+	return schema
+}
+
+func exportedName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
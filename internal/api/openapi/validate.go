@@ -0,0 +1,201 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// patterns caches the compiled patterns of the schemas.
+var patterns sync.Map
+
+// validate checks the value, decoded from JSON with numbers as json.Number, against the schema. The error names
+// the invalid value by its path.
+func (d *Document) validate(value any, schema *Schema, path string) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		component, ok := d.lookup(schema.Ref)
+		if !ok {
+			return fmt.Errorf("%s refers to the unknown schema %s", path, schema.Ref)
+		}
+		return d.validate(value, component, path)
+	}
+	if len(schema.AnyOf) > 0 {
+		var errs []string
+		for _, alternative := range schema.AnyOf {
+			err := d.validate(value, alternative, path)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s", strings.Join(errs, " or "))
+	}
+	if value == nil {
+		if schema.Type == "" || schema.Type == "null" || schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s should not be null", path)
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(allowed any) bool { return fmt.Sprint(allowed) == fmt.Sprint(value) }) {
+		values := make([]string, len(schema.Enum))
+		for i, allowed := range schema.Enum {
+			values[i] = fmt.Sprint(allowed)
+		}
+		return fmt.Errorf("%s should be one of %s", path, strings.Join(values, ", "))
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "null":
+		return fmt.Errorf("%s should be null", path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s should be a boolean", path)
+		}
+	case "integer", "number":
+		return validateNumber(value, schema, path)
+	case "string":
+		return validateString(value, schema, path)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s should be an array", path)
+		}
+		for i, item := range items {
+			if err := d.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		return d.validateObject(value, schema, path)
+	default:
+		return fmt.Errorf("%s has the unsupported type %s", path, schema.Type)
+	}
+	return nil
+}
+
+func validateNumber(value any, schema *Schema, path string) error {
+	number, ok := value.(json.Number)
+	if !ok {
+		return fmt.Errorf("%s should be %s", path, typeName(schema.Type))
+	}
+	if schema.Type == "integer" {
+		if _, err := number.Int64(); err != nil {
+			return fmt.Errorf("%s should be an integer", path)
+		}
+	}
+	parsed, err := number.Float64()
+	if err != nil {
+		return fmt.Errorf("%s should be a number", path)
+	}
+	if schema.Minimum != nil && parsed < *schema.Minimum {
+		return fmt.Errorf("%s should be at least %v", path, *schema.Minimum)
+	}
+	if schema.Maximum != nil && parsed > *schema.Maximum {
+		return fmt.Errorf("%s should be at most %v", path, *schema.Maximum)
+	}
+	return nil
+}
+
+func validateString(value any, schema *Schema, path string) error {
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s should be a string", path)
+	}
+	length := utf8.RuneCountInString(str)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		return fmt.Errorf("%s should be at least %d characters", path, *schema.MinLength)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return fmt.Errorf("%s should be at most %d characters", path, *schema.MaxLength)
+	}
+	if schema.Pattern != "" {
+		pattern, ok := patterns.Load(schema.Pattern)
+		if !ok {
+			compiled, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return fmt.Errorf("%s has the invalid pattern %s", path, schema.Pattern)
+			}
+			pattern, _ = patterns.LoadOrStore(schema.Pattern, compiled)
+		}
+		if !pattern.(*regexp.Regexp).MatchString(str) {
+			return fmt.Errorf("%s should match %s", path, schema.Pattern)
+		}
+	}
+	switch schema.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return fmt.Errorf("%s should be an RFC 3339 timestamp", path)
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, str); err != nil {
+			return fmt.Errorf("%s should be formatted as YYYY-MM-DD", path)
+		}
+	}
+	return nil
+}
+
+func (d *Document) validateObject(value any, schema *Schema, path string) error {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s should be an object", path)
+	}
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s.%s should be present", path, name)
+		}
+	}
+	// properties are checked in order for the error to be the same every time
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		switch {
+		case ok:
+		case schema.AdditionalProperties != nil:
+			property = schema.AdditionalProperties
+		case schema.Closed:
+			return fmt.Errorf("%s.%s is not documented", path, name)
+		default:
+			continue
+		}
+		if err := d.validate(object[name], property, path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseParam converts the value of a path or query parameter to the type of its schema, so that it can be
+// validated like a JSON value.
+func parseParam(raw string, schema *Schema) (any, bool) {
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(raw), true
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(raw), true
+	case "boolean":
+		parsed, err := strconv.ParseBool(raw)
+		return parsed, err == nil
+	}
+	return raw, true
+}
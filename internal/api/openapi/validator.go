@@ -0,0 +1,278 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/utils"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Validator checks the requests of the documented operations against the document, answering invalid ones with a
+// 400 response, a 413 one for bodies exceeding their limit, or a 415 one for undocumented content types. Requests
+// of authenticated operations are answered with a 401 response without being checked if they do not come with valid
+// credentials, so it should be used after the middleware setting the user of the request context. Responses can be checked too, in which case the ones
+// not matching the document are replaced by a 500 response; as they are buffered to do so, this is meant for
+// tests and development.
+type Validator struct {
+	doc               *Document
+	validateResponses bool
+}
+
+func NewValidator(doc *Document, validateResponses bool) *Validator {
+	return &Validator{doc: doc, validateResponses: validateResponses}
+}
+
+// Middleware validates the requests of the routes matched by the router, whose operations are found by the method
+// and the path template of the route. Requests of undocumented routes are passed through.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		route := mux.CurrentRoute(req)
+		if route == nil {
+			next.ServeHTTP(res, req)
+			return
+		}
+		path, err := route.GetPathTemplate()
+		operation := v.doc.Operation(req.Method, path)
+		if err != nil || operation == nil {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		if operation.Security != nil && reqctx.User(req.Context()) == nil {
+			utils.ReturnUnauthorizedResponse(res)
+			return
+		}
+		if operation.RequestBody != nil {
+			req.Body = http.MaxBytesReader(res, req.Body, operation.RequestBody.maxBytes())
+		}
+		if status, err := v.checkRequest(req, operation); err != nil {
+			returnError(err.Error(), status, res)
+			return
+		}
+		if !v.validateResponses {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		recorder := &responseRecorder{header: http.Header{}}
+		next.ServeHTTP(recorder, req)
+		if err = v.checkResponse(operation, recorder.statusCode(), recorder.header, recorder.body.Bytes()); err != nil {
+			slog.Error("Response does not match the API document", "method", req.Method, "path", path,
+				"status", recorder.statusCode(), "error", err)
+			returnError("Response does not match the API document: "+err.Error(), http.StatusInternalServerError, res)
+			return
+		}
+		recorder.writeTo(res)
+	})
+}
+
+// checkRequest returns the status of the error response if the parameters or the body of the request do not
+// match the operation.
+func (v *Validator) checkRequest(req *http.Request, operation *Operation) (int, error) {
+	vars := mux.Vars(req)
+	query := req.URL.Query()
+	for _, param := range operation.Parameters {
+		var values []string
+		switch param.In {
+		case "path":
+			values = []string{vars[param.Name]}
+		case "query":
+			values = query[param.Name]
+		case "header":
+			values = req.Header.Values(param.Name)
+		}
+		if len(values) == 0 {
+			if param.Required {
+				return http.StatusBadRequest, fmt.Errorf("%s parameter should be present", param.Name)
+			}
+			continue
+		}
+		if err := v.checkParam(param, values); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if operation.RequestBody == nil {
+		return 0, nil
+	}
+	return v.checkBody(req, operation.RequestBody)
+}
+
+// checkParam validates the values of the parameter. Only array parameters can be repeated, the handlers reading
+// the first value of the others.
+func (v *Validator) checkParam(param *Parameter, values []string) error {
+	schema := param.Schema
+	if schema.Type == "array" {
+		schema = schema.Items
+	} else {
+		values = values[:1]
+	}
+	for _, raw := range values {
+		value, ok := parseParam(raw, schema)
+		if !ok {
+			return fmt.Errorf("%s should be %s", param.Name, typeName(schema.Type))
+		}
+		if err := v.doc.validate(value, schema, param.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBody validates the content type of the request body and, for JSON, its content. A body without content
+// type is taken for JSON if the operation accepts it.
+func (v *Validator) checkBody(req *http.Request, body *RequestBody) (int, error) {
+	contentType := req.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if contentType == "" {
+		if _, ok := body.Content["application/json"]; !ok {
+			return 0, nil
+		}
+		mediaType = "application/json"
+	}
+	media, ok := body.Content[mediaType]
+	if !ok {
+		accepted := make([]string, 0, len(body.Content))
+		for accept := range body.Content {
+			accepted = append(accepted, accept)
+		}
+		slices.Sort(accepted)
+		return http.StatusUnsupportedMediaType, fmt.Errorf("Content-Type should be %s", strings.Join(accepted, " or "))
+	}
+	if !isJSON(mediaType) || media.Schema == nil {
+		return 0, nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return http.StatusRequestEntityTooLarge, fmt.Errorf("Request body should be at most %d bytes", maxBytesErr.Limit)
+		}
+		slog.Error("Error when reading the request body", "error", err)
+		return http.StatusBadRequest, errors.New("Could not read request body")
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return http.StatusBadRequest, errors.New("Request body should be present")
+	}
+	value, err := decodeJSON(data)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("Could not parse request body")
+	}
+	if err = v.doc.validate(value, media.Schema, "body"); err != nil {
+		return http.StatusBadRequest, err
+	}
+	return 0, nil
+}
+
+// checkResponse returns an error if the status, content type or JSON content of the response is not the one of
+// the operation.
+func (v *Validator) checkResponse(operation *Operation, status int, header http.Header, body []byte) error {
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d should have no content", status)
+		}
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	media, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("content type %q is not documented for status %d", header.Get("Content-Type"), status)
+	}
+	if !isJSON(mediaType) || media.Schema == nil {
+		return nil
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("body should be valid JSON: %w", err)
+	}
+	return v.doc.validate(value, media.Schema, "body")
+}
+
+func (b *RequestBody) maxBytes() int64 {
+	if b.MaxBytes == 0 {
+		return DefaultMaxBodyBytes
+	}
+	return b.MaxBytes
+}
+
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func typeName(schemaType string) string {
+	switch schemaType {
+	case "integer", "array", "object":
+		return "an " + schemaType
+	case "":
+		return "a value"
+	}
+	return "a " + schemaType
+}
+
+func returnError(message string, status int, res http.ResponseWriter) {
+	responseBytes, _ := json.Marshal(model.ResponseMessage{Success: false, Message: message})
+	utils.ReturnJsonResponse(res, status, responseBytes)
+}
+
+// responseRecorder buffers a response until it is validated.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *responseRecorder) writeTo(res http.ResponseWriter) {
+	for name, values := range r.header {
+		res.Header()[name] = values
+	}
+	res.WriteHeader(r.statusCode())
+	res.Write(r.body.Bytes())
+}
@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Tags      []string   `json:"tags"`
+	Note      string     `json:"note,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Parent    *testItem  `json:"parent"`
+}
+
+func TestDocument_Add(t *testing.T) {
+	doc := NewDocument("test", "1.0.0", "")
+
+	err := doc.Add(http.MethodGet, "/items/{itemId}", NewOperation("getItem", "").
+		PathParam("itemId", Integer()).
+		Returns(http.StatusOK, "Item", SchemaOf(&testItem{})))
+	require.NoError(t, err)
+	assert.EqualError(t, doc.Add(http.MethodGet, "/items/{itemId}", NewOperation("again", "").PathParam("itemId", Integer())),
+		"operation GET /items/{itemId} is already documented")
+	assert.EqualError(t, doc.Add(http.MethodDelete, "/items/{itemId}", NewOperation("deleteItem", "")),
+		"path parameter itemId of operation DELETE /items/{itemId} is not documented")
+	assert.EqualError(t, doc.Add(http.MethodPut, "/items", NewOperation("updateItem", "").PathParam("itemId", Integer())),
+		"operation PUT /items documents path parameters missing from the path: itemId")
+
+	documentJSON, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var document struct {
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(documentJSON, &document))
+	assert.Contains(t, document.Paths["/items/{itemId}"]["get"]["responses"], "400")
+	assert.Contains(t, document.Paths["/items/{itemId}"]["get"]["responses"], "500")
+	assert.NotContains(t, document.Paths["/items/{itemId}"]["get"]["responses"], "413")
+	item := document.Components.Schemas["TestItem"]
+	assert.ElementsMatch(t, []any{"id", "name", "tags", "parent"}, item["required"])
+	assert.Equal(t, false, item["additionalProperties"])
+	properties := item["properties"].(map[string]any)
+	assert.Equal(t, []any{"array", "null"}, properties["tags"].(map[string]any)["type"])
+	assert.Equal(t, "date-time", properties["updatedAt"].(map[string]any)["format"])
+	assert.Len(t, properties["parent"].(map[string]any)["anyOf"], 2)
+}
+
+func TestValidator_Middleware(t *testing.T) {
+	var response string
+	status := http.StatusOK
+	r := mux.NewRouter()
+	r.HandleFunc("/items/{itemId}", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-type", "application/json")
+		res.WriteHeader(status)
+		res.Write([]byte(response))
+	}).Methods(http.MethodPut)
+	r.HandleFunc("/private", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+	r.HandleFunc("/undocumented", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusTeapot)
+	}).Methods(http.MethodGet)
+
+	doc := NewDocument("test", "1.0.0", "")
+	require.NoError(t, doc.Add(http.MethodPut, "/items/{itemId}", NewOperation("updateItem", "").
+		PathParam("itemId", Integer().AtLeast(1)).
+		Query("mode", String().OneOf("merge", "replace"), "").
+		Body("application/json", Object(map[string]*Schema{
+			"name": String().Length(1, 10),
+			"tags": Array(String()),
+		}, "name")).
+		BodyLimit(64).
+		Returns(http.StatusOK, "Item", SchemaOf(testItem{}))))
+	require.NoError(t, doc.Add(http.MethodPost, "/private", NewOperation("createPrivate", "").Authenticated().
+		Body("application/json", Object(map[string]*Schema{"name": String()}, "name")).
+		Returns(http.StatusNoContent, "Created", nil)))
+	r.Use(NewValidator(doc, true).Middleware)
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		status      int
+		response    string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "valid", target: "/items/1?mode=merge", body: `{"name":"bear","tags":["drama"]}`,
+			response: `{"id":1,"name":"bear","tags":null,"parent":null}`, wantStatus: http.StatusOK},
+		{name: "invalid path parameter", target: "/items/0", body: `{"name":"bear"}`,
+			wantStatus: http.StatusBadRequest, wantMessage: "itemId should be at least 1"},
+		{name: "invalid query parameter", target: "/items/1?mode=append", body: `{"name":"bear"}`,
+			wantStatus: http.StatusBadRequest, wantMessage: "mode should be one of merge, replace"},
+		{name: "missing body", target: "/items/1", wantStatus: http.StatusBadRequest, wantMessage: "Request body should be present"},
+		{name: "malformed body", target: "/items/1", body: `{"name":`, wantStatus: http.StatusBadRequest,
+			wantMessage: "Could not parse request body"},
+		{name: "invalid item", target: "/items/1", body: `{"name":"bear","tags":["drama",5]}`,
+			wantStatus: http.StatusBadRequest, wantMessage: "body.tags[1] should be a string"},
+		{name: "too long name", target: "/items/1", body: `{"name":"the bear of the woods"}`,
+			wantStatus: http.StatusBadRequest, wantMessage: "body.name should be at most 10 characters"},
+		{name: "too large body", target: "/items/1", body: `{"name":"bear","tags":["` + strings.Repeat("drama", 20) + `"]}`,
+			wantStatus: http.StatusRequestEntityTooLarge, wantMessage: "Request body should be at most 64 bytes"},
+		{name: "unsupported content type", target: "/items/1", contentType: "text/csv", body: `name\nbear`,
+			wantStatus: http.StatusUnsupportedMediaType, wantMessage: "Content-Type should be application/json"},
+		{name: "undocumented response field", target: "/items/1", body: `{"name":"bear"}`,
+			response: `{"id":1,"name":"bear","tags":[],"parent":null,"rating":5}`, wantStatus: http.StatusInternalServerError,
+			wantMessage: "Response does not match the API document: body.rating is not documented"},
+		{name: "missing response field", target: "/items/1", body: `{"name":"bear"}`,
+			response: `{"id":1,"name":"bear","tags":[]}`, wantStatus: http.StatusInternalServerError,
+			wantMessage: "Response does not match the API document: body.parent should be present"},
+		{name: "undocumented status", target: "/items/1", body: `{"name":"bear"}`, status: http.StatusConflict,
+			response: `{}`, wantStatus: http.StatusInternalServerError,
+			wantMessage: "Response does not match the API document: status 409 is not documented"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response = http.StatusOK, tt.response
+			if tt.status != 0 {
+				status = tt.status
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "http://localhost:3000"+tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			bytes, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, res.StatusCode, string(bytes))
+			if tt.wantMessage != "" {
+				assert.JSONEq(t, `{"success":false,"message":"`+tt.wantMessage+`"}`, string(bytes))
+			} else {
+				assert.JSONEq(t, tt.response, string(bytes))
+			}
+		})
+	}
+
+	t.Run("missing credentials", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:3000/private", strings.NewReader(`{}`)))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("authenticated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:3000/private", strings.NewReader(`{}`))
+		req = req.WithContext(reqctx.WithUser(req.Context(), &model.User{Username: "alice"}))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"success":false,"message":"body.name should be present"}`, w.Body.String())
+	})
+
+	t.Run("undocumented route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:3000/undocumented", nil))
+		assert.Equal(t, http.StatusTeapot, w.Code)
+	})
+}
@@ -0,0 +1,94 @@
+// Package recommend implements item-based collaborative filtering over the ratings of users, and the
+// popularity based recommendations for users without enough ratings.
+package recommend
+
+import (
+	"cmp"
+	"math"
+	"rest_api/internal/api/model"
+	"slices"
+)
+
+type pair struct {
+	a, b int
+}
+
+type pairStats struct {
+	dot, normA, normB float64
+	overlap           int
+}
+
+type centered struct {
+	movieId int
+	value   float64
+}
+
+// Neighbors computes the item-item similarity matrix, keeping for every movie its size most similar movies.
+// Movies are compared by the adjusted cosine similarity of their ratings: ratings are centered on the mean
+// rating of their user, so that generous and strict users weigh the same. Pairs rated by fewer than
+// minOverlap common users, and pairs not positively correlated, are left out.
+func Neighbors(ratings []*model.Rating, size, minOverlap int) []model.MovieNeighbor {
+	byUser := map[string][]*model.Rating{}
+	for _, rating := range ratings {
+		byUser[rating.Username] = append(byUser[rating.Username], rating)
+	}
+
+	stats := map[pair]*pairStats{}
+	for _, userRatings := range byUser {
+		if len(userRatings) < 2 {
+			continue
+		}
+		var sum float64
+		for _, rating := range userRatings {
+			sum += float64(rating.Rating)
+		}
+		mean := sum / float64(len(userRatings))
+		values := make([]centered, len(userRatings))
+		for i, rating := range userRatings {
+			values[i] = centered{rating.MovieId, float64(rating.Rating) - mean}
+		}
+		slices.SortFunc(values, func(x, y centered) int { return cmp.Compare(x.movieId, y.movieId) })
+
+		for i, x := range values {
+			for _, y := range values[i+1:] {
+				key := pair{x.movieId, y.movieId}
+				s, ok := stats[key]
+				if !ok {
+					s = &pairStats{}
+					stats[key] = s
+				}
+				s.dot += x.value * y.value
+				s.normA += x.value * x.value
+				s.normB += y.value * y.value
+				s.overlap++
+			}
+		}
+	}
+
+	byMovie := map[int][]model.MovieNeighbor{}
+	for key, s := range stats {
+		if s.overlap < minOverlap || s.dot <= 0 {
+			continue
+		}
+		similarity := s.dot / math.Sqrt(s.normA*s.normB)
+		byMovie[key.a] = append(byMovie[key.a], neighbor(key.a, key.b, similarity))
+		byMovie[key.b] = append(byMovie[key.b], neighbor(key.b, key.a, similarity))
+	}
+
+	var neighbors []model.MovieNeighbor
+	for _, movieNeighbors := range byMovie {
+		slices.SortFunc(movieNeighbors, func(x, y model.MovieNeighbor) int {
+			if c := cmp.Compare(y.Similarity, x.Similarity); c != 0 {
+				return c
+			}
+			return cmp.Compare(x.Neighbor.MovieId, y.Neighbor.MovieId)
+		})
+		neighbors = append(neighbors, movieNeighbors[:min(len(movieNeighbors), size)]...)
+	}
+	slices.SortStableFunc(neighbors, func(x, y model.MovieNeighbor) int { return cmp.Compare(x.MovieId, y.MovieId) })
+	return neighbors
+}
+
+func neighbor(movieId, neighborId int, similarity float64) model.MovieNeighbor {
+	return model.MovieNeighbor{MovieId: movieId, Neighbor: model.MovieSummary{MovieId: neighborId}, Similarity: similarity}
+}
@@ -0,0 +1,130 @@
+package recommend
+
+import (
+	"cmp"
+	"math"
+	"rest_api/internal/api/model"
+	"slices"
+)
+
+// maxBecauseOf bounds the rated movies given as the reason of a recommendation.
+const maxBecauseOf = 3
+
+type prediction struct {
+	movie        model.MovieSummary
+	weighted     float64
+	similarities float64
+	contributors []contributor
+}
+
+type contributor struct {
+	movieId int
+	weight  float64
+}
+
+// FromRatings predicts the rating of the user for the neighbors of the movies they rated, as the mean rating of the
+// user adjusted by the similarity weighted deviations of their ratings of the similar movies. Only the movies the
+// user is predicted to like more than their average are recommended, best first, leaving out the excluded ones.
+func FromRatings(ratings map[int]int, neighbors []model.MovieNeighbor, exclude map[int]bool) []*model.Recommendation {
+	if len(ratings) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, rating := range ratings {
+		sum += float64(rating)
+	}
+	mean := sum / float64(len(ratings))
+
+	predictions := map[int]*prediction{}
+	for _, n := range neighbors {
+		rating, rated := ratings[n.MovieId]
+		if !rated || exclude[n.Neighbor.MovieId] || n.Similarity <= 0 {
+			continue
+		}
+		p, ok := predictions[n.Neighbor.MovieId]
+		if !ok {
+			p = &prediction{movie: n.Neighbor}
+			predictions[n.Neighbor.MovieId] = p
+		}
+		deviation := float64(rating) - mean
+		p.weighted += n.Similarity * deviation
+		p.similarities += n.Similarity
+		p.contributors = append(p.contributors, contributor{n.MovieId, n.Similarity * deviation})
+	}
+
+	recommendations := []*model.Recommendation{}
+	for _, p := range predictions {
+		if p.weighted <= 0 {
+			continue
+		}
+		score := min(mean+p.weighted/p.similarities, model.MaxRating)
+		recommendations = append(recommendations, &model.Recommendation{
+			MovieSummary: p.movie,
+			Score:        math.Round(score*100) / 100,
+			Source:       model.RecommendedFromRatings,
+			BecauseOf:    becauseOf(p.contributors),
+		})
+	}
+	slices.SortFunc(recommendations, compareRecommendations)
+	return recommendations
+}
+
+// FromPopularity ranks the popular movies, given most popular first, by their popularity rank and the affinity of
+// the user for their genres, leaving out the excluded ones. Without any genre affinity the popularity order is kept.
+func FromPopularity(popular []*model.Movie, genreAffinity map[int]int, genreWeight float64, exclude map[int]bool) []*model.Recommendation {
+	maxAffinity := 0
+	for _, count := range genreAffinity {
+		maxAffinity = max(maxAffinity, count)
+	}
+	if maxAffinity == 0 {
+		genreWeight = 0
+	}
+
+	recommendations := []*model.Recommendation{}
+	for rank, movie := range popular {
+		if exclude[movie.MovieId] {
+			continue
+		}
+		popularity := 1 - float64(rank)/float64(len(popular))
+		var affinity float64
+		if maxAffinity > 0 && len(movie.Genres) > 0 {
+			for _, genre := range movie.Genres {
+				affinity += float64(genreAffinity[genre.ID]) / float64(maxAffinity)
+			}
+			affinity /= float64(len(movie.Genres))
+		}
+		score := (1-genreWeight)*popularity + genreWeight*affinity
+		recommendations = append(recommendations, &model.Recommendation{
+			MovieSummary: model.MovieSummary{MovieId: movie.MovieId, MovieName: movie.MovieName, Runtime: movie.Runtime, ReleaseDate: movie.ReleaseDate},
+			Score:        math.Round(score*1000) / 1000,
+			Source:       model.RecommendedFromPopularity,
+		})
+	}
+	slices.SortStableFunc(recommendations, func(a, b *model.Recommendation) int { return cmp.Compare(b.Score, a.Score) })
+	return recommendations
+}
+
+// becauseOf returns the rated movies contributing most to the prediction.
+func becauseOf(contributors []contributor) []int {
+	slices.SortFunc(contributors, func(a, b contributor) int {
+		if c := cmp.Compare(b.weight, a.weight); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.movieId, b.movieId)
+	})
+	var movieIds []int
+	for _, c := range contributors {
+		if c.weight <= 0 || len(movieIds) == maxBecauseOf {
+			break
+		}
+		movieIds = append(movieIds, c.movieId)
+	}
+	return movieIds
+}
+
+func compareRecommendations(a, b *model.Recommendation) int {
+	if c := cmp.Compare(b.Score, a.Score); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.MovieId, b.MovieId)
+}
@@ -0,0 +1,93 @@
+package recommend
+
+import (
+	"rest_api/internal/api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ratings() []*model.Rating {
+	var all []*model.Rating
+	for username, userRatings := range map[string]map[int]int{
+		"alice": {1: 9, 2: 8, 3: 2},
+		"bob":   {1: 8, 2: 9, 3: 3, 4: 9},
+		"carol": {1: 3, 2: 2, 3: 9, 4: 2},
+		"dave":  {5: 7},
+	} {
+		for movieId, rating := range userRatings {
+			all = append(all, &model.Rating{MovieId: movieId, Username: username, Rating: rating})
+		}
+	}
+	return all
+}
+
+func neighborIds(neighbors []model.MovieNeighbor, movieId int) []int {
+	var ids []int
+	for _, n := range neighbors {
+		if n.MovieId == movieId {
+			ids = append(ids, n.Neighbor.MovieId)
+		}
+	}
+	return ids
+}
+
+func TestNeighbors(t *testing.T) {
+	neighbors := Neighbors(ratings(), 5, 2)
+	assert.Equal(t, []int{4, 2}, neighborIds(neighbors, 1))
+	assert.Equal(t, []int{4, 1}, neighborIds(neighbors, 2))
+	assert.Empty(t, neighborIds(neighbors, 3), "movie 3 is liked by other users than the other movies")
+	assert.Empty(t, neighborIds(neighbors, 5), "a single rating is not compared")
+	for _, n := range neighbors {
+		assert.Greater(t, n.Similarity, 0.0)
+		assert.LessOrEqual(t, n.Similarity, 1.0+1e-9)
+	}
+
+	assert.Equal(t, []int{4}, neighborIds(Neighbors(ratings(), 1, 2), 1))
+	assert.Empty(t, Neighbors(ratings(), 5, 4))
+}
+
+func TestFromRatings(t *testing.T) {
+	neighbors := Neighbors(ratings(), 5, 2)
+
+	recommendations := FromRatings(map[int]int{1: 10, 3: 4}, neighbors, map[int]bool{1: true, 3: true})
+	require.Len(t, recommendations, 2)
+	for _, recommendation := range recommendations {
+		assert.Equal(t, model.RecommendedFromRatings, recommendation.Source)
+		assert.Equal(t, []int{1}, recommendation.BecauseOf)
+		assert.Greater(t, recommendation.Score, 7.0)
+		assert.LessOrEqual(t, recommendation.Score, float64(model.MaxRating))
+	}
+
+	recommendations = FromRatings(map[int]int{1: 10, 3: 4}, neighbors, map[int]bool{1: true, 2: true, 3: true})
+	require.Len(t, recommendations, 1)
+	assert.Equal(t, 4, recommendations[0].MovieId)
+
+	assert.Empty(t, FromRatings(map[int]int{1: 2, 3: 8}, neighbors, nil), "disliked movies recommend nothing")
+	assert.Nil(t, FromRatings(nil, neighbors, nil))
+}
+
+func TestFromPopularity(t *testing.T) {
+	popular := []*model.Movie{
+		{MovieId: 1, Genres: []model.Genre{{ID: 28}}},
+		{MovieId: 2, Genres: []model.Genre{{ID: 35}}},
+		{MovieId: 3, Genres: []model.Genre{{ID: 35}, {ID: 18}}},
+		{MovieId: 4},
+	}
+
+	recommendations := FromPopularity(popular, nil, 0.5, map[int]bool{2: true})
+	assert.Equal(t, []int{1, 3, 4}, recommendationIds(recommendations))
+	assert.Equal(t, model.RecommendedFromPopularity, recommendations[0].Source)
+
+	recommendations = FromPopularity(popular, map[int]int{35: 4, 18: 2}, 0.5, nil)
+	assert.Equal(t, []int{2, 3, 1, 4}, recommendationIds(recommendations))
+}
+
+func recommendationIds(recommendations []*model.Recommendation) []int {
+	ids := make([]int, len(recommendations))
+	for i, recommendation := range recommendations {
+		ids[i] = recommendation.MovieId
+	}
+	return ids
+}
@@ -0,0 +1,70 @@
+// Package reqctx carries the per request values, the request id and the authenticated user, through the
+// context so that lower layers such as the audit log can record who made a change.
+package reqctx
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"strings"
+)
+
+const (
+	// ActorAnonymous is the actor of requests made without valid credentials.
+	ActorAnonymous = "anonymous"
+	// ActorSystem is the actor of changes made outside a request, by scheduled and queued jobs.
+	ActorSystem = "system"
+)
+
+const maxRequestIDLength = 64
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	userKey
+)
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the id of the request, or an empty string outside a request.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+func WithUser(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// User returns the authenticated user of the request, or nil.
+func User(ctx context.Context) *model.User {
+	user, _ := ctx.Value(userKey).(*model.User)
+	return user
+}
+
+// Actor returns the name recorded as the author of changes made with the context: the authenticated user,
+// ActorAnonymous for requests without one and ActorSystem outside a request.
+func Actor(ctx context.Context) string {
+	if user := User(ctx); user != nil {
+		return user.Username
+	}
+	if RequestID(ctx) != "" {
+		return ActorAnonymous
+	}
+	return ActorSystem
+}
+
+// ValidRequestID accepts client provided request ids of up to 64 letters, digits, dots, dashes and underscores.
+func ValidRequestID(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestId {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("._-", c)) {
+			return false
+		}
+	}
+	return true
+}
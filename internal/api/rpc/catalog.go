@@ -0,0 +1,191 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/rpc/catalogpb"
+	"rest_api/internal/api/service"
+	"rest_api/internal/api/tmdb"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// updatablePaths are the fields of a movie that Update can change.
+var updatablePaths = []string{"title", "overview", "runtime", "release_date", "genres"}
+
+// CatalogServer implements the MovieCatalog service with the services of the REST API. RefreshService may be nil,
+// in which case created movies are not queued for enrichment.
+type CatalogServer struct {
+	catalogpb.UnimplementedMovieCatalogServer
+	movieService   *service.MovieService
+	tmdbService    *tmdb.Service
+	refreshService *service.RefreshService
+	feed           *ChangeFeed
+}
+
+func NewCatalogServer(movieService *service.MovieService, tmdbService *tmdb.Service, refreshService *service.RefreshService,
+	feed *ChangeFeed) *CatalogServer {
+	return &CatalogServer{movieService: movieService, tmdbService: tmdbService, refreshService: refreshService, feed: feed}
+}
+
+func (s *CatalogServer) Get(_ context.Context, req *catalogpb.GetRequest) (*catalogpb.Movie, error) {
+	movie, err := s.movieService.Get(int(req.GetId()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(movie), nil
+}
+
+func (s *CatalogServer) List(req *catalogpb.ListRequest, stream grpc.ServerStreamingServer[catalogpb.Movie]) error {
+	filter, err := toFilter(req)
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	err = s.movieService.Stream(stream.Context(), filter, func(movie *model.Movie) error {
+		sendErr = stream.Send(toProto(movie))
+		return sendErr
+	})
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case err != nil:
+		return statusError(err)
+	}
+	return nil
+}
+
+// Create adds the movie found on TMDB for the title, like the REST API. Details and credits are fetched in the
+// background, the movie is returned without them.
+func (s *CatalogServer) Create(ctx context.Context, req *catalogpb.CreateRequest) (*catalogpb.Movie, error) {
+	title := strings.TrimSpace(req.GetTitle())
+	if title == "" {
+		return nil, status.Error(codes.InvalidArgument, "title should be present")
+	}
+	movieInfo, err := s.tmdbService.GetMovieByTitle(title)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "a movie with the provided title does not exist")
+	}
+
+	createdMovie, err := s.movieService.Create(ctx, &model.Movie{
+		MovieId:         int(req.GetId()),
+		MovieName:       title,
+		Overview:        movieInfo.Overview,
+		TmdbId:          movieInfo.ID,
+		Runtime:         int(movieInfo.Runtime),
+		TmdbVoteAverage: movieInfo.RoundedVoteAverage(),
+		ReleaseDate:     movieInfo.ReleaseDate,
+		Genres:          model.GenresOf(movieInfo.GenreIDs()),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if s.refreshService != nil {
+		if _, err = s.refreshService.EnqueueEnrichment(ctx, createdMovie.MovieId); err != nil {
+			slog.Warn("Unable to enqueue movie enrichment", "movieId", createdMovie.MovieId, "error", err)
+		}
+	}
+	return toProto(createdMovie), nil
+}
+
+func (s *CatalogServer) Update(ctx context.Context, req *catalogpb.UpdateRequest) (*catalogpb.Movie, error) {
+	update := req.GetMovie()
+	if update.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "movie.id should be present")
+	}
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = updatablePaths
+	}
+	for _, path := range paths {
+		if !slices.Contains(updatablePaths, path) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s cannot be updated", path))
+		}
+	}
+
+	current, err := s.movieService.Get(int(update.GetId()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	movie := *current
+	for _, path := range paths {
+		switch path {
+		case "title":
+			movie.MovieName = strings.TrimSpace(update.GetTitle())
+		case "overview":
+			movie.Overview = update.GetOverview()
+		case "runtime":
+			movie.Runtime = int(update.GetRuntime())
+		case "release_date":
+			movie.ReleaseDate = update.GetReleaseDate()
+		case "genres":
+			movie.Genres = make([]model.Genre, len(update.GetGenres()))
+			for i, genre := range update.GetGenres() {
+				movie.Genres[i] = model.Genre{ID: int(genre.GetId())}
+			}
+		}
+	}
+	switch {
+	case movie.MovieName == "":
+		return nil, status.Error(codes.InvalidArgument, "title should be present")
+	case movie.Runtime < 0:
+		return nil, status.Error(codes.InvalidArgument, "runtime should not be negative")
+	}
+	if _, err = time.Parse(time.DateOnly, movie.ReleaseDate); movie.ReleaseDate != "" && err != nil {
+		return nil, status.Error(codes.InvalidArgument, "release_date should be formatted as YYYY-MM-DD")
+	}
+
+	updatedMovie, err := s.movieService.Update(ctx, &movie)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(updatedMovie), nil
+}
+
+func (s *CatalogServer) Delete(ctx context.Context, req *catalogpb.DeleteRequest) (*catalogpb.DeleteResponse, error) {
+	if err := s.movieService.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, statusError(err)
+	}
+	return &catalogpb.DeleteResponse{}, nil
+}
+
+// WatchChanges streams the movie events published by all the replicas until the client cancels the call. Headers
+// are sent once the changes are watched. Clients that cannot keep up are disconnected with RESOURCE_EXHAUSTED and
+// should watch again, all of them are with UNAVAILABLE when the replica shuts down.
+func (s *CatalogServer) WatchChanges(req *catalogpb.WatchChangesRequest, stream grpc.ServerStreamingServer[catalogpb.MovieChange]) error {
+	events, unsubscribe := s.feed.subscribe()
+	defer unsubscribe()
+	// headers tell the client that the changes from now on are watched
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok && s.feed.isClosed() {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "too many pending changes, watch again")
+			}
+			if len(req.GetMovieIds()) > 0 && !slices.Contains(req.GetMovieIds(), int32(event.Movie.MovieId)) {
+				continue
+			}
+			change := &catalogpb.MovieChange{Type: changeTypes[event.Type], Movie: toProto(event.Movie), ReceivedAt: timestamppb.Now()}
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+		}
+	}
+}
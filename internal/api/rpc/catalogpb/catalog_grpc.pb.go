@@ -0,0 +1,334 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: catalog.proto
+
+package catalogpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MovieCatalog_Get_FullMethodName          = "/catalog.v1.MovieCatalog/Get"
+	MovieCatalog_List_FullMethodName         = "/catalog.v1.MovieCatalog/List"
+	MovieCatalog_Create_FullMethodName       = "/catalog.v1.MovieCatalog/Create"
+	MovieCatalog_Update_FullMethodName       = "/catalog.v1.MovieCatalog/Update"
+	MovieCatalog_Delete_FullMethodName       = "/catalog.v1.MovieCatalog/Delete"
+	MovieCatalog_WatchChanges_FullMethodName = "/catalog.v1.MovieCatalog/WatchChanges"
+)
+
+// MovieCatalogClient is the client API for MovieCatalog service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MovieCatalog gives internal services access to the movies of the catalog. Changes are made through the same
+// service as the REST API, so that they publish events and are audited.
+type MovieCatalogClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Movie, error)
+	// List streams the movies matching the filter, ordered like the REST list.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Movie], error)
+	// Create adds a movie, filled in from the TMDB search for its title. Requires credentials.
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Movie, error)
+	// Update changes the fields of the update mask, or all the fields if it is empty. Requires credentials.
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Movie, error)
+	// Delete moves a movie to the trash. Requires credentials.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// WatchChanges streams the changes of movies made from now on, by any replica.
+	WatchChanges(ctx context.Context, in *WatchChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MovieChange], error)
+}
+
+type movieCatalogClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMovieCatalogClient(cc grpc.ClientConnInterface) MovieCatalogClient {
+	return &movieCatalogClient{cc}
+}
+
+func (c *movieCatalogClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Movie, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Movie)
+	err := c.cc.Invoke(ctx, MovieCatalog_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieCatalogClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Movie], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MovieCatalog_ServiceDesc.Streams[0], MovieCatalog_List_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRequest, Movie]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MovieCatalog_ListClient = grpc.ServerStreamingClient[Movie]
+
+func (c *movieCatalogClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Movie, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Movie)
+	err := c.cc.Invoke(ctx, MovieCatalog_Create_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieCatalogClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Movie, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Movie)
+	err := c.cc.Invoke(ctx, MovieCatalog_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieCatalogClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, MovieCatalog_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieCatalogClient) WatchChanges(ctx context.Context, in *WatchChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MovieChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MovieCatalog_ServiceDesc.Streams[1], MovieCatalog_WatchChanges_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchChangesRequest, MovieChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MovieCatalog_WatchChangesClient = grpc.ServerStreamingClient[MovieChange]
+
+// MovieCatalogServer is the server API for MovieCatalog service.
+// All implementations must embed UnimplementedMovieCatalogServer
+// for forward compatibility.
+//
+// MovieCatalog gives internal services access to the movies of the catalog. Changes are made through the same
+// service as the REST API, so that they publish events and are audited.
+type MovieCatalogServer interface {
+	Get(context.Context, *GetRequest) (*Movie, error)
+	// List streams the movies matching the filter, ordered like the REST list.
+	List(*ListRequest, grpc.ServerStreamingServer[Movie]) error
+	// Create adds a movie, filled in from the TMDB search for its title. Requires credentials.
+	Create(context.Context, *CreateRequest) (*Movie, error)
+	// Update changes the fields of the update mask, or all the fields if it is empty. Requires credentials.
+	Update(context.Context, *UpdateRequest) (*Movie, error)
+	// Delete moves a movie to the trash. Requires credentials.
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// WatchChanges streams the changes of movies made from now on, by any replica.
+	WatchChanges(*WatchChangesRequest, grpc.ServerStreamingServer[MovieChange]) error
+	mustEmbedUnimplementedMovieCatalogServer()
+}
+
+// UnimplementedMovieCatalogServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMovieCatalogServer struct{}
+
+func (UnimplementedMovieCatalogServer) Get(context.Context, *GetRequest) (*Movie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedMovieCatalogServer) List(*ListRequest, grpc.ServerStreamingServer[Movie]) error {
+	return status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedMovieCatalogServer) Create(context.Context, *CreateRequest) (*Movie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedMovieCatalogServer) Update(context.Context, *UpdateRequest) (*Movie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedMovieCatalogServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedMovieCatalogServer) WatchChanges(*WatchChangesRequest, grpc.ServerStreamingServer[MovieChange]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchChanges not implemented")
+}
+func (UnimplementedMovieCatalogServer) mustEmbedUnimplementedMovieCatalogServer() {}
+func (UnimplementedMovieCatalogServer) testEmbeddedByValue()                      {}
+
+// UnsafeMovieCatalogServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MovieCatalogServer will
+// result in compilation errors.
+type UnsafeMovieCatalogServer interface {
+	mustEmbedUnimplementedMovieCatalogServer()
+}
+
+func RegisterMovieCatalogServer(s grpc.ServiceRegistrar, srv MovieCatalogServer) {
+	// If the following call pancis, it indicates UnimplementedMovieCatalogServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MovieCatalog_ServiceDesc, srv)
+}
+
+func _MovieCatalog_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieCatalogServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieCatalog_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieCatalogServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieCatalog_List_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MovieCatalogServer).List(m, &grpc.GenericServerStream[ListRequest, Movie]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MovieCatalog_ListServer = grpc.ServerStreamingServer[Movie]
+
+func _MovieCatalog_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieCatalogServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieCatalog_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieCatalogServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieCatalog_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieCatalogServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieCatalog_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieCatalogServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieCatalog_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieCatalogServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieCatalog_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieCatalogServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieCatalog_WatchChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MovieCatalogServer).WatchChanges(m, &grpc.GenericServerStream[WatchChangesRequest, MovieChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MovieCatalog_WatchChangesServer = grpc.ServerStreamingServer[MovieChange]
+
+// MovieCatalog_ServiceDesc is the grpc.ServiceDesc for MovieCatalog service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MovieCatalog_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "catalog.v1.MovieCatalog",
+	HandlerType: (*MovieCatalogServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _MovieCatalog_Get_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _MovieCatalog_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _MovieCatalog_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _MovieCatalog_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			Handler:       _MovieCatalog_List_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchChanges",
+			Handler:       _MovieCatalog_WatchChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "catalog.proto",
+}
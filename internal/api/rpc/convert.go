@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"errors"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/rpc/catalogpb"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var changeTypes = map[string]catalogpb.MovieChange_Type{
+	model.MovieCreated:  catalogpb.MovieChange_CREATED,
+	model.MovieUpdated:  catalogpb.MovieChange_UPDATED,
+	model.MovieDeleted:  catalogpb.MovieChange_DELETED,
+	model.MovieRestored: catalogpb.MovieChange_RESTORED,
+}
+
+func toProto(movie *model.Movie) *catalogpb.Movie {
+	pb := &catalogpb.Movie{
+		Id:              int32(movie.MovieId),
+		Title:           movie.MovieName,
+		Overview:        movie.Overview,
+		TmdbId:          int32(movie.TmdbId),
+		Runtime:         int32(movie.Runtime),
+		ReleaseDate:     movie.ReleaseDate,
+		TmdbVoteAverage: movie.TmdbVoteAverage,
+		RatingAverage:   movie.RatingAverage,
+		RatingCount:     int32(movie.RatingCount),
+	}
+	for _, genre := range movie.Genres {
+		pb.Genres = append(pb.Genres, &catalogpb.Genre{Id: int32(genre.ID), Name: genre.Name})
+	}
+	for _, tag := range movie.Tags {
+		pb.Tags = append(pb.Tags, &catalogpb.Tag{Id: int32(tag.ID), Name: tag.Name})
+	}
+	return pb
+}
+
+// toFilter converts the request to the filter of the REST list, with the same validation.
+func toFilter(req *catalogpb.ListRequest) (model.MovieFilter, error) {
+	if req.GetMinRuntime() < 0 || req.GetMaxRuntime() < 0 || req.GetPersonId() < 0 {
+		return model.MovieFilter{}, status.Error(codes.InvalidArgument, "runtimes and person id should not be negative")
+	}
+	return model.MovieFilter{
+		Title:          strings.TrimSpace(req.GetTitle()),
+		MinRuntime:     int(req.GetMinRuntime()),
+		MaxRuntime:     int(req.GetMaxRuntime()),
+		Genres:         labels(req.GetGenres()),
+		MatchAllGenres: req.GetMatchAllGenres(),
+		Tags:           labels(req.GetTags()),
+		MatchAllTags:   req.GetMatchAllTags(),
+		PersonId:       int(req.GetPersonId()),
+	}, nil
+}
+
+// labels returns the distinct lower-cased names.
+func labels(names []string) []string {
+	var labels []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(labels, name) {
+			labels = append(labels, name)
+		}
+	}
+	return labels
+}
+
+// statusError maps the errors of the services to gRPC statuses. Unexpected errors are already logged by the
+// services and reported without details.
+func statusError(err error) error {
+	var nfErr model.NotFoundError
+	var cErr model.ConflictError
+	var vErr model.ValidationError
+	switch {
+	case errors.As(err, &nfErr):
+		return status.Error(codes.NotFound, "movie not found")
+	case errors.As(err, &cErr):
+		return status.Error(codes.AlreadyExists, "a movie with the provided id already exists")
+	case errors.As(err, &vErr):
+		return status.Error(codes.InvalidArgument, vErr.Message)
+	}
+	return status.Error(codes.Internal, "unexpected error")
+}
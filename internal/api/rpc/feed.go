@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"encoding/json"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"sync"
+)
+
+// ChangeFeed fans the movie events consumed from Kafka out to the WatchChanges streams. Streams that fall behind
+// by more than the buffer size are dropped rather than slowing down the others.
+type ChangeFeed struct {
+	buffer int
+
+	mu          sync.Mutex
+	subscribers map[chan *model.MovieEvent]struct{}
+	closed      bool
+}
+
+func NewChangeFeed(buffer int) *ChangeFeed {
+	return &ChangeFeed{buffer: buffer, subscribers: map[chan *model.MovieEvent]struct{}{}}
+}
+
+// HandleMessage passes a message of the movie-events topic to the subscribers. Invalidation events are skipped, as
+// they are not changes of the movies.
+func (f *ChangeFeed) HandleMessage(msg []byte) {
+	var event model.MovieEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		slog.Error("Error when unmarshalling movie event", "error", err)
+		return
+	}
+	if _, ok := changeTypes[event.Type]; !ok {
+		return
+	}
+	if event.Movie == nil {
+		slog.Error("Received movie event without a movie", "type", event.Type)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- &event:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends the streams of the subscribers, so that the server can stop gracefully.
+func (f *ChangeFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+// subscribe returns the channel of the events from now on, closed if the subscriber falls behind or the feed is
+// closed, and the function to unsubscribe.
+func (f *ChangeFeed) subscribe() (<-chan *model.MovieEvent, func()) {
+	ch := make(chan *model.MovieEvent, f.buffer)
+	f.mu.Lock()
+	if f.closed {
+		close(ch)
+	} else {
+		f.subscribers[ch] = struct{}{}
+	}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subscribers[ch]; ok {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (f *ChangeFeed) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
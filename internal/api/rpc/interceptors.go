@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"rest_api/internal/api/rpc/catalogpb"
+	"rest_api/internal/api/utils"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const requestIDKey = "x-request-id"
+
+// writeMethods require credentials, like the REST endpoints behind BasicAuth.
+var writeMethods = map[string]bool{
+	catalogpb.MovieCatalog_Create_FullMethodName: true,
+	catalogpb.MovieCatalog_Update_FullMethodName: true,
+	catalogpb.MovieCatalog_Delete_FullMethodName: true,
+}
+
+type userGetter interface {
+	GetUser(username string) (*model.User, error)
+}
+
+// interceptors do for gRPC calls what the HTTP middleware does for requests: every call gets a request id, echoed
+// in the x-request-id header, and the user of its basic auth credentials, and is logged and counted. Write methods
+// are rejected without valid credentials.
+type interceptors struct {
+	users   userGetter
+	metrics *Metrics
+}
+
+func (i *interceptors) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, err := i.callContext(ctx, info.FullMethod)
+	if err == nil {
+		var res any
+		res, err = handler(ctx, req)
+		i.done(ctx, info.FullMethod, start, err)
+		return res, err
+	}
+	i.done(ctx, info.FullMethod, start, err)
+	return nil, err
+}
+
+func (i *interceptors) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, err := i.callContext(ss.Context(), info.FullMethod)
+	if err == nil {
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+	i.done(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// callContext returns the context of the call with its request id and user.
+func (i *interceptors) callContext(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestId := firstValue(md, requestIDKey)
+	if !reqctx.ValidRequestID(requestId) {
+		requestId = uuid.NewString()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestId)); err != nil {
+		slog.Warn("Unable to set gRPC request id header", "error", err)
+	}
+	ctx = reqctx.WithRequestID(ctx, requestId)
+	slog.Info("Received gRPC request", "method", method, "requestId", requestId)
+
+	user, err := i.verifyCredentials(firstValue(md, "authorization"))
+	if err != nil {
+		slog.Error("Error when getting user from db", "error", err)
+		if writeMethods[method] {
+			return ctx, status.Error(codes.Internal, "error when accessing user list")
+		}
+	}
+	if user != nil {
+		ctx = reqctx.WithUser(ctx, user)
+	} else if writeMethods[method] {
+		return ctx, status.Error(codes.Unauthenticated, "valid basic auth credentials are required")
+	}
+	return ctx, nil
+}
+
+// verifyCredentials returns the user of basic auth credentials, or nil if they are missing or invalid.
+func (i *interceptors) verifyCredentials(authorization string) (*model.User, error) {
+	encoded, ok := strings.CutPrefix(authorization, "Basic ")
+	if !ok {
+		return nil, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, nil
+	}
+	user, err := i.users.GetUser(username)
+	if err != nil {
+		var nfErr *model.NotFoundError
+		if errors.As(err, &nfErr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !utils.PasswordMatches(password, user.Password) {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// done logs failed calls and records the call in the metrics.
+func (i *interceptors) done(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	if code != codes.OK {
+		slog.Info("gRPC request failed", "method", method, "requestId", reqctx.RequestID(ctx), "code", code.String(),
+			"error", err)
+	}
+	i.metrics.record(method, code, time.Since(start))
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// serverStream is a stream with the context of the call.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// MethodStats are the calls of a gRPC method since the replica started.
+type MethodStats struct {
+	Method string `json:"method"`
+	Calls  int64  `json:"calls"`
+	// Codes counts the calls by status code.
+	Codes         map[string]int64 `json:"codes"`
+	AverageMillis float64          `json:"averageMillis"`
+}
+
+// Metrics counts the gRPC calls of the replica, by method and status code. The duration of streaming calls is
+// the one of the whole stream.
+type Metrics struct {
+	mu      sync.Mutex
+	methods map[string]*methodMetrics
+}
+
+type methodMetrics struct {
+	calls    int64
+	codes    map[codes.Code]int64
+	duration time.Duration
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{methods: map[string]*methodMetrics{}}
+}
+
+func (m *Metrics) record(method string, code codes.Code, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metrics, ok := m.methods[method]
+	if !ok {
+		metrics = &methodMetrics{codes: map[codes.Code]int64{}}
+		m.methods[method] = metrics
+	}
+	metrics.calls++
+	metrics.codes[code]++
+	metrics.duration += duration
+}
+
+// Snapshot returns the stats of the methods called so far, ordered by method.
+func (m *Metrics) Snapshot() []*MethodStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]*MethodStats, 0, len(m.methods))
+	for method, metrics := range m.methods {
+		methodStats := &MethodStats{
+			Method:        method,
+			Calls:         metrics.calls,
+			Codes:         make(map[string]int64, len(metrics.codes)),
+			AverageMillis: float64(metrics.duration.Microseconds()) / 1000 / float64(metrics.calls),
+		}
+		for code, count := range metrics.codes {
+			methodStats.Codes[code.String()] = count
+		}
+		stats = append(stats, methodStats)
+	}
+	slices.SortFunc(stats, func(a, b *MethodStats) int { return strings.Compare(a.Method, b.Method) })
+	return stats
+}
@@ -0,0 +1,24 @@
+package rpc
+
+import (
+	"rest_api/internal/api/rpc/catalogpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer returns the gRPC server of the catalog, with health checking and reflection. The health server is
+// returned so that the replica can report itself as not serving while shutting down.
+func NewServer(catalog *CatalogServer, users userGetter, metrics *Metrics) (*grpc.Server, *health.Server) {
+	i := &interceptors{users: users, metrics: metrics}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(i.unary), grpc.ChainStreamInterceptor(i.stream))
+	catalogpb.RegisterMovieCatalogServer(server, catalog)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(catalogpb.MovieCatalog_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	return server, healthServer
+}
@@ -0,0 +1,237 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/rpc/catalogpb"
+	"rest_api/internal/api/service"
+	"rest_api/internal/data/datatest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+type mockUserGetter struct {
+	mock.Mock
+}
+
+func (g *mockUserGetter) GetUser(username string) (*model.User, error) {
+	args := g.Called(username)
+	arg0 := args.Get(0)
+	if arg0 == nil {
+		return nil, args.Error(1)
+	}
+	return arg0.(*model.User), args.Error(1)
+}
+
+// newTestClient serves the catalog over an in-memory connection.
+func newTestClient(t *testing.T, repository *datatest.MovieStore, feed *ChangeFeed) (*grpc.ClientConn, *Metrics) {
+	users := new(mockUserGetter)
+	users.On("GetUser", "alice").Return(&model.User{Username: "alice", Password: "secret"}, nil)
+	users.On("GetUser", mock.Anything).Return(nil, &model.NotFoundError{})
+	metrics := NewMetrics()
+	server, _ := NewServer(NewCatalogServer(service.NewMovieService(repository, nil), nil, nil, feed), users, metrics)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, metrics
+}
+
+func withCredentials(ctx context.Context, username, password string) context.Context {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+credentials)
+}
+
+func TestCatalogServer_Get(t *testing.T) {
+	repository := new(datatest.MovieStore)
+	repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "Alien", Runtime: 117, Genres: []model.Genre{{ID: 27, Name: "Horror"}}}, nil)
+	repository.On("Get", 2).Return(nil, model.NotFoundError{})
+	conn, metrics := newTestClient(t, repository, NewChangeFeed(1))
+	client := catalogpb.NewMovieCatalogClient(conn)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDKey, "req-1")
+	movie, err := client.Get(ctx, &catalogpb.GetRequest{Id: 1}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "Alien", movie.GetTitle())
+	assert.Equal(t, int32(117), movie.GetRuntime())
+	assert.Equal(t, "Horror", movie.GetGenres()[0].GetName())
+	assert.Equal(t, []string{"req-1"}, header.Get(requestIDKey))
+
+	_, err = client.Get(context.Background(), &catalogpb.GetRequest{Id: 2})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	stats := metrics.Snapshot()
+	require.Len(t, stats, 1)
+	assert.Equal(t, catalogpb.MovieCatalog_Get_FullMethodName, stats[0].Method)
+	assert.Equal(t, map[string]int64{"OK": 1, "NotFound": 1}, stats[0].Codes)
+}
+
+func TestCatalogServer_List(t *testing.T) {
+	repository := new(datatest.MovieStore)
+	repository.On("Stream", model.MovieFilter{Title: "alien", Genres: []string{"horror"}}).
+		Return([]*model.Movie{{MovieId: 1, MovieName: "Alien"}, {MovieId: 2, MovieName: "Aliens"}}, nil)
+	conn, _ := newTestClient(t, repository, NewChangeFeed(1))
+
+	stream, err := catalogpb.NewMovieCatalogClient(conn).List(context.Background(),
+		&catalogpb.ListRequest{Title: " alien ", Genres: []string{"Horror", "horror"}})
+	require.NoError(t, err)
+	var titles []string
+	for {
+		movie, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		titles = append(titles, movie.GetTitle())
+	}
+	assert.Equal(t, []string{"Alien", "Aliens"}, titles)
+}
+
+func TestCatalogServer_Update(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		req      *catalogpb.UpdateRequest
+		setup    func(repository *datatest.MovieStore)
+		wantCode codes.Code
+	}{
+		{
+			name:     "without credentials",
+			ctx:      context.Background(),
+			req:      &catalogpb.UpdateRequest{Movie: &catalogpb.Movie{Id: 1, Runtime: 117}},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "with a wrong password",
+			ctx:      withCredentials(context.Background(), "alice", "guess"),
+			req:      &catalogpb.UpdateRequest{Movie: &catalogpb.Movie{Id: 1, Runtime: 117}},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "masked fields",
+			ctx:  withCredentials(context.Background(), "alice", "secret"),
+			req: &catalogpb.UpdateRequest{
+				Movie:      &catalogpb.Movie{Id: 1, Title: "ignored", Runtime: 117},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"runtime"}},
+			},
+			setup: func(repository *datatest.MovieStore) {
+				repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "Alien", Overview: "In space"}, nil)
+				repository.On("Update", &model.Movie{MovieId: 1, MovieName: "Alien", Overview: "In space", Runtime: 117}).
+					Return(&model.Movie{MovieId: 1, MovieName: "Alien", Overview: "In space", Runtime: 117}, nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "unknown path",
+			ctx:  withCredentials(context.Background(), "alice", "secret"),
+			req: &catalogpb.UpdateRequest{
+				Movie:      &catalogpb.Movie{Id: 1},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"tmdb_id"}},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid release date",
+			ctx:  withCredentials(context.Background(), "alice", "secret"),
+			req: &catalogpb.UpdateRequest{
+				Movie:      &catalogpb.Movie{Id: 1, ReleaseDate: "1979"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"release_date"}},
+			},
+			setup: func(repository *datatest.MovieStore) {
+				repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "Alien"}, nil)
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := new(datatest.MovieStore)
+			if tt.setup != nil {
+				tt.setup(repository)
+			}
+			conn, _ := newTestClient(t, repository, NewChangeFeed(1))
+
+			_, err := catalogpb.NewMovieCatalogClient(conn).Update(tt.ctx, tt.req)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			repository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCatalogServer_WatchChanges(t *testing.T) {
+	feed := NewChangeFeed(4)
+	conn, _ := newTestClient(t, new(datatest.MovieStore), feed)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := catalogpb.NewMovieCatalogClient(conn).WatchChanges(ctx, &catalogpb.WatchChangesRequest{MovieIds: []int32{2}})
+	require.NoError(t, err)
+	// the stream is subscribed once its headers are received
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	feed.HandleMessage([]byte(`{"type":"movie.updated","movie":{"id":1,"title":"Alien"}}`))
+	// invalidations are not changes of the movie
+	feed.HandleMessage([]byte(`{"type":"movie.invalidated","movie":{"id":2}}`))
+	feed.HandleMessage([]byte(`{"type":"movie.invalidated","movie":null}`))
+	feed.HandleMessage([]byte(`{"type":"movie.deleted","movie":{"id":2}}`))
+
+	change, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, catalogpb.MovieChange_DELETED, change.GetType())
+	assert.Equal(t, int32(2), change.GetMovie().GetId())
+
+	feed.Close()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestChangeFeed_DropsSlowSubscribers(t *testing.T) {
+	feed := NewChangeFeed(1)
+	slow, _ := feed.subscribe()
+	fast, unsubscribe := feed.subscribe()
+	defer unsubscribe()
+
+	feed.HandleMessage([]byte(`{"type":"movie.created","movie":{"id":1}}`))
+	<-fast
+	feed.HandleMessage([]byte(`{"type":"movie.created","movie":{"id":2}}`))
+
+	event := <-slow
+	assert.Equal(t, 1, event.Movie.MovieId)
+	_, ok := <-slow
+	assert.False(t, ok)
+	event = <-fast
+	assert.Equal(t, 2, event.Movie.MovieId)
+}
+
+func TestServer_Health(t *testing.T) {
+	conn, _ := newTestClient(t, new(datatest.MovieStore), NewChangeFeed(1))
+
+	res, err := healthpb.NewHealthClient(conn).Check(context.Background(),
+		&healthpb.HealthCheckRequest{Service: catalogpb.MovieCatalog_ServiceDesc.ServiceName})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"rest_api/internal/api/minio"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	miniogo "github.com/minio/minio-go/v7"
+)
+
+type assetRepository interface {
+	GetByMovie(movieId int) ([]*model.MovieAsset, error)
+	Get(movieId, assetId int) (*model.MovieAsset, error)
+	Create(asset *model.MovieAsset) (*model.MovieAsset, error)
+}
+
+type objectStorage interface {
+	StatObject(ctx context.Context, id string) (miniogo.ObjectInfo, error)
+	PresignedPutURL(ctx context.Context, id string, expiry time.Duration) (*url.URL, error)
+	PresignedGetURL(ctx context.Context, id string, expiry time.Duration) (*url.URL, error)
+	ListObjects(ctx context.Context, prefix string) ([]miniogo.ObjectInfo, error)
+	RemoveObject(ctx context.Context, id string) error
+}
+
+type AssetService struct {
+	assetRepository assetRepository
+	movieRepository data.Repository[*model.Movie]
+	storage         objectStorage
+	urlExpiry       time.Duration
+}
+
+func NewAssetService(assetRepository assetRepository, movieRepository data.Repository[*model.Movie], storage objectStorage, urlExpiry time.Duration) *AssetService {
+	return &AssetService{
+		assetRepository: assetRepository,
+		movieRepository: movieRepository,
+		storage:         storage,
+		urlExpiry:       urlExpiry,
+	}
+}
+
+// UploadURL reserves a new object key for the movie and asset type and returns a presigned PUT URL for it.
+func (s *AssetService) UploadURL(ctx context.Context, movieId int, assetType string) (*model.PresignedURL, error) {
+	if !slices.Contains(model.AssetTypes, assetType) {
+		return nil, model.ValidationError{Message: fmt.Sprintf("asset type should be one of %s", strings.Join(model.AssetTypes, ", "))}
+	}
+	if err := s.checkMovieExists(movieId); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s%s", assetKeyPrefix(movieId, assetType), uuid.NewString())
+	u, err := s.storage.PresignedPutURL(ctx, key, s.urlExpiry)
+	if err != nil {
+		slog.Error("Error when presigning upload url", "key", key, "error", err)
+		return nil, err
+	}
+	return &model.PresignedURL{
+		URL:       u.String(),
+		Method:    http.MethodPut,
+		Key:       key,
+		ExpiresAt: time.Now().Add(s.urlExpiry).UTC(),
+	}, nil
+}
+
+// Confirm registers an uploaded object as an asset of the movie, once it has been verified against the storage.
+func (s *AssetService) Confirm(ctx context.Context, asset *model.MovieAsset) (*model.MovieAsset, error) {
+	if !slices.Contains(model.AssetTypes, asset.AssetType) {
+		return nil, model.ValidationError{Message: fmt.Sprintf("asset type should be one of %s", strings.Join(model.AssetTypes, ", "))}
+	}
+	if !strings.HasPrefix(asset.ObjectKey, assetKeyPrefix(asset.MovieId, asset.AssetType)) {
+		return nil, model.ValidationError{Message: "key does not belong to the provided movie and asset type"}
+	}
+	if err := s.checkMovieExists(asset.MovieId); err != nil {
+		return nil, err
+	}
+
+	info, err := s.storage.StatObject(ctx, asset.ObjectKey)
+	if err != nil {
+		if errors.Is(err, minio.ErrObjectNotFound) {
+			return nil, model.ValidationError{Message: "no uploaded object exists for the provided key"}
+		}
+		slog.Error("Error when getting uploaded object info", "key", asset.ObjectKey, "error", err)
+		return nil, err
+	}
+	if asset.Size != 0 && asset.Size != info.Size {
+		return nil, model.ValidationError{Message: fmt.Sprintf("size mismatch: uploaded object has %d bytes", info.Size)}
+	}
+	if asset.Checksum != "" && !matchesChecksum(info, asset.Checksum) {
+		return nil, model.ValidationError{Message: fmt.Sprintf("checksum mismatch: uploaded object has ETag %s", info.ETag)}
+	}
+	asset.Size = info.Size
+	if asset.ContentType == "" {
+		asset.ContentType = info.ContentType
+	}
+	if asset.Checksum == "" {
+		asset.Checksum = info.ETag
+	}
+
+	createdAsset, err := s.assetRepository.Create(asset)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordExists) {
+			return nil, model.ConflictError{}
+		}
+		slog.Error("Unable to create movie asset in the database", "error", err)
+		return nil, err
+	}
+	return createdAsset, nil
+}
+
+func (s *AssetService) GetAll(movieId int) ([]*model.MovieAsset, error) {
+	if err := s.checkMovieExists(movieId); err != nil {
+		return nil, err
+	}
+	assets, err := s.assetRepository.GetByMovie(movieId)
+	if err != nil {
+		slog.Error("Error when getting movie assets from db", "movieId", movieId, "error", err)
+		return nil, err
+	}
+	return assets, nil
+}
+
+// DownloadURL returns a presigned GET URL for an asset of the movie.
+func (s *AssetService) DownloadURL(ctx context.Context, movieId, assetId int) (*model.PresignedURL, error) {
+	asset, err := s.assetRepository.Get(movieId, assetId)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when getting movie asset from db", "assetId", assetId, "error", err)
+		return nil, err
+	}
+
+	u, err := s.storage.PresignedGetURL(ctx, asset.ObjectKey, s.urlExpiry)
+	if err != nil {
+		slog.Error("Error when presigning download url", "key", asset.ObjectKey, "error", err)
+		return nil, err
+	}
+	return &model.PresignedURL{
+		URL:       u.String(),
+		Method:    http.MethodGet,
+		Key:       asset.ObjectKey,
+		ExpiresAt: time.Now().Add(s.urlExpiry).UTC(),
+	}, nil
+}
+
+// RemoveObjects removes the stored objects of the movies, confirmed as assets or not, once the movies have been
+// purged. A failure is logged and the removal goes on with the other objects; the first error is returned.
+func (s *AssetService) RemoveObjects(ctx context.Context, movieIds []int) error {
+	var firstErr error
+	for _, movieId := range movieIds {
+		prefix := movieKeyPrefix(movieId)
+		objects, err := s.storage.ListObjects(ctx, prefix)
+		if err != nil {
+			slog.Error("Error when listing movie objects", "prefix", prefix, "error", err)
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		for _, object := range objects {
+			if err = s.storage.RemoveObject(ctx, object.Key); err != nil {
+				slog.Error("Error when removing movie object", "key", object.Key, "error", err)
+				firstErr = cmp.Or(firstErr, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+func (s *AssetService) checkMovieExists(movieId int) error {
+	_, err := s.movieRepository.Get(movieId)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return model.NotFoundError{}
+		}
+		slog.Error("Error when getting movie from db", "movieId", movieId, "error", err)
+		return err
+	}
+	return nil
+}
+
+// matchesChecksum reports whether the checksum is the ETag of the object, the hex MD5 of single part uploads, or
+// one of the base64 checksums sent with the upload.
+func matchesChecksum(info miniogo.ObjectInfo, checksum string) bool {
+	if strings.EqualFold(strings.Trim(info.ETag, `"`), strings.Trim(checksum, `"`)) {
+		return true
+	}
+	for _, stored := range []string{info.ChecksumSHA256, info.ChecksumSHA1, info.ChecksumCRC32C, info.ChecksumCRC32} {
+		if stored != "" && stored == checksum {
+			return true
+		}
+	}
+	return false
+}
+
+func assetKeyPrefix(movieId int, assetType string) string {
+	return fmt.Sprintf("%s%s/", movieKeyPrefix(movieId), assetType)
+}
+
+func movieKeyPrefix(movieId int) string {
+	return fmt.Sprintf("movies/%d/", movieId)
+}
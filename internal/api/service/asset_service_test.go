@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"rest_api/internal/api/minio"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"strings"
+	"testing"
+	"time"
+
+	miniogo "github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAssetRepository struct {
+	mock.Mock
+}
+
+func (r *MockAssetRepository) GetByMovie(movieId int) ([]*model.MovieAsset, error) {
+	args := r.Called(movieId)
+	return args.Get(0).([]*model.MovieAsset), args.Error(1)
+}
+
+func (r *MockAssetRepository) Get(movieId, assetId int) (*model.MovieAsset, error) {
+	args := r.Called(movieId, assetId)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*model.MovieAsset), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (r *MockAssetRepository) Create(asset *model.MovieAsset) (*model.MovieAsset, error) {
+	args := r.Called(asset)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*model.MovieAsset), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockStorage struct {
+	mock.Mock
+}
+
+func (m *MockStorage) StatObject(_ context.Context, id string) (miniogo.ObjectInfo, error) {
+	args := m.Called(id)
+	return args.Get(0).(miniogo.ObjectInfo), args.Error(1)
+}
+
+func (m *MockStorage) PresignedPutURL(_ context.Context, id string, _ time.Duration) (*url.URL, error) {
+	args := m.Called(id)
+	return &url.URL{Scheme: "http", Host: "minio:9000", Path: "/default/" + id}, args.Error(0)
+}
+
+func (m *MockStorage) PresignedGetURL(_ context.Context, id string, _ time.Duration) (*url.URL, error) {
+	args := m.Called(id)
+	return &url.URL{Scheme: "http", Host: "minio:9000", Path: "/default/" + id}, args.Error(0)
+}
+
+func (m *MockStorage) ListObjects(_ context.Context, prefix string) ([]miniogo.ObjectInfo, error) {
+	args := m.Called(prefix)
+	return args.Get(0).([]miniogo.ObjectInfo), args.Error(1)
+}
+
+func (m *MockStorage) RemoveObject(_ context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestAssetService_UploadURL(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 1).Return(&model.Movie{MovieId: 1}, nil)
+	movieRepository.On("Get", 2).Return(nil, data.ErrRecordNotFound)
+	storage := &MockStorage{}
+	storage.On("PresignedPutURL", mock.Anything).Return(nil)
+	s := NewAssetService(&MockAssetRepository{}, movieRepository, storage, time.Minute)
+
+	presigned, err := s.UploadURL(context.Background(), 1, model.AssetTypePoster)
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT", presigned.Method)
+	assert.True(t, strings.HasPrefix(presigned.Key, "movies/1/poster/"))
+	assert.Contains(t, presigned.URL, presigned.Key)
+
+	_, err = s.UploadURL(context.Background(), 1, "soundtrack")
+	assert.ErrorAs(t, err, &model.ValidationError{})
+
+	_, err = s.UploadURL(context.Background(), 2, model.AssetTypePoster)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+}
+
+func TestAssetService_Confirm(t *testing.T) {
+	randErr := errors.New("random")
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", mock.Anything).Return(&model.Movie{MovieId: 1}, nil)
+	key := "movies/1/trailer/abc"
+
+	tests := []struct {
+		name     string
+		input    *model.MovieAsset
+		want     *model.MovieAsset
+		wantErr  error
+		mockFunc func(r *MockAssetRepository, m *MockStorage)
+	}{
+		{
+			"success",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 10},
+			&model.MovieAsset{ID: 3, MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 10, Checksum: "etag", ContentType: "video/mp4"},
+			nil,
+			func(r *MockAssetRepository, m *MockStorage) {
+				m.On("StatObject", key).Return(miniogo.ObjectInfo{Size: 10, ETag: "etag", ContentType: "video/mp4"}, nil)
+				r.On("Create", &model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 10, Checksum: "etag", ContentType: "video/mp4"}).
+					Return(&model.MovieAsset{ID: 3, MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 10, Checksum: "etag", ContentType: "video/mp4"}, nil)
+			},
+		},
+		{
+			"key of other movie",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: "movies/2/trailer/abc"},
+			nil,
+			model.ValidationError{Message: "key does not belong to the provided movie and asset type"},
+			func(r *MockAssetRepository, m *MockStorage) {},
+		},
+		{
+			"not uploaded",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key},
+			nil,
+			model.ValidationError{Message: "no uploaded object exists for the provided key"},
+			func(r *MockAssetRepository, m *MockStorage) {
+				m.On("StatObject", key).Return(miniogo.ObjectInfo{}, minio.ErrObjectNotFound)
+			},
+		},
+		{
+			"size mismatch",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 11},
+			nil,
+			model.ValidationError{Message: "size mismatch: uploaded object has 10 bytes"},
+			func(r *MockAssetRepository, m *MockStorage) {
+				m.On("StatObject", key).Return(miniogo.ObjectInfo{Size: 10}, nil)
+			},
+		},
+		{
+			"matching checksum",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Checksum: "c2hhMjU2"},
+			&model.MovieAsset{ID: 3, MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 10, Checksum: "c2hhMjU2"},
+			nil,
+			func(r *MockAssetRepository, m *MockStorage) {
+				m.On("StatObject", key).Return(miniogo.ObjectInfo{Size: 10, ETag: "etag", ChecksumSHA256: "c2hhMjU2"}, nil)
+				r.On("Create", &model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 10, Checksum: "c2hhMjU2"}).
+					Return(&model.MovieAsset{ID: 3, MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Size: 10, Checksum: "c2hhMjU2"}, nil)
+			},
+		},
+		{
+			"checksum mismatch",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key, Checksum: "other"},
+			nil,
+			model.ValidationError{Message: "checksum mismatch: uploaded object has ETag etag"},
+			func(r *MockAssetRepository, m *MockStorage) {
+				m.On("StatObject", key).Return(miniogo.ObjectInfo{Size: 10, ETag: "etag"}, nil)
+			},
+		},
+		{
+			"conflict",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key},
+			nil,
+			model.ConflictError{},
+			func(r *MockAssetRepository, m *MockStorage) {
+				m.On("StatObject", key).Return(miniogo.ObjectInfo{Size: 10}, nil)
+				r.On("Create", mock.Anything).Return(nil, data.ErrRecordExists)
+			},
+		},
+		{
+			"other error",
+			&model.MovieAsset{MovieId: 1, AssetType: model.AssetTypeTrailer, ObjectKey: key},
+			nil,
+			randErr,
+			func(r *MockAssetRepository, m *MockStorage) {
+				m.On("StatObject", key).Return(miniogo.ObjectInfo{}, randErr)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetRepository := &MockAssetRepository{}
+			storage := &MockStorage{}
+			tt.mockFunc(assetRepository, storage)
+			s := NewAssetService(assetRepository, movieRepository, storage, time.Minute)
+
+			got, err := s.Confirm(context.Background(), tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAssetService_RemoveObjects(t *testing.T) {
+	randErr := errors.New("random")
+	storage := &MockStorage{}
+	storage.On("ListObjects", "movies/1/").Return([]miniogo.ObjectInfo{
+		{Key: "movies/1/poster/a"}, {Key: "movies/1/trailer/b"},
+	}, nil)
+	storage.On("ListObjects", "movies/2/").Return([]miniogo.ObjectInfo(nil), randErr)
+	storage.On("ListObjects", "movies/3/").Return([]miniogo.ObjectInfo{{Key: "movies/3/poster/c"}}, nil)
+	storage.On("RemoveObject", "movies/1/poster/a").Return(randErr)
+	storage.On("RemoveObject", "movies/1/trailer/b").Return(nil)
+	storage.On("RemoveObject", "movies/3/poster/c").Return(nil)
+	s := NewAssetService(&MockAssetRepository{}, &datatest.MovieStore{}, storage, time.Minute)
+
+	err := s.RemoveObjects(context.Background(), []int{1, 2, 3})
+	assert.ErrorIs(t, err, randErr)
+	storage.AssertExpectations(t)
+}
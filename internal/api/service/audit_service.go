@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"rest_api/internal/api/model"
+)
+
+type auditLog interface {
+	List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error)
+}
+
+// AuditService queries the audit log of the changes made to movies and users.
+type AuditService struct {
+	auditRepository auditLog
+}
+
+func NewAuditService(auditRepository auditLog) *AuditService {
+	return &AuditService{auditRepository: auditRepository}
+}
+
+func (s *AuditService) List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error) {
+	entries, err := s.auditRepository.List(ctx, filter)
+	if err != nil {
+		slog.Error("Error when getting audit entries from db", "filter", filter, "error", err)
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data"
+	"strings"
+)
+
+const (
+	maxCollectionNameLength        = 200
+	maxCollectionDescriptionLength = 2000
+	maxCollectionMovies            = 500
+)
+
+type collectionRepository interface {
+	List(ctx context.Context) ([]*model.Collection, error)
+	Get(ctx context.Context, collectionId int) (*model.Collection, error)
+	Create(ctx context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error)
+	Update(ctx context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error)
+	Delete(ctx context.Context, collectionId int) error
+	LinkTmdb(ctx context.Context, movieId, tmdbId int, name string) error
+}
+
+// CollectionService manages the collections of movies. TMDB collections follow the metadata of their movies,
+// manual collections are managed by curators.
+type CollectionService struct {
+	collectionRepository collectionRepository
+	movieService         *MovieService
+}
+
+func NewCollectionService(collectionRepository collectionRepository, movieService *MovieService) *CollectionService {
+	return &CollectionService{collectionRepository: collectionRepository, movieService: movieService}
+}
+
+func (s *CollectionService) List(ctx context.Context) ([]*model.Collection, error) {
+	collections, err := s.collectionRepository.List(ctx)
+	if err != nil {
+		slog.Error("Error when getting collections from db", "error", err)
+		return nil, err
+	}
+	return collections, nil
+}
+
+func (s *CollectionService) Get(ctx context.Context, collectionId int) (*model.Collection, error) {
+	collection, err := s.collectionRepository.Get(ctx, collectionId)
+	if err != nil {
+		return nil, s.collectionError(err, collectionId)
+	}
+	return collection, nil
+}
+
+// Create adds a manual collection with the movies in the given order.
+func (s *CollectionService) Create(ctx context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	if err := s.validate(ctx, collection, movieIds); err != nil {
+		return nil, err
+	}
+	created, err := s.collectionRepository.Create(ctx, collection, movieIds)
+	if err != nil {
+		return nil, s.collectionError(err, 0)
+	}
+	return created, nil
+}
+
+// Update changes a manual collection, replacing its movies with the given ones in order.
+func (s *CollectionService) Update(ctx context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	if err := s.validate(ctx, collection, movieIds); err != nil {
+		return nil, err
+	}
+	updated, err := s.collectionRepository.Update(ctx, collection, movieIds)
+	if err != nil {
+		return nil, s.collectionError(err, collection.ID)
+	}
+	return updated, nil
+}
+
+func (s *CollectionService) Delete(ctx context.Context, collectionId int) error {
+	if err := s.collectionRepository.Delete(ctx, collectionId); err != nil {
+		return s.collectionError(err, collectionId)
+	}
+	return nil
+}
+
+// LinkTmdb puts the movie in the TMDB collection it belongs to, or takes it out of its TMDB collection if it
+// belongs to none.
+func (s *CollectionService) LinkTmdb(ctx context.Context, movieId int, collection *tmdb.Collection) error {
+	var err error
+	if collection == nil {
+		err = s.collectionRepository.LinkTmdb(ctx, movieId, 0, "")
+	} else {
+		err = s.collectionRepository.LinkTmdb(ctx, movieId, collection.ID, collection.Name)
+	}
+	if err != nil {
+		slog.Error("Error when linking movie to TMDB collection", "movieId", movieId, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validate checks the collection and that its movies exist, are not in the trash and are listed once.
+func (s *CollectionService) validate(ctx context.Context, collection *model.Collection, movieIds []int) error {
+	collection.Name = strings.TrimSpace(collection.Name)
+	collection.Description = strings.TrimSpace(collection.Description)
+	switch {
+	case collection.Name == "":
+		return model.ValidationError{Message: "name should be present"}
+	case len(collection.Name) > maxCollectionNameLength:
+		return model.ValidationError{Message: fmt.Sprintf("name should be at most %d characters", maxCollectionNameLength)}
+	case len(collection.Description) > maxCollectionDescriptionLength:
+		return model.ValidationError{Message: fmt.Sprintf("description should be at most %d characters", maxCollectionDescriptionLength)}
+	case len(movieIds) > maxCollectionMovies:
+		return model.ValidationError{Message: fmt.Sprintf("a collection should have at most %d movies", maxCollectionMovies)}
+	}
+
+	seen := make(map[int]bool, len(movieIds))
+	for _, movieId := range movieIds {
+		if seen[movieId] {
+			return model.ValidationError{Message: fmt.Sprintf("movie %d is listed more than once", movieId)}
+		}
+		seen[movieId] = true
+	}
+	if len(movieIds) == 0 {
+		return nil
+	}
+	movies, err := s.movieService.GetMany(ctx, movieIds)
+	if err != nil {
+		return err
+	}
+	found := make(map[int]bool, len(movies))
+	for _, movie := range movies {
+		found[movie.MovieId] = true
+	}
+	for _, movieId := range movieIds {
+		if !found[movieId] {
+			return model.ValidationError{Message: fmt.Sprintf("no movie with id %d exists", movieId)}
+		}
+	}
+	return nil
+}
+
+// collectionError maps the repository errors of collections.
+func (s *CollectionService) collectionError(err error, collectionId int) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return model.NotFoundError{}
+	case errors.Is(err, data.ErrInvalidState):
+		return model.ValidationError{Message: "TMDB collections cannot be changed"}
+	}
+	slog.Error("Error when accessing collection in db", "collectionId", collectionId, "error", err)
+	return err
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCollectionRepository struct {
+	mock.Mock
+}
+
+func (r *MockCollectionRepository) List(_ context.Context) ([]*model.Collection, error) {
+	args := r.Called()
+	return args.Get(0).([]*model.Collection), args.Error(1)
+}
+
+func (r *MockCollectionRepository) Get(_ context.Context, collectionId int) (*model.Collection, error) {
+	args := r.Called(collectionId)
+	return &model.Collection{ID: collectionId}, args.Error(0)
+}
+
+func (r *MockCollectionRepository) Create(_ context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	args := r.Called(collection.Name, movieIds)
+	return collection, args.Error(0)
+}
+
+func (r *MockCollectionRepository) Update(_ context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	args := r.Called(collection.ID, movieIds)
+	return collection, args.Error(0)
+}
+
+func (r *MockCollectionRepository) Delete(_ context.Context, collectionId int) error {
+	args := r.Called(collectionId)
+	return args.Error(0)
+}
+
+func (r *MockCollectionRepository) LinkTmdb(_ context.Context, movieId, tmdbId int, name string) error {
+	args := r.Called(movieId, tmdbId, name)
+	return args.Error(0)
+}
+
+func TestCollectionService_Create(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("GetMany", []int{11, 10}).Return([]*model.Movie{{MovieId: 10}, {MovieId: 11}}, nil)
+	movieRepository.On("GetMany", []int{10, 12}).Return([]*model.Movie{{MovieId: 10}}, nil)
+	collections := &MockCollectionRepository{}
+	collections.On("Create", "Heist movies", []int{11, 10}).Return(nil)
+	s := NewCollectionService(collections, NewMovieService(movieRepository, nil))
+
+	tests := []struct {
+		name     string
+		collName string
+		movieIds []int
+		wantErr  error
+	}{
+		{"created", " Heist movies ", []int{11, 10}, nil},
+		{"empty name", " ", nil, model.ValidationError{Message: "name should be present"}},
+		{"duplicate movie", "Heist movies", []int{10, 11, 10}, model.ValidationError{Message: "movie 10 is listed more than once"}},
+		{"unknown movie", "Heist movies", []int{10, 12}, model.ValidationError{Message: "no movie with id 12 exists"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection, err := s.Create(context.Background(), &model.Collection{Name: tt.collName}, tt.movieIds)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Heist movies", collection.Name)
+		})
+	}
+	collections.AssertNumberOfCalls(t, "Create", 1)
+	movieRepository.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestCollectionService_Delete(t *testing.T) {
+	collections := &MockCollectionRepository{}
+	collections.On("Delete", 1).Return(nil)
+	collections.On("Delete", 2).Return(data.ErrInvalidState)
+	collections.On("Delete", 3).Return(data.ErrRecordNotFound)
+	s := NewCollectionService(collections, nil)
+
+	assert.NoError(t, s.Delete(context.Background(), 1))
+	assert.ErrorIs(t, s.Delete(context.Background(), 2), model.ValidationError{Message: "TMDB collections cannot be changed"})
+	assert.ErrorIs(t, s.Delete(context.Background(), 3), model.NotFoundError{})
+}
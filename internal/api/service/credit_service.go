@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data"
+	"rest_api/internal/queue"
+)
+
+// SyncCreditsJob is the queue job kind which fetched the cast and crew of a movie created from TMDB data, before the
+// enrichment replaced it. It is still handled for the jobs queued by older replicas.
+const SyncCreditsJob = "movie.credits"
+
+type SyncCredits struct {
+	MovieId int `json:"movieId"`
+	TmdbId  int `json:"tmdbId"`
+}
+
+type creditRepository interface {
+	Replace(ctx context.Context, movieId int, credits []*model.Credit) error
+	ListForMovie(ctx context.Context, movieId int) ([]*model.Credit, error)
+	ListForMovies(ctx context.Context, movieIds []int) (map[int][]*model.Credit, error)
+	GetPerson(ctx context.Context, personId int) (*model.Person, error)
+	GetPeople(ctx context.Context, personIds []int) (map[int]*model.Person, error)
+}
+
+type creditsFetcher interface {
+	GetMovieCredits(ctx context.Context, id int) (*tmdb.Credits, error)
+}
+
+// CreditService stores the cast and crew of movies, fetched from TMDB whenever a movie is enriched.
+type CreditService struct {
+	creditRepository creditRepository
+	movieService     *MovieService
+	tmdbService      creditsFetcher
+}
+
+func NewCreditService(creditRepository creditRepository, movieService *MovieService, tmdbService creditsFetcher) *CreditService {
+	return &CreditService{creditRepository: creditRepository, movieService: movieService, tmdbService: tmdbService}
+}
+
+// Sync fetches the credits of the movie from TMDB and replaces the stored ones.
+func (s *CreditService) Sync(ctx context.Context, movieId, tmdbId int) error {
+	fetched, err := s.tmdbService.GetMovieCredits(ctx, tmdbId)
+	if err != nil {
+		return err
+	}
+
+	credits := make([]*model.Credit, 0, len(fetched.Cast)+len(fetched.Crew))
+	for _, member := range fetched.Cast {
+		credits = append(credits, &model.Credit{
+			Person:     &model.Person{ID: member.ID, Name: member.Name, ProfilePath: member.ProfilePath, KnownForDepartment: member.KnownForDepartment},
+			Role:       model.CreditCast,
+			Character:  member.Character,
+			Department: "Acting",
+			Order:      member.Order,
+		})
+	}
+	for i, member := range fetched.Crew {
+		credits = append(credits, &model.Credit{
+			Person:     &model.Person{ID: member.ID, Name: member.Name, ProfilePath: member.ProfilePath, KnownForDepartment: member.KnownForDepartment},
+			Role:       model.CreditCrew,
+			Job:        member.Job,
+			Department: member.Department,
+			Order:      i,
+		})
+	}
+	if err = s.creditRepository.Replace(ctx, movieId, credits); err != nil {
+		slog.Error("Error when storing movie credits in db", "movieId", movieId, "error", err)
+		return err
+	}
+	return nil
+}
+
+// SyncJob is the handler of SyncCreditsJob.
+func (s *CreditService) SyncJob(ctx context.Context, job SyncCredits) error {
+	err := s.Sync(ctx, job.MovieId, job.TmdbId)
+	if errors.Is(err, tmdb.ErrNoMoviesFound) || errors.Is(err, data.ErrRecordNotFound) {
+		return queue.Permanent(err)
+	}
+	return err
+}
+
+// MovieCredits returns the cast and crew of the movie.
+func (s *CreditService) MovieCredits(ctx context.Context, movieId int) (*model.MovieCredits, error) {
+	if _, err := s.movieService.Get(movieId); err != nil {
+		return nil, err
+	}
+	credits, err := s.creditRepository.ListForMovie(ctx, movieId)
+	if err != nil {
+		slog.Error("Error when getting movie credits from db", "movieId", movieId, "error", err)
+		return nil, err
+	}
+	return splitCredits(movieId, credits), nil
+}
+
+// CreditsOfMovies returns the cast and crew of the movies by movie id, without checking that the movies exist.
+func (s *CreditService) CreditsOfMovies(ctx context.Context, movieIds []int) (map[int]*model.MovieCredits, error) {
+	credits, err := s.creditRepository.ListForMovies(ctx, movieIds)
+	if err != nil {
+		slog.Error("Error when getting movie credits from db", "movieIds", movieIds, "error", err)
+		return nil, err
+	}
+	movieCredits := make(map[int]*model.MovieCredits, len(movieIds))
+	for _, movieId := range movieIds {
+		movieCredits[movieId] = splitCredits(movieId, credits[movieId])
+	}
+	return movieCredits, nil
+}
+
+// GetPerson returns the person with their filmography.
+func (s *CreditService) GetPerson(ctx context.Context, personId int) (*model.Person, error) {
+	person, err := s.creditRepository.GetPerson(ctx, personId)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when getting person from db", "personId", personId, "error", err)
+		return nil, err
+	}
+	return person, nil
+}
+
+// GetPeople returns the people by id with their filmography. Unknown ids are left out.
+func (s *CreditService) GetPeople(ctx context.Context, personIds []int) (map[int]*model.Person, error) {
+	people, err := s.creditRepository.GetPeople(ctx, personIds)
+	if err != nil {
+		slog.Error("Error when getting people from db", "personIds", personIds, "error", err)
+		return nil, err
+	}
+	return people, nil
+}
+
+// splitCredits separates the cast and the crew of a movie.
+func splitCredits(movieId int, credits []*model.Credit) *model.MovieCredits {
+	movieCredits := &model.MovieCredits{MovieId: movieId, Cast: []*model.Credit{}, Crew: []*model.Credit{}}
+	for _, credit := range credits {
+		if credit.Role == model.CreditCast {
+			movieCredits.Cast = append(movieCredits.Cast, credit)
+		} else {
+			movieCredits.Crew = append(movieCredits.Crew, credit)
+		}
+	}
+	return movieCredits
+}
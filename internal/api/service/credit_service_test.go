@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"rest_api/internal/queue"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCreditRepository struct {
+	mock.Mock
+}
+
+func (r *MockCreditRepository) Replace(_ context.Context, movieId int, credits []*model.Credit) error {
+	args := r.Called(movieId, credits)
+	return args.Error(0)
+}
+
+func (r *MockCreditRepository) ListForMovie(_ context.Context, movieId int) ([]*model.Credit, error) {
+	args := r.Called(movieId)
+	return args.Get(0).([]*model.Credit), args.Error(1)
+}
+
+func (r *MockCreditRepository) ListForMovies(_ context.Context, movieIds []int) (map[int][]*model.Credit, error) {
+	args := r.Called(movieIds)
+	return args.Get(0).(map[int][]*model.Credit), args.Error(1)
+}
+
+func (r *MockCreditRepository) GetPerson(_ context.Context, personId int) (*model.Person, error) {
+	args := r.Called(personId)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*model.Person), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (r *MockCreditRepository) GetPeople(_ context.Context, personIds []int) (map[int]*model.Person, error) {
+	args := r.Called(personIds)
+	return args.Get(0).(map[int]*model.Person), args.Error(1)
+}
+
+func (m *MockTmdbService) GetMovieCredits(_ context.Context, id int) (*tmdb.Credits, error) {
+	args := m.Called(id)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*tmdb.Credits), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestCreditService_Sync(t *testing.T) {
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieCredits", 11).Return(&tmdb.Credits{
+		ID:   11,
+		Cast: []tmdb.CastMember{{ID: 1, Name: "Jeremy Allen White", Character: "Carmy", Order: 0}},
+		Crew: []tmdb.CrewMember{{ID: 2, Name: "Christopher Storer", Job: "Director", Department: "Directing"},
+			{ID: 2, Name: "Christopher Storer", Job: "Writer", Department: "Writing"}},
+	}, nil)
+	tmdbService.On("GetMovieCredits", 12).Return(nil, tmdb.ErrNoMoviesFound)
+	creditRepository := &MockCreditRepository{}
+	creditRepository.On("Replace", 1, mock.Anything).Return(nil)
+	s := NewCreditService(creditRepository, nil, tmdbService)
+
+	require.NoError(t, s.Sync(context.Background(), 1, 11))
+	creditRepository.AssertCalled(t, "Replace", 1, []*model.Credit{
+		{Person: &model.Person{ID: 1, Name: "Jeremy Allen White"}, Role: model.CreditCast, Character: "Carmy", Department: "Acting"},
+		{Person: &model.Person{ID: 2, Name: "Christopher Storer"}, Role: model.CreditCrew, Job: "Director", Department: "Directing"},
+		{Person: &model.Person{ID: 2, Name: "Christopher Storer"}, Role: model.CreditCrew, Job: "Writer", Department: "Writing", Order: 1},
+	})
+
+	err := s.Sync(context.Background(), 2, 12)
+	assert.ErrorIs(t, err, tmdb.ErrNoMoviesFound)
+	creditRepository.AssertNumberOfCalls(t, "Replace", 1)
+
+	err = s.SyncJob(context.Background(), SyncCredits{MovieId: 2, TmdbId: 12})
+	assert.True(t, queue.IsPermanent(err))
+}
+
+func TestCreditService_MovieCredits(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 1).Return(&model.Movie{MovieId: 1}, nil)
+	movieRepository.On("Get", 2).Return(nil, data.ErrRecordNotFound)
+	cast := &model.Credit{Person: &model.Person{ID: 1}, Role: model.CreditCast}
+	crew := &model.Credit{Person: &model.Person{ID: 2}, Role: model.CreditCrew}
+	creditRepository := &MockCreditRepository{}
+	creditRepository.On("ListForMovie", 1).Return([]*model.Credit{cast, crew}, nil)
+	s := NewCreditService(creditRepository, NewMovieService(movieRepository, nil), nil)
+
+	credits, err := s.MovieCredits(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &model.MovieCredits{MovieId: 1, Cast: []*model.Credit{cast}, Crew: []*model.Credit{crew}}, credits)
+
+	_, err = s.MovieCredits(context.Background(), 2)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"rest_api/internal/api/export"
+	"rest_api/internal/api/model"
+	"time"
+
+	miniogo "github.com/minio/minio-go/v7"
+)
+
+type movieStreamer interface {
+	Stream(ctx context.Context, filter model.MovieFilter, fn func(*model.Movie) error) error
+}
+
+type exportStorage interface {
+	PutObject(ctx context.Context, id string, reader io.Reader, contentType string) error
+	ListObjects(ctx context.Context, prefix string) ([]miniogo.ObjectInfo, error)
+	RemoveObject(ctx context.Context, id string) error
+}
+
+// ExportService streams the movie catalog in the export formats, to a client or to the object storage.
+type ExportService struct {
+	movieRepository movieStreamer
+	storage         exportStorage
+	flushRows       int
+	format          string
+	prefix          string
+	retention       time.Duration
+}
+
+func NewExportService(movieRepository movieStreamer, storage exportStorage, flushRows int, format, prefix string, retention time.Duration) *ExportService {
+	return &ExportService{
+		movieRepository: movieRepository,
+		storage:         storage,
+		flushRows:       flushRows,
+		format:          format,
+		prefix:          prefix,
+		retention:       retention,
+	}
+}
+
+// Export writes every movie matching the filter to the writer, flushing it every s.flushRows movies. The
+// writer is not closed. It returns the number of exported movies.
+func (s *ExportService) Export(ctx context.Context, filter model.MovieFilter, writer export.Writer) (int, error) {
+	count := 0
+	err := s.movieRepository.Stream(ctx, filter, func(movie *model.Movie) error {
+		if err := writer.Write(movie); err != nil {
+			return err
+		}
+		count++
+		if count%s.flushRows == 0 {
+			return writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error when exporting movies", "exported", count, "error", err)
+		return count, err
+	}
+	return count, nil
+}
+
+// RunScheduled exports the whole catalog to the object storage and removes the exports older than the
+// retention period.
+func (s *ExportService) RunScheduled(ctx context.Context) error {
+	startedAt := time.Now().UTC()
+	key := fmt.Sprintf("%smovies-%s.%s", s.prefix, startedAt.Format("20060102T150405Z"), s.format)
+
+	reader, pipe := io.Pipe()
+	go func() {
+		writer, err := export.NewWriter(s.format, pipe)
+		if err == nil {
+			_, err = s.Export(ctx, model.MovieFilter{}, writer)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		pipe.CloseWithError(err)
+	}()
+	if err := s.storage.PutObject(ctx, key, reader, export.ContentType(s.format)); err != nil {
+		reader.CloseWithError(err)
+		slog.Error("Unable to upload movie export", "key", key, "error", err)
+		return err
+	}
+	slog.Info("Uploaded movie export", "key", key)
+
+	return s.purge(ctx, startedAt.Add(-s.retention))
+}
+
+// purge removes the exports last modified before the given time.
+func (s *ExportService) purge(ctx context.Context, before time.Time) error {
+	objects, err := s.storage.ListObjects(ctx, s.prefix)
+	if err != nil {
+		slog.Error("Unable to list movie exports", "error", err)
+		return err
+	}
+	for _, object := range objects {
+		if !object.LastModified.Before(before) {
+			continue
+		}
+		if err = s.storage.RemoveObject(ctx, object.Key); err != nil {
+			slog.Error("Unable to remove expired movie export", "key", object.Key, "error", err)
+			return err
+		}
+		slog.Info("Removed expired movie export", "key", object.Key)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"io"
+	"rest_api/internal/api/export"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data/datatest"
+	"strings"
+	"testing"
+	"time"
+
+	miniogo "github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockExportStorage struct {
+	mock.Mock
+	uploaded string
+}
+
+func (m *MockExportStorage) PutObject(_ context.Context, id string, reader io.Reader, contentType string) error {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.uploaded = string(content)
+	args := m.Called(id, contentType)
+	return args.Error(0)
+}
+
+func (m *MockExportStorage) ListObjects(_ context.Context, prefix string) ([]miniogo.ObjectInfo, error) {
+	args := m.Called(prefix)
+	return args.Get(0).([]miniogo.ObjectInfo), args.Error(1)
+}
+
+func (m *MockExportStorage) RemoveObject(_ context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestExportService_RunScheduled(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Stream", model.MovieFilter{}).Return([]*model.Movie{{MovieId: 1, MovieName: "The bear"}, {MovieId: 2, MovieName: "Heat"}}, nil)
+
+	storage := &MockExportStorage{}
+	storage.On("PutObject", mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "exports/movies-") && strings.HasSuffix(key, ".ndjson")
+	}), "application/x-ndjson").Return(nil)
+	storage.On("ListObjects", "exports/").Return([]miniogo.ObjectInfo{
+		{Key: "exports/movies-old.ndjson", LastModified: time.Now().Add(-48 * time.Hour)},
+		{Key: "exports/movies-new.ndjson", LastModified: time.Now().Add(-time.Hour)},
+	}, nil)
+	storage.On("RemoveObject", "exports/movies-old.ndjson").Return(nil)
+
+	s := NewExportService(movieRepository, storage, 1, export.FormatNDJSON, "exports/", 24*time.Hour)
+	require.NoError(t, s.RunScheduled(context.Background()))
+
+	assert.Equal(t, "{\"id\":1,\"title\":\"The bear\",\"overview\":\"\"}\n{\"id\":2,\"title\":\"Heat\",\"overview\":\"\"}\n", storage.uploaded)
+	storage.AssertNumberOfCalls(t, "RemoveObject", 1)
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"rest_api/internal/api/model"
+)
+
+type genreRepository interface {
+	List(ctx context.Context) ([]*model.GenreCount, error)
+}
+
+type GenreService struct {
+	genreRepository genreRepository
+}
+
+func NewGenreService(genreRepository genreRepository) *GenreService {
+	return &GenreService{genreRepository: genreRepository}
+}
+
+func (s *GenreService) List(ctx context.Context) ([]*model.GenreCount, error) {
+	genres, err := s.genreRepository.List(ctx)
+	if err != nil {
+		slog.Error("Error when getting genres from db", "error", err)
+		return nil, err
+	}
+	return genres, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"time"
+)
+
+type idempotencyRepository interface {
+	Claim(ctx context.Context, key *model.IdempotencyKey, ttl, lockTimeout time.Duration) (*model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, key *model.IdempotencyKey) error
+	Release(ctx context.Context, key *model.IdempotencyKey) error
+	Purge(ctx context.Context) (int64, error)
+}
+
+// IdempotencyService keeps the responses of the requests sent with an idempotency key for the TTL, so that their
+// retries are answered with them rather than processed again.
+type IdempotencyService struct {
+	repository  idempotencyRepository
+	ttl         time.Duration
+	lockTimeout time.Duration
+}
+
+// NewIdempotencyService returns a service keeping keys for ttl. A key whose request is still being processed after
+// lockTimeout can be claimed again by a retry, so it should exceed the time requests can take.
+func NewIdempotencyService(repository idempotencyRepository, ttl, lockTimeout time.Duration) *IdempotencyService {
+	return &IdempotencyService{repository: repository, ttl: ttl, lockTimeout: lockTimeout}
+}
+
+// Begin claims the key of the actor for the request with the fingerprint. It returns the claimed key, without a
+// status, when the request should be processed, in which case Complete or Release has to be called once it is.
+// For a retry, it returns the key with the stored response. A model.ConflictError is returned while the first
+// request is still being processed, and a model.ValidationError when the key was sent with another request.
+func (s *IdempotencyService) Begin(ctx context.Context, actor, key, fingerprint string) (*model.IdempotencyKey, error) {
+	claimed, ok, err := s.repository.Claim(ctx, &model.IdempotencyKey{Actor: actor, Key: key, Fingerprint: fingerprint}, s.ttl, s.lockTimeout)
+	if err != nil {
+		slog.Error("Error when claiming idempotency key in db", "actor", actor, "error", err)
+		return nil, err
+	}
+	switch {
+	case ok:
+		return claimed, nil
+	case claimed.Fingerprint != fingerprint:
+		return nil, model.ValidationError{Message: "The Idempotency-Key was already used for another request"}
+	case claimed.Status == 0:
+		return nil, model.ConflictError{}
+	}
+	return claimed, nil
+}
+
+// Complete stores the response of the request of a claimed key. The response was already sent, so a failure is
+// only logged; retries are then processed again once the lock timeout has passed.
+func (s *IdempotencyService) Complete(ctx context.Context, key *model.IdempotencyKey) {
+	if err := s.repository.Complete(ctx, key); err != nil {
+		slog.Error("Error when storing idempotent response in db", "actor", key.Actor, "error", err)
+	}
+}
+
+// Release forgets a claimed key whose request failed, so that its retries are processed.
+func (s *IdempotencyService) Release(ctx context.Context, key *model.IdempotencyKey) {
+	if err := s.repository.Release(ctx, key); err != nil {
+		slog.Error("Error when releasing idempotency key in db", "actor", key.Actor, "error", err)
+	}
+}
+
+// Purge removes the expired keys, which are otherwise only replaced when they are sent again.
+func (s *IdempotencyService) Purge(ctx context.Context) error {
+	purged, err := s.repository.Purge(ctx)
+	if err != nil {
+		slog.Error("Error when purging expired idempotency keys from db", "error", err)
+		return err
+	}
+	slog.Info("Purged expired idempotency keys", "count", purged)
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rest_api/internal/api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (r *MockIdempotencyRepository) Claim(_ context.Context, key *model.IdempotencyKey, ttl, lockTimeout time.Duration) (*model.IdempotencyKey, bool, error) {
+	args := r.Called(key, ttl, lockTimeout)
+	claimed, _ := args.Get(0).(*model.IdempotencyKey)
+	return claimed, args.Bool(1), args.Error(2)
+}
+
+func (r *MockIdempotencyRepository) Complete(_ context.Context, key *model.IdempotencyKey) error {
+	args := r.Called(key)
+	return args.Error(0)
+}
+
+func (r *MockIdempotencyRepository) Release(_ context.Context, key *model.IdempotencyKey) error {
+	args := r.Called(key)
+	return args.Error(0)
+}
+
+func (r *MockIdempotencyRepository) Purge(_ context.Context) (int64, error) {
+	args := r.Called()
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	stored := &model.IdempotencyKey{Actor: "alice", Key: "k1", Fingerprint: "abc", Status: 201, Body: []byte(`{"id":1}`)}
+	tests := []struct {
+		name        string
+		fingerprint string
+		claimed     *model.IdempotencyKey
+		ok          bool
+		claimErr    error
+		want        *model.IdempotencyKey
+		wantErr     error
+	}{
+		{name: "first request", fingerprint: "abc", claimed: &model.IdempotencyKey{Actor: "alice", Key: "k1", Fingerprint: "abc"}, ok: true,
+			want: &model.IdempotencyKey{Actor: "alice", Key: "k1", Fingerprint: "abc"}},
+		{name: "retry", fingerprint: "abc", claimed: stored, want: stored},
+		{name: "retry while processing", fingerprint: "abc", claimed: &model.IdempotencyKey{Actor: "alice", Key: "k1", Fingerprint: "abc"},
+			wantErr: model.ConflictError{}},
+		{name: "other request", fingerprint: "def", claimed: stored,
+			wantErr: model.ValidationError{Message: "The Idempotency-Key was already used for another request"}},
+		{name: "db error", fingerprint: "abc", claimErr: errors.New("connection lost"), wantErr: errors.New("connection lost")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &MockIdempotencyRepository{}
+			repository.On("Claim", &model.IdempotencyKey{Actor: "alice", Key: "k1", Fingerprint: tt.fingerprint}, 24*time.Hour, time.Minute).
+				Return(tt.claimed, tt.ok, tt.claimErr)
+			s := NewIdempotencyService(repository, 24*time.Hour, time.Minute)
+
+			got, err := s.Begin(context.Background(), "alice", "k1", tt.fingerprint)
+
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
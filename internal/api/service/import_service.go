@@ -0,0 +1,416 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data"
+	"rest_api/internal/queue"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxTitleLength       = 50
+	maxErrorTitleLength  = 255
+	importErrorsPreview  = 100
+	maxNDJSONLineLength  = 1 << 20
+	errMovieExists       = "A movie with the provided id already exists"
+	errMovieNotInTmdb    = "A movie with the provided name does not exist"
+	errTmdbLookupFailure = "Could not fetch movie details"
+)
+
+type importRepository interface {
+	Create(imp *model.MovieImport) (*model.MovieImport, error)
+	Get(id int) (*model.MovieImport, error)
+	UpdateProgress(imp *model.MovieImport) error
+	AddErrors(importId int, rowErrors []*model.ImportRowError) error
+	GetErrors(importId, limit int) ([]*model.ImportRowError, error)
+}
+
+type movieSearcher interface {
+	GetMovieByTitle(title string) (*tmdb.Movie, error)
+}
+
+type jobEnqueuer interface {
+	Enqueue(ctx context.Context, kind string, payload any) (*model.QueueJob, error)
+}
+
+// ImportMoviesJob is the queue job kind processing the rows of an import.
+const ImportMoviesJob = "movie.import"
+
+// ImportMovies is the payload of ImportMoviesJob, holding the rows that passed validation.
+type ImportMovies struct {
+	ImportID int                `json:"importId"`
+	Rows     []*model.ImportRow `json:"rows"`
+}
+
+// ImportService bulk imports movies from CSV or NDJSON files. Imports are processed by the job queue; rows are
+// enriched through TMDB with bounded concurrency and inserted in batches.
+type ImportService struct {
+	importRepository importRepository
+	movieService     *MovieService
+	tmdbService      movieSearcher
+	jobs             jobEnqueuer
+	batchSize        int
+	concurrency      int
+}
+
+func NewImportService(importRepository importRepository, movieService *MovieService, tmdbService movieSearcher, jobs jobEnqueuer, batchSize, concurrency int) *ImportService {
+	return &ImportService{
+		importRepository: importRepository,
+		movieService:     movieService,
+		tmdbService:      tmdbService,
+		jobs:             jobs,
+		batchSize:        batchSize,
+		concurrency:      concurrency,
+	}
+}
+
+// ParseImport reads and validates the rows of an import file. Invalid rows are returned as row errors; an error
+// is only returned if the file as a whole cannot be read.
+func ParseImport(format string, r io.Reader) ([]*model.ImportRow, []*model.ImportRowError, error) {
+	var rows []*model.ImportRow
+	var rowErrors []*model.ImportRowError
+	var err error
+	switch format {
+	case model.ImportFormatCSV:
+		rows, rowErrors, err = parseCSV(r)
+	case model.ImportFormatNDJSON:
+		rows, rowErrors, err = parseNDJSON(r)
+	default:
+		return nil, nil, model.ValidationError{Message: fmt.Sprintf("unsupported import format %q", format)}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// validate rows and reject duplicate ids within the file
+	seen := map[int]int{}
+	valid := rows[:0]
+	for _, row := range rows {
+		message := validateImportRow(row.Movie)
+		if line, ok := seen[row.Movie.MovieId]; ok && message == "" {
+			message = fmt.Sprintf("duplicate id, already used on line %d", line)
+		}
+		if message != "" {
+			rowErrors = append(rowErrors, newImportRowError(row, message))
+			continue
+		}
+		seen[row.Movie.MovieId] = row.Line
+		valid = append(valid, row)
+	}
+	return valid, rowErrors, nil
+}
+
+func parseCSV(r io.Reader) ([]*model.ImportRow, []*model.ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.Is(err, io.EOF) || errors.As(err, &parseErr) {
+			return nil, nil, model.ValidationError{Message: "could not read csv header"}
+		}
+		return nil, nil, err
+	}
+	idColumn, titleColumn := -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "id":
+			idColumn = i
+		case "title":
+			titleColumn = i
+		}
+	}
+	if idColumn == -1 || titleColumn == -1 {
+		return nil, nil, model.ValidationError{Message: "csv header should contain id and title columns"}
+	}
+
+	var rows []*model.ImportRow
+	var rowErrors []*model.ImportRowError
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, &model.ImportRowError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) <= idColumn || len(record) <= titleColumn {
+			rowErrors = append(rowErrors, &model.ImportRowError{Line: line, Message: "missing columns"})
+			continue
+		}
+		movieId, err := strconv.Atoi(strings.TrimSpace(record[idColumn]))
+		if err != nil {
+			rowErrors = append(rowErrors, &model.ImportRowError{Line: line, Title: record[titleColumn], Message: "id should be a number"})
+			continue
+		}
+		rows = append(rows, &model.ImportRow{Line: line, Movie: &model.Movie{MovieId: movieId, MovieName: strings.TrimSpace(record[titleColumn])}})
+	}
+	return rows, rowErrors, nil
+}
+
+func parseNDJSON(r io.Reader) ([]*model.ImportRow, []*model.ImportRowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLineLength)
+
+	var rows []*model.ImportRow
+	var rowErrors []*model.ImportRowError
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		movie := &model.Movie{}
+		if err := json.Unmarshal([]byte(text), movie); err != nil {
+			rowErrors = append(rowErrors, &model.ImportRowError{Line: line, Message: "could not parse line as a json object"})
+			continue
+		}
+		movie.MovieName = strings.TrimSpace(movie.MovieName)
+		rows = append(rows, &model.ImportRow{Line: line, Movie: &model.Movie{MovieId: movie.MovieId, MovieName: movie.MovieName}})
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, nil, model.ValidationError{Message: fmt.Sprintf("line %d is longer than %d bytes", line+1, maxNDJSONLineLength)}
+		}
+		return nil, nil, err
+	}
+	return rows, rowErrors, nil
+}
+
+func validateImportRow(movie *model.Movie) string {
+	switch {
+	case movie.MovieId <= 0:
+		return "id should be a positive number"
+	case movie.MovieName == "":
+		return "title should be present"
+	case len(movie.MovieName) > maxTitleLength:
+		return fmt.Sprintf("title should be at most %d characters", maxTitleLength)
+	}
+	return ""
+}
+
+// newImportRowError returns the error of the row, whose title is truncated to fit in the error report since the
+// titles which are too long are errors too.
+func newImportRowError(row *model.ImportRow, message string) *model.ImportRowError {
+	title := row.Movie.MovieName
+	if runes := []rune(title); len(runes) > maxErrorTitleLength {
+		title = string(runes[:maxErrorTitleLength])
+	}
+	return &model.ImportRowError{Line: row.Line, MovieId: row.Movie.MovieId, Title: title, Message: message}
+}
+
+// Start records a new import and enqueues the processing of its rows. Rows that failed validation are recorded
+// as failed right away.
+func (s *ImportService) Start(ctx context.Context, format string, rows []*model.ImportRow, rowErrors []*model.ImportRowError) (*model.MovieImport, error) {
+	imp, err := s.importRepository.Create(&model.MovieImport{
+		Format:        format,
+		Status:        model.ImportPending,
+		TotalRows:     len(rows) + len(rowErrors),
+		ProcessedRows: len(rowErrors),
+		FailedRows:    len(rowErrors),
+	})
+	if err != nil {
+		slog.Error("Unable to create movie import in the database", "error", err)
+		return nil, err
+	}
+	if err = s.importRepository.AddErrors(imp.ID, rowErrors); err != nil {
+		slog.Error("Unable to store import row errors", "importId", imp.ID, "error", err)
+		return nil, err
+	}
+
+	if _, err = s.jobs.Enqueue(ctx, ImportMoviesJob, ImportMovies{ImportID: imp.ID, Rows: rows}); err != nil {
+		slog.Error("Unable to enqueue movie import", "importId", imp.ID, "error", err)
+		return nil, err
+	}
+	return imp, nil
+}
+
+// Get returns the import with a preview of its row errors.
+func (s *ImportService) Get(id int) (*model.MovieImport, error) {
+	imp, err := s.importRepository.Get(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when getting movie import from db", "importId", id, "error", err)
+		return nil, err
+	}
+	if imp.FailedRows > 0 {
+		imp.Errors, err = s.importRepository.GetErrors(id, importErrorsPreview)
+		if err != nil {
+			slog.Error("Error when getting import row errors from db", "importId", id, "error", err)
+			return nil, err
+		}
+	}
+	return imp, nil
+}
+
+// GetErrors returns all row errors of the import.
+func (s *ImportService) GetErrors(id int) ([]*model.ImportRowError, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	rowErrors, err := s.importRepository.GetErrors(id, 0)
+	if err != nil {
+		slog.Error("Error when getting import row errors from db", "importId", id, "error", err)
+		return nil, err
+	}
+	return rowErrors, nil
+}
+
+// Process is the handler of ImportMoviesJob. A retried job resumes after the rows processed by the previous
+// attempts.
+func (s *ImportService) Process(ctx context.Context, job ImportMovies) error {
+	imp, err := s.importRepository.Get(job.ImportID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return queue.Permanent(err)
+		}
+		return err
+	}
+	if imp.FinishedAt != nil {
+		return nil
+	}
+
+	// rows that failed validation are not part of the job but were counted as processed on creation
+	done := imp.ProcessedRows - (imp.TotalRows - len(job.Rows))
+	rows := job.Rows[min(max(done, 0), len(job.Rows)):]
+	slog.Info("Processing movie import", "importId", imp.ID, "rows", len(rows))
+	imp.Status = model.ImportRunning
+	imp.Error = ""
+	s.updateProgress(imp)
+
+	for start := 0; start < len(rows); start += s.batchSize {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		batch := rows[start:min(start+s.batchSize, len(rows))]
+		if err = s.processBatch(ctx, imp, batch); err != nil {
+			slog.Error("Movie import batch failed", "importId", imp.ID, "error", err)
+			imp.Error = err.Error()
+			if queue.IsLastAttempt(ctx) {
+				imp.Status = model.ImportFailed
+				s.finish(imp)
+			} else {
+				s.updateProgress(imp)
+			}
+			return err
+		}
+		s.updateProgress(imp)
+	}
+
+	imp.Status = model.ImportCompleted
+	s.finish(imp)
+	slog.Info("Finished movie import", "importId", imp.ID, "imported", imp.ImportedRows, "failed", imp.FailedRows)
+	return nil
+}
+
+func (s *ImportService) processBatch(ctx context.Context, imp *model.MovieImport, batch []*model.ImportRow) error {
+	enriched, rowErrors := s.enrich(batch)
+
+	movies := make([]*model.Movie, len(enriched))
+	for i, row := range enriched {
+		movies[i] = row.Movie
+	}
+	created, err := s.movieService.CreateBatch(ctx, movies)
+	if err != nil {
+		return err
+	}
+	createdIds := make(map[int]bool, len(created))
+	for _, movie := range created {
+		createdIds[movie.MovieId] = true
+		// details and credits are fetched in the background, a failure only delays them until the next refresh
+		if _, err = s.jobs.Enqueue(ctx, EnrichMovieJob, EnrichMovie{MovieId: movie.MovieId}); err != nil {
+			slog.Warn("Unable to enqueue movie enrichment", "movieId", movie.MovieId, "error", err)
+		}
+	}
+	for _, row := range enriched {
+		if !createdIds[row.Movie.MovieId] {
+			rowErrors = append(rowErrors, newImportRowError(row, errMovieExists))
+		}
+	}
+	if err = s.importRepository.AddErrors(imp.ID, rowErrors); err != nil {
+		return err
+	}
+
+	imp.ProcessedRows += len(batch)
+	imp.ImportedRows += len(created)
+	imp.FailedRows += len(rowErrors)
+	return nil
+}
+
+// enrich looks up the rows in TMDB, at most s.concurrency at a time. Rows that could not be looked up are
+// returned as row errors.
+func (s *ImportService) enrich(batch []*model.ImportRow) ([]*model.ImportRow, []*model.ImportRowError) {
+	results := make([]*model.ImportRowError, len(batch))
+	sem := make(chan struct{}, s.concurrency)
+	wg := sync.WaitGroup{}
+	for i, row := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			info, err := s.tmdbService.GetMovieByTitle(row.Movie.MovieName)
+			if err != nil {
+				message := errTmdbLookupFailure
+				if errors.Is(err, tmdb.ErrNoMoviesFound) {
+					message = errMovieNotInTmdb
+				}
+				results[i] = newImportRowError(row, message)
+				return
+			}
+			row.Movie.Overview = info.Overview
+			row.Movie.TmdbId = info.ID
+			row.Movie.Runtime = int(info.Runtime)
+			row.Movie.TmdbVoteAverage = info.RoundedVoteAverage()
+			row.Movie.ReleaseDate = info.ReleaseDate
+			row.Movie.Genres = model.GenresOf(info.GenreIDs())
+		}()
+	}
+	wg.Wait()
+
+	var enriched []*model.ImportRow
+	var rowErrors []*model.ImportRowError
+	for i, row := range batch {
+		if results[i] != nil {
+			rowErrors = append(rowErrors, results[i])
+			continue
+		}
+		enriched = append(enriched, row)
+	}
+	return enriched, rowErrors
+}
+
+func (s *ImportService) updateProgress(imp *model.MovieImport) {
+	if err := s.importRepository.UpdateProgress(imp); err != nil {
+		slog.Error("Unable to update movie import progress", "importId", imp.ID, "error", err)
+	}
+}
+
+func (s *ImportService) finish(imp *model.MovieImport) {
+	finishedAt := time.Now()
+	imp.FinishedAt = &finishedAt
+	s.updateProgress(imp)
+}
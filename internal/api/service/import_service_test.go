@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data/datatest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockImportRepository struct {
+	mock.Mock
+}
+
+func (r *MockImportRepository) Create(imp *model.MovieImport) (*model.MovieImport, error) {
+	args := r.Called(imp)
+	imp.ID = 1
+	return imp, args.Error(0)
+}
+
+func (r *MockImportRepository) Get(id int) (*model.MovieImport, error) {
+	args := r.Called(id)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*model.MovieImport), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (r *MockImportRepository) UpdateProgress(imp *model.MovieImport) error {
+	copied := *imp
+	args := r.Called(&copied)
+	return args.Error(0)
+}
+
+func (r *MockImportRepository) AddErrors(importId int, rowErrors []*model.ImportRowError) error {
+	args := r.Called(importId, rowErrors)
+	return args.Error(0)
+}
+
+func (r *MockImportRepository) GetErrors(importId, limit int) ([]*model.ImportRowError, error) {
+	args := r.Called(importId, limit)
+	return args.Get(0).([]*model.ImportRowError), args.Error(1)
+}
+
+type MockQueue struct {
+	mock.Mock
+}
+
+func (q *MockQueue) Enqueue(_ context.Context, kind string, payload any) (*model.QueueJob, error) {
+	args := q.Called(kind, payload)
+	return &model.QueueJob{ID: 1, Kind: kind, Status: model.QueueJobPending}, args.Error(0)
+}
+
+func (m *MockTmdbService) GetMovieByTitle(title string) (*tmdb.Movie, error) {
+	args := m.Called(title)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*tmdb.Movie), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestParseImport(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		input     string
+		rows      []*model.ImportRow
+		rowErrors []*model.ImportRowError
+		err       string
+	}{
+		{
+			name:   "csv",
+			format: model.ImportFormatCSV,
+			input:  "Title,ID\nThe bear, 1\n,2\nDune,x\nAlien,1\nHeat,3\n",
+			rows: []*model.ImportRow{
+				{Line: 2, Movie: &model.Movie{MovieId: 1, MovieName: "The bear"}},
+				{Line: 6, Movie: &model.Movie{MovieId: 3, MovieName: "Heat"}},
+			},
+			rowErrors: []*model.ImportRowError{
+				{Line: 4, Title: "Dune", Message: "id should be a number"},
+				{Line: 3, MovieId: 2, Message: "title should be present"},
+				{Line: 5, MovieId: 1, Title: "Alien", Message: "duplicate id, already used on line 2"},
+			},
+		},
+		{
+			name:   "ndjson",
+			format: model.ImportFormatNDJSON,
+			input:  "{\"id\":1,\"title\":\"The bear\"}\n\nnot json\n{\"id\":-1,\"title\":\"Alien\"}\n",
+			rows: []*model.ImportRow{
+				{Line: 1, Movie: &model.Movie{MovieId: 1, MovieName: "The bear"}},
+			},
+			rowErrors: []*model.ImportRowError{
+				{Line: 3, Message: "could not parse line as a json object"},
+				{Line: 4, MovieId: -1, Title: "Alien", Message: "id should be a positive number"},
+			},
+		},
+		{
+			name:   "ndjson with long title",
+			format: model.ImportFormatNDJSON,
+			input:  "{\"id\":1,\"title\":\"" + strings.Repeat("é", 300) + "\"}\n",
+			rows:   []*model.ImportRow{},
+			rowErrors: []*model.ImportRowError{
+				{Line: 1, MovieId: 1, Title: strings.Repeat("é", 255), Message: "title should be at most 50 characters"},
+			},
+		},
+		{
+			name:   "csv without title column",
+			format: model.ImportFormatCSV,
+			input:  "id,name\n1,The bear\n",
+			err:    "csv header should contain id and title columns",
+		},
+		{
+			name:   "unsupported format",
+			format: "xml",
+			err:    `unsupported import format "xml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, err := ParseImport(tt.format, strings.NewReader(tt.input))
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.rows, rows)
+			assert.Equal(t, tt.rowErrors, rowErrors)
+		})
+	}
+}
+
+func TestImportService_Start(t *testing.T) {
+	importRepository := &MockImportRepository{}
+	importRepository.On("Create", mock.Anything).Return(nil)
+	rowErrors := []*model.ImportRowError{{Line: 3, Message: "title should be present"}}
+	importRepository.On("AddErrors", 1, rowErrors).Return(nil)
+	rows := []*model.ImportRow{{Line: 2, Movie: &model.Movie{MovieId: 1, MovieName: "The bear"}}}
+	jobs := &MockQueue{}
+	jobs.On("Enqueue", ImportMoviesJob, ImportMovies{ImportID: 1, Rows: rows}).Return(nil)
+
+	s := NewImportService(importRepository, nil, nil, jobs, 10, 2)
+	imp, err := s.Start(context.Background(), model.ImportFormatCSV, rows, rowErrors)
+	require.NoError(t, err)
+
+	assert.Equal(t, &model.MovieImport{ID: 1, Format: model.ImportFormatCSV, Status: model.ImportPending, TotalRows: 2, ProcessedRows: 1, FailedRows: 1}, imp)
+	jobs.AssertNumberOfCalls(t, "Enqueue", 1)
+}
+
+func TestImportService_Process(t *testing.T) {
+	imp := &model.MovieImport{ID: 1, Status: model.ImportPending, TotalRows: 5, ProcessedRows: 1, FailedRows: 1}
+	importRepository := &MockImportRepository{}
+	importRepository.On("Get", 1).Return(imp, nil)
+	importRepository.On("UpdateProgress", mock.Anything).Return(nil)
+	importRepository.On("AddErrors", 1, mock.Anything).Return(nil)
+
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieByTitle", "The bear").Return(&tmdb.Movie{ID: 11, Overview: "bear", Runtime: 90}, nil)
+	tmdbService.On("GetMovieByTitle", "Heat").Return(&tmdb.Movie{ID: 12, Overview: "heat", Runtime: 170}, nil)
+	tmdbService.On("GetMovieByTitle", "Unknown").Return(nil, tmdb.ErrNoMoviesFound)
+	tmdbService.On("GetMovieByTitle", "Alien").Return(&tmdb.Movie{ID: 13, Overview: "alien", Runtime: 117}, nil)
+
+	bear := &model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear", TmdbId: 11, Runtime: 90}
+	heat := &model.Movie{MovieId: 2, MovieName: "Heat", Overview: "heat", TmdbId: 12, Runtime: 170}
+	alien := &model.Movie{MovieId: 4, MovieName: "Alien", Overview: "alien", TmdbId: 13, Runtime: 117}
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("CreateBatch", []*model.Movie{bear, heat}).Return([]*model.Movie{bear}, nil)
+	movieRepository.On("CreateBatch", []*model.Movie{alien}).Return([]*model.Movie{alien}, nil)
+	publisher := &MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+
+	jobs := &MockQueue{}
+	jobs.On("Enqueue", EnrichMovieJob, mock.Anything).Return(nil)
+
+	s := NewImportService(importRepository, NewMovieService(movieRepository, publisher), tmdbService, jobs, 3, 2)
+	err := s.Process(context.Background(), ImportMovies{ImportID: 1, Rows: []*model.ImportRow{
+		{Line: 2, Movie: &model.Movie{MovieId: 1, MovieName: "The bear"}},
+		{Line: 3, Movie: &model.Movie{MovieId: 2, MovieName: "Heat"}},
+		{Line: 4, Movie: &model.Movie{MovieId: 3, MovieName: "Unknown"}},
+		{Line: 6, Movie: &model.Movie{MovieId: 4, MovieName: "Alien"}},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, model.ImportCompleted, imp.Status)
+	assert.NotNil(t, imp.FinishedAt)
+	assert.Equal(t, 5, imp.ProcessedRows)
+	assert.Equal(t, 2, imp.ImportedRows)
+	assert.Equal(t, 3, imp.FailedRows)
+	importRepository.AssertCalled(t, "AddErrors", 1, []*model.ImportRowError{
+		{Line: 4, MovieId: 3, Title: "Unknown", Message: errMovieNotInTmdb},
+		{Line: 3, MovieId: 2, Title: "Heat", Message: errMovieExists},
+	})
+	importRepository.AssertNumberOfCalls(t, "UpdateProgress", 4)
+	publisher.AssertNumberOfCalls(t, "Publish", 2)
+	jobs.AssertCalled(t, "Enqueue", EnrichMovieJob, EnrichMovie{MovieId: 1})
+	jobs.AssertCalled(t, "Enqueue", EnrichMovieJob, EnrichMovie{MovieId: 4})
+	jobs.AssertNumberOfCalls(t, "Enqueue", 2)
+}
+
+func TestImportService_ProcessResumesAfterFailure(t *testing.T) {
+	imp := &model.MovieImport{ID: 1, Status: model.ImportRunning, TotalRows: 3, ProcessedRows: 2, ImportedRows: 1, FailedRows: 1}
+	importRepository := &MockImportRepository{}
+	importRepository.On("Get", 1).Return(imp, nil)
+	importRepository.On("UpdateProgress", mock.Anything).Return(nil)
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieByTitle", "Heat").Return(&tmdb.Movie{ID: 12}, nil)
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("CreateBatch", mock.Anything).Return([]*model.Movie{}, errors.New("connection refused"))
+
+	s := NewImportService(importRepository, NewMovieService(movieRepository, nil), tmdbService, nil, 10, 2)
+	err := s.Process(context.Background(), ImportMovies{ImportID: 1, Rows: []*model.ImportRow{
+		{Line: 2, Movie: &model.Movie{MovieId: 1, MovieName: "The bear"}},
+		{Line: 3, Movie: &model.Movie{MovieId: 2, MovieName: "Heat"}},
+	}})
+
+	assert.EqualError(t, err, "connection refused")
+	movieRepository.AssertCalled(t, "CreateBatch", []*model.Movie{{MovieId: 2, MovieName: "Heat", TmdbId: 12}})
+	assert.Equal(t, model.ImportRunning, imp.Status)
+	assert.Equal(t, "connection refused", imp.Error)
+	assert.Equal(t, 2, imp.ProcessedRows)
+	assert.Nil(t, imp.FinishedAt)
+}
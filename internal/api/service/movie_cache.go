@@ -0,0 +1,228 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MovieCacheStats are the counters of a MovieCache since it was created.
+type MovieCacheStats struct {
+	Size     int `json:"size"`
+	Capacity int `json:"capacity"`
+	// Hits counts the lookups answered from the cache, NegativeHits the ones of them answering that the movie does
+	// not exist.
+	Hits          int64   `json:"hits"`
+	NegativeHits  int64   `json:"negativeHits"`
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hitRatio"`
+	Evictions     int64   `json:"evictions"`
+	Invalidations int64   `json:"invalidations"`
+	Flushes       int64   `json:"flushes"`
+}
+
+type cachedMovie struct {
+	movieId int
+	// movie is nil for a movie that does not exist, or is in the trash.
+	movie     *model.Movie
+	expiresAt time.Time
+}
+
+// MovieCache is a MovieStore keeping the movies read by id in memory, the least recently used ones being evicted
+// beyond its capacity. Movies are cached for the TTL, and the ids of missing movies for the negative TTL.
+//
+// Movies are invalidated when they are changed through the cache, and when the change events of the other replicas
+// are consumed with HandleMessage. Their ratings and tags, which are changed by other repositories, are invalidated
+// through MovieService.Invalidate, which publishes invalidation events for the other replicas.
+type MovieCache struct {
+	data.MovieStore
+	capacity    int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu sync.Mutex
+	// entries holds the *cachedMovie, most recently used first.
+	entries *list.List
+	index   map[int]*list.Element
+	// generation changes on every invalidation, so that movies read meanwhile are not cached.
+	generation int
+	stats      MovieCacheStats
+}
+
+func NewMovieCache(store data.MovieStore, capacity int, ttl, negativeTTL time.Duration) *MovieCache {
+	return &MovieCache{
+		MovieStore:  store,
+		capacity:    capacity,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     list.New(),
+		index:       map[int]*list.Element{},
+		stats:       MovieCacheStats{Capacity: capacity},
+	}
+}
+
+// Get returns a copy of the cached movie, reading it from the store if it is not cached or expired.
+func (c *MovieCache) Get(movieId int) (*model.Movie, error) {
+	c.mu.Lock()
+	if element, ok := c.index[movieId]; ok {
+		cached := element.Value.(*cachedMovie)
+		if time.Now().Before(cached.expiresAt) {
+			c.entries.MoveToFront(element)
+			c.stats.Hits++
+			if cached.movie == nil {
+				c.stats.NegativeHits++
+			}
+			c.mu.Unlock()
+			// cached movies are never changed, so they can be copied without the lock
+			if cached.movie == nil {
+				return nil, data.ErrRecordNotFound
+			}
+			return copyMovie(cached.movie), nil
+		}
+		c.remove(element)
+	}
+	c.stats.Misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	movie, err := c.MovieStore.Get(movieId)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.add(movieId, movie)
+	}
+	c.mu.Unlock()
+	return movie, err
+}
+
+func (c *MovieCache) Create(ctx context.Context, movie *model.Movie) (*model.Movie, error) {
+	defer c.Invalidate(movie.MovieId)
+	return c.MovieStore.Create(ctx, movie)
+}
+
+func (c *MovieCache) CreateBatch(ctx context.Context, movies []*model.Movie) ([]*model.Movie, error) {
+	movieIds := make([]int, len(movies))
+	for i, movie := range movies {
+		movieIds[i] = movie.MovieId
+	}
+	defer c.Invalidate(movieIds...)
+	return c.MovieStore.CreateBatch(ctx, movies)
+}
+
+func (c *MovieCache) Update(ctx context.Context, movie *model.Movie) (*model.Movie, error) {
+	defer c.Invalidate(movie.MovieId)
+	return c.MovieStore.Update(ctx, movie)
+}
+
+func (c *MovieCache) Delete(ctx context.Context, movieId int) error {
+	defer c.Invalidate(movieId)
+	return c.MovieStore.Delete(ctx, movieId)
+}
+
+func (c *MovieCache) Restore(ctx context.Context, movieId int) (*model.Movie, error) {
+	defer c.Invalidate(movieId)
+	return c.MovieStore.Restore(ctx, movieId)
+}
+
+// Invalidate drops the cached movies.
+func (c *MovieCache) Invalidate(movieIds ...int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, movieId := range movieIds {
+		if element, ok := c.index[movieId]; ok {
+			c.remove(element)
+		}
+	}
+	c.generation++
+	c.stats.Invalidations += int64(len(movieIds))
+}
+
+// Flush drops all the cached movies.
+func (c *MovieCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries.Init()
+	clear(c.index)
+	c.generation++
+	c.stats.Flushes++
+}
+
+// Stats returns the counters of the cache.
+func (c *MovieCache) Stats() MovieCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.entries.Len()
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// HandleMessage invalidates the movie of an event of the movie-events topic, changed by this replica or another one.
+// An invalidation event without a movie flushes the cache.
+func (c *MovieCache) HandleMessage(msg []byte) {
+	var event model.MovieEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		slog.Error("Error when unmarshalling movie event", "error", err)
+		return
+	}
+	if event.Movie == nil && event.Type == model.MovieInvalidated {
+		c.Flush()
+		return
+	}
+	if event.Movie == nil {
+		slog.Error("Received movie event without a movie", "type", event.Type)
+		return
+	}
+	c.Invalidate(event.Movie.MovieId)
+}
+
+// add caches the movie, nil for a missing one, evicting the least recently used movie if the cache is full. The
+// lock has to be held.
+func (c *MovieCache) add(movieId int, movie *model.Movie) {
+	ttl := c.ttl
+	if movie == nil {
+		ttl = c.negativeTTL
+	} else {
+		movie = copyMovie(movie)
+	}
+	if element, ok := c.index[movieId]; ok {
+		c.remove(element)
+	}
+	for c.entries.Len() >= c.capacity && c.entries.Len() > 0 {
+		c.remove(c.entries.Back())
+		c.stats.Evictions++
+	}
+	if c.capacity > 0 {
+		c.index[movieId] = c.entries.PushFront(&cachedMovie{movieId: movieId, movie: movie, expiresAt: time.Now().Add(ttl)})
+	}
+}
+
+// remove drops an entry. The lock has to be held.
+func (c *MovieCache) remove(element *list.Element) {
+	c.entries.Remove(element)
+	delete(c.index, element.Value.(*cachedMovie).movieId)
+}
+
+// copyMovie returns a copy of the movie, which callers can change without changing the cached one.
+func copyMovie(movie *model.Movie) *model.Movie {
+	copied := *movie
+	copied.Genres = slices.Clone(movie.Genres)
+	copied.Tags = slices.Clone(movie.Tags)
+	if movie.DeletedAt != nil {
+		deletedAt := *movie.DeletedAt
+		copied.DeletedAt = &deletedAt
+	}
+	return &copied
+}
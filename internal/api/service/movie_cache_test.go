@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovieCache_Get(t *testing.T) {
+	repository := &datatest.MovieStore{}
+	repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "The bear", Genres: []model.Genre{{ID: 18, Name: "Drama"}}}, nil)
+	repository.On("Get", 2).Return(nil, data.ErrRecordNotFound)
+	repository.On("Get", 3).Return(nil, errors.New("connection lost"))
+	c := NewMovieCache(repository, 10, time.Hour, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		movie, err := c.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "The bear", movie.MovieName)
+		// callers changing the movie do not change the cached one
+		movie.MovieName = "Changed"
+		movie.Genres[0].Name = "Changed"
+
+		_, err = c.Get(2)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		_, err = c.Get(3)
+		assert.EqualError(t, err, "connection lost")
+	}
+	movie, err := c.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, &model.Movie{MovieId: 1, MovieName: "The bear", Genres: []model.Genre{{ID: 18, Name: "Drama"}}}, movie)
+
+	repository.AssertNumberOfCalls(t, "Get", 1+1+3)
+	assert.Equal(t, MovieCacheStats{Size: 2, Capacity: 10, Hits: 5, NegativeHits: 2, Misses: 5, HitRatio: 0.5}, c.Stats())
+}
+
+func TestMovieCache_Expiry(t *testing.T) {
+	repository := &datatest.MovieStore{}
+	repository.On("Get", 1).Return(&model.Movie{MovieId: 1}, nil)
+	repository.On("Get", 2).Return(nil, data.ErrRecordNotFound)
+	c := NewMovieCache(repository, 10, time.Hour, -time.Second)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(1)
+		require.NoError(t, err)
+		_, err = c.Get(2)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	}
+
+	// the negative entry expired at once
+	repository.AssertNumberOfCalls(t, "Get", 1+2)
+}
+
+func TestMovieCache_Eviction(t *testing.T) {
+	repository := &datatest.MovieStore{}
+	for movieId := 1; movieId <= 3; movieId++ {
+		repository.On("Get", movieId).Return(&model.Movie{MovieId: movieId}, nil)
+	}
+	c := NewMovieCache(repository, 2, time.Hour, time.Hour)
+
+	for _, movieId := range []int{1, 2, 1, 3, 1, 2} {
+		_, err := c.Get(movieId)
+		require.NoError(t, err)
+	}
+
+	// 2 was the least recently used movie when 3 was read, then 3 when 2 was read again
+	assert.Len(t, repository.Calls, 4)
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, int64(2), stats.Evictions)
+}
+
+func TestMovieCache_Invalidation(t *testing.T) {
+	movie := &model.Movie{MovieId: 1, MovieName: "The bear"}
+	repository := &datatest.MovieStore{}
+	repository.On("Get", 1).Return(movie, nil)
+	repository.On("Get", 2).Return(nil, data.ErrRecordNotFound)
+	repository.On("Update", mock.Anything).Return(movie, nil)
+	repository.On("Create", mock.Anything).Return(&model.Movie{MovieId: 2}, nil)
+	repository.On("Delete", 1).Return(nil)
+	c := NewMovieCache(repository, 10, time.Hour, time.Hour)
+	s := NewMovieService(c, nil)
+
+	tests := []struct {
+		name       string
+		movieId    int
+		invalidate func() error
+		// storeCalls are the calls of the store made by the change
+		storeCalls int
+	}{
+		{"update", 1, func() error {
+			_, err := s.Update(context.Background(), &model.Movie{MovieId: 1})
+			return err
+		}, 1},
+		{"delete", 1, func() error { return s.Delete(context.Background(), 1) }, 1},
+		{"create", 2, func() error {
+			_, err := s.Create(context.Background(), &model.Movie{MovieId: 2})
+			return err
+		}, 1},
+		{"event of another replica", 1, func() error {
+			c.HandleMessage([]byte(`{"type":"movie.updated","movie":{"id":1,"title":"The bear"}}`))
+			return nil
+		}, 0},
+		{"change of a rating", 1, func() error {
+			s.Invalidate(1)
+			return nil
+		}, 0},
+		{"flush", 2, func() error {
+			s.InvalidateAll()
+			return nil
+		}, 0},
+		{"change of a rating on another replica", 1, func() error {
+			c.HandleMessage([]byte(`{"type":"movie.invalidated","movie":{"id":1}}`))
+			return nil
+		}, 0},
+		{"flush on another replica", 2, func() error {
+			c.HandleMessage([]byte(`{"type":"movie.invalidated","movie":null}`))
+			return nil
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Get(tt.movieId)
+			s.Get(tt.movieId)
+			calls := len(repository.Calls)
+
+			require.NoError(t, tt.invalidate())
+			s.Get(tt.movieId)
+
+			assert.Len(t, repository.Calls, calls+tt.storeCalls+1, "movie %d should be read again", tt.movieId)
+		})
+	}
+}
+
+func TestMovieCache_InvalidatedWhileReading(t *testing.T) {
+	repository := &datatest.MovieStore{}
+	c := NewMovieCache(repository, 10, time.Hour, time.Hour)
+	// the movie is changed on another replica while it is being read
+	repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "Old"}, nil).Once().
+		Run(func(mock.Arguments) { c.HandleMessage([]byte(`{"type":"movie.updated","movie":{"id":1}}`)) })
+	repository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "New"}, nil).Once()
+
+	movie, err := c.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "Old", movie.MovieName)
+	movie, err = c.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "New", movie.MovieName)
+}
+
+func TestMovieService_InvalidatePublishesEvents(t *testing.T) {
+	publisher := &MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+	var notified []string
+	s := NewMovieService(NewMovieCache(&datatest.MovieStore{}, 10, time.Hour, time.Hour), publisher)
+	s.Subscribe(func(eventType string, _ *model.Movie) { notified = append(notified, eventType) })
+
+	s.Invalidate(1, 2)
+	s.InvalidateAll()
+
+	assert.Equal(t, []string{
+		`{"type":"movie.invalidated","movie":{"id":1,"title":"","overview":""}}`,
+		`{"type":"movie.invalidated","movie":{"id":2,"title":"","overview":""}}`,
+		`{"type":"movie.invalidated","movie":null}`,
+	}, publishedMessages(publisher))
+	assert.Empty(t, notified, "invalidations are not changes of the movies")
+}
+
+func publishedMessages(publisher *MockPublisher) []string {
+	messages := make([]string, len(publisher.Calls))
+	for i, call := range publisher.Calls {
+		messages[i] = call.Arguments.String(0)
+	}
+	return messages
+}
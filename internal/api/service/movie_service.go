@@ -1,75 +1,258 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"rest_api/internal/api/kafka"
 	"rest_api/internal/api/model"
 	"rest_api/internal/data"
+	"time"
 )
 
+// MovieListener is notified in-process of every change of a movie, with the type of the published event. Deleted
+// movies only have their id set.
+type MovieListener func(eventType string, movie *model.Movie)
+
 type MovieService struct {
-	movieRepository data.Repository[*model.Movie]
+	movieRepository data.MovieStore
+	publisher       kafka.Publisher
+	listeners       []MovieListener
+}
+
+func NewMovieService(repository data.MovieStore, publisher kafka.Publisher) *MovieService {
+	return &MovieService{movieRepository: repository, publisher: publisher}
+}
+
+// PublishCreatedMovies returns a listener sending the JSON of every created movie to the publisher, the message the
+// consumers of the movies topic received before the change events were introduced.
+func PublishCreatedMovies(publisher kafka.Publisher) MovieListener {
+	return func(eventType string, movie *model.Movie) {
+		if eventType != model.MovieCreated {
+			return
+		}
+		movieJSON, err := json.Marshal(movie)
+		if err != nil {
+			slog.Error("Error when marshalling movie", "error", err)
+			return
+		}
+		if err = publisher.Publish(string(movieJSON)); err != nil {
+			slog.Error("Error when publishing movie", "movieId", movie.MovieId, "error", err)
+		}
+	}
 }
 
-func NewMovieService(repository data.Repository[*model.Movie]) *MovieService {
-	return &MovieService{movieRepository: repository}
+// Subscribe registers a listener for the changes of movies. Listeners are called synchronously, so they should be
+// quick, and have to be registered before the service is used.
+func (s *MovieService) Subscribe(listener MovieListener) {
+	s.listeners = append(s.listeners, listener)
 }
 
 func (s *MovieService) GetAll() ([]*model.Movie, error) {
 	movies, err := s.movieRepository.GetAll()
 	if err != nil {
-		slog.Error("Error when getting movies from db: %s\n", err)
+		slog.Error("Error when getting movies from db", "error", err)
 		return []*model.Movie{}, err
 	}
 	return movies, nil
 }
 
+func (s *MovieService) List(filter model.MovieFilter) ([]*model.Movie, error) {
+	movies, err := s.movieRepository.List(filter)
+	if err != nil {
+		slog.Error("Error when listing movies from db", "filter", filter, "error", err)
+		return nil, err
+	}
+	return movies, nil
+}
+
+// Stream calls fn for every movie matching the filter, without holding them all in memory. It stops at the first
+// error returned by fn, which is returned as is.
+func (s *MovieService) Stream(ctx context.Context, filter model.MovieFilter, fn func(*model.Movie) error) error {
+	var fnErr error
+	err := s.movieRepository.Stream(ctx, filter, func(movie *model.Movie) error {
+		fnErr = fn(movie)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		slog.Error("Error when streaming movies from db", "filter", filter, "error", err)
+	}
+	return err
+}
+
 func (s *MovieService) Get(movieId int) (*model.Movie, error) {
 	movie, err := s.movieRepository.Get(movieId)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			return nil, model.NotFoundError{}
 		}
-		slog.Error("Error when getting movie with id %s from db: %s\n", err, movieId)
+		slog.Error("Error when getting movie from db", "movieId", movieId, "error", err)
 		return nil, err
 	}
 	return movie, nil
 }
 
-func (s *MovieService) Create(movie *model.Movie) (*model.Movie, error) {
-	createdMovie, err := s.movieRepository.Create(movie)
+// LastModified returns when the movies last changed, deletions and purges included. It never goes back.
+func (s *MovieService) LastModified(ctx context.Context) (time.Time, error) {
+	lastModified, err := s.movieRepository.LastModified(ctx)
+	if err != nil {
+		slog.Error("Error when getting the last modification of movies from db", "error", err)
+		return time.Time{}, err
+	}
+	return lastModified, nil
+}
+
+// GetMany returns the movies with the given ids, leaving out the ones that do not exist or are in the trash.
+func (s *MovieService) GetMany(ctx context.Context, movieIds []int) ([]*model.Movie, error) {
+	movies, err := s.movieRepository.GetMany(ctx, movieIds)
+	if err != nil {
+		slog.Error("Error when getting movies from db", "movieIds", movieIds, "error", err)
+		return nil, err
+	}
+	return movies, nil
+}
+
+func (s *MovieService) Create(ctx context.Context, movie *model.Movie) (*model.Movie, error) {
+	createdMovie, err := s.movieRepository.Create(ctx, movie)
 
 	if err != nil {
 		if errors.Is(err, data.ErrRecordExists) {
 			return nil, model.ConflictError{}
 		}
-		slog.Error("Unable to create movie in the database: error: %s\n", err)
+		slog.Error("Unable to create movie in the database", "error", err)
 		return nil, err
 	}
+	s.publish(model.MovieCreated, createdMovie)
 	return createdMovie, nil
 }
 
-func (s *MovieService) Update(movie *model.Movie) (*model.Movie, error) {
-	updatedMovie, err := s.movieRepository.Update(movie)
+// CreateBatch creates the movies in bulk, skipping those whose id already exists. An event is published for
+// every created movie.
+func (s *MovieService) CreateBatch(ctx context.Context, movies []*model.Movie) ([]*model.Movie, error) {
+	if len(movies) == 0 {
+		return nil, nil
+	}
+	createdMovies, err := s.movieRepository.CreateBatch(ctx, movies)
+	if err != nil {
+		slog.Error("Unable to create movies in the database", "count", len(movies), "error", err)
+		return nil, err
+	}
+	for _, movie := range createdMovies {
+		s.publish(model.MovieCreated, movie)
+	}
+	return createdMovies, nil
+}
+
+func (s *MovieService) Update(ctx context.Context, movie *model.Movie) (*model.Movie, error) {
+	updatedMovie, err := s.movieRepository.Update(ctx, movie)
 
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			return nil, model.NotFoundError{}
 		}
-		slog.Error("Error when updating movie with id %s in the db: %s\n", err, movie.MovieId)
+		slog.Error("Error when updating movie in the db", "movieId", movie.MovieId, "error", err)
 		return nil, err
 	}
+	s.publish(model.MovieUpdated, updatedMovie)
 	return updatedMovie, nil
 }
 
-func (s *MovieService) Delete(movieId int) error {
-	err := s.movieRepository.Delete(movieId)
+// Delete moves the movie to the trash, from which it can be restored until it is purged.
+func (s *MovieService) Delete(ctx context.Context, movieId int) error {
+	err := s.movieRepository.Delete(ctx, movieId)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
-			return nil
+			return model.NotFoundError{}
 		}
-		slog.Error("Error when deleting movie in db: %s\n", err)
+		slog.Error("Error when deleting movie in db", "error", err)
 		return err
 	}
+	s.publish(model.MovieDeleted, &model.Movie{MovieId: movieId})
 	return nil
 }
+
+func (s *MovieService) GetTrash() ([]*model.Movie, error) {
+	movies, err := s.movieRepository.GetTrash()
+	if err != nil {
+		slog.Error("Error when getting deleted movies from db", "error", err)
+		return nil, err
+	}
+	return movies, nil
+}
+
+func (s *MovieService) Restore(ctx context.Context, movieId int) (*model.Movie, error) {
+	movie, err := s.movieRepository.Restore(ctx, movieId)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when restoring movie in db", "movieId", movieId, "error", err)
+		return nil, err
+	}
+	s.publish(model.MovieRestored, movie)
+	return movie, nil
+}
+
+// movieCache is implemented by the movie stores keeping movies in memory, such as MovieCache.
+type movieCache interface {
+	Invalidate(movieIds ...int)
+	Flush()
+}
+
+// Invalidate drops the movies from the cache of the store, if it has one, after a change stored by another
+// repository, such as the ones of their ratings or tags. The other replicas drop them on the invalidation events
+// published for them.
+func (s *MovieService) Invalidate(movieIds ...int) {
+	if cache, ok := s.movieRepository.(movieCache); ok {
+		cache.Invalidate(movieIds...)
+	}
+	for _, movieId := range movieIds {
+		s.send(model.MovieInvalidated, &model.Movie{MovieId: movieId})
+	}
+}
+
+// InvalidateAll drops all the movies from the cache of the store, if it has one, after a change of any number of
+// movies, such as the renaming of a tag. The other replicas drop theirs on the invalidation event without a movie.
+func (s *MovieService) InvalidateAll() {
+	if cache, ok := s.movieRepository.(movieCache); ok {
+		cache.Flush()
+	}
+	s.send(model.MovieInvalidated, nil)
+}
+
+// PurgeTrash permanently removes the movies that have been in the trash for longer than the retention period and
+// returns their ids. Their assets are left in the storage, see AssetService.RemoveObjects.
+func (s *MovieService) PurgeTrash(ctx context.Context, retention time.Duration) ([]int, error) {
+	purged, err := s.movieRepository.Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		slog.Error("Error when purging deleted movies from db", "error", err)
+		return nil, err
+	}
+	slog.Info("Purged deleted movies", "count", len(purged))
+	return purged, nil
+}
+
+// publish notifies the listeners and sends a change event for the movie. The change is already persisted at this point,
+// so a failure is only logged.
+func (s *MovieService) publish(eventType string, movie *model.Movie) {
+	for _, listener := range s.listeners {
+		listener(eventType, movie)
+	}
+	s.send(eventType, movie)
+}
+
+// send publishes an event to the movie-events topic, without notifying the listeners. A failure is only logged.
+func (s *MovieService) send(eventType string, movie *model.Movie) {
+	if s.publisher == nil {
+		return
+	}
+	eventJSON, err := json.Marshal(model.MovieEvent{Type: eventType, Movie: movie})
+	if err != nil {
+		slog.Error("Error when marshalling movie event", "error", err)
+		return
+	}
+	if err = s.publisher.Publish(string(eventJSON)); err != nil {
+		slog.Error("Error when publishing movie event", "type", eventType, "error", err)
+	}
+}
@@ -1,58 +1,20 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"reflect"
 	"rest_api/internal/api/model"
 	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
 	"testing"
 )
 
-type MockRepository struct {
-	mock.Mock
-}
-
-func (r *MockRepository) GetAll() ([]*model.Movie, error) {
-	args := r.Called()
-	return args.Get(0).([]*model.Movie), args.Error(1)
-}
-
-func (r *MockRepository) Get(movieId int) (*model.Movie, error) {
-	args := r.Called(movieId)
-	arg1 := args.Get(0)
-	if arg1 != nil {
-		return arg1.(*model.Movie), args.Error(1)
-	}
-	return nil, args.Error(1)
-}
-
-func (r *MockRepository) Create(movie *model.Movie) (*model.Movie, error) {
-	args := r.Called(movie)
-	arg1 := args.Get(0)
-	if arg1 != nil {
-		return arg1.(*model.Movie), args.Error(1)
-	}
-	return nil, args.Error(1)
-}
-
-func (r *MockRepository) Update(movie *model.Movie) (*model.Movie, error) {
-	args := r.Called(movie)
-	arg1 := args.Get(0)
-	if arg1 != nil {
-		return arg1.(*model.Movie), args.Error(1)
-	}
-	return nil, args.Error(1)
-}
-
-func (r *MockRepository) Delete(movieId int) error {
-	args := r.Called(movieId)
-	return args.Error(0)
-}
-
 func TestMovieService_GetAll(t *testing.T) {
 	randErr := errors.New("random")
-	mockRepository := MockRepository{}
+	mockRepository := datatest.MovieStore{}
 	s := &MovieService{
 		movieRepository: &mockRepository,
 	}
@@ -60,13 +22,13 @@ func TestMovieService_GetAll(t *testing.T) {
 		name     string
 		want     []*model.Movie
 		wantErr  error
-		mockFunc func(r *MockRepository) *mock.Call
+		mockFunc func(r *datatest.MovieStore) *mock.Call
 	}{
 		{
 			"success",
 			[]*model.Movie{{MovieId: 1}},
 			nil,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("GetAll", mock.Anything).Return([]*model.Movie{{MovieId: 1}}, nil)
 			},
 		},
@@ -74,7 +36,7 @@ func TestMovieService_GetAll(t *testing.T) {
 			"other error",
 			[]*model.Movie{},
 			randErr,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("GetAll", mock.Anything).Return([]*model.Movie{}, randErr)
 			},
 		},
@@ -103,7 +65,7 @@ func TestMovieService_GetAll(t *testing.T) {
 
 func TestMovieService_Get(t *testing.T) {
 	randErr := errors.New("random")
-	mockRepository := MockRepository{}
+	mockRepository := datatest.MovieStore{}
 	s := &MovieService{
 		movieRepository: &mockRepository,
 	}
@@ -112,14 +74,14 @@ func TestMovieService_Get(t *testing.T) {
 		input    int
 		want     *model.Movie
 		wantErr  error
-		mockFunc func(r *MockRepository) *mock.Call
+		mockFunc func(r *datatest.MovieStore) *mock.Call
 	}{
 		{
 			"success",
 			1,
 			&model.Movie{MovieId: 1},
 			nil,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Get", mock.Anything).Return(&model.Movie{MovieId: 1}, nil)
 			},
 		},
@@ -128,7 +90,7 @@ func TestMovieService_Get(t *testing.T) {
 			1,
 			nil,
 			model.NotFoundError{},
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Get", mock.Anything).Return(nil, data.ErrRecordNotFound)
 			},
 		},
@@ -137,7 +99,7 @@ func TestMovieService_Get(t *testing.T) {
 			1,
 			nil,
 			randErr,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Get", mock.Anything).Return(nil, randErr)
 			},
 		},
@@ -166,7 +128,7 @@ func TestMovieService_Get(t *testing.T) {
 
 func TestMovieService_Create(t *testing.T) {
 	randErr := errors.New("random")
-	mockRepository := MockRepository{}
+	mockRepository := datatest.MovieStore{}
 	s := &MovieService{
 		movieRepository: &mockRepository,
 	}
@@ -175,15 +137,15 @@ func TestMovieService_Create(t *testing.T) {
 		input    *model.Movie
 		want     *model.Movie
 		wantErr  error
-		mockFunc func(r *MockRepository) *mock.Call
+		mockFunc func(r *datatest.MovieStore) *mock.Call
 	}{
 		{
 			"success",
 			&model.Movie{},
 			&model.Movie{MovieId: 1},
 			nil,
-			func(r *MockRepository) *mock.Call {
-				return r.On("Configure", mock.Anything).Return(&model.Movie{MovieId: 1}, nil)
+			func(r *datatest.MovieStore) *mock.Call {
+				return r.On("Create", mock.Anything).Return(&model.Movie{MovieId: 1}, nil)
 			},
 		},
 		{
@@ -191,8 +153,8 @@ func TestMovieService_Create(t *testing.T) {
 			&model.Movie{},
 			nil,
 			model.ConflictError{},
-			func(r *MockRepository) *mock.Call {
-				return r.On("Configure", mock.Anything).Return(nil, data.ErrRecordExists)
+			func(r *datatest.MovieStore) *mock.Call {
+				return r.On("Create", mock.Anything).Return(nil, data.ErrRecordExists)
 			},
 		},
 		{
@@ -200,8 +162,8 @@ func TestMovieService_Create(t *testing.T) {
 			&model.Movie{},
 			nil,
 			randErr,
-			func(r *MockRepository) *mock.Call {
-				return r.On("Configure", mock.Anything).Return(nil, randErr)
+			func(r *datatest.MovieStore) *mock.Call {
+				return r.On("Create", mock.Anything).Return(nil, randErr)
 			},
 		},
 	}
@@ -209,27 +171,48 @@ func TestMovieService_Create(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockCall := tt.mockFunc(&mockRepository)
 			defer mockCall.Unset()
-			got, err := s.Create(tt.input)
+			got, err := s.Create(context.Background(), tt.input)
 			if tt.wantErr != nil {
 				if !errors.Is(err, tt.wantErr) {
-					t.Errorf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+					t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
 					return
 				}
 			} else {
 				if err != nil {
-					t.Errorf("Configure() error = %v, wantErr is nil", err)
+					t.Errorf("Create() error = %v, wantErr is nil", err)
 				}
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Configure() got = %v, want %v", got, tt.want)
+				t.Errorf("Create() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestPublishCreatedMovies(t *testing.T) {
+	publisher := &MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+	events := &MockPublisher{}
+	events.On("Publish", mock.Anything).Return(nil)
+	mockRepository := &datatest.MovieStore{}
+	mockRepository.On("Create", mock.Anything).Return(&model.Movie{MovieId: 1, MovieName: "The bear"}, nil)
+	mockRepository.On("Delete", 1).Return(nil)
+
+	s := NewMovieService(mockRepository, events)
+	s.Subscribe(PublishCreatedMovies(publisher))
+	_, err := s.Create(context.Background(), &model.Movie{MovieId: 1, MovieName: "The bear"})
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(context.Background(), 1))
+
+	publisher.AssertCalled(t, "Publish", `{"id":1,"title":"The bear","overview":""}`)
+	publisher.AssertNumberOfCalls(t, "Publish", 1)
+	events.AssertCalled(t, "Publish", `{"type":"movie.created","movie":{"id":1,"title":"The bear","overview":""}}`)
+	events.AssertCalled(t, "Publish", `{"type":"movie.deleted","movie":{"id":1,"title":"","overview":""}}`)
+}
+
 func TestMovieService_Update(t *testing.T) {
 	randErr := errors.New("random")
-	mockRepository := MockRepository{}
+	mockRepository := datatest.MovieStore{}
 	s := &MovieService{
 		movieRepository: &mockRepository,
 	}
@@ -238,14 +221,14 @@ func TestMovieService_Update(t *testing.T) {
 		input    *model.Movie
 		want     *model.Movie
 		wantErr  error
-		mockFunc func(r *MockRepository) *mock.Call
+		mockFunc func(r *datatest.MovieStore) *mock.Call
 	}{
 		{
 			"success",
 			&model.Movie{},
 			&model.Movie{MovieId: 1},
 			nil,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Update", mock.Anything).Return(&model.Movie{MovieId: 1}, nil)
 			},
 		},
@@ -254,7 +237,7 @@ func TestMovieService_Update(t *testing.T) {
 			&model.Movie{},
 			nil,
 			model.NotFoundError{},
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Update", mock.Anything).Return(nil, data.ErrRecordNotFound)
 			},
 		},
@@ -263,7 +246,7 @@ func TestMovieService_Update(t *testing.T) {
 			&model.Movie{},
 			nil,
 			randErr,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Update", mock.Anything).Return(nil, randErr)
 			},
 		},
@@ -272,7 +255,7 @@ func TestMovieService_Update(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockCall := tt.mockFunc(&mockRepository)
 			defer mockCall.Unset()
-			got, err := s.Update(tt.input)
+			got, err := s.Update(context.Background(), tt.input)
 			if tt.wantErr != nil {
 				if !errors.Is(err, tt.wantErr) {
 					t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
@@ -292,7 +275,7 @@ func TestMovieService_Update(t *testing.T) {
 
 func TestMovieService_Delete(t *testing.T) {
 	randErr := errors.New("random")
-	mockRepository := MockRepository{}
+	mockRepository := datatest.MovieStore{}
 	s := &MovieService{
 		movieRepository: &mockRepository,
 	}
@@ -300,21 +283,21 @@ func TestMovieService_Delete(t *testing.T) {
 		name     string
 		input    int
 		wantErr  error
-		mockFunc func(r *MockRepository) *mock.Call
+		mockFunc func(r *datatest.MovieStore) *mock.Call
 	}{
 		{
 			"success",
 			1,
 			nil,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Delete", mock.Anything).Return(nil)
 			},
 		},
 		{
 			"not found",
 			1,
-			nil,
-			func(r *MockRepository) *mock.Call {
+			model.NotFoundError{},
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Delete", mock.Anything).Return(data.ErrRecordNotFound)
 			},
 		},
@@ -322,7 +305,7 @@ func TestMovieService_Delete(t *testing.T) {
 			"other error",
 			1,
 			randErr,
-			func(r *MockRepository) *mock.Call {
+			func(r *datatest.MovieStore) *mock.Call {
 				return r.On("Delete", mock.Anything).Return(randErr)
 			},
 		},
@@ -331,7 +314,7 @@ func TestMovieService_Delete(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockCall := tt.mockFunc(&mockRepository)
 			defer mockCall.Unset()
-			err := s.Delete(tt.input)
+			err := s.Delete(context.Background(), tt.input)
 			if tt.wantErr != nil {
 				if !errors.Is(err, tt.wantErr) {
 					t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
@@ -345,3 +328,26 @@ func TestMovieService_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestMovieService_Restore(t *testing.T) {
+	mockRepository := &datatest.MovieStore{}
+	mockRepository.On("Restore", 1).Return(&model.Movie{MovieId: 1, MovieName: "The bear"}, nil)
+	mockRepository.On("Restore", 2).Return(nil, data.ErrRecordNotFound)
+	publisher := &MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+	s := NewMovieService(mockRepository, publisher)
+
+	movie, err := s.Restore(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Restore() error = %v, wantErr is nil", err)
+	}
+	if !reflect.DeepEqual(movie, &model.Movie{MovieId: 1, MovieName: "The bear"}) {
+		t.Errorf("Restore() got = %v", movie)
+	}
+	publisher.AssertCalled(t, "Publish", `{"type":"movie.restored","movie":{"id":1,"title":"The bear","overview":""}}`)
+
+	_, err = s.Restore(context.Background(), 2)
+	if !errors.Is(err, model.NotFoundError{}) {
+		t.Errorf("Restore() error = %v, want not found", err)
+	}
+}
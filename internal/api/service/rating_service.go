@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"strings"
+)
+
+const maxReviewLength = 5000
+
+type ratingRepository interface {
+	ListForUser(ctx context.Context, username string, movieIds []int) ([]*model.Rating, error)
+	Create(ctx context.Context, rating *model.Rating) (*model.Rating, error)
+	Update(ctx context.Context, rating *model.Rating) (*model.Rating, error)
+	Delete(ctx context.Context, movieId int, username string) error
+}
+
+type reviewRepository interface {
+	List(ctx context.Context, movieId int, includeHidden bool) ([]*model.Review, error)
+	Create(ctx context.Context, review *model.Review) (*model.Review, error)
+	Update(ctx context.Context, review *model.Review) (*model.Review, error)
+	Delete(ctx context.Context, movieId int, username string) error
+	SetHidden(ctx context.Context, reviewId int, hiddenBy string) (*model.Review, error)
+}
+
+// RatingListener is notified in-process of every change of the rating of a user.
+type RatingListener func(username string, movieId int)
+
+// RatingService manages the ratings and reviews users give to movies.
+type RatingService struct {
+	ratingRepository ratingRepository
+	reviewRepository reviewRepository
+	movieService     *MovieService
+	listeners        []RatingListener
+}
+
+func NewRatingService(ratingRepository ratingRepository, reviewRepository reviewRepository, movieService *MovieService) *RatingService {
+	return &RatingService{ratingRepository: ratingRepository, reviewRepository: reviewRepository, movieService: movieService}
+}
+
+// Subscribe registers a listener for the rating changes. Listeners are called synchronously and have to be
+// registered before the service is used.
+func (s *RatingService) Subscribe(listener RatingListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// UserRatings returns the ratings of the user, limited to the given movies unless movieIds is nil.
+func (s *RatingService) UserRatings(ctx context.Context, username string, movieIds []int) ([]*model.Rating, error) {
+	ratings, err := s.ratingRepository.ListForUser(ctx, username, movieIds)
+	if err != nil {
+		slog.Error("Error when getting ratings from db", "username", username, "error", err)
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// Rate records the first rating of the user for the movie.
+func (s *RatingService) Rate(ctx context.Context, rating *model.Rating) (*model.Rating, error) {
+	if err := s.validateRating(rating); err != nil {
+		return nil, err
+	}
+	created, err := s.ratingRepository.Create(ctx, rating)
+	if err != nil {
+		return nil, s.ratingError(err, rating.MovieId)
+	}
+	s.notify(rating.Username, rating.MovieId)
+	return created, nil
+}
+
+// UpdateRating changes the rating the user already gave to the movie.
+func (s *RatingService) UpdateRating(ctx context.Context, rating *model.Rating) (*model.Rating, error) {
+	if err := s.validateRating(rating); err != nil {
+		return nil, err
+	}
+	updated, err := s.ratingRepository.Update(ctx, rating)
+	if err != nil {
+		return nil, s.ratingError(err, rating.MovieId)
+	}
+	s.notify(rating.Username, rating.MovieId)
+	return updated, nil
+}
+
+func (s *RatingService) DeleteRating(ctx context.Context, movieId int, username string) error {
+	if err := s.ratingRepository.Delete(ctx, movieId, username); err != nil {
+		return s.ratingError(err, movieId)
+	}
+	s.notify(username, movieId)
+	return nil
+}
+
+// GetReviews returns the reviews of the movie. Hidden reviews are only included for moderators.
+func (s *RatingService) GetReviews(ctx context.Context, movieId int, includeHidden bool) ([]*model.Review, error) {
+	if _, err := s.movieService.Get(movieId); err != nil {
+		return nil, err
+	}
+	reviews, err := s.reviewRepository.List(ctx, movieId, includeHidden)
+	if err != nil {
+		slog.Error("Error when getting reviews from db", "movieId", movieId, "error", err)
+		return nil, err
+	}
+	return reviews, nil
+}
+
+func (s *RatingService) CreateReview(ctx context.Context, review *model.Review) (*model.Review, error) {
+	if err := s.validateReview(review); err != nil {
+		return nil, err
+	}
+	created, err := s.reviewRepository.Create(ctx, review)
+	if err != nil {
+		return nil, s.ratingError(err, review.MovieId)
+	}
+	return created, nil
+}
+
+func (s *RatingService) UpdateReview(ctx context.Context, review *model.Review) (*model.Review, error) {
+	if err := s.validateReview(review); err != nil {
+		return nil, err
+	}
+	updated, err := s.reviewRepository.Update(ctx, review)
+	if err != nil {
+		return nil, s.ratingError(err, review.MovieId)
+	}
+	return updated, nil
+}
+
+func (s *RatingService) DeleteReview(ctx context.Context, movieId int, username string) error {
+	if err := s.reviewRepository.Delete(ctx, movieId, username); err != nil {
+		return s.ratingError(err, movieId)
+	}
+	return nil
+}
+
+// SetReviewHidden hides a review on behalf of the moderator, or shows it again.
+func (s *RatingService) SetReviewHidden(ctx context.Context, reviewId int, moderator string, hidden bool) (*model.Review, error) {
+	hiddenBy := ""
+	if hidden {
+		hiddenBy = moderator
+	}
+	review, err := s.reviewRepository.SetHidden(ctx, reviewId, hiddenBy)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when moderating review in db", "reviewId", reviewId, "error", err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// validateRating checks the rating and that the movie exists and is not in the trash.
+func (s *RatingService) validateRating(rating *model.Rating) error {
+	if rating.Rating < model.MinRating || rating.Rating > model.MaxRating {
+		return model.ValidationError{Message: fmt.Sprintf("rating should be between %d and %d", model.MinRating, model.MaxRating)}
+	}
+	_, err := s.movieService.Get(rating.MovieId)
+	return err
+}
+
+func (s *RatingService) validateReview(review *model.Review) error {
+	review.Text = strings.TrimSpace(review.Text)
+	if review.Text == "" {
+		return model.ValidationError{Message: "text should be present"}
+	}
+	if len(review.Text) > maxReviewLength {
+		return model.ValidationError{Message: fmt.Sprintf("text should be at most %d characters", maxReviewLength)}
+	}
+	_, err := s.movieService.Get(review.MovieId)
+	return err
+}
+
+func (s *RatingService) notify(username string, movieId int) {
+	for _, listener := range s.listeners {
+		listener(username, movieId)
+	}
+}
+
+// ratingError maps the repository errors of ratings and reviews.
+func (s *RatingService) ratingError(err error, movieId int) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return model.NotFoundError{}
+	case errors.Is(err, data.ErrRecordExists):
+		return model.ConflictError{}
+	}
+	slog.Error("Error when changing rating or review in db", "movieId", movieId, "error", err)
+	return err
+}
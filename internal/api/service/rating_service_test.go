@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRatingRepository struct {
+	mock.Mock
+}
+
+func (r *MockRatingRepository) ListForUser(_ context.Context, username string, movieIds []int) ([]*model.Rating, error) {
+	args := r.Called(username, movieIds)
+	return args.Get(0).([]*model.Rating), args.Error(1)
+}
+
+func (r *MockRatingRepository) Create(_ context.Context, rating *model.Rating) (*model.Rating, error) {
+	args := r.Called(rating)
+	return rating, args.Error(0)
+}
+
+func (r *MockRatingRepository) Update(_ context.Context, rating *model.Rating) (*model.Rating, error) {
+	args := r.Called(rating)
+	return rating, args.Error(0)
+}
+
+func (r *MockRatingRepository) Delete(_ context.Context, movieId int, username string) error {
+	args := r.Called(movieId, username)
+	return args.Error(0)
+}
+
+type MockReviewRepository struct {
+	mock.Mock
+}
+
+func (r *MockReviewRepository) List(_ context.Context, movieId int, includeHidden bool) ([]*model.Review, error) {
+	args := r.Called(movieId, includeHidden)
+	return args.Get(0).([]*model.Review), args.Error(1)
+}
+
+func (r *MockReviewRepository) Create(_ context.Context, review *model.Review) (*model.Review, error) {
+	args := r.Called(review)
+	return review, args.Error(0)
+}
+
+func (r *MockReviewRepository) Update(_ context.Context, review *model.Review) (*model.Review, error) {
+	args := r.Called(review)
+	return review, args.Error(0)
+}
+
+func (r *MockReviewRepository) Delete(_ context.Context, movieId int, username string) error {
+	args := r.Called(movieId, username)
+	return args.Error(0)
+}
+
+func (r *MockReviewRepository) SetHidden(_ context.Context, reviewId int, hiddenBy string) (*model.Review, error) {
+	args := r.Called(reviewId, hiddenBy)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*model.Review), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestRatingService_Rate(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 10).Return(&model.Movie{MovieId: 10}, nil)
+	movieRepository.On("Get", 12).Return(nil, data.ErrRecordNotFound)
+	ratings := &MockRatingRepository{}
+	ratings.On("Create", &model.Rating{MovieId: 10, Username: "alice", Rating: 8}).Return(nil)
+	ratings.On("Create", &model.Rating{MovieId: 10, Username: "bob", Rating: 8}).Return(data.ErrRecordExists)
+	s := NewRatingService(ratings, nil, NewMovieService(movieRepository, nil))
+
+	tests := []struct {
+		name    string
+		rating  *model.Rating
+		wantErr error
+	}{
+		{"rated", &model.Rating{MovieId: 10, Username: "alice", Rating: 8}, nil},
+		{"already rated", &model.Rating{MovieId: 10, Username: "bob", Rating: 8}, model.ConflictError{}},
+		{"too low", &model.Rating{MovieId: 10, Username: "alice", Rating: 0}, model.ValidationError{Message: "rating should be between 1 and 10"}},
+		{"too high", &model.Rating{MovieId: 10, Username: "alice", Rating: 11}, model.ValidationError{Message: "rating should be between 1 and 10"}},
+		{"unknown movie", &model.Rating{MovieId: 12, Username: "alice", Rating: 5}, model.NotFoundError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Rate(context.Background(), tt.rating)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	ratings.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func TestRatingService_CreateReview(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 10).Return(&model.Movie{MovieId: 10}, nil)
+	reviews := &MockReviewRepository{}
+	reviews.On("Create", mock.Anything).Return(nil)
+	s := NewRatingService(nil, reviews, NewMovieService(movieRepository, nil))
+
+	review, err := s.CreateReview(context.Background(), &model.Review{MovieId: 10, Username: "alice", Text: "  Great  "})
+	require.NoError(t, err)
+	assert.Equal(t, "Great", review.Text)
+
+	_, err = s.CreateReview(context.Background(), &model.Review{MovieId: 10, Username: "alice", Text: " "})
+	assert.ErrorIs(t, err, model.ValidationError{Message: "text should be present"})
+
+	_, err = s.CreateReview(context.Background(), &model.Review{MovieId: 10, Username: "alice", Text: strings.Repeat("a", maxReviewLength+1)})
+	assert.ErrorIs(t, err, model.ValidationError{Message: "text should be at most 5000 characters"})
+	reviews.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestRatingService_SetReviewHidden(t *testing.T) {
+	reviews := &MockReviewRepository{}
+	reviews.On("SetHidden", 1, "admin").Return(&model.Review{ID: 1, HiddenBy: "admin"}, nil)
+	reviews.On("SetHidden", 1, "").Return(&model.Review{ID: 1}, nil)
+	reviews.On("SetHidden", 2, "admin").Return(nil, data.ErrRecordNotFound)
+	s := NewRatingService(nil, reviews, nil)
+
+	review, err := s.SetReviewHidden(context.Background(), 1, "admin", true)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", review.HiddenBy)
+
+	review, err = s.SetReviewHidden(context.Background(), 1, "admin", false)
+	require.NoError(t, err)
+	assert.Empty(t, review.HiddenBy)
+
+	_, err = s.SetReviewHidden(context.Background(), 2, "admin", true)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+}
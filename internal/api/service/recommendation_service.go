@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/recommend"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRecommendations is the number of recommendations computed and cached for a user.
+	maxRecommendations = 50
+	// popularPoolSize is the number of popular movies the fallback recommendations are picked from.
+	popularPoolSize = 200
+	// likedRating is the lowest rating counted in the genre affinity of a user.
+	likedRating = 7
+	// recommendationGenreWeight is the share of the genre affinity in the score of fallback recommendations.
+	recommendationGenreWeight = 0.5
+	// maxCachedUsers is the number of cached users beyond which expired entries are evicted.
+	maxCachedUsers = 10000
+)
+
+type recommendationRepository interface {
+	ListRatings(ctx context.Context) ([]*model.Rating, error)
+	ReplaceNeighbors(ctx context.Context, neighbors []model.MovieNeighbor) error
+	Neighbors(ctx context.Context, movieIds []int) ([]model.MovieNeighbor, error)
+	History(ctx context.Context, username string, likedRating int) (*model.UserHistory, error)
+	Popular(ctx context.Context, limit int) ([]*model.Movie, error)
+}
+
+type cachedRecommendations struct {
+	recommendations []*model.Recommendation
+	expiresAt       time.Time
+}
+
+// RecommendationService recommends movies to users by item-based collaborative filtering over the precomputed
+// item-item similarity matrix. Users with too few ratings, or too few recommendations from them, get popular movies
+// of the genres they like. Recommendations are cached per user until they change a rating or a watched movie, or
+// the matrix is recomputed on this replica, and for at most the cache TTL otherwise.
+type RecommendationService struct {
+	repository       recommendationRepository
+	neighborhoodSize int
+	minOverlap       int
+	minRatings       int
+	cacheTTL         time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedRecommendations
+	// generation changes on every invalidation, so that recommendations computed meanwhile are not cached.
+	generation int
+}
+
+// NewRecommendationService creates the service and subscribes it to the rating and watched movie changes, which
+// invalidate the recommendations of the user. neighborhoodSize is the number of similar movies kept per movie,
+// minOverlap the number of common raters for two movies to be compared and minRatings the number of ratings from
+// which recommendations are based on ratings.
+func NewRecommendationService(repository recommendationRepository, ratingService *RatingService,
+	watchlistService *WatchlistService, neighborhoodSize, minOverlap, minRatings int, cacheTTL time.Duration) *RecommendationService {
+	s := &RecommendationService{
+		repository:       repository,
+		neighborhoodSize: neighborhoodSize,
+		minOverlap:       minOverlap,
+		minRatings:       minRatings,
+		cacheTTL:         cacheTTL,
+		cache:            map[string]cachedRecommendations{},
+	}
+	ratingService.Subscribe(func(username string, _ int) { s.Invalidate(username) })
+	watchlistService.Subscribe(s.Invalidate)
+	return s
+}
+
+// Recommend returns up to limit movies for the user, best first, leaving out the movies they rated or watched.
+func (s *RecommendationService) Recommend(ctx context.Context, username string, limit int) ([]*model.Recommendation, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[username]
+	generation := s.generation
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.recommendations[:min(limit, len(cached.recommendations))], nil
+	}
+
+	recommendations, err := s.compute(ctx, username)
+	if err != nil {
+		slog.Error("Error when computing recommendations", "username", username, "error", err)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		if len(s.cache) >= maxCachedUsers {
+			s.evictExpired()
+		}
+		s.cache[username] = cachedRecommendations{recommendations: recommendations, expiresAt: time.Now().Add(s.cacheTTL)}
+	}
+	s.mu.Unlock()
+	return recommendations[:min(limit, len(recommendations))], nil
+}
+
+// Invalidate drops the cached recommendations of the user.
+func (s *RecommendationService) Invalidate(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, username)
+	s.generation++
+}
+
+// ComputeMatrix recomputes the item-item similarity matrix from the ratings of all users.
+func (s *RecommendationService) ComputeMatrix(ctx context.Context) error {
+	ratings, err := s.repository.ListRatings(ctx)
+	if err != nil {
+		slog.Error("Error when getting ratings from db", "error", err)
+		return err
+	}
+	neighbors := recommend.Neighbors(ratings, s.neighborhoodSize, s.minOverlap)
+	if err = s.repository.ReplaceNeighbors(ctx, neighbors); err != nil {
+		slog.Error("Error when storing similarity matrix in db", "error", err)
+		return err
+	}
+
+	s.mu.Lock()
+	clear(s.cache)
+	s.generation++
+	s.mu.Unlock()
+	slog.Info("Computed similarity matrix", "ratings", len(ratings), "neighbors", len(neighbors))
+	return nil
+}
+
+func (s *RecommendationService) compute(ctx context.Context, username string) ([]*model.Recommendation, error) {
+	history, err := s.repository.History(ctx, username, likedRating)
+	if err != nil {
+		return nil, err
+	}
+	exclude := map[int]bool{}
+	rated := make([]int, 0, len(history.Ratings))
+	for movieId := range history.Ratings {
+		exclude[movieId] = true
+		rated = append(rated, movieId)
+	}
+	for _, movieId := range history.Watched {
+		exclude[movieId] = true
+	}
+
+	recommendations := []*model.Recommendation{}
+	if len(history.Ratings) >= s.minRatings {
+		neighbors, err := s.repository.Neighbors(ctx, rated)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = recommend.FromRatings(history.Ratings, neighbors, exclude)
+	}
+
+	if len(recommendations) < maxRecommendations {
+		popular, err := s.repository.Popular(ctx, popularPoolSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, recommendation := range recommendations {
+			exclude[recommendation.MovieId] = true
+		}
+		recommendations = append(recommendations,
+			recommend.FromPopularity(popular, history.GenreAffinity, recommendationGenreWeight, exclude)...)
+	}
+	return recommendations[:min(len(recommendations), maxRecommendations)], nil
+}
+
+// evictExpired removes the expired cache entries. The lock has to be held.
+func (s *RecommendationService) evictExpired() {
+	now := time.Now()
+	maps.DeleteFunc(s.cache, func(_ string, cached cachedRecommendations) bool { return now.After(cached.expiresAt) })
+}
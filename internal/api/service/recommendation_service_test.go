@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRecommendationRepository struct {
+	mock.Mock
+}
+
+func (r *MockRecommendationRepository) ListRatings(_ context.Context) ([]*model.Rating, error) {
+	args := r.Called()
+	return args.Get(0).([]*model.Rating), args.Error(1)
+}
+
+func (r *MockRecommendationRepository) ReplaceNeighbors(_ context.Context, neighbors []model.MovieNeighbor) error {
+	args := r.Called(neighbors)
+	return args.Error(0)
+}
+
+func (r *MockRecommendationRepository) Neighbors(_ context.Context, movieIds []int) ([]model.MovieNeighbor, error) {
+	args := r.Called(movieIds)
+	return args.Get(0).([]model.MovieNeighbor), args.Error(1)
+}
+
+func (r *MockRecommendationRepository) History(_ context.Context, username string, likedRating int) (*model.UserHistory, error) {
+	args := r.Called(username, likedRating)
+	return args.Get(0).(*model.UserHistory), args.Error(1)
+}
+
+func (r *MockRecommendationRepository) Popular(_ context.Context, limit int) ([]*model.Movie, error) {
+	args := r.Called(limit)
+	return args.Get(0).([]*model.Movie), args.Error(1)
+}
+
+func TestRecommendationService_Recommend(t *testing.T) {
+	repository := &MockRecommendationRepository{}
+	repository.On("History", "alice", likedRating).Return(&model.UserHistory{
+		Ratings: map[int]int{1: 9, 2: 8, 3: 3}, Watched: []int{4}, GenreAffinity: map[int]int{18: 2}}, nil)
+	repository.On("History", "bob", likedRating).Return(&model.UserHistory{
+		Ratings: map[int]int{1: 9}, GenreAffinity: map[int]int{35: 1}}, nil)
+	repository.On("Neighbors", mock.Anything).Return([]model.MovieNeighbor{
+		{MovieId: 1, Neighbor: model.MovieSummary{MovieId: 5, MovieName: "Heat"}, Similarity: 0.8},
+		{MovieId: 3, Neighbor: model.MovieSummary{MovieId: 6, MovieName: "Cats"}, Similarity: 0.9},
+		{MovieId: 2, Neighbor: model.MovieSummary{MovieId: 4, MovieName: "Watched"}, Similarity: 0.9},
+	}, nil)
+	repository.On("Popular", popularPoolSize).Return([]*model.Movie{
+		{MovieId: 1}, {MovieId: 7, Genres: []model.Genre{{ID: 35}}}, {MovieId: 5}, {MovieId: 8, Genres: []model.Genre{{ID: 18}}},
+	}, nil)
+	ratingRepository := &MockRatingRepository{}
+	ratingRepository.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	ratingService := NewRatingService(ratingRepository, nil, nil)
+	watchlists := &MockWatchlistRepository{}
+	watchlists.On("Get", "alice", 2).Return(&model.Watchlist{ID: 2, Name: "Horror"}, nil)
+	watchlists.On("SetWatched", 2, 5, true).Return(nil)
+	watchlistService := NewWatchlistService(watchlists, nil)
+	s := NewRecommendationService(repository, ratingService, watchlistService, 30, 2, 3, time.Hour)
+
+	recommendations, err := s.Recommend(context.Background(), "alice", 10)
+	require.NoError(t, err)
+	require.Len(t, recommendations, 3)
+	assert.Equal(t, 5, recommendations[0].MovieId)
+	assert.Equal(t, model.RecommendedFromRatings, recommendations[0].Source)
+	assert.Equal(t, []int{1}, recommendations[0].BecauseOf)
+	assert.Equal(t, []int{8, 7}, []int{recommendations[1].MovieId, recommendations[2].MovieId})
+	assert.Equal(t, model.RecommendedFromPopularity, recommendations[1].Source)
+
+	recommendations, err = s.Recommend(context.Background(), "alice", 1)
+	require.NoError(t, err)
+	assert.Len(t, recommendations, 1)
+	repository.AssertNumberOfCalls(t, "History", 1)
+
+	require.NoError(t, ratingService.DeleteRating(context.Background(), 1, "alice"))
+	_, err = s.Recommend(context.Background(), "alice", 10)
+	require.NoError(t, err)
+	repository.AssertNumberOfCalls(t, "History", 2)
+
+	_, err = watchlistService.SetWatched(context.Background(), "alice", 2, 5, true)
+	require.NoError(t, err)
+	_, err = s.Recommend(context.Background(), "alice", 10)
+	require.NoError(t, err)
+	repository.AssertNumberOfCalls(t, "History", 3)
+
+	// too few ratings for collaborative filtering
+	recommendations, err = s.Recommend(context.Background(), "bob", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 7, recommendations[0].MovieId)
+	for _, recommendation := range recommendations {
+		assert.Equal(t, model.RecommendedFromPopularity, recommendation.Source)
+		assert.NotEqual(t, 1, recommendation.MovieId)
+	}
+	repository.AssertNumberOfCalls(t, "Neighbors", 3)
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/queue"
+	"slices"
+	"time"
+)
+
+// EnrichMovieJob is the queue job kind fetching the TMDB metadata of a single movie.
+const EnrichMovieJob = "movie.enrich"
+
+type EnrichMovie struct {
+	MovieId int `json:"movieId"`
+}
+
+type staleMovieRepository interface {
+	GetStale(enrichedBefore time.Time, limit int) ([]*model.Movie, error)
+	MarkEnriched(movieId int, at time.Time) error
+	MarkEnrichAttempted(movieId int, at time.Time) error
+}
+
+type refreshRunRepository interface {
+	Create(run *model.RefreshRun) (*model.RefreshRun, error)
+	GetRecent(limit int) ([]*model.RefreshRun, error)
+}
+
+type movieDetailsFetcher interface {
+	GetMovieByID(ctx context.Context, id int) (*tmdb.Movie, error)
+	GetMovieByTitle(title string) (*tmdb.Movie, error)
+}
+
+type creditSyncer interface {
+	Sync(ctx context.Context, movieId, tmdbId int) error
+}
+
+type collectionLinker interface {
+	LinkTmdb(ctx context.Context, movieId int, collection *tmdb.Collection) error
+}
+
+// RefreshService re-fetches the TMDB metadata of movies that were enriched too long ago, and enriches single
+// movies on demand through the job queue.
+type RefreshService struct {
+	movieRepository staleMovieRepository
+	runRepository   refreshRunRepository
+	movieService    *MovieService
+	tmdbService     movieDetailsFetcher
+	credits         creditSyncer
+	collections     collectionLinker
+	jobs            jobEnqueuer
+	maxAge          time.Duration
+	batchSize       int
+	requestInterval time.Duration
+}
+
+func NewRefreshService(movieRepository staleMovieRepository, runRepository refreshRunRepository, movieService *MovieService,
+	tmdbService movieDetailsFetcher, credits creditSyncer, collections collectionLinker, jobs jobEnqueuer, maxAge time.Duration, batchSize int, requestsPerSecond int) *RefreshService {
+	return &RefreshService{
+		movieRepository: movieRepository,
+		runRepository:   runRepository,
+		movieService:    movieService,
+		tmdbService:     tmdbService,
+		credits:         credits,
+		collections:     collections,
+		jobs:            jobs,
+		maxAge:          maxAge,
+		batchSize:       batchSize,
+		requestInterval: time.Second / time.Duration(requestsPerSecond),
+	}
+}
+
+// Run refreshes up to one batch of stale movies, issuing at most the configured number of TMDB requests
+// per second, details and credits requests alike. Movies are only updated, and update events only emitted, when their
+// metadata changed.
+func (s *RefreshService) Run(ctx context.Context) error {
+	run := &model.RefreshRun{StartedAt: time.Now()}
+
+	movies, err := s.movieRepository.GetStale(run.StartedAt.Add(-s.maxAge), s.batchSize)
+	if err != nil {
+		slog.Error("Error when getting stale movies from db", "error", err)
+		return err
+	}
+	run.Candidates = len(movies)
+
+	limiter := newRequestLimiter(s.requestInterval)
+	defer limiter.stop()
+
+	for _, movie := range movies {
+		changed, err := s.refresh(ctx, movie, movie.TmdbId, limiter)
+		if ctx.Err() != nil {
+			break
+		}
+		switch {
+		case err != nil:
+			run.Failed++
+			slog.Warn("Could not refresh movie metadata", "movieId", movie.MovieId, "tmdbId", movie.TmdbId, "error", err)
+			// the movie goes to the end of the next batches, behind the stale movies not attempted yet
+			if err = s.movieRepository.MarkEnrichAttempted(movie.MovieId, time.Now()); err != nil {
+				slog.Error("Error when recording metadata refresh attempt", "movieId", movie.MovieId, "error", err)
+			}
+		case changed:
+			run.Refreshed++
+		default:
+			run.Unchanged++
+		}
+	}
+
+	run.FinishedAt = time.Now()
+	if _, err = s.runRepository.Create(run); err != nil {
+		slog.Error("Error when recording metadata refresh run", "error", err)
+		return err
+	}
+	slog.Info("Metadata refresh finished", "candidates", run.Candidates, "refreshed", run.Refreshed,
+		"unchanged", run.Unchanged, "failed", run.Failed)
+	return ctx.Err()
+}
+
+func (s *RefreshService) GetRecentRuns(limit int) ([]*model.RefreshRun, error) {
+	runs, err := s.runRepository.GetRecent(limit)
+	if err != nil {
+		slog.Error("Error when getting metadata refresh runs from db", "error", err)
+		return nil, err
+	}
+	return runs, nil
+}
+
+// EnqueueEnrichment queues the enrichment of an existing movie. It is also queued for movies created from TMDB
+// search results, to fetch what only the movie details and credits provide.
+func (s *RefreshService) EnqueueEnrichment(ctx context.Context, movieId int) (*model.QueueJob, error) {
+	if _, err := s.movieService.Get(movieId); err != nil {
+		return nil, err
+	}
+	job, err := s.jobs.Enqueue(ctx, EnrichMovieJob, EnrichMovie{MovieId: movieId})
+	if err != nil {
+		slog.Error("Unable to enqueue movie enrichment", "movieId", movieId, "error", err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Enrich is the handler of EnrichMovieJob. Movies that were never matched with TMDB are searched by title.
+func (s *RefreshService) Enrich(ctx context.Context, job EnrichMovie) error {
+	movie, err := s.movieService.Get(job.MovieId)
+	if err != nil {
+		var nfErr model.NotFoundError
+		if errors.As(err, &nfErr) {
+			return queue.Permanent(err)
+		}
+		return err
+	}
+
+	tmdbId := movie.TmdbId
+	if tmdbId == 0 {
+		match, err := s.tmdbService.GetMovieByTitle(movie.MovieName)
+		if err != nil {
+			if errors.Is(err, tmdb.ErrNoMoviesFound) {
+				return queue.Permanent(err)
+			}
+			return err
+		}
+		tmdbId = match.ID
+	}
+	_, err = s.refresh(ctx, movie, tmdbId, nil)
+	return err
+}
+
+// refresh fetches the TMDB details of the movie and updates it if they changed. The TMDB collection and the
+// credits of the movie are replaced with the current ones every time, before the update, so that a movie is only
+// updated, and its update event only published, once everything it was refreshed with is stored. Each TMDB request
+// waits for the limiter first, unless it is nil.
+func (s *RefreshService) refresh(ctx context.Context, movie *model.Movie, tmdbId int, limiter *requestLimiter) (bool, error) {
+	if err := limiter.wait(ctx); err != nil {
+		return false, err
+	}
+	details, err := s.tmdbService.GetMovieByID(ctx, tmdbId)
+	if err != nil {
+		return false, err
+	}
+	if err = s.collections.LinkTmdb(ctx, movie.MovieId, details.BelongsToCollection); err != nil {
+		return false, err
+	}
+	if err = limiter.wait(ctx); err != nil {
+		return false, err
+	}
+	if err = s.credits.Sync(ctx, movie.MovieId, details.ID); err != nil {
+		return false, err
+	}
+
+	genreIds := details.GenreIDs()
+	changed := details.ID != movie.TmdbId || details.Overview != movie.Overview || int(details.Runtime) != movie.Runtime ||
+		details.RoundedVoteAverage() != movie.TmdbVoteAverage || details.ReleaseDate != movie.ReleaseDate ||
+		!sameGenres(movie.Genres, genreIds)
+	if changed {
+		updated := *movie
+		updated.TmdbId = details.ID
+		updated.Overview = details.Overview
+		updated.Runtime = int(details.Runtime)
+		updated.TmdbVoteAverage = details.RoundedVoteAverage()
+		updated.ReleaseDate = details.ReleaseDate
+		updated.Genres = model.GenresOf(genreIds)
+		if _, err = s.movieService.Update(ctx, &updated); err != nil {
+			return false, err
+		}
+	}
+	return changed, s.movieRepository.MarkEnriched(movie.MovieId, time.Now())
+}
+
+// sameGenres reports whether the movie has exactly the genres with the given ids.
+func sameGenres(genres []model.Genre, ids []int) bool {
+	current := make([]int, len(genres))
+	for i, genre := range genres {
+		current[i] = genre.ID
+	}
+	ids = slices.Clone(ids)
+	slices.Sort(current)
+	slices.Sort(ids)
+	return slices.Equal(current, slices.Compact(ids))
+}
+
+// requestLimiter spaces out requests by a fixed interval, the first one going out at once.
+type requestLimiter struct {
+	ticker  *time.Ticker
+	started bool
+}
+
+func newRequestLimiter(interval time.Duration) *requestLimiter {
+	return &requestLimiter{ticker: time.NewTicker(interval)}
+}
+
+// wait blocks until the next request can be sent or the context is done. A nil limiter never blocks.
+func (l *requestLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if !l.started {
+		l.started = true
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.ticker.C:
+		return nil
+	}
+}
+
+func (l *requestLimiter) stop() {
+	l.ticker.Stop()
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/tmdb"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"rest_api/internal/queue"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockStaleMovieRepository struct {
+	mock.Mock
+}
+
+func (r *MockStaleMovieRepository) GetStale(enrichedBefore time.Time, limit int) ([]*model.Movie, error) {
+	args := r.Called(enrichedBefore, limit)
+	return args.Get(0).([]*model.Movie), args.Error(1)
+}
+
+func (r *MockStaleMovieRepository) MarkEnriched(movieId int, _ time.Time) error {
+	args := r.Called(movieId)
+	return args.Error(0)
+}
+
+func (r *MockStaleMovieRepository) MarkEnrichAttempted(movieId int, _ time.Time) error {
+	args := r.Called(movieId)
+	return args.Error(0)
+}
+
+type MockRefreshRunRepository struct {
+	mock.Mock
+}
+
+func (r *MockRefreshRunRepository) Create(run *model.RefreshRun) (*model.RefreshRun, error) {
+	args := r.Called(run)
+	return run, args.Error(0)
+}
+
+func (r *MockRefreshRunRepository) GetRecent(limit int) ([]*model.RefreshRun, error) {
+	args := r.Called(limit)
+	return args.Get(0).([]*model.RefreshRun), args.Error(1)
+}
+
+type MockTmdbService struct {
+	mock.Mock
+}
+
+func (m *MockTmdbService) GetMovieByID(_ context.Context, id int) (*tmdb.Movie, error) {
+	args := m.Called(id)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*tmdb.Movie), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockCreditSyncer struct {
+	mock.Mock
+}
+
+func (c *MockCreditSyncer) Sync(_ context.Context, movieId, tmdbId int) error {
+	args := c.Called(movieId, tmdbId)
+	return args.Error(0)
+}
+
+type MockCollectionLinker struct {
+	mock.Mock
+}
+
+func (c *MockCollectionLinker) LinkTmdb(_ context.Context, movieId int, collection *tmdb.Collection) error {
+	args := c.Called(movieId, collection)
+	return args.Error(0)
+}
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (p *MockPublisher) Publish(msg string) error {
+	args := p.Called(msg)
+	return args.Error(0)
+}
+
+func (p *MockPublisher) Configure(_ string) {}
+
+func TestRefreshService_Run(t *testing.T) {
+	staleRepository := &MockStaleMovieRepository{}
+	staleRepository.On("GetStale", mock.Anything, 10).Return([]*model.Movie{
+		{MovieId: 1, MovieName: "unchanged", Overview: "same", TmdbId: 11, Runtime: 90},
+		{MovieId: 2, MovieName: "changed", Overview: "old", TmdbId: 12, Runtime: 100},
+		{MovieId: 3, MovieName: "gone", Overview: "x", TmdbId: 13},
+	}, nil)
+	staleRepository.On("MarkEnriched", mock.Anything).Return(nil)
+	staleRepository.On("MarkEnrichAttempted", 3).Return(nil)
+
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieByID", 11).Return(&tmdb.Movie{ID: 11, Overview: "same", Runtime: 90}, nil)
+	tmdbService.On("GetMovieByID", 12).Return(&tmdb.Movie{ID: 12, Overview: "new", Runtime: 101}, nil)
+	tmdbService.On("GetMovieByID", 13).Return(nil, tmdb.ErrNoMoviesFound)
+
+	movieRepository := &datatest.MovieStore{}
+	updated := &model.Movie{MovieId: 2, MovieName: "changed", Overview: "new", TmdbId: 12, Runtime: 101}
+	movieRepository.On("Update", updated).Return(updated, nil)
+	publisher := &MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+
+	runRepository := &MockRefreshRunRepository{}
+	runRepository.On("Create", mock.Anything).Return(nil)
+	credits := &MockCreditSyncer{}
+	credits.On("Sync", mock.Anything, mock.Anything).Return(nil)
+	collections := &MockCollectionLinker{}
+	collections.On("LinkTmdb", mock.Anything, mock.Anything).Return(nil)
+
+	s := NewRefreshService(staleRepository, runRepository, NewMovieService(movieRepository, publisher), tmdbService, credits, collections,
+		nil, time.Hour, 10, 1000)
+	require.NoError(t, s.Run(context.Background()))
+
+	movieRepository.AssertNumberOfCalls(t, "Update", 1)
+	publisher.AssertNumberOfCalls(t, "Publish", 1)
+	publisher.AssertCalled(t, "Publish", `{"type":"movie.updated","movie":{"id":2,"title":"changed","overview":"new","tmdbId":12,"runtime":101}}`)
+	staleRepository.AssertNumberOfCalls(t, "MarkEnriched", 2)
+	staleRepository.AssertCalled(t, "MarkEnrichAttempted", 3)
+	staleRepository.AssertNumberOfCalls(t, "MarkEnrichAttempted", 1)
+	credits.AssertCalled(t, "Sync", 1, 11)
+	credits.AssertCalled(t, "Sync", 2, 12)
+	credits.AssertNumberOfCalls(t, "Sync", 2)
+
+	run := runRepository.Calls[0].Arguments.Get(0).(*model.RefreshRun)
+	assert.Equal(t, 3, run.Candidates)
+	assert.Equal(t, 1, run.Refreshed)
+	assert.Equal(t, 1, run.Unchanged)
+	assert.Equal(t, 1, run.Failed)
+}
+
+func TestRefreshService_RunStopsWhenCancelled(t *testing.T) {
+	staleRepository := &MockStaleMovieRepository{}
+	staleRepository.On("GetStale", mock.Anything, 10).Return([]*model.Movie{{MovieId: 1, TmdbId: 11}, {MovieId: 2, TmdbId: 12}}, nil)
+	staleRepository.On("MarkEnriched", mock.Anything).Return(nil)
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieByID", mock.Anything).Return(&tmdb.Movie{}, nil)
+	runRepository := &MockRefreshRunRepository{}
+	runRepository.On("Create", mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := NewRefreshService(staleRepository, runRepository, NewMovieService(&datatest.MovieStore{}, nil), tmdbService, &MockCreditSyncer{}, &MockCollectionLinker{}, nil,
+		time.Hour, 10, 1)
+	err := s.Run(ctx)
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	tmdbService.AssertNotCalled(t, "GetMovieByID", mock.Anything)
+	runRepository.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestRefreshService_RunLimitsEveryTmdbRequest(t *testing.T) {
+	staleRepository := &MockStaleMovieRepository{}
+	staleRepository.On("GetStale", mock.Anything, 10).Return([]*model.Movie{
+		{MovieId: 1, TmdbId: 11}, {MovieId: 2, TmdbId: 12}, {MovieId: 3, TmdbId: 13},
+	}, nil)
+	staleRepository.On("MarkEnriched", mock.Anything).Return(nil)
+	runRepository := &MockRefreshRunRepository{}
+	runRepository.On("Create", mock.Anything).Return(nil)
+	collections := &MockCollectionLinker{}
+	collections.On("LinkTmdb", mock.Anything, mock.Anything).Return(nil)
+
+	// every request to TMDB, for details or credits, has to go out on its own tick
+	var requests []time.Time
+	record := func(mock.Arguments) { requests = append(requests, time.Now()) }
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieByID", 11).Run(record).Return(&tmdb.Movie{ID: 11}, nil)
+	tmdbService.On("GetMovieByID", 12).Run(record).Return(&tmdb.Movie{ID: 12}, nil)
+	tmdbService.On("GetMovieByID", 13).Run(record).Return(&tmdb.Movie{ID: 13}, nil)
+	credits := &MockCreditSyncer{}
+	credits.On("Sync", mock.Anything, mock.Anything).Run(record).Return(nil)
+
+	interval := 20 * time.Millisecond
+	s := NewRefreshService(staleRepository, runRepository, NewMovieService(&datatest.MovieStore{}, nil), tmdbService, credits,
+		collections, nil, time.Hour, 10, int(time.Second/interval))
+	require.NoError(t, s.Run(context.Background()))
+
+	require.Len(t, requests, 6)
+	for i := 1; i < len(requests); i++ {
+		assert.GreaterOrEqual(t, requests[i].Sub(requests[i-1]), interval/2, "request %d", i)
+	}
+	assert.GreaterOrEqual(t, requests[len(requests)-1].Sub(requests[0]), 5*interval-interval/2)
+}
+
+func TestRefreshService_Enrich(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "The bear"}, nil)
+	movieRepository.On("Get", 2).Return(nil, data.ErrRecordNotFound)
+	enriched := &model.Movie{MovieId: 1, MovieName: "The bear", Overview: "bear", TmdbId: 11, Runtime: 90}
+	movieRepository.On("Update", enriched).Return(enriched, nil)
+	staleRepository := &MockStaleMovieRepository{}
+	staleRepository.On("MarkEnriched", 1).Return(nil)
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieByTitle", "The bear").Return(&tmdb.Movie{ID: 11}, nil)
+	tmdbService.On("GetMovieByID", 11).Return(&tmdb.Movie{ID: 11, Overview: "bear", Runtime: 90,
+		BelongsToCollection: &tmdb.Collection{ID: 5, Name: "The bear collection"}}, nil)
+
+	credits := &MockCreditSyncer{}
+	credits.On("Sync", 1, 11).Return(nil)
+	collections := &MockCollectionLinker{}
+	collections.On("LinkTmdb", 1, &tmdb.Collection{ID: 5, Name: "The bear collection"}).Return(nil)
+
+	s := NewRefreshService(staleRepository, nil, NewMovieService(movieRepository, nil), tmdbService, credits, collections, nil, time.Hour, 10, 1)
+	require.NoError(t, s.Enrich(context.Background(), EnrichMovie{MovieId: 1}))
+	movieRepository.AssertCalled(t, "Update", enriched)
+	staleRepository.AssertCalled(t, "MarkEnriched", 1)
+	collections.AssertCalled(t, "LinkTmdb", 1, &tmdb.Collection{ID: 5, Name: "The bear collection"})
+
+	err := s.Enrich(context.Background(), EnrichMovie{MovieId: 2})
+	assert.True(t, queue.IsPermanent(err))
+}
+
+func TestRefreshService_EnrichFailsBeforeUpdate(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "The bear", TmdbId: 11}, nil)
+	publisher := &MockPublisher{}
+	tmdbService := &MockTmdbService{}
+	tmdbService.On("GetMovieByID", 11).Return(&tmdb.Movie{ID: 11, Overview: "bear", Runtime: 90}, nil)
+	credits := &MockCreditSyncer{}
+	credits.On("Sync", 1, 11).Return(errors.New("tmdb unavailable"))
+	collections := &MockCollectionLinker{}
+	collections.On("LinkTmdb", 1, mock.Anything).Return(nil)
+	staleRepository := &MockStaleMovieRepository{}
+
+	s := NewRefreshService(staleRepository, nil, NewMovieService(movieRepository, publisher), tmdbService, credits, collections, nil, time.Hour, 10, 1)
+	assert.EqualError(t, s.Enrich(context.Background(), EnrichMovie{MovieId: 1}), "tmdb unavailable")
+	movieRepository.AssertNotCalled(t, "Update", mock.Anything)
+	publisher.AssertNotCalled(t, "Publish", mock.Anything)
+	staleRepository.AssertNotCalled(t, "MarkEnriched", mock.Anything)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"slices"
+)
+
+type revisionRepository interface {
+	List(ctx context.Context, movieId int) ([]*model.MovieRevision, error)
+	Get(ctx context.Context, movieId, revision int) (*model.MovieRevision, error)
+}
+
+// RevisionService gives access to the prior versions of movies, recorded on every update, and reverts movies to
+// them.
+type RevisionService struct {
+	revisionRepository revisionRepository
+	movieService       *MovieService
+}
+
+func NewRevisionService(revisionRepository revisionRepository, movieService *MovieService) *RevisionService {
+	return &RevisionService{revisionRepository: revisionRepository, movieService: movieService}
+}
+
+// List returns the revisions of the movie, most recent first. A movie that was never updated has none.
+func (s *RevisionService) List(ctx context.Context, movieId int) ([]*model.MovieRevision, error) {
+	if _, err := s.movieService.Get(movieId); err != nil {
+		return nil, err
+	}
+	revisions, err := s.revisionRepository.List(ctx, movieId)
+	if err != nil {
+		slog.Error("Error when getting movie revisions from db", "movieId", movieId, "error", err)
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Get returns a revision of the movie. Like List, it answers NotFoundError for a movie in the trash.
+func (s *RevisionService) Get(ctx context.Context, movieId, revision int) (*model.MovieRevision, error) {
+	if _, err := s.movieService.Get(movieId); err != nil {
+		return nil, err
+	}
+	return s.get(ctx, movieId, revision)
+}
+
+// Diff returns the fields that changed between two revisions of the movie, in field name order.
+func (s *RevisionService) Diff(ctx context.Context, movieId, from, to int) (*model.RevisionDiff, error) {
+	if _, err := s.movieService.Get(movieId); err != nil {
+		return nil, err
+	}
+	fromRev, err := s.get(ctx, movieId, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := s.get(ctx, movieId, to)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffFields(fromRev.Movie, toRev.Movie)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevisionDiff{From: from, To: to, Changes: changes}, nil
+}
+
+// get returns a revision without checking that the movie exists.
+func (s *RevisionService) get(ctx context.Context, movieId, revision int) (*model.MovieRevision, error) {
+	rev, err := s.revisionRepository.Get(ctx, movieId, revision)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when getting movie revision from db", "movieId", movieId, "revision", revision, "error", err)
+		return nil, err
+	}
+	return rev, nil
+}
+
+// Revert updates the movie with the state of one of its revisions. It goes through MovieService.Update, so the
+// change is audited, recorded as a new revision and published like any other update.
+func (s *RevisionService) Revert(ctx context.Context, movieId, revision int) (*model.Movie, error) {
+	rev, err := s.Get(ctx, movieId, revision)
+	if err != nil {
+		return nil, err
+	}
+	movie := *rev.Movie
+	movie.MovieId = movieId
+	return s.movieService.Update(ctx, &movie)
+}
+
+// diffFields compares the JSON fields of two versions of an entity.
+func diffFields(from, to any) ([]*model.FieldChange, error) {
+	fromFields, err := jsonFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := jsonFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for field := range fromFields {
+		fields = append(fields, field)
+	}
+	for field := range toFields {
+		if _, ok := fromFields[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+
+	changes := []*model.FieldChange{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(fromFields[field], toFields[field]) {
+			changes = append(changes, &model.FieldChange{Field: field, From: fromFields[field], To: toFields[field]})
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(value any) (map[string]any, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	if err = json.Unmarshal(valueJSON, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRevisionRepository struct {
+	mock.Mock
+}
+
+func (r *MockRevisionRepository) List(_ context.Context, movieId int) ([]*model.MovieRevision, error) {
+	args := r.Called(movieId)
+	return args.Get(0).([]*model.MovieRevision), args.Error(1)
+}
+
+func (r *MockRevisionRepository) Get(_ context.Context, movieId, revision int) (*model.MovieRevision, error) {
+	args := r.Called(movieId, revision)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*model.MovieRevision), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestRevisionService_Diff(t *testing.T) {
+	revisions := &MockRevisionRepository{}
+	revisions.On("Get", 1, 1).Return(&model.MovieRevision{Revision: 1, Movie: &model.Movie{MovieId: 1, MovieName: "The bear", Runtime: 90}}, nil)
+	revisions.On("Get", 1, 2).Return(&model.MovieRevision{Revision: 2, Movie: &model.Movie{MovieId: 1, MovieName: "The Bear", Overview: "bear", TmdbId: 11, Runtime: 90}}, nil)
+	revisions.On("Get", 1, 3).Return(nil, data.ErrRecordNotFound)
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 1).Return(&model.Movie{MovieId: 1}, nil)
+	// movie 2 is in the trash, its revisions are hidden with it
+	movieRepository.On("Get", 2).Return(nil, data.ErrRecordNotFound)
+	s := NewRevisionService(revisions, NewMovieService(movieRepository, nil))
+
+	diff, err := s.Diff(context.Background(), 1, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, &model.RevisionDiff{From: 1, To: 2, Changes: []*model.FieldChange{
+		{Field: "overview", From: "", To: "bear"},
+		{Field: "title", From: "The bear", To: "The Bear"},
+		{Field: "tmdbId", From: nil, To: float64(11)},
+	}}, diff)
+
+	diff, err = s.Diff(context.Background(), 1, 2, 2)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Changes)
+
+	_, err = s.Diff(context.Background(), 1, 1, 3)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+
+	_, err = s.Diff(context.Background(), 2, 1, 2)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+	_, err = s.Get(context.Background(), 2, 1)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+	revisions.AssertNotCalled(t, "Get", 2, mock.Anything)
+}
+
+func TestRevisionService_Revert(t *testing.T) {
+	revisions := &MockRevisionRepository{}
+	revisions.On("Get", 1, 1).Return(&model.MovieRevision{Revision: 1, Movie: &model.Movie{MovieId: 1, MovieName: "The bear", Runtime: 90}}, nil)
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 1).Return(&model.Movie{MovieId: 1, MovieName: "The Bear", Runtime: 95}, nil)
+	reverted := &model.Movie{MovieId: 1, MovieName: "The bear", Runtime: 90}
+	movieRepository.On("Update", reverted).Return(reverted, nil)
+	publisher := &MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+	s := NewRevisionService(revisions, NewMovieService(movieRepository, publisher))
+
+	movie, err := s.Revert(context.Background(), 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, reverted, movie)
+	publisher.AssertCalled(t, "Publish", `{"type":"movie.updated","movie":{"id":1,"title":"The bear","overview":"","runtime":90}}`)
+}
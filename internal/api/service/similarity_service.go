@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/similarity"
+)
+
+// SimilarityService finds the movies similar to a movie in an in-process index. The index follows the changes made
+// through this replica at once, the changes of the other replicas through the movie-events topic, and is rebuilt from
+// the database on schedule.
+type SimilarityService struct {
+	movieService *MovieService
+	index        *similarity.Index
+}
+
+// NewSimilarityService creates the service and subscribes its index to the changes of movies. The index is empty
+// until the first Rebuild.
+func NewSimilarityService(movieService *MovieService, index *similarity.Index) *SimilarityService {
+	s := &SimilarityService{movieService: movieService, index: index}
+	movieService.Subscribe(s.HandleMovieEvent)
+	return s
+}
+
+// Rebuild indexes all the movies that are not in the trash.
+func (s *SimilarityService) Rebuild(_ context.Context) error {
+	movies, err := s.movieService.GetAll()
+	if err != nil {
+		return err
+	}
+	s.index.Rebuild(movies)
+	slog.Info("Rebuilt similarity index", "movies", len(movies))
+	return nil
+}
+
+// HandleMovieEvent updates the index after a change of a movie.
+func (s *SimilarityService) HandleMovieEvent(eventType string, movie *model.Movie) {
+	switch eventType {
+	case model.MovieCreated, model.MovieUpdated, model.MovieRestored:
+		s.index.Upsert(movie)
+	case model.MovieDeleted:
+		s.index.Remove(movie.MovieId)
+	}
+}
+
+// HandleMessage updates the index after a change of a movie consumed from the movie-events topic, made by this replica
+// or another one.
+func (s *SimilarityService) HandleMessage(msg []byte) {
+	var event model.MovieEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		slog.Error("Error when unmarshalling movie event", "error", err)
+		return
+	}
+	if event.Movie == nil {
+		return
+	}
+	s.HandleMovieEvent(event.Type, event.Movie)
+}
+
+// Similar returns up to limit movies similar to the movie, best first. A movie not indexed yet, such as one created
+// on another replica since the last rebuild, is indexed first.
+func (s *SimilarityService) Similar(_ context.Context, movieId, limit int) ([]*model.SimilarMovie, error) {
+	movie, err := s.movieService.Get(movieId)
+	if err != nil {
+		return nil, err
+	}
+	similar, ok := s.index.Similar(movieId, limit)
+	if !ok {
+		s.index.Upsert(movie)
+		similar, _ = s.index.Similar(movieId, limit)
+	}
+	return similar, nil
+}
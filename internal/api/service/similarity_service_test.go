@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/similarity"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimilarityService_Similar(t *testing.T) {
+	heist := &model.Movie{MovieId: 1, MovieName: "Heat", Overview: "A crew of thieves plans a bank heist."}
+	caper := &model.Movie{MovieId: 2, MovieName: "Rififi", Overview: "Thieves plan a jewellery heist."}
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("GetAll").Return([]*model.Movie{heist}, nil)
+	movieRepository.On("Get", 1).Return(heist, nil)
+	movieRepository.On("Get", 2).Return(caper, nil)
+	movieRepository.On("Get", 3).Return(nil, data.ErrRecordNotFound)
+	movieRepository.On("Create", mock.Anything).Return(caper, nil)
+	movieRepository.On("Delete", 2).Return(nil)
+	movieService := NewMovieService(movieRepository, nil)
+	s := NewSimilarityService(movieService, similarity.NewIndex(0.3))
+	require.NoError(t, s.Rebuild(context.Background()))
+
+	similar, err := s.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, similar)
+
+	_, err = movieService.Create(context.Background(), caper)
+	require.NoError(t, err)
+	similar, err = s.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, similar, 1)
+	assert.Equal(t, 2, similar[0].MovieId)
+	assert.ElementsMatch(t, []string{"thieves", "heist"}, similar[0].SharedTerms)
+
+	require.NoError(t, movieService.Delete(context.Background(), 2))
+	similar, err = s.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, similar)
+
+	// movies missing from the index, such as ones created on another replica, are indexed on demand
+	similar, err = s.Similar(context.Background(), 2, 10)
+	require.NoError(t, err)
+	require.Len(t, similar, 1)
+	assert.Equal(t, 1, similar[0].MovieId)
+
+	_, err = s.Similar(context.Background(), 3, 10)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+}
+
+func TestSimilarityService_HandleMessage(t *testing.T) {
+	heist := &model.Movie{MovieId: 1, MovieName: "Heat", Overview: "A crew of thieves plans a bank heist."}
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("GetAll").Return([]*model.Movie{heist, {MovieId: 2, MovieName: "Rififi", Overview: "A comedy."}}, nil)
+	movieRepository.On("Get", 1).Return(heist, nil)
+	s := NewSimilarityService(NewMovieService(movieRepository, nil), similarity.NewIndex(0.3))
+	require.NoError(t, s.Rebuild(context.Background()))
+
+	// another replica updates the overview of a movie, then deletes it
+	s.HandleMessage([]byte(`{"type":"movie.updated","movie":{"id":2,"title":"Rififi","overview":"Thieves plan a jewellery heist."}}`))
+	s.HandleMessage([]byte(`{"type":"movie.invalidated","movie":null}`))
+	similar, err := s.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, similar, 1)
+	assert.Equal(t, 2, similar[0].MovieId)
+
+	s.HandleMessage([]byte(`{"type":"movie.deleted","movie":{"id":2}}`))
+	similar, err = s.Similar(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, similar)
+}
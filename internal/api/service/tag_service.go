@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"strings"
+)
+
+const maxTagNameLength = 50
+
+type tagRepository interface {
+	List(ctx context.Context) ([]*model.TagCount, error)
+	Create(ctx context.Context, name, createdBy string) (*model.Tag, error)
+	Update(ctx context.Context, tag *model.Tag) (*model.Tag, error)
+	Delete(ctx context.Context, tagId int) error
+	AddToMovie(ctx context.Context, movieId, tagId int) error
+	RemoveFromMovie(ctx context.Context, movieId, tagId int) error
+}
+
+// TagService manages the free-form tags users give to movies.
+type TagService struct {
+	tagRepository tagRepository
+	movieService  *MovieService
+}
+
+func NewTagService(tagRepository tagRepository, movieService *MovieService) *TagService {
+	return &TagService{tagRepository: tagRepository, movieService: movieService}
+}
+
+func (s *TagService) List(ctx context.Context) ([]*model.TagCount, error) {
+	tags, err := s.tagRepository.List(ctx)
+	if err != nil {
+		slog.Error("Error when getting tags from db", "error", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *TagService) Create(ctx context.Context, name, createdBy string) (*model.Tag, error) {
+	name, err := validateTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := s.tagRepository.Create(ctx, name, createdBy)
+	if err != nil {
+		return nil, s.tagError(err, 0)
+	}
+	return tag, nil
+}
+
+// Rename changes the name of the tag on every movie it is given to.
+func (s *TagService) Rename(ctx context.Context, tagId int, name string) (*model.Tag, error) {
+	name, err := validateTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := s.tagRepository.Update(ctx, &model.Tag{ID: tagId, Name: name})
+	if err != nil {
+		return nil, s.tagError(err, tagId)
+	}
+	s.movieService.InvalidateAll()
+	return tag, nil
+}
+
+func (s *TagService) Delete(ctx context.Context, tagId int) error {
+	if err := s.tagRepository.Delete(ctx, tagId); err != nil {
+		return s.tagError(err, tagId)
+	}
+	s.movieService.InvalidateAll()
+	return nil
+}
+
+// TagMovie gives the tag to the movie and returns the updated movie.
+func (s *TagService) TagMovie(ctx context.Context, movieId, tagId int) (*model.Movie, error) {
+	if _, err := s.movieService.Get(movieId); err != nil {
+		return nil, err
+	}
+	if err := s.tagError(s.tagRepository.AddToMovie(ctx, movieId, tagId), tagId); err != nil {
+		return nil, err
+	}
+	s.movieService.Invalidate(movieId)
+	return s.movieService.Get(movieId)
+}
+
+func (s *TagService) UntagMovie(ctx context.Context, movieId, tagId int) error {
+	if err := s.tagRepository.RemoveFromMovie(ctx, movieId, tagId); err != nil {
+		return s.tagError(err, tagId)
+	}
+	s.movieService.Invalidate(movieId)
+	return nil
+}
+
+func validateTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", model.ValidationError{Message: "name should be present"}
+	}
+	if len(name) > maxTagNameLength {
+		return "", model.ValidationError{Message: fmt.Sprintf("name should be at most %d characters", maxTagNameLength)}
+	}
+	if strings.Contains(name, ",") {
+		return "", model.ValidationError{Message: "name should not contain commas"}
+	}
+	return name, nil
+}
+
+// tagError maps the repository errors of tag changes.
+func (s *TagService) tagError(err error, tagId int) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, data.ErrRecordNotFound):
+		return model.NotFoundError{}
+	case errors.Is(err, data.ErrRecordExists):
+		return model.ConflictError{}
+	}
+	slog.Error("Error when changing tag in db", "tagId", tagId, "error", err)
+	return err
+}
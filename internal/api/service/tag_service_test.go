@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTagRepository struct {
+	mock.Mock
+}
+
+func (r *MockTagRepository) List(_ context.Context) ([]*model.TagCount, error) {
+	args := r.Called()
+	return args.Get(0).([]*model.TagCount), args.Error(1)
+}
+
+func (r *MockTagRepository) Create(_ context.Context, name, createdBy string) (*model.Tag, error) {
+	args := r.Called(name, createdBy)
+	return &model.Tag{ID: 1, Name: name}, args.Error(0)
+}
+
+func (r *MockTagRepository) Update(_ context.Context, tag *model.Tag) (*model.Tag, error) {
+	args := r.Called(tag)
+	return tag, args.Error(0)
+}
+
+func (r *MockTagRepository) Delete(_ context.Context, tagId int) error {
+	args := r.Called(tagId)
+	return args.Error(0)
+}
+
+func (r *MockTagRepository) AddToMovie(_ context.Context, movieId, tagId int) error {
+	args := r.Called(movieId, tagId)
+	return args.Error(0)
+}
+
+func (r *MockTagRepository) RemoveFromMovie(_ context.Context, movieId, tagId int) error {
+	args := r.Called(movieId, tagId)
+	return args.Error(0)
+}
+
+func TestTagService_Create(t *testing.T) {
+	tags := &MockTagRepository{}
+	tags.On("Create", "cult classic", "alice").Return(nil)
+	tags.On("Create", "noir", "alice").Return(data.ErrRecordExists)
+	s := NewTagService(tags, nil)
+
+	tests := []struct {
+		name    string
+		tagName string
+		wantErr error
+	}{
+		{"created", "  cult classic ", nil},
+		{"existing", "noir", model.ConflictError{}},
+		{"empty", " ", model.ValidationError{Message: "name should be present"}},
+		{"comma", "a,b", model.ValidationError{Message: "name should not contain commas"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := s.Create(context.Background(), tt.tagName, "alice")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "cult classic", tag.Name)
+		})
+	}
+}
+
+func TestTagService_TagMovie(t *testing.T) {
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 10).Return(&model.Movie{MovieId: 10, Tags: []model.Tag{{ID: 1, Name: "noir"}}}, nil)
+	movieRepository.On("Get", 12).Return(nil, data.ErrRecordNotFound)
+	tags := &MockTagRepository{}
+	tags.On("AddToMovie", 10, 1).Return(nil)
+	tags.On("AddToMovie", 10, 2).Return(data.ErrRecordNotFound)
+	s := NewTagService(tags, NewMovieService(movieRepository, nil))
+
+	movie, err := s.TagMovie(context.Background(), 10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Tag{{ID: 1, Name: "noir"}}, movie.Tags)
+
+	_, err = s.TagMovie(context.Background(), 10, 2)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+
+	_, err = s.TagMovie(context.Background(), 12, 1)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+	tags.AssertNumberOfCalls(t, "AddToMovie", 2)
+}
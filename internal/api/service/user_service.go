@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+)
+
+const maxUserFieldLength = 50
+
+type userStore interface {
+	List(ctx context.Context) ([]*model.User, error)
+	Create(ctx context.Context, user *model.User) (*model.User, error)
+	Update(ctx context.Context, user *model.User) (*model.User, error)
+	Delete(ctx context.Context, username string) ([]int, error)
+}
+
+// UserService manages the API users. Every change is recorded in the audit log by the repository.
+type UserService struct {
+	userRepository userStore
+	movieService   *MovieService
+}
+
+func NewUserService(userRepository userStore, movieService *MovieService) *UserService {
+	return &UserService{userRepository: userRepository, movieService: movieService}
+}
+
+func (s *UserService) List(ctx context.Context) ([]*model.User, error) {
+	users, err := s.userRepository.List(ctx)
+	if err != nil {
+		slog.Error("Error when getting users from db", "error", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *UserService) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	if err := validateUser(user, true); err != nil {
+		return nil, err
+	}
+	created, err := s.userRepository.Create(ctx, user)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordExists) {
+			return nil, model.ConflictError{}
+		}
+		slog.Error("Unable to create user in the database", "username", user.Username, "error", err)
+		return nil, err
+	}
+	return created, nil
+}
+
+// Update changes the admin flag of the user, and its password if one is provided.
+func (s *UserService) Update(ctx context.Context, user *model.User) (*model.User, error) {
+	if err := validateUser(user, false); err != nil {
+		return nil, err
+	}
+	updated, err := s.userRepository.Update(ctx, user)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when updating user in the db", "username", user.Username, "error", err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete removes the user, and their ratings from the movies they rated, which are invalidated.
+func (s *UserService) Delete(ctx context.Context, username string) error {
+	movieIds, err := s.userRepository.Delete(ctx, username)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return model.NotFoundError{}
+		}
+		slog.Error("Error when deleting user in db", "username", username, "error", err)
+		return err
+	}
+	s.movieService.Invalidate(movieIds...)
+	return nil
+}
+
+func validateUser(user *model.User, passwordRequired bool) error {
+	switch {
+	case user.Username == "":
+		return model.ValidationError{Message: "username should be present"}
+	case len(user.Username) > maxUserFieldLength:
+		return model.ValidationError{Message: fmt.Sprintf("username should be at most %d characters", maxUserFieldLength)}
+	case passwordRequired && user.Password == "":
+		return model.ValidationError{Message: "password should be present"}
+	case len(user.Password) > maxUserFieldLength:
+		return model.ValidationError{Message: fmt.Sprintf("password should be at most %d characters", maxUserFieldLength)}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (r *MockUserRepository) List(_ context.Context) ([]*model.User, error) {
+	args := r.Called()
+	return args.Get(0).([]*model.User), args.Error(1)
+}
+
+func (r *MockUserRepository) Create(_ context.Context, user *model.User) (*model.User, error) {
+	args := r.Called(user)
+	return &model.User{Username: user.Username, Admin: user.Admin}, args.Error(0)
+}
+
+func (r *MockUserRepository) Update(_ context.Context, user *model.User) (*model.User, error) {
+	args := r.Called(user)
+	return &model.User{Username: user.Username, Admin: user.Admin}, args.Error(0)
+}
+
+func (r *MockUserRepository) Delete(_ context.Context, username string) ([]int, error) {
+	args := r.Called(username)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func TestUserService_Create(t *testing.T) {
+	randErr := errors.New("random")
+	tests := []struct {
+		name    string
+		input   *model.User
+		repoErr error
+		want    *model.User
+		wantErr error
+	}{
+		{
+			name:  "success",
+			input: &model.User{Username: "alice", Password: "secret", Admin: true},
+			want:  &model.User{Username: "alice", Admin: true},
+		},
+		{
+			name:    "missing password",
+			input:   &model.User{Username: "alice"},
+			wantErr: model.ValidationError{Message: "password should be present"},
+		},
+		{
+			name:    "conflict",
+			input:   &model.User{Username: "alice", Password: "secret"},
+			repoErr: data.ErrRecordExists,
+			wantErr: model.ConflictError{},
+		},
+		{
+			name:    "other error",
+			input:   &model.User{Username: "alice", Password: "secret"},
+			repoErr: randErr,
+			wantErr: randErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &MockUserRepository{}
+			repository.On("Create", tt.input).Return(tt.repoErr)
+			s := NewUserService(repository, nil)
+
+			got, err := s.Create(context.Background(), tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserService_Update(t *testing.T) {
+	repository := &MockUserRepository{}
+	repository.On("Update", &model.User{Username: "alice"}).Return(nil)
+	repository.On("Update", &model.User{Username: "bob"}).Return(data.ErrRecordNotFound)
+	s := NewUserService(repository, nil)
+
+	got, err := s.Update(context.Background(), &model.User{Username: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, &model.User{Username: "alice"}, got)
+
+	_, err = s.Update(context.Background(), &model.User{Username: "bob"})
+	assert.ErrorIs(t, err, model.NotFoundError{})
+}
+
+func TestUserService_Delete(t *testing.T) {
+	repository := &MockUserRepository{}
+	repository.On("Delete", "alice").Return([]int{1, 2}, nil)
+	repository.On("Delete", "bob").Return([]int(nil), data.ErrRecordNotFound)
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", mock.Anything).Return(&model.Movie{MovieId: 1}, nil)
+	movieCache := NewMovieCache(movieRepository, 10, time.Hour, time.Hour)
+	s := NewUserService(repository, NewMovieService(movieCache, nil))
+
+	// the movies rated by alice are cached with her ratings
+	_, _ = movieCache.Get(1)
+	_, _ = movieCache.Get(2)
+	require.NoError(t, s.Delete(context.Background(), "alice"))
+	assert.Equal(t, int64(2), movieCache.Stats().Invalidations)
+	assert.Equal(t, 0, movieCache.Stats().Size)
+
+	assert.ErrorIs(t, s.Delete(context.Background(), "bob"), model.NotFoundError{})
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"strings"
+)
+
+const maxWatchlistNameLength = 100
+
+type watchlistRepository interface {
+	List(ctx context.Context, username string) ([]*model.Watchlist, error)
+	Get(ctx context.Context, username string, watchlistId int) (*model.Watchlist, error)
+	GetDefault(ctx context.Context, username string) (*model.Watchlist, error)
+	Create(ctx context.Context, username, name string) (*model.Watchlist, error)
+	Delete(ctx context.Context, username string, watchlistId int) error
+	AddMovie(ctx context.Context, watchlistId, movieId int) error
+	RemoveMovie(ctx context.Context, watchlistId, movieId int) error
+	SetWatched(ctx context.Context, watchlistId, movieId int, watched bool) error
+	Reorder(ctx context.Context, watchlistId int, movieIds []int) error
+}
+
+// WatchedListener is notified in-process of every change of the movies a user watched.
+type WatchedListener func(username string)
+
+// WatchlistService manages the watchlists of users. Every user has a default watchlist, created on first use,
+// and can create named ones. A watchlist id of 0 designates the default watchlist of the user.
+type WatchlistService struct {
+	watchlistRepository watchlistRepository
+	movieService        *MovieService
+	listeners           []WatchedListener
+}
+
+func NewWatchlistService(watchlistRepository watchlistRepository, movieService *MovieService) *WatchlistService {
+	return &WatchlistService{watchlistRepository: watchlistRepository, movieService: movieService}
+}
+
+// Subscribe registers a listener for the changes of the watched movies, which are marked or unmarked as watched,
+// or removed from a watchlist with it. Listeners are called synchronously and have to be registered before the
+// service is used.
+func (s *WatchlistService) Subscribe(listener WatchedListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *WatchlistService) List(ctx context.Context, username string) ([]*model.Watchlist, error) {
+	watchlists, err := s.watchlistRepository.List(ctx, username)
+	if err != nil {
+		slog.Error("Error when getting watchlists from db", "username", username, "error", err)
+		return nil, err
+	}
+	return watchlists, nil
+}
+
+func (s *WatchlistService) Get(ctx context.Context, username string, watchlistId int) (*model.Watchlist, error) {
+	var watchlist *model.Watchlist
+	var err error
+	if watchlistId == 0 {
+		watchlist, err = s.watchlistRepository.GetDefault(ctx, username)
+	} else {
+		watchlist, err = s.watchlistRepository.Get(ctx, username, watchlistId)
+	}
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, model.NotFoundError{}
+		}
+		slog.Error("Error when getting watchlist from db", "username", username, "watchlistId", watchlistId, "error", err)
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+func (s *WatchlistService) Create(ctx context.Context, username, name string) (*model.Watchlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, model.ValidationError{Message: "name should be present"}
+	}
+	if len(name) > maxWatchlistNameLength {
+		return nil, model.ValidationError{Message: fmt.Sprintf("name should be at most %d characters", maxWatchlistNameLength)}
+	}
+	watchlist, err := s.watchlistRepository.Create(ctx, username, name)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordExists) {
+			return nil, model.ConflictError{}
+		}
+		slog.Error("Unable to create watchlist in the database", "username", username, "error", err)
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// Delete removes a named watchlist. The default watchlist cannot be deleted.
+func (s *WatchlistService) Delete(ctx context.Context, username string, watchlistId int) error {
+	if watchlistId == 0 {
+		return model.ValidationError{Message: "the default watchlist cannot be deleted"}
+	}
+	err := s.watchlistRepository.Delete(ctx, username, watchlistId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return model.NotFoundError{}
+		case errors.Is(err, data.ErrInvalidState):
+			return model.ValidationError{Message: "the default watchlist cannot be deleted"}
+		}
+		slog.Error("Error when deleting watchlist in db", "username", username, "watchlistId", watchlistId, "error", err)
+		return err
+	}
+	s.notify(username)
+	return nil
+}
+
+// AddMovie appends an existing movie to the watchlist and returns the updated watchlist.
+func (s *WatchlistService) AddMovie(ctx context.Context, username string, watchlistId, movieId int) (*model.Watchlist, error) {
+	watchlist, err := s.Get(ctx, username, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.movieService.Get(movieId); err != nil {
+		var nfErr model.NotFoundError
+		if errors.As(err, &nfErr) {
+			return nil, model.ValidationError{Message: "no movie with provided id exists"}
+		}
+		return nil, err
+	}
+	if err = s.watchlistRepository.AddMovie(ctx, watchlist.ID, movieId); err != nil {
+		if errors.Is(err, data.ErrRecordExists) {
+			return nil, model.ConflictError{}
+		}
+		slog.Error("Unable to add movie to watchlist", "watchlistId", watchlist.ID, "movieId", movieId, "error", err)
+		return nil, err
+	}
+	return s.Get(ctx, username, watchlist.ID)
+}
+
+func (s *WatchlistService) RemoveMovie(ctx context.Context, username string, watchlistId, movieId int) error {
+	watchlist, err := s.Get(ctx, username, watchlistId)
+	if err != nil {
+		return err
+	}
+	if err = s.itemChange(s.watchlistRepository.RemoveMovie(ctx, watchlist.ID, movieId), watchlist.ID, movieId); err != nil {
+		return err
+	}
+	s.notify(username)
+	return nil
+}
+
+// SetWatched marks a movie of the watchlist as watched or not and returns the updated watchlist.
+func (s *WatchlistService) SetWatched(ctx context.Context, username string, watchlistId, movieId int, watched bool) (*model.Watchlist, error) {
+	watchlist, err := s.Get(ctx, username, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.itemChange(s.watchlistRepository.SetWatched(ctx, watchlist.ID, movieId, watched), watchlist.ID, movieId); err != nil {
+		return nil, err
+	}
+	s.notify(username)
+	return s.Get(ctx, username, watchlist.ID)
+}
+
+// Reorder sets the order of the movies of the watchlist, given as the complete list of its movie ids.
+func (s *WatchlistService) Reorder(ctx context.Context, username string, watchlistId int, movieIds []int) (*model.Watchlist, error) {
+	watchlist, err := s.Get(ctx, username, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.watchlistRepository.Reorder(ctx, watchlist.ID, movieIds); err != nil {
+		if errors.Is(err, data.ErrInvalidState) {
+			return nil, model.ValidationError{Message: "movie ids should be the movies of the watchlist, each listed once"}
+		}
+		slog.Error("Unable to reorder watchlist", "watchlistId", watchlist.ID, "error", err)
+		return nil, err
+	}
+	return s.Get(ctx, username, watchlist.ID)
+}
+
+// itemChange maps the error of a change of a single watchlist item.
+func (s *WatchlistService) itemChange(err error, watchlistId, movieId int) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return model.NotFoundError{}
+	}
+	slog.Error("Unable to change watchlist item", "watchlistId", watchlistId, "movieId", movieId, "error", err)
+	return err
+}
+
+func (s *WatchlistService) notify(username string) {
+	for _, listener := range s.listeners {
+		listener(username)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"rest_api/internal/data/datatest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWatchlistRepository struct {
+	mock.Mock
+}
+
+func (r *MockWatchlistRepository) List(_ context.Context, username string) ([]*model.Watchlist, error) {
+	args := r.Called(username)
+	return args.Get(0).([]*model.Watchlist), args.Error(1)
+}
+
+func (r *MockWatchlistRepository) Get(_ context.Context, username string, watchlistId int) (*model.Watchlist, error) {
+	args := r.Called(username, watchlistId)
+	arg1 := args.Get(0)
+	if arg1 != nil {
+		return arg1.(*model.Watchlist), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (r *MockWatchlistRepository) GetDefault(_ context.Context, username string) (*model.Watchlist, error) {
+	args := r.Called(username)
+	return args.Get(0).(*model.Watchlist), args.Error(1)
+}
+
+func (r *MockWatchlistRepository) Create(_ context.Context, username, name string) (*model.Watchlist, error) {
+	args := r.Called(username, name)
+	return &model.Watchlist{ID: 2, Name: name}, args.Error(0)
+}
+
+func (r *MockWatchlistRepository) Delete(_ context.Context, username string, watchlistId int) error {
+	args := r.Called(username, watchlistId)
+	return args.Error(0)
+}
+
+func (r *MockWatchlistRepository) AddMovie(_ context.Context, watchlistId, movieId int) error {
+	args := r.Called(watchlistId, movieId)
+	return args.Error(0)
+}
+
+func (r *MockWatchlistRepository) RemoveMovie(_ context.Context, watchlistId, movieId int) error {
+	args := r.Called(watchlistId, movieId)
+	return args.Error(0)
+}
+
+func (r *MockWatchlistRepository) SetWatched(_ context.Context, watchlistId, movieId int, watched bool) error {
+	args := r.Called(watchlistId, movieId, watched)
+	return args.Error(0)
+}
+
+func (r *MockWatchlistRepository) Reorder(_ context.Context, watchlistId int, movieIds []int) error {
+	args := r.Called(watchlistId, movieIds)
+	return args.Error(0)
+}
+
+func TestWatchlistService_AddMovie(t *testing.T) {
+	defaultList := &model.Watchlist{ID: 1, Name: model.DefaultWatchlistName, Default: true}
+	watchlists := &MockWatchlistRepository{}
+	watchlists.On("GetDefault", "alice").Return(defaultList, nil)
+	watchlists.On("Get", "alice", 1).Return(defaultList, nil)
+	watchlists.On("Get", "alice", 5).Return(nil, data.ErrRecordNotFound)
+	watchlists.On("AddMovie", 1, 10).Return(nil)
+	watchlists.On("AddMovie", 1, 11).Return(data.ErrRecordExists)
+	movieRepository := &datatest.MovieStore{}
+	movieRepository.On("Get", 10).Return(&model.Movie{MovieId: 10}, nil)
+	movieRepository.On("Get", 11).Return(&model.Movie{MovieId: 11}, nil)
+	movieRepository.On("Get", 12).Return(nil, data.ErrRecordNotFound)
+	s := NewWatchlistService(watchlists, NewMovieService(movieRepository, nil))
+
+	watchlist, err := s.AddMovie(context.Background(), "alice", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, defaultList, watchlist)
+	watchlists.AssertCalled(t, "AddMovie", 1, 10)
+
+	_, err = s.AddMovie(context.Background(), "alice", 0, 11)
+	assert.ErrorIs(t, err, model.ConflictError{})
+
+	_, err = s.AddMovie(context.Background(), "alice", 0, 12)
+	assert.ErrorIs(t, err, model.ValidationError{Message: "no movie with provided id exists"})
+
+	_, err = s.AddMovie(context.Background(), "alice", 5, 10)
+	assert.ErrorIs(t, err, model.NotFoundError{})
+}
+
+func TestWatchlistService_Reorder(t *testing.T) {
+	watchlist := &model.Watchlist{ID: 2, Name: "Horror"}
+	watchlists := &MockWatchlistRepository{}
+	watchlists.On("Get", "alice", 2).Return(watchlist, nil)
+	watchlists.On("Reorder", 2, []int{3, 1, 2}).Return(nil)
+	watchlists.On("Reorder", 2, []int{3, 1}).Return(data.ErrInvalidState)
+	s := NewWatchlistService(watchlists, nil)
+
+	_, err := s.Reorder(context.Background(), "alice", 2, []int{3, 1, 2})
+	require.NoError(t, err)
+
+	_, err = s.Reorder(context.Background(), "alice", 2, []int{3, 1})
+	var vErr model.ValidationError
+	assert.ErrorAs(t, err, &vErr)
+}
+
+func TestWatchlistService_Delete(t *testing.T) {
+	watchlists := &MockWatchlistRepository{}
+	watchlists.On("Delete", "alice", 2).Return(nil)
+	watchlists.On("Delete", "alice", 1).Return(data.ErrInvalidState)
+	s := NewWatchlistService(watchlists, nil)
+
+	assert.NoError(t, s.Delete(context.Background(), "alice", 2))
+	assert.ErrorIs(t, s.Delete(context.Background(), "alice", 1), model.ValidationError{Message: "the default watchlist cannot be deleted"})
+	assert.ErrorIs(t, s.Delete(context.Background(), "alice", 0), model.ValidationError{Message: "the default watchlist cannot be deleted"})
+	watchlists.AssertNumberOfCalls(t, "Delete", 2)
+}
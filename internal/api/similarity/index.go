@@ -0,0 +1,215 @@
+package similarity
+
+import (
+	"cmp"
+	"math"
+	"rest_api/internal/api/model"
+	"slices"
+	"sync"
+)
+
+// maxSharedTerms bounds the terms given as the explanation of a match.
+const maxSharedTerms = 5
+
+type document struct {
+	movie  model.MovieSummary
+	terms  map[string]int
+	genres []model.Genre
+}
+
+// Index ranks movies by the similarity of their overview and genres. Overviews are compared by the cosine of
+// their TF-IDF vectors, genres by the Jaccard index of the genre sets, and the score is the weighted sum of both.
+// Document frequencies are kept up to date on every change, so that movies can be added and removed one by one.
+type Index struct {
+	mu          sync.RWMutex
+	genreWeight float64
+	docs        map[int]*document
+	df          map[string]int
+}
+
+// NewIndex creates an empty index. genreWeight is the share of the genre overlap in the score, between 0 and 1.
+func NewIndex(genreWeight float64) *Index {
+	return &Index{genreWeight: min(max(genreWeight, 0), 1), docs: map[int]*document{}, df: map[string]int{}}
+}
+
+// Rebuild replaces the indexed movies with the given ones.
+func (i *Index) Rebuild(movies []*model.Movie) {
+	docs := make(map[int]*document, len(movies))
+	df := map[string]int{}
+	for _, movie := range movies {
+		doc := newDocument(movie)
+		if previous, ok := docs[movie.MovieId]; ok {
+			removeTerms(df, previous)
+		}
+		docs[movie.MovieId] = doc
+		addTerms(df, doc)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.docs, i.df = docs, df
+}
+
+// Upsert adds the movie to the index, or replaces it if it was already indexed.
+func (i *Index) Upsert(movie *model.Movie) {
+	doc := newDocument(movie)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if previous, ok := i.docs[movie.MovieId]; ok {
+		removeTerms(i.df, previous)
+	}
+	i.docs[movie.MovieId] = doc
+	addTerms(i.df, doc)
+}
+
+func (i *Index) Remove(movieId int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if previous, ok := i.docs[movieId]; ok {
+		removeTerms(i.df, previous)
+		delete(i.docs, movieId)
+	}
+}
+
+// Len returns the number of indexed movies.
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.docs)
+}
+
+// Similar returns up to limit movies most similar to the movie, best first, leaving out the ones sharing nothing
+// with it. The second result is false if the movie is not indexed.
+func (i *Index) Similar(movieId, limit int) ([]*model.SimilarMovie, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	query, ok := i.docs[movieId]
+	if !ok {
+		return nil, false
+	}
+
+	queryWeights := i.weights(query)
+	queryNorm := norm(queryWeights)
+	similar := []*model.SimilarMovie{}
+	for id, doc := range i.docs {
+		if id == movieId {
+			continue
+		}
+		var cosine float64
+		var shared []termWeight
+		if queryNorm > 0 {
+			weights := i.weights(doc)
+			var dot float64
+			for term, weight := range queryWeights {
+				if other, ok := weights[term]; ok {
+					dot += weight * other
+					shared = append(shared, termWeight{term, weight * other})
+				}
+			}
+			if dot > 0 {
+				cosine = dot / (queryNorm * norm(weights))
+			}
+		}
+		sharedGenres, jaccard := genreOverlap(query.genres, doc.genres)
+		score := (1-i.genreWeight)*cosine + i.genreWeight*jaccard
+		if score <= 0 {
+			continue
+		}
+		similar = append(similar, &model.SimilarMovie{
+			MovieSummary: doc.movie,
+			Score:        math.Round(score*10000) / 10000,
+			SharedTerms:  topTerms(shared),
+			SharedGenres: sharedGenres,
+		})
+	}
+
+	slices.SortFunc(similar, func(a, b *model.SimilarMovie) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MovieId, b.MovieId)
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, true
+}
+
+// weights returns the TF-IDF vector of the document, with a sublinear term frequency and a smoothed inverse
+// document frequency.
+func (i *Index) weights(doc *document) map[string]float64 {
+	n := float64(len(i.docs))
+	weights := make(map[string]float64, len(doc.terms))
+	for term, count := range doc.terms {
+		idf := math.Log((1+n)/(1+float64(i.df[term]))) + 1
+		weights[term] = (1 + math.Log(float64(count))) * idf
+	}
+	return weights
+}
+
+func newDocument(movie *model.Movie) *document {
+	doc := &document{
+		movie:  model.MovieSummary{MovieId: movie.MovieId, MovieName: movie.MovieName, Runtime: movie.Runtime, ReleaseDate: movie.ReleaseDate},
+		terms:  map[string]int{},
+		genres: slices.Clone(movie.Genres),
+	}
+	for _, term := range tokenize(movie.Overview) {
+		doc.terms[term]++
+	}
+	return doc
+}
+
+func addTerms(df map[string]int, doc *document) {
+	for term := range doc.terms {
+		df[term]++
+	}
+}
+
+func removeTerms(df map[string]int, doc *document) {
+	for term := range doc.terms {
+		if df[term]--; df[term] <= 0 {
+			delete(df, term)
+		}
+	}
+}
+
+func norm(weights map[string]float64) float64 {
+	var sum float64
+	for _, weight := range weights {
+		sum += weight * weight
+	}
+	return math.Sqrt(sum)
+}
+
+// genreOverlap returns the genres in both sets and the Jaccard index of the sets.
+func genreOverlap(a, b []model.Genre) ([]model.Genre, float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return nil, 0
+	}
+	var shared []model.Genre
+	for _, genre := range a {
+		if slices.ContainsFunc(b, func(other model.Genre) bool { return other.ID == genre.ID }) {
+			shared = append(shared, genre)
+		}
+	}
+	return shared, float64(len(shared)) / float64(len(a)+len(b)-len(shared))
+}
+
+type termWeight struct {
+	term   string
+	weight float64
+}
+
+// topTerms returns the shared terms contributing most to the similarity.
+func topTerms(shared []termWeight) []string {
+	slices.SortFunc(shared, func(a, b termWeight) int {
+		if c := cmp.Compare(b.weight, a.weight); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.term, b.term)
+	})
+	terms := make([]string, 0, min(len(shared), maxSharedTerms))
+	for _, s := range shared[:min(len(shared), maxSharedTerms)] {
+		terms = append(terms, s.term)
+	}
+	return terms
+}
@@ -0,0 +1,73 @@
+package similarity
+
+import (
+	"rest_api/internal/api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"bear", "wanders", "chicago", "kitchen", "mess"},
+		tokenize("A bear wanders into Chicago's kitchen, and it is a mess."))
+	assert.Empty(t, tokenize(""))
+}
+
+func movies() []*model.Movie {
+	return []*model.Movie{
+		{MovieId: 1, MovieName: "The bear", Overview: "A young chef returns to run the family sandwich shop in Chicago.",
+			Genres: []model.Genre{{ID: 18, Name: "Drama"}, {ID: 35, Name: "Comedy"}}},
+		{MovieId: 2, MovieName: "Ratatouille", Overview: "A rat who dreams of becoming a chef in a Paris restaurant.",
+			Genres: []model.Genre{{ID: 16, Name: "Animation"}, {ID: 35, Name: "Comedy"}}},
+		{MovieId: 3, MovieName: "Boiling point", Overview: "A head chef balances a failing restaurant on its busiest night.",
+			Genres: []model.Genre{{ID: 18, Name: "Drama"}}},
+		{MovieId: 4, MovieName: "Alien", Overview: "The crew of a spaceship meets a deadly creature.",
+			Genres: []model.Genre{{ID: 878, Name: "Science Fiction"}}},
+	}
+}
+
+func TestIndex_Similar(t *testing.T) {
+	index := NewIndex(0.3)
+	index.Rebuild(movies())
+
+	similar, ok := index.Similar(3, 10)
+	require.True(t, ok)
+	require.Len(t, similar, 2)
+	assert.Equal(t, 1, similar[0].MovieId)
+	assert.Equal(t, []string{"chef"}, similar[0].SharedTerms)
+	assert.Equal(t, []model.Genre{{ID: 18, Name: "Drama"}}, similar[0].SharedGenres)
+	assert.Equal(t, 2, similar[1].MovieId)
+	assert.ElementsMatch(t, []string{"restaurant", "chef"}, similar[1].SharedTerms)
+	assert.Greater(t, similar[0].Score, similar[1].Score)
+
+	similar, _ = index.Similar(3, 1)
+	assert.Len(t, similar, 1)
+	similar, _ = index.Similar(4, 10)
+	assert.Empty(t, similar)
+	_, ok = index.Similar(5, 10)
+	assert.False(t, ok)
+}
+
+func TestIndex_Incremental(t *testing.T) {
+	all := movies()
+	index := NewIndex(0.3)
+	index.Rebuild(all[:2])
+	for _, movie := range all[2:] {
+		index.Upsert(movie)
+	}
+	rebuilt := NewIndex(0.3)
+	rebuilt.Rebuild(all)
+	assert.Equal(t, rebuilt.df, index.df)
+
+	index.Upsert(&model.Movie{MovieId: 4, MovieName: "Alien", Overview: "A chef on a spaceship."})
+	similar, _ := index.Similar(4, 10)
+	require.Len(t, similar, 3)
+	assert.Equal(t, []string{"chef"}, similar[0].SharedTerms)
+
+	index.Remove(4)
+	index.Remove(4)
+	rebuilt.Rebuild(all[:3])
+	assert.Equal(t, rebuilt.df, index.df)
+	assert.Equal(t, 3, index.Len())
+}
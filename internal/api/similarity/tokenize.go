@@ -0,0 +1,43 @@
+package similarity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are the English words too common in overviews to tell movies apart.
+var stopWords = toSet(`a about above after again against all also am an and any are as at be because been before being
+below between both but by can could did do does doing down during each even ever every few for from further get gets
+had has have having he her here hers herself him himself his how however i if in into is it its itself just me more
+most must my myself new no nor not now of off on once one only or other our ours ourselves out over own same she should
+so some such than that the their theirs them themselves then there these they this those through to too two under
+until up upon very was we were what when where which while who whom whose why will with within without would you your
+yours yourself yourselves`)
+
+// minTermLength drops initials and other fragments that carry no meaning on their own.
+const minTermLength = 3
+
+// tokenize splits the text into lower case terms, leaving out stop words and short fragments.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.Trim(word, "'")
+		word = strings.TrimSuffix(word, "'s")
+		if len([]rune(word)) < minTermLength || stopWords[word] {
+			continue
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+func toSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
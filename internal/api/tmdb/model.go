@@ -1,17 +1,81 @@
 package tmdb
 
-import "errors"
+import (
+	"errors"
+	"math"
+)
 
 var ErrNoMoviesFound = errors.New("no movies found")
+var ErrUnexpectedStatus = errors.New("unexpected response status")
 
 type Movie struct {
-	ID            int    `json:"id"`
-	OriginalTitle string `json:"original_title"`
-	Overview      string `json:"overview"`
-	Runtime       int32  `json:"runtime"`
+	ID            int     `json:"id"`
+	OriginalTitle string  `json:"original_title"`
+	Overview      string  `json:"overview"`
+	Runtime       int32   `json:"runtime"`
+	VoteAverage   float64 `json:"vote_average"`
+	ReleaseDate   string  `json:"release_date"`
+	// BelongsToCollection is only set in movie details.
+	BelongsToCollection *Collection `json:"belongs_to_collection"`
+	// GenreIds is set in search results and Genres in movie details.
+	GenreIds []int   `json:"genre_ids"`
+	Genres   []Genre `json:"genres"`
+}
+
+type Collection struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// GenreIDs returns the ids of the genres of the movie, from search results or details. It is nil if the
+// response had no genres.
+func (m *Movie) GenreIDs() []int {
+	if m.GenreIds != nil || m.Genres == nil {
+		return m.GenreIds
+	}
+	ids := make([]int, len(m.Genres))
+	for i, genre := range m.Genres {
+		ids[i] = genre.ID
+	}
+	return ids
+}
+
+// RoundedVoteAverage returns the vote average with the single decimal it is stored with.
+func (m *Movie) RoundedVoteAverage() float64 {
+	return math.Round(m.VoteAverage*10) / 10
+}
+
+// Credits is the cast and crew of a movie. People are identified by their TMDB person id.
+type Credits struct {
+	ID   int          `json:"id"`
+	Cast []CastMember `json:"cast"`
+	Crew []CrewMember `json:"crew"`
+}
+
+type CastMember struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	ProfilePath        string `json:"profile_path"`
+	KnownForDepartment string `json:"known_for_department"`
+	Character          string `json:"character"`
+	Order              int    `json:"order"`
+}
+
+type CrewMember struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	ProfilePath        string `json:"profile_path"`
+	KnownForDepartment string `json:"known_for_department"`
+	Job                string `json:"job"`
+	Department         string `json:"department"`
 }
 
 type GetMoviesResponse struct {
 	Results      []Movie `json:"results"`
-	TotalResults int     `json:"total_results""`
+	TotalResults int     `json:"total_results"`
 }
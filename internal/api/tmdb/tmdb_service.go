@@ -18,6 +18,7 @@ const (
 	apiKeyParam     = "?api_key=%s"
 	titleQuery      = "&query=%s"
 	idQuery         = "/%d"
+	creditsPath     = "/credits"
 )
 
 type Service struct {
@@ -59,7 +60,7 @@ func (s *Service) GetMovieByTitle(title string) (*Movie, error) {
 	//}
 	//responseBytes, err = io.ReadAll(response.Body)
 	//movie := &Movie{}
-	err = json.Unmarshal(responseBytes, movie)
+	err = json.Unmarshal(responseBytes, &movie)
 	//if err != nil {
 	//	return nil, err
 	//}
@@ -68,22 +69,46 @@ func (s *Service) GetMovieByTitle(title string) (*Movie, error) {
 	return &movie, nil
 }
 
-func (s *Service) GetMovieByID(id int) (*Movie, error) {
-	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, s.createDetailsUrl(id), nil)
-	if err != nil {
+func (s *Service) GetMovieByID(ctx context.Context, id int) (*Movie, error) {
+	movie := &Movie{}
+	if err := s.get(ctx, s.createDetailsUrl(id), movie); err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+// GetMovieCredits returns the cast and crew of the movie with the given TMDB id.
+func (s *Service) GetMovieCredits(ctx context.Context, id int) (*Credits, error) {
+	credits := &Credits{}
+	if err := s.get(ctx, s.createCreditsUrl(id), credits); err != nil {
 		return nil, err
 	}
+	return credits, nil
+}
+
+// get requests the url and unmarshals the response into value. ErrNoMoviesFound is returned if TMDB does not
+// know the movie.
+func (s *Service) get(ctx context.Context, url string, value any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
 	response, err := s.client.Do(request)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return ErrNoMoviesFound
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, response.StatusCode)
 	}
 	responseBytes, err := io.ReadAll(response.Body)
-	movie := &Movie{}
-	err = json.Unmarshal(responseBytes, movie)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return movie, nil
+	return json.Unmarshal(responseBytes, value)
 }
 
 func (s *Service) createSearchUrl(title string) string {
@@ -93,3 +118,7 @@ func (s *Service) createSearchUrl(title string) string {
 func (s *Service) createDetailsUrl(id int) string {
 	return strings.Join([]string{s.baseURL, detailsEndpoint, fmt.Sprintf(idQuery, id), fmt.Sprintf(apiKeyParam, config.ApiKey)}, "")
 }
+
+func (s *Service) createCreditsUrl(id int) string {
+	return strings.Join([]string{s.baseURL, detailsEndpoint, fmt.Sprintf(idQuery, id), creditsPath, fmt.Sprintf(apiKeyParam, config.ApiKey)}, "")
+}
@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// PasswordMatches compares the given password with the expected one in constant time.
+func PasswordMatches(password, expected string) bool {
+	passwordHash := sha256.Sum256([]byte(password))
+	expectedHash := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(passwordHash[:], expectedHash[:]) == 1
+}
@@ -8,7 +8,6 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"regexp"
 	"rest_api/internal/api/application"
 	"rest_api/internal/api/model"
@@ -43,7 +42,6 @@ func (s *AppSuite) SetupSuite() {
 	if err != nil {
 		log.Fatalf("test init failed: %s", err)
 	}
-	os.Setenv()
 	clearDatabase()
 }
 
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+)
+
+// AdvisoryLocker hands out Postgres session level advisory locks. Each lock is held on its own connection,
+// so it is released by the database if the process holding it dies.
+type AdvisoryLocker struct {
+	DB *sql.DB
+}
+
+// TryLock takes the lock for the key without waiting. If ok is true, unlock must be called to release it.
+func (l *AdvisoryLocker) TryLock(ctx context.Context, key string) (unlock func(), ok bool, err error) {
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1));", key).Scan(&ok)
+	if err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+
+	unlock = func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1));", key)
+		if err != nil {
+			slog.Error("Could not release advisory lock, discarding connection", "key", key, "error", err)
+			// a connection still holding the lock must not go back to the pool
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,77 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+
+	"github.com/lib/pq"
+)
+
+type AssetRepository struct {
+	DB *sql.DB
+}
+
+const assetColumns = "id, movieID, asset_type, object_key, size, checksum, content_type, created_at"
+
+func (r *AssetRepository) GetByMovie(movieId int) ([]*model.MovieAsset, error) {
+	rows, err := r.DB.Query("SELECT "+assetColumns+" FROM movie_assets WHERE movieID = $1 ORDER BY id;", movieId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []*model.MovieAsset{}
+	for rows.Next() {
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (r *AssetRepository) Get(movieId, assetId int) (*model.MovieAsset, error) {
+	row := r.DB.QueryRow("SELECT "+assetColumns+" FROM movie_assets WHERE movieID = $1 AND id = $2;", movieId, assetId)
+	asset, err := scanAsset(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return asset, nil
+}
+
+func (r *AssetRepository) Create(asset *model.MovieAsset) (*model.MovieAsset, error) {
+	err := r.DB.QueryRow(
+		"INSERT INTO movie_assets(movieID, asset_type, object_key, size, checksum, content_type) VALUES($1, $2, $3, $4, $5, $6) returning id, created_at;",
+		asset.MovieId, asset.AssetType, asset.ObjectKey, asset.Size, asset.Checksum, asset.ContentType).
+		Scan(&asset.ID, &asset.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrRecordExists
+		}
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+func scanAsset(row scanner) (*model.MovieAsset, error) {
+	asset := &model.MovieAsset{}
+	var checksum, contentType sql.NullString
+	err := row.Scan(&asset.ID, &asset.MovieId, &asset.AssetType, &asset.ObjectKey, &asset.Size, &checksum, &contentType, &asset.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	asset.Checksum = checksum.String
+	asset.ContentType = contentType.String
+	return asset, nil
+}
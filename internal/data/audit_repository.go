@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"strings"
+)
+
+// AuditRepository reads the audit log. Entries are written by the repositories of the audited entities, in
+// the transaction of the change they record, and are never updated or deleted.
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+const auditColumns = "id, occurred_at, actor, request_id, action, entity, entity_id, before, after"
+
+// List returns the entries matching the filter, most recent first.
+func (r *AuditRepository) List(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	if filter.Entity != "" {
+		args = append(args, filter.Entity)
+		conditions = append(conditions, fmt.Sprintf("entity = $%d", len(args)))
+	}
+	if filter.EntityID != "" {
+		args = append(args, filter.EntityID)
+		conditions = append(conditions, fmt.Sprintf("entity_id = $%d", len(args)))
+	}
+	if filter.Actor != "" {
+		args = append(args, filter.Actor)
+		conditions = append(conditions, fmt.Sprintf("actor = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("occurred_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("occurred_at < $%d", len(args)))
+	}
+	query := "SELECT " + auditColumns + " FROM audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT $%d;", len(args))
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*model.AuditEntry{}
+	for rows.Next() {
+		entry := &model.AuditEntry{}
+		var requestId sql.NullString
+		var before, after []byte
+		err = rows.Scan(&entry.ID, &entry.OccurredAt, &entry.Actor, &requestId, &entry.Action, &entry.Entity,
+			&entry.EntityID, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		entry.RequestID = requestId.String
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// insertAudit appends an entry to the audit log in the transaction of the change. The actor and request id
+// come from the context. before is nil for creations and after for deletions; for updates only the fields that
+// differ between the two are recorded.
+func insertAudit(ctx context.Context, tx *sql.Tx, action, entity, entityId string, before, after any) error {
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO audit_log(actor, request_id, action, entity, entity_id, before, after) VALUES($1, NULLIF($2, ''), $3, $4, $5, $6, $7);",
+		reqctx.Actor(ctx), reqctx.RequestID(ctx), action, entity, entityId, beforeJSON, afterJSON)
+	return err
+}
+
+// insertAuditCreations records the creation of several entities with a single statement. The entities must
+// marshal to JSON objects with an id field.
+func insertAuditCreations[T any](ctx context.Context, tx *sql.Tx, entity string, created []T) error {
+	if len(created) == 0 {
+		return nil
+	}
+	createdJSON, err := json.Marshal(created)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO audit_log(actor, request_id, action, entity, entity_id, after)
+		SELECT $1, NULLIF($2, ''), $3, $4, created->>'id', created FROM jsonb_array_elements($5::jsonb) AS created;`,
+		reqctx.Actor(ctx), reqctx.RequestID(ctx), model.AuditCreate, entity, string(createdJSON))
+	return err
+}
+
+// auditDiff returns the JSON recorded for the before and after states of a change. When both are present,
+// the fields with the same value are left out.
+func auditDiff(before, after any) (any, any, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if beforeFields != nil && afterFields != nil {
+		for field, value := range beforeFields {
+			if afterValue, ok := afterFields[field]; ok && reflect.DeepEqual(value, afterValue) {
+				delete(beforeFields, field)
+				delete(afterFields, field)
+			}
+		}
+	}
+	beforeJSON, err := nullableJSON(beforeFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterJSON, err := nullableJSON(afterFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeJSON, afterJSON, nil
+}
+
+// auditFields converts the state of an entity to its JSON fields.
+func auditFields(state any) (map[string]any, error) {
+	if value := reflect.ValueOf(state); !value.IsValid() || (value.Kind() == reflect.Pointer && value.IsNil()) {
+		return nil, nil
+	}
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	if err = json.Unmarshal(stateJSON, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func nullableJSON(fields map[string]any) (any, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return string(fieldsJSON), nil
+}
@@ -0,0 +1,217 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// CollectionRepository stores the collections of movies. TMDB collections are only changed through LinkTmdb,
+// manual collections only through Create, Update and Delete.
+type CollectionRepository struct {
+	DB *sql.DB
+}
+
+const collectionColumns = "c.id, c.name, c.description, c.tmdb_id, c.created_by, c.created_at, c.updated_at"
+
+// List returns the collections by name, with the number of their movies outside the trash.
+func (r *CollectionRepository) List(ctx context.Context) ([]*model.Collection, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+collectionColumns+`, count(m.movieID) FROM collections c
+		LEFT JOIN collection_movies cm ON cm.collection_id = c.id
+		LEFT JOIN movies m ON m.movieID = cm.movie_id AND m.deleted_at IS NULL
+		GROUP BY c.id ORDER BY c.name, c.id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*model.Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows, true)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// Get returns the collection with its movies outside the trash, the ones with a position first and the others
+// by release date.
+func (r *CollectionRepository) Get(ctx context.Context, collectionId int) (*model.Collection, error) {
+	collection, err := scanCollection(r.DB.QueryRowContext(ctx,
+		"SELECT "+collectionColumns+" FROM collections c WHERE c.id = $1;", collectionId), false)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `SELECT m.movieID, m.movieName, m.runtime, to_char(m.release_date, 'YYYY-MM-DD')
+		FROM collection_movies cm JOIN movies m ON m.movieID = cm.movie_id
+		WHERE cm.collection_id = $1 AND m.deleted_at IS NULL
+		ORDER BY cm.position NULLS LAST, m.release_date NULLS LAST, m.movieName;`, collectionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collection.Movies = []*model.MovieSummary{}
+	for rows.Next() {
+		movie := &model.MovieSummary{}
+		var runtime sql.NullInt64
+		var releaseDate sql.NullString
+		if err = rows.Scan(&movie.MovieId, &movie.MovieName, &runtime, &releaseDate); err != nil {
+			return nil, err
+		}
+		movie.Runtime = int(runtime.Int64)
+		movie.ReleaseDate = releaseDate.String
+		collection.Movies = append(collection.Movies, movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	collection.MovieCount = len(collection.Movies)
+	return collection, nil
+}
+
+// Create adds a manual collection with the movies in the given order. ErrRecordNotFound is returned if one of
+// the movies does not exist.
+func (r *CollectionRepository) Create(ctx context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	var collectionId int
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "INSERT INTO collections(name, description, created_by) VALUES($1, NULLIF($2, ''), $3) RETURNING id;",
+			collection.Name, collection.Description, collection.CreatedBy).Scan(&collectionId)
+		if err != nil {
+			return err
+		}
+		return insertCollectionMovies(ctx, tx, collectionId, movieIds)
+	})
+	if err != nil {
+		return nil, collectionError(err)
+	}
+	return r.Get(ctx, collectionId)
+}
+
+// Update changes the name and description of a manual collection and replaces its movies. ErrInvalidState is
+// returned for TMDB collections.
+func (r *CollectionRepository) Update(ctx context.Context, collection *model.Collection, movieIds []int) (*model.Collection, error) {
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE collections SET name = $2, description = NULLIF($3, ''), updated_at = now()
+			WHERE id = $1 AND tmdb_id IS NULL;`, collection.ID, collection.Name, collection.Description)
+		if err != nil {
+			return err
+		}
+		if err = r.expectManual(ctx, tx, res, collection.ID); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM collection_movies WHERE collection_id = $1;", collection.ID); err != nil {
+			return err
+		}
+		return insertCollectionMovies(ctx, tx, collection.ID, movieIds)
+	})
+	if err != nil {
+		return nil, collectionError(err)
+	}
+	return r.Get(ctx, collection.ID)
+}
+
+// Delete removes a manual collection. ErrInvalidState is returned for TMDB collections.
+func (r *CollectionRepository) Delete(ctx context.Context, collectionId int) error {
+	return withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM collections WHERE id = $1 AND tmdb_id IS NULL;", collectionId)
+		if err != nil {
+			return err
+		}
+		return r.expectManual(ctx, tx, res, collectionId)
+	})
+}
+
+// LinkTmdb makes the movie a member of the TMDB collection, created or renamed as needed, and of no other TMDB
+// collection. A zero tmdbId removes the movie from its TMDB collection.
+func (r *CollectionRepository) LinkTmdb(ctx context.Context, movieId, tmdbId int, name string) error {
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM collection_movies cm USING collections c
+			WHERE c.id = cm.collection_id AND c.tmdb_id IS NOT NULL AND c.tmdb_id <> $2 AND cm.movie_id = $1;`,
+			strconv.Itoa(movieId), tmdbId)
+		if err != nil || tmdbId == 0 {
+			return err
+		}
+		var collectionId int
+		err = tx.QueryRowContext(ctx, `INSERT INTO collections(name, tmdb_id) VALUES($1, $2)
+			ON CONFLICT (tmdb_id) DO UPDATE SET name = EXCLUDED.name,
+				updated_at = CASE WHEN collections.name = EXCLUDED.name THEN collections.updated_at ELSE now() END
+			RETURNING id;`, name, tmdbId).Scan(&collectionId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO collection_movies(collection_id, movie_id) VALUES($1, $2) ON CONFLICT DO NOTHING;",
+			collectionId, strconv.Itoa(movieId))
+		return err
+	})
+	return collectionError(err)
+}
+
+// expectManual checks that the statement affected a manual collection. Otherwise ErrInvalidState is returned
+// if the collection is a TMDB one, ErrRecordNotFound if it does not exist.
+func (r *CollectionRepository) expectManual(ctx context.Context, tx *sql.Tx, res sql.Result, collectionId int) error {
+	count, err := res.RowsAffected()
+	if err != nil || count > 0 {
+		return err
+	}
+	var exists bool
+	if err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM collections WHERE id = $1);", collectionId).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrInvalidState
+	}
+	return ErrRecordNotFound
+}
+
+// insertCollectionMovies adds the movies to the collection, positioned in the given order.
+func insertCollectionMovies(ctx context.Context, tx *sql.Tx, collectionId int, movieIds []int) error {
+	ids := make([]string, len(movieIds))
+	for i, movieId := range movieIds {
+		ids[i] = strconv.Itoa(movieId)
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO collection_movies(collection_id, movie_id, position)
+		SELECT $1, o.movie_id, o.position FROM unnest($2::varchar[]) WITH ORDINALITY AS o(movie_id, position);`,
+		collectionId, pq.Array(ids))
+	return err
+}
+
+// collectionError maps the foreign key violations of collection statements.
+func collectionError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+func scanCollection(row scanner, withCount bool) (*model.Collection, error) {
+	collection := &model.Collection{}
+	var description, createdBy sql.NullString
+	var tmdbId sql.NullInt64
+	dest := []any{&collection.ID, &collection.Name, &description, &tmdbId, &createdBy, &collection.CreatedAt, &collection.UpdatedAt}
+	if withCount {
+		dest = append(dest, &collection.MovieCount)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	collection.Description = description.String
+	collection.TmdbId = int(tmdbId.Int64)
+	collection.Manual = !tmdbId.Valid
+	collection.CreatedBy = createdBy.String
+	return collection, nil
+}
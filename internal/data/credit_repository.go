@@ -0,0 +1,196 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// CreditRepository stores the cast and crew of movies. People are shared between movies and identified by their
+// TMDB person id.
+type CreditRepository struct {
+	DB *sql.DB
+}
+
+// Replace sets the credits of the movie, adding or updating the people they refer to. ErrRecordNotFound is
+// returned if the movie does not exist.
+func (r *CreditRepository) Replace(ctx context.Context, movieId int, credits []*model.Credit) error {
+	var personIds []int64
+	var names, profilePaths, departments []string
+	seen := map[int]bool{}
+	roles := make([]string, len(credits))
+	characters := make([]string, len(credits))
+	jobs := make([]string, len(credits))
+	creditDepartments := make([]string, len(credits))
+	creditPersonIds := make([]int64, len(credits))
+	orders := make([]int64, len(credits))
+	for i, credit := range credits {
+		if !seen[credit.Person.ID] {
+			seen[credit.Person.ID] = true
+			personIds = append(personIds, int64(credit.Person.ID))
+			names = append(names, credit.Person.Name)
+			profilePaths = append(profilePaths, credit.Person.ProfilePath)
+			departments = append(departments, credit.Person.KnownForDepartment)
+		}
+		creditPersonIds[i] = int64(credit.Person.ID)
+		roles[i] = credit.Role
+		characters[i] = credit.Character
+		jobs[i] = credit.Job
+		creditDepartments[i] = credit.Department
+		orders[i] = int64(credit.Order)
+	}
+
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO people(id, name, profile_path, known_for_department)
+			SELECT id, name, NULLIF(profile_path, ''), NULLIF(department, '')
+			FROM unnest($1::integer[], $2::varchar[], $3::varchar[], $4::varchar[]) AS p(id, name, profile_path, department)
+			ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, profile_path = EXCLUDED.profile_path,
+				known_for_department = EXCLUDED.known_for_department, updated_at = now();`,
+			pq.Array(personIds), pq.Array(names), pq.Array(profilePaths), pq.Array(departments))
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM movie_credits WHERE movie_id = $1;", strconv.Itoa(movieId)); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO movie_credits(movie_id, person_id, role, character_name, job, department, credit_order)
+			SELECT $1::varchar, person_id, role, NULLIF(character_name, ''), NULLIF(job, ''), NULLIF(department, ''), credit_order
+			FROM unnest($2::integer[], $3::varchar[], $4::text[], $5::varchar[], $6::varchar[], $7::integer[])
+				AS c(person_id, role, character_name, job, department, credit_order);`,
+			strconv.Itoa(movieId), pq.Array(creditPersonIds), pq.Array(roles), pq.Array(characters), pq.Array(jobs),
+			pq.Array(creditDepartments), pq.Array(orders))
+		return err
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+// ListForMovie returns the credits of the movie, the cast first, each in billing order.
+func (r *CreditRepository) ListForMovie(ctx context.Context, movieId int) ([]*model.Credit, error) {
+	credits, err := r.ListForMovies(ctx, []int{movieId})
+	if err != nil {
+		return nil, err
+	}
+	if credits[movieId] == nil {
+		return []*model.Credit{}, nil
+	}
+	return credits[movieId], nil
+}
+
+// ListForMovies returns the credits of the movies by movie id, ordered like ListForMovie. Movies without credits
+// are left out.
+func (r *CreditRepository) ListForMovies(ctx context.Context, movieIds []int) (map[int][]*model.Credit, error) {
+	ids := make([]string, len(movieIds))
+	for i, movieId := range movieIds {
+		ids[i] = strconv.Itoa(movieId)
+	}
+	rows, err := r.DB.QueryContext(ctx, `SELECT c.movie_id, p.id, p.name, p.profile_path, p.known_for_department,
+			c.role, c.character_name, c.job, c.department, c.credit_order
+		FROM movie_credits c JOIN people p ON p.id = c.person_id
+		WHERE c.movie_id = ANY($1::varchar[]) ORDER BY c.movie_id, c.role, c.credit_order;`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := map[int][]*model.Credit{}
+	for rows.Next() {
+		var movieId int
+		credit := &model.Credit{Person: &model.Person{}}
+		var profilePath, knownFor, character, job, department sql.NullString
+		err = rows.Scan(&movieId, &credit.Person.ID, &credit.Person.Name, &profilePath, &knownFor,
+			&credit.Role, &character, &job, &department, &credit.Order)
+		if err != nil {
+			return nil, err
+		}
+		credit.Person.ProfilePath = profilePath.String
+		credit.Person.KnownForDepartment = knownFor.String
+		credit.Character = character.String
+		credit.Job = job.String
+		credit.Department = department.String
+		credits[movieId] = append(credits[movieId], credit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return credits, nil
+}
+
+// GetPerson returns the person with their filmography, leaving out the movies in the trash.
+func (r *CreditRepository) GetPerson(ctx context.Context, personId int) (*model.Person, error) {
+	people, err := r.GetPeople(ctx, []int{personId})
+	if err != nil {
+		return nil, err
+	}
+	person, ok := people[personId]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return person, nil
+}
+
+// GetPeople returns the people by id with their filmography, like GetPerson. Unknown ids are left out.
+func (r *CreditRepository) GetPeople(ctx context.Context, personIds []int) (map[int]*model.Person, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT id, name, profile_path, known_for_department FROM people WHERE id = ANY($1);",
+		pq.Array(personIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	people := map[int]*model.Person{}
+	for rows.Next() {
+		person := &model.Person{Filmography: []*model.FilmographyEntry{}}
+		var profilePath, knownFor sql.NullString
+		if err = rows.Scan(&person.ID, &person.Name, &profilePath, &knownFor); err != nil {
+			return nil, err
+		}
+		person.ProfilePath = profilePath.String
+		person.KnownForDepartment = knownFor.String
+		people[person.ID] = person
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(people) == 0 {
+		return people, nil
+	}
+
+	rows, err = r.DB.QueryContext(ctx, `SELECT c.person_id, m.movieID, m.movieName, m.runtime, c.role, c.character_name, c.job, c.department
+		FROM movie_credits c JOIN movies m ON m.movieID = c.movie_id
+		WHERE c.person_id = ANY($1) AND m.deleted_at IS NULL ORDER BY c.person_id, m.movieName, c.role, c.credit_order;`,
+		pq.Array(personIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var personId int
+		entry := &model.FilmographyEntry{Movie: &model.MovieSummary{}}
+		var runtime sql.NullInt64
+		var character, job, department sql.NullString
+		err = rows.Scan(&personId, &entry.Movie.MovieId, &entry.Movie.MovieName, &runtime, &entry.Role, &character, &job, &department)
+		if err != nil {
+			return nil, err
+		}
+		entry.Movie.Runtime = int(runtime.Int64)
+		entry.Character = character.String
+		entry.Job = job.String
+		entry.Department = department.String
+		if person, ok := people[personId]; ok {
+			person.Filmography = append(person.Filmography, entry)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return people, nil
+}
@@ -0,0 +1,95 @@
+// Package datatest provides a mock of the movie store, shared by the tests of the packages reading movies through it.
+package datatest
+
+import (
+	"context"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ data.MovieStore = (*MovieStore)(nil)
+
+// MovieStore is a mock of data.MovieStore. The methods returning a movie accept a nil one, and Stream passes the
+// movies of its first return value to the callback.
+type MovieStore struct {
+	mock.Mock
+}
+
+func (r *MovieStore) GetAll() ([]*model.Movie, error) {
+	args := r.Called()
+	return args.Get(0).([]*model.Movie), args.Error(1)
+}
+
+func (r *MovieStore) List(filter model.MovieFilter) ([]*model.Movie, error) {
+	args := r.Called(filter)
+	return args.Get(0).([]*model.Movie), args.Error(1)
+}
+
+func (r *MovieStore) GetMany(_ context.Context, movieIds []int) ([]*model.Movie, error) {
+	args := r.Called(movieIds)
+	return args.Get(0).([]*model.Movie), args.Error(1)
+}
+
+func (r *MovieStore) LastModified(_ context.Context) (time.Time, error) {
+	args := r.Called()
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (r *MovieStore) Stream(_ context.Context, filter model.MovieFilter, fn func(*model.Movie) error) error {
+	args := r.Called(filter)
+	for _, m := range args.Get(0).([]*model.Movie) {
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (r *MovieStore) GetTrash() ([]*model.Movie, error) {
+	args := r.Called()
+	return args.Get(0).([]*model.Movie), args.Error(1)
+}
+
+func (r *MovieStore) Restore(_ context.Context, movieId int) (*model.Movie, error) {
+	args := r.Called(movieId)
+	return movie(args), args.Error(1)
+}
+
+func (r *MovieStore) Purge(_ context.Context, deletedBefore time.Time) ([]int, error) {
+	args := r.Called(deletedBefore)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (r *MovieStore) Get(movieId int) (*model.Movie, error) {
+	args := r.Called(movieId)
+	return movie(args), args.Error(1)
+}
+
+func (r *MovieStore) Create(_ context.Context, m *model.Movie) (*model.Movie, error) {
+	args := r.Called(m)
+	return movie(args), args.Error(1)
+}
+
+func (r *MovieStore) CreateBatch(_ context.Context, movies []*model.Movie) ([]*model.Movie, error) {
+	args := r.Called(movies)
+	return args.Get(0).([]*model.Movie), args.Error(1)
+}
+
+func (r *MovieStore) Update(_ context.Context, m *model.Movie) (*model.Movie, error) {
+	args := r.Called(m)
+	return movie(args), args.Error(1)
+}
+
+func (r *MovieStore) Delete(_ context.Context, movieId int) error {
+	args := r.Called(movieId)
+	return args.Error(0)
+}
+
+// movie returns the movie of the first return value, which can be nil.
+func movie(args mock.Arguments) *model.Movie {
+	m, _ := args.Get(0).(*model.Movie)
+	return m
+}
@@ -3,4 +3,5 @@ package data
 import "errors"
 
 var ErrRecordNotFound = errors.New("record not found")
-var ErrRecordExists = errors.New("record already exists")
\ No newline at end of file
+var ErrRecordExists = errors.New("record already exists")
+var ErrInvalidState = errors.New("record is not in a valid state for the operation")
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"rest_api/internal/api/model"
+)
+
+// GenreRepository reads the genres, which are seeded with the genres of TMDB.
+type GenreRepository struct {
+	DB *sql.DB
+}
+
+// List returns the genres by name, with the number of movies outside the trash they are given to.
+func (r *GenreRepository) List(ctx context.Context) ([]*model.GenreCount, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT g.id, g.name, count(m.movieID) FROM genres g
+		LEFT JOIN movie_genres mg ON mg.genre_id = g.id
+		LEFT JOIN movies m ON m.movieID = mg.movie_id AND m.deleted_at IS NULL
+		GROUP BY g.id ORDER BY g.name;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*model.GenreCount{}
+	for rows.Next() {
+		genre := &model.GenreCount{}
+		if err = rows.Scan(&genre.ID, &genre.Name, &genre.MovieCount); err != nil {
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
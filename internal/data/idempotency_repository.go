@@ -0,0 +1,106 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"rest_api/internal/api/model"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	DB *sql.DB
+}
+
+// Claim records the key of a request about to be processed, for ttl, and returns true. If the key is already
+// recorded, it is returned instead, with false. Expired keys are claimed again, and so are the keys of the same
+// request still being processed after lockTimeout, whose replica most likely stopped before completing them.
+// Expiry is evaluated with the database clock.
+func (r *IdempotencyRepository) Claim(ctx context.Context, key *model.IdempotencyKey, ttl, lockTimeout time.Duration) (*model.IdempotencyKey, bool, error) {
+	err := r.DB.QueryRowContext(ctx, `INSERT INTO idempotency_keys(actor, key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, now(), now() + $4 * interval '1 millisecond')
+		ON CONFLICT (actor, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status = NULL,
+			headers = NULL,
+			body = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < now()
+			OR (idempotency_keys.status IS NULL AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+				AND idempotency_keys.created_at < now() - $5 * interval '1 millisecond')
+		RETURNING created_at, expires_at;`,
+		key.Actor, key.Key, key.Fingerprint, ttl.Milliseconds(), lockTimeout.Milliseconds()).Scan(&key.CreatedAt, &key.ExpiresAt)
+	if err == nil {
+		return key, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	existing, err := r.Get(ctx, key.Actor, key.Key)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, actor, key string) (*model.IdempotencyKey, error) {
+	existing := &model.IdempotencyKey{Actor: actor, Key: key}
+	var status sql.NullInt32
+	var headers []byte
+	err := r.DB.QueryRowContext(ctx, `SELECT fingerprint, status, headers, body, created_at, expires_at
+		FROM idempotency_keys WHERE actor = $1 AND key = $2;`, actor, key).
+		Scan(&existing.Fingerprint, &status, &headers, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	existing.Status = int(status.Int32)
+	if headers != nil {
+		if err = json.Unmarshal(headers, &existing.Headers); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// Complete stores the response of the request of a claimed key.
+func (r *IdempotencyRepository) Complete(ctx context.Context, key *model.IdempotencyKey) error {
+	headers, err := json.Marshal(key.Headers)
+	if err != nil {
+		return err
+	}
+	res, err := r.DB.ExecContext(ctx, `UPDATE idempotency_keys SET status = $4, headers = $5, body = $6
+		WHERE actor = $1 AND key = $2 AND fingerprint = $3 AND status IS NULL;`,
+		key.Actor, key.Key, key.Fingerprint, key.Status, headers, key.Body)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Release forgets a claimed key whose request was not processed, so that it can be retried.
+func (r *IdempotencyRepository) Release(ctx context.Context, key *model.IdempotencyKey) error {
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE actor = $1 AND key = $2 AND fingerprint = $3 AND status IS NULL;",
+		key.Actor, key.Key, key.Fingerprint)
+	return err
+}
+
+// Purge removes the expired keys.
+func (r *IdempotencyRepository) Purge(ctx context.Context) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < now();")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+
+	"github.com/lib/pq"
+)
+
+type ImportRepository struct {
+	DB *sql.DB
+}
+
+func (r *ImportRepository) Create(imp *model.MovieImport) (*model.MovieImport, error) {
+	err := r.DB.QueryRow(
+		"INSERT INTO movie_imports(format, status, total_rows, processed_rows, failed_rows) VALUES($1, $2, $3, $4, $5) returning id, created_at;",
+		imp.Format, imp.Status, imp.TotalRows, imp.ProcessedRows, imp.FailedRows).Scan(&imp.ID, &imp.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+func (r *ImportRepository) Get(id int) (*model.MovieImport, error) {
+	imp := &model.MovieImport{}
+	var importErr sql.NullString
+	var finishedAt sql.NullTime
+	err := r.DB.QueryRow(
+		"SELECT id, format, status, total_rows, processed_rows, imported_rows, failed_rows, error, created_at, finished_at FROM movie_imports WHERE id = $1;", id).
+		Scan(&imp.ID, &imp.Format, &imp.Status, &imp.TotalRows, &imp.ProcessedRows, &imp.ImportedRows, &imp.FailedRows, &importErr, &imp.CreatedAt, &finishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	imp.Error = importErr.String
+	if finishedAt.Valid {
+		imp.FinishedAt = &finishedAt.Time
+	}
+	return imp, nil
+}
+
+// UpdateProgress stores the status, counters, error and finish time of the import.
+func (r *ImportRepository) UpdateProgress(imp *model.MovieImport) error {
+	_, err := r.DB.Exec(
+		"UPDATE movie_imports SET status = $2, processed_rows = $3, imported_rows = $4, failed_rows = $5, error = NULLIF($6, ''), finished_at = $7 WHERE id = $1;",
+		imp.ID, imp.Status, imp.ProcessedRows, imp.ImportedRows, imp.FailedRows, imp.Error, imp.FinishedAt)
+	return err
+}
+
+func (r *ImportRepository) AddErrors(importId int, rowErrors []*model.ImportRowError) error {
+	if len(rowErrors) == 0 {
+		return nil
+	}
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(pq.CopyIn("movie_import_errors", "import_id", "line", "movie_id", "title", "message"))
+	if err != nil {
+		return err
+	}
+	for _, rowErr := range rowErrors {
+		if _, err = stmt.Exec(importId, rowErr.Line, nullableInt(rowErr.MovieId), rowErr.Title, rowErr.Message); err != nil {
+			return err
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		return err
+	}
+	if err = stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetErrors returns the row errors of an import ordered by line. A limit of 0 returns all of them.
+func (r *ImportRepository) GetErrors(importId, limit int) ([]*model.ImportRowError, error) {
+	query := "SELECT line, movie_id, title, message FROM movie_import_errors WHERE import_id = $1 ORDER BY line"
+	args := []any{importId}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rowErrors := []*model.ImportRowError{}
+	for rows.Next() {
+		rowErr := &model.ImportRowError{}
+		var movieId sql.NullInt64
+		var title sql.NullString
+		if err = rows.Scan(&rowErr.Line, &movieId, &title, &rowErr.Message); err != nil {
+			return nil, err
+		}
+		rowErr.MovieId = int(movieId.Int64)
+		rowErr.Title = title.String
+		rowErrors = append(rowErrors, rowErr)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rowErrors, nil
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+	"time"
+)
+
+type LeaseRepository struct {
+	DB *sql.DB
+}
+
+// TryAcquire takes or renews the named lease for the holder. It fails if another holder owns a lease that has
+// not expired yet. Expiry is evaluated with the database clock, so replicas do not need synchronised clocks.
+func (r *LeaseRepository) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	var current string
+	err := r.DB.QueryRowContext(ctx, `INSERT INTO scheduler_leases(name, holder, acquired_at, renewed_at, expires_at)
+		VALUES ($1, $2, now(), now(), now() + $3 * interval '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET
+			holder = EXCLUDED.holder,
+			acquired_at = CASE WHEN scheduler_leases.holder = EXCLUDED.holder THEN scheduler_leases.acquired_at ELSE now() END,
+			renewed_at = now(),
+			expires_at = EXCLUDED.expires_at
+		WHERE scheduler_leases.holder = EXCLUDED.holder OR scheduler_leases.expires_at < now()
+		RETURNING holder;`, name, holder, ttl.Milliseconds()).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return current == holder, nil
+}
+
+// Release gives up the lease if it is still owned by the holder, so another replica can take over immediately.
+func (r *LeaseRepository) Release(ctx context.Context, name, holder string) error {
+	_, err := r.DB.ExecContext(ctx, "DELETE FROM scheduler_leases WHERE name = $1 AND holder = $2;", name, holder)
+	return err
+}
+
+func (r *LeaseRepository) Get(ctx context.Context, name string) (*model.Lease, error) {
+	lease := &model.Lease{}
+	err := r.DB.QueryRowContext(ctx, "SELECT name, holder, acquired_at, renewed_at, expires_at FROM scheduler_leases WHERE name = $1;", name).
+		Scan(&lease.Name, &lease.Holder, &lease.AcquiredAt, &lease.RenewedAt, &lease.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return lease, nil
+}
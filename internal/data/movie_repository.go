@@ -1,10 +1,17 @@
 package data
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -13,51 +20,84 @@ type MovieRepository struct {
 	DB *sql.DB
 }
 
+const (
+	movieColumns = "movieId, movieName, overview, tmdb_id, runtime, to_char(release_date, 'YYYY-MM-DD'), tmdb_vote_average, " +
+		"rating_count, rating_sum, " + movieGenresColumn + ", " + movieTagsColumn + ", updated_at"
+	movieGenresColumn = `(SELECT COALESCE(jsonb_agg(jsonb_build_object('id', g.id, 'name', g.name) ORDER BY g.name), '[]')
+		FROM movie_genres mg JOIN genres g ON g.id = mg.genre_id WHERE mg.movie_id = movies.movieID)`
+	movieTagsColumn = `(SELECT COALESCE(jsonb_agg(jsonb_build_object('id', t.id, 'name', t.name) ORDER BY lower(t.name)), '[]')
+		FROM movie_tags mt JOIN tags t ON t.id = mt.tag_id WHERE mt.movie_id = movies.movieID)`
+	movieCursorFetchSize = 500
+)
+
 // TODO handle not found in all
 func (r *MovieRepository) GetAll() ([]*model.Movie, error) {
 	fmt.Println("Getting movies...")
 
-	rows, err := r.DB.Query("SELECT movieId, movieName, overview FROM movies")
+	rows, err := r.DB.Query("SELECT " + movieColumns + " FROM movies WHERE deleted_at IS NULL")
 
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var movies []*model.Movie
+	return scanMovies(rows)
+}
 
-	for rows.Next() {
-		var movieID int
-		var movieName string
-		var overview sql.NullString
+// List returns the movies matching the filter.
+func (r *MovieRepository) List(filter model.MovieFilter) ([]*model.Movie, error) {
+	fmt.Printf("Listing movies with filter %+v\n", filter)
+
+	query, args := movieFilterQuery(filter)
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		err := rows.Scan(&movieID, &movieName, &overview)
+	return scanMovies(rows)
+}
+
+// Stream calls fn for every movie matching the filter. Movies are read through a server-side cursor, so only
+// movieCursorFetchSize rows are held in memory at a time. Stream stops at the first error returned by fn.
+func (r *MovieRepository) Stream(ctx context.Context, filter model.MovieFilter, fn func(*model.Movie) error) error {
+	fmt.Printf("Streaming movies with filter %+v\n", filter)
+
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args := movieFilterQuery(filter)
+	if _, err = tx.ExecContext(ctx, "DECLARE movie_cursor NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return err
+	}
+	for {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("FETCH %d FROM movie_cursor;", movieCursorFetchSize))
 		if err != nil {
-			return nil, err
+			return err
 		}
-		movie := &model.Movie{
-			MovieId:   movieID,
-			MovieName: movieName,
+		movies, err := scanMovies(rows)
+		rows.Close()
+		if err != nil {
+			return err
 		}
-		if overview.Valid {
-			movie.Overview = overview.String
+		for _, movie := range movies {
+			if err = fn(movie); err != nil {
+				return err
+			}
+		}
+		if len(movies) < movieCursorFetchSize {
+			return tx.Commit()
 		}
-		movies = append(movies, movie)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
 	}
-
-	return movies, nil
 }
 
 func (r *MovieRepository) Get(movieId int) (*model.Movie, error) {
 	fmt.Printf("Getting movie with movieId %d\n", movieId)
 
-	var overview sql.NullString
-	movie := model.Movie{}
-	err := r.DB.QueryRow("SELECT movieId, movieName, overview FROM movies WHERE movieID = $1;", movieId).
-		Scan(&movie.MovieId, &movie.MovieName, &overview)
+	movie, err := scanMovie(r.DB.QueryRow("SELECT "+movieColumns+" FROM movies WHERE movieID = $1 AND deleted_at IS NULL;", movieId))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -65,65 +105,457 @@ func (r *MovieRepository) Get(movieId int) (*model.Movie, error) {
 		}
 		return nil, err
 	}
-	if overview.Valid {
-		movie.Overview = overview.String
+
+	return movie, nil
+}
+
+// LastModified returns when a movie was last created, changed, deleted or purged. It never goes back, unlike the
+// last updated_at of the movies.
+func (r *MovieRepository) LastModified(ctx context.Context) (time.Time, error) {
+	var lastModified time.Time
+	err := r.DB.QueryRowContext(ctx, "SELECT modified_at FROM catalog_modified;").Scan(&lastModified)
+	return lastModified, err
+}
+
+// GetMany returns the movies with the given ids, in no particular order. Missing movies and movies in the trash
+// are left out.
+func (r *MovieRepository) GetMany(ctx context.Context, movieIds []int) ([]*model.Movie, error) {
+	ids := make([]string, len(movieIds))
+	for i, movieId := range movieIds {
+		ids[i] = strconv.Itoa(movieId)
 	}
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE movieID = ANY($1::varchar[]) AND deleted_at IS NULL;",
+		pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return &movie, nil
+	return scanMovies(rows)
 }
 
-func (r *MovieRepository) Create(movie *model.Movie) (*model.Movie, error) {
+func (r *MovieRepository) Create(ctx context.Context, movie *model.Movie) (*model.Movie, error) {
 	fmt.Printf("Inserting new movie with ID: %d  and name: %s\n", movie.MovieId, movie.MovieName)
 
-	var lastInsertID int
-	err := r.DB.QueryRow(
-		"INSERT INTO movies(movieID, movieName, overview) VALUES($1, $2, $3) returning id;", movie.MovieId, movie.MovieName, movie.Overview).Scan(&lastInsertID)
+	// movies created from TMDB data are enriched at creation time
+	enrichedAt := sql.NullTime{Time: time.Now(), Valid: movie.TmdbId != 0}
+
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO movies(movieID, movieName, overview, tmdb_id, runtime, tmdb_vote_average, release_date, enriched_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8);",
+			movie.MovieId, movie.MovieName, movie.Overview, nullableInt(movie.TmdbId), nullableInt(movie.Runtime),
+			nullableFloat(movie.TmdbVoteAverage), nullableString(movie.ReleaseDate), enrichedAt)
+		if err != nil {
+			return err
+		}
+		if movie.Genres != nil {
+			if movie.Genres, err = replaceMovieGenres(ctx, tx, movie.MovieId, movie.Genres); err != nil {
+				return err
+			}
+		}
+		return insertAudit(ctx, tx, model.AuditCreate, model.AuditEntityMovie, strconv.Itoa(movie.MovieId), nil, movie)
+	})
 	if err != nil {
-		pqErr := err.(*pq.Error)
-		switch pqErr.Code {
-		case "23505":
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
 			return nil, ErrRecordExists
-		default:
-			return nil, err
 		}
+		return nil, err
 	}
 
 	return movie, nil
 }
 
-func (r *MovieRepository) Update(movie *model.Movie) (*model.Movie, error) {
+// Update stores the movie and returns it as persisted, recording the new state as a revision. A zero TMDB id,
+// vote average or release date and nil genres keep the current ones; the rating aggregate is only changed by the
+// RatingRepository.
+func (r *MovieRepository) Update(ctx context.Context, movie *model.Movie) (*model.Movie, error) {
 	fmt.Printf("Updating movie with ID: %d\n", movie.MovieId)
 
-	res, err := r.DB.Exec(
-		"UPDATE movies SET movieId = $1, movieName = $2, overview = $3 WHERE movieId = $1;", movie.MovieId, movie.MovieName, movie.Overview)
-
+	var updated *model.Movie
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		before, err := scanMovie(tx.QueryRowContext(ctx,
+			"SELECT "+movieColumns+" FROM movies WHERE movieID = $1 AND deleted_at IS NULL FOR UPDATE;", movie.MovieId))
+		if err != nil {
+			return err
+		}
+		if movie.Genres != nil {
+			if _, err = replaceMovieGenres(ctx, tx, movie.MovieId, movie.Genres); err != nil {
+				return err
+			}
+		}
+		updated, err = scanMovie(tx.QueryRowContext(ctx,
+			`UPDATE movies SET movieName = $2, overview = $3, runtime = COALESCE($4, runtime), tmdb_id = COALESCE($5, tmdb_id),
+				tmdb_vote_average = COALESCE($6, tmdb_vote_average), release_date = COALESCE($7, release_date)
+			WHERE movieID = $1 RETURNING `+movieColumns+";",
+			movie.MovieId, movie.MovieName, movie.Overview, nullableInt(movie.Runtime), nullableInt(movie.TmdbId),
+			nullableFloat(movie.TmdbVoteAverage), nullableString(movie.ReleaseDate)))
+		if err != nil {
+			return err
+		}
+		if err = insertAudit(ctx, tx, model.AuditUpdate, model.AuditEntityMovie, strconv.Itoa(movie.MovieId), before, updated); err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, before, updated)
+	})
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
 		return nil, err
 	}
-	count, err := res.RowsAffected()
+	return updated, nil
+}
+
+// Delete moves the movie to the trash. It is only removed for good by Purge.
+func (r *MovieRepository) Delete(ctx context.Context, movieId int) error {
+	fmt.Printf("Deleting movie with movieId %d\n", movieId)
+
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		deleted, err := scanMovie(tx.QueryRowContext(ctx,
+			"UPDATE movies SET deleted_at = now() WHERE movieID = $1 AND deleted_at IS NULL RETURNING "+movieColumns+";", movieId))
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, model.AuditDelete, model.AuditEntityMovie, strconv.Itoa(movieId), deleted, nil)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetTrash returns the deleted movies, most recently deleted first.
+func (r *MovieRepository) GetTrash() ([]*model.Movie, error) {
+	fmt.Println("Getting deleted movies...")
+
+	rows, err := r.DB.Query("SELECT " + movieColumns + ", deleted_at FROM movies WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC;")
 	if err != nil {
 		return nil, err
 	}
-	if count == 0 {
-		return nil, ErrRecordNotFound
+	defer rows.Close()
+
+	movies := []*model.Movie{}
+	for rows.Next() {
+		var deletedAt time.Time
+		movie, err := scanMovie(rows, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		movie.DeletedAt = &deletedAt
+		movies = append(movies, movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// Restore takes the movie out of the trash.
+func (r *MovieRepository) Restore(ctx context.Context, movieId int) (*model.Movie, error) {
+	fmt.Printf("Restoring movie with movieId %d\n", movieId)
+
+	var movie *model.Movie
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		var err error
+		movie, err = scanMovie(tx.QueryRowContext(ctx,
+			"UPDATE movies SET deleted_at = NULL WHERE movieID = $1 AND deleted_at IS NOT NULL RETURNING "+movieColumns+";", movieId))
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, model.AuditRestore, model.AuditEntityMovie, strconv.Itoa(movieId), nil, movie)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
 	}
 	return movie, nil
 }
 
-func (r *MovieRepository) Delete(movieId int) error {
-	fmt.Printf("Deleting movie with movieId %s\n", movieId)
+// Purge permanently removes the movies deleted before the given time and returns their ids. The removal of every
+// movie is recorded in the audit log by the same statement.
+func (r *MovieRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]int, error) {
+	rows, err := r.DB.QueryContext(ctx, `WITH purged AS (
+			DELETE FROM movies WHERE deleted_at < $1 RETURNING movieID, movieName, overview, tmdb_id, runtime, deleted_at),
+		audited AS (
+			INSERT INTO audit_log(actor, request_id, action, entity, entity_id, before)
+			SELECT $2, NULLIF($3, ''), $4, $5, movieID, jsonb_strip_nulls(jsonb_build_object(
+				'id', movieID::int, 'title', movieName, 'overview', overview, 'tmdbId', tmdb_id, 'runtime', runtime, 'deletedAt', deleted_at))
+			FROM purged)
+		SELECT movieID::int FROM purged;`,
+		deletedBefore, reqctx.Actor(ctx), reqctx.RequestID(ctx), model.AuditPurge, model.AuditEntityMovie)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movieIds := []int{}
+	for rows.Next() {
+		var movieId int
+		if err = rows.Scan(&movieId); err != nil {
+			return nil, err
+		}
+		movieIds = append(movieIds, movieId)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movieIds, nil
+}
 
-	res, err := r.DB.Exec("DELETE FROM movies WHERE movieID = $1;", movieId)
+// CreateBatch inserts the movies with a single COPY. Movies whose id already exists are skipped, only the
+// inserted ones are returned.
+func (r *MovieRepository) CreateBatch(ctx context.Context, movies []*model.Movie) ([]*model.Movie, error) {
+	fmt.Printf("Inserting batch of %d movies\n", len(movies))
 
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	count, err := res.RowsAffected()
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "CREATE TEMP TABLE movies_batch (movieid varchar(50), moviename varchar(50), overview text, tmdb_id integer, runtime smallint, tmdb_vote_average numeric(3, 1), release_date date, enriched_at timestamptz) ON COMMIT DROP;")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if count == 0 {
-		return ErrRecordNotFound
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("movies_batch", "movieid", "moviename", "overview", "tmdb_id", "runtime", "tmdb_vote_average", "release_date", "enriched_at"))
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	now := time.Now()
+	for _, movie := range movies {
+		enrichedAt := sql.NullTime{Time: now, Valid: movie.TmdbId != 0}
+		_, err = stmt.ExecContext(ctx, strconv.Itoa(movie.MovieId), movie.MovieName, movie.Overview, nullableInt(movie.TmdbId), nullableInt(movie.Runtime),
+			nullableFloat(movie.TmdbVoteAverage), nullableString(movie.ReleaseDate), enrichedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return nil, err
+	}
+	if err = stmt.Close(); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `INSERT INTO movies(movieID, movieName, overview, tmdb_id, runtime, tmdb_vote_average, release_date, enriched_at)
+		SELECT DISTINCT ON (movieid) movieid, moviename, overview, tmdb_id, runtime, tmdb_vote_average, release_date, enriched_at FROM movies_batch
+		ON CONFLICT (movieID) DO NOTHING RETURNING movieID;`)
+	if err != nil {
+		return nil, err
+	}
+	inserted := map[int]bool{}
+	for rows.Next() {
+		var movieId int
+		if err = rows.Scan(&movieId); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inserted[movieId] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	created := make([]*model.Movie, 0, len(inserted))
+	var genreMovieIds []string
+	var genreIds []int64
+	for _, movie := range movies {
+		if inserted[movie.MovieId] {
+			created = append(created, movie)
+			delete(inserted, movie.MovieId)
+			for _, genre := range movie.Genres {
+				genreMovieIds = append(genreMovieIds, strconv.Itoa(movie.MovieId))
+				genreIds = append(genreIds, int64(genre.ID))
+			}
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO movie_genres(movie_id, genre_id)
+		SELECT p.movie_id, p.genre_id FROM unnest($1::varchar[], $2::integer[]) AS p(movie_id, genre_id)
+		JOIN genres g ON g.id = p.genre_id ON CONFLICT DO NOTHING;`, pq.Array(genreMovieIds), pq.Array(genreIds))
+	if err != nil {
+		return nil, err
+	}
+	if err = insertAuditCreations(ctx, tx, model.AuditEntityMovie, created); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetStale returns movies linked to TMDB whose metadata was last fetched before the given time, least recently
+// attempted first, so that movies whose refresh keeps failing do not hold back the others.
+func (r *MovieRepository) GetStale(enrichedBefore time.Time, limit int) ([]*model.Movie, error) {
+	rows, err := r.DB.Query(
+		"SELECT "+movieColumns+" FROM movies WHERE deleted_at IS NULL AND tmdb_id IS NOT NULL AND (enriched_at IS NULL OR enriched_at < $1) ORDER BY enrich_attempted_at NULLS FIRST, enriched_at NULLS FIRST LIMIT $2;",
+		enrichedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMovies(rows)
+}
+
+// MarkEnriched records that the metadata of a movie was fetched from TMDB at the given time.
+func (r *MovieRepository) MarkEnriched(movieId int, at time.Time) error {
+	_, err := r.DB.Exec("UPDATE movies SET enriched_at = $2, enrich_attempted_at = $2 WHERE movieID = $1;", movieId, at)
+	return err
+}
+
+// MarkEnrichAttempted records that fetching the metadata of a movie from TMDB failed at the given time.
+func (r *MovieRepository) MarkEnrichAttempted(movieId int, at time.Time) error {
+	_, err := r.DB.Exec("UPDATE movies SET enrich_attempted_at = $2 WHERE movieID = $1;", movieId, at)
+	return err
+}
+
+func scanMovies(rows *sql.Rows) ([]*model.Movie, error) {
+	var movies []*model.Movie
+
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, movie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+// scanMovie scans a row of movieColumns, followed by the extra columns if any.
+func scanMovie(row scanner, extra ...any) (*model.Movie, error) {
+	movie := &model.Movie{}
+	var overview, releaseDate sql.NullString
+	var tmdbId, runtime sql.NullInt64
+	var voteAverage sql.NullFloat64
+	var ratingSum int
+	var genres, tags []byte
+
+	dest := append([]any{&movie.MovieId, &movie.MovieName, &overview, &tmdbId, &runtime, &releaseDate, &voteAverage,
+		&movie.RatingCount, &ratingSum, &genres, &tags, &movie.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(genres, &movie.Genres); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tags, &movie.Tags); err != nil {
+		return nil, err
+	}
+	movie.Overview = overview.String
+	movie.TmdbId = int(tmdbId.Int64)
+	movie.Runtime = int(runtime.Int64)
+	movie.ReleaseDate = releaseDate.String
+	movie.TmdbVoteAverage = voteAverage.Float64
+	if movie.RatingCount > 0 {
+		movie.RatingAverage = math.Round(float64(ratingSum)/float64(movie.RatingCount)*10) / 10
+	}
+	return movie, nil
+}
+
+// movieFilterQuery builds the select query and its arguments for the filter.
+func movieFilterQuery(filter model.MovieFilter) (string, []any) {
+	var conditions []string
+	var args []any
+	if filter.Title != "" {
+		args = append(args, "%"+likeEscaper.Replace(filter.Title)+"%")
+		conditions = append(conditions, fmt.Sprintf("movieName ILIKE $%d", len(args)))
+	}
+	if filter.MinRuntime > 0 {
+		args = append(args, filter.MinRuntime)
+		conditions = append(conditions, fmt.Sprintf("runtime >= $%d", len(args)))
+	}
+	if filter.MaxRuntime > 0 {
+		args = append(args, filter.MaxRuntime)
+		conditions = append(conditions, fmt.Sprintf("runtime <= $%d", len(args)))
+	}
+	if len(filter.Genres) > 0 {
+		args = append(args, pq.Array(filter.Genres))
+		conditions = append(conditions, labelCondition(
+			"SELECT mg.movie_id FROM movie_genres mg JOIN genres g ON g.id = mg.genre_id WHERE lower(g.name) = ANY($%d)",
+			len(args), len(filter.Genres), filter.MatchAllGenres))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, pq.Array(filter.Tags))
+		conditions = append(conditions, labelCondition(
+			"SELECT mt.movie_id FROM movie_tags mt JOIN tags t ON t.id = mt.tag_id WHERE lower(t.name) = ANY($%d)",
+			len(args), len(filter.Tags), filter.MatchAllTags))
+	}
+
+	if filter.PersonId > 0 {
+		args = append(args, filter.PersonId)
+		conditions = append(conditions, fmt.Sprintf("movieID IN (SELECT movie_id FROM movie_credits WHERE person_id = $%d)", len(args)))
+	}
+
+	query := "SELECT " + movieColumns + " FROM movies WHERE deleted_at IS NULL"
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" ORDER BY movieID LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+	return query, args
+}
+
+// labelCondition restricts the movies to the ones returned by the query of genres or tags, which takes the
+// names as parameter n. If all is set, movies must be returned for every one of the count names.
+func labelCondition(query string, n, count int, all bool) string {
+	query = fmt.Sprintf(query, n)
+	if all {
+		query += fmt.Sprintf(" GROUP BY 1 HAVING count(*) = %d", count)
+	}
+	return "movieID IN (" + query + ")"
+}
+
+// replaceMovieGenres sets the genres of the movie and returns them with their names. Unknown genre ids are
+// ignored.
+func replaceMovieGenres(ctx context.Context, tx *sql.Tx, movieId int, genres []model.Genre) ([]model.Genre, error) {
+	ids := make([]int64, len(genres))
+	for i, genre := range genres {
+		ids[i] = int64(genre.ID)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_genres WHERE movie_id = $1;", strconv.Itoa(movieId)); err != nil {
+		return nil, err
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO movie_genres(movie_id, genre_id)
+		SELECT $1::varchar, id FROM genres WHERE id = ANY($2::integer[]) ON CONFLICT DO NOTHING;`,
+		strconv.Itoa(movieId), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	var named []byte
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(jsonb_agg(jsonb_build_object('id', g.id, 'name', g.name) ORDER BY g.name), '[]')
+		FROM movie_genres mg JOIN genres g ON g.id = mg.genre_id WHERE mg.movie_id = $1;`, strconv.Itoa(movieId)).Scan(&named)
+	if err != nil {
+		return nil, err
+	}
+	stored := []model.Genre{}
+	return stored, json.Unmarshal(named, &stored)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func nullableInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+func nullableString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+func nullableFloat(v float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: v, Valid: v != 0}
 }
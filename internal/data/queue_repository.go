@@ -0,0 +1,181 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"rest_api/internal/api/model"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type QueueRepository struct {
+	DB *sql.DB
+}
+
+const queueJobColumns = "id, kind, payload, status, attempts, max_attempts, run_at, locked_by, last_error, created_at, updated_at, finished_at"
+
+func (r *QueueRepository) Enqueue(ctx context.Context, job *model.QueueJob) (*model.QueueJob, error) {
+	return scanQueueJob(r.DB.QueryRowContext(ctx,
+		"INSERT INTO queue_jobs(kind, payload, max_attempts) VALUES($1, $2, $3) RETURNING "+queueJobColumns+";",
+		job.Kind, []byte(job.Payload), job.MaxAttempts))
+}
+
+// Claim locks the next due job of one of the kinds for the worker and counts an attempt. Running jobs whose lock
+// expired, because their worker died, are claimed again if they have attempts left, and are dead otherwise, so that
+// a job crashing its worker is not retried forever. ErrRecordNotFound is returned if no job is due.
+func (r *QueueRepository) Claim(ctx context.Context, kinds []string, worker string, lock time.Duration) (*model.QueueJob, error) {
+	var job *model.QueueJob
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE queue_jobs SET
+				status = 'dead', last_error = 'lock expired on the last attempt', finished_at = now(),
+				locked_by = NULL, locked_until = NULL, updated_at = now()
+			WHERE kind = ANY($1) AND status = 'running' AND locked_until < now() AND attempts >= max_attempts;`,
+			pq.Array(kinds))
+		if err != nil {
+			return err
+		}
+		job, err = scanQueueJob(tx.QueryRowContext(ctx, `UPDATE queue_jobs SET
+				status = 'running', attempts = attempts + 1, locked_by = $2,
+				locked_until = now() + $3 * interval '1 millisecond', updated_at = now()
+			WHERE id = (
+				SELECT id FROM queue_jobs
+				WHERE kind = ANY($1) AND (
+					(status = 'pending' AND run_at <= now()) OR
+					(status = 'running' AND locked_until < now() AND attempts < max_attempts))
+				ORDER BY run_at
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED)
+			RETURNING `+queueJobColumns+";", pq.Array(kinds), worker, lock.Milliseconds()))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// Finish stores the outcome of an attempt and unlocks the job. It is a no-op if the worker lost the lock.
+func (r *QueueRepository) Finish(ctx context.Context, job *model.QueueJob) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE queue_jobs SET
+			status = $3, run_at = $4, last_error = NULLIF($5, ''), finished_at = $6,
+			locked_by = NULL, locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running';`,
+		job.ID, job.LockedBy, job.Status, job.RunAt, job.LastError, job.FinishedAt)
+	return err
+}
+
+// Release puts a claimed job back in the queue without counting the attempt, used when the worker stops.
+func (r *QueueRepository) Release(ctx context.Context, job *model.QueueJob) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE queue_jobs SET
+			status = 'pending', attempts = attempts - 1, locked_by = NULL, locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running';`, job.ID, job.LockedBy)
+	return err
+}
+
+func (r *QueueRepository) Get(ctx context.Context, id int64) (*model.QueueJob, error) {
+	job, err := scanQueueJob(r.DB.QueryRowContext(ctx, "SELECT "+queueJobColumns+" FROM queue_jobs WHERE id = $1;", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// List returns the jobs matching the filter, most recent first.
+func (r *QueueRepository) List(ctx context.Context, filter model.QueueJobFilter) ([]*model.QueueJob, error) {
+	var conditions []string
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		conditions = append(conditions, fmt.Sprintf("kind = $%d", len(args)))
+	}
+	query := "SELECT " + queueJobColumns + " FROM queue_jobs"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d;", len(args))
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*model.QueueJob{}
+	for rows.Next() {
+		job, err := scanQueueJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Retry makes a dead or cancelled job due again with a fresh set of attempts.
+func (r *QueueRepository) Retry(ctx context.Context, id int64) (*model.QueueJob, error) {
+	return r.transition(ctx, id, `UPDATE queue_jobs SET
+			status = 'pending', attempts = 0, run_at = now(), finished_at = NULL, updated_at = now()
+		WHERE id = $1 AND status IN ('dead', 'cancelled')
+		RETURNING `+queueJobColumns+";")
+}
+
+// Cancel prevents a pending job from running.
+func (r *QueueRepository) Cancel(ctx context.Context, id int64) (*model.QueueJob, error) {
+	return r.transition(ctx, id, `UPDATE queue_jobs SET
+			status = 'cancelled', finished_at = now(), updated_at = now()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING `+queueJobColumns+";")
+}
+
+// transition runs a status update of a single job. ErrInvalidState is returned if the job exists but is not in
+// a status the update applies to.
+func (r *QueueRepository) transition(ctx context.Context, id int64, query string) (*model.QueueJob, error) {
+	job, err := scanQueueJob(r.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, err = r.Get(ctx, id); err != nil {
+				return nil, err
+			}
+			return nil, ErrInvalidState
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+func scanQueueJob(row scanner) (*model.QueueJob, error) {
+	job := &model.QueueJob{}
+	var payload []byte
+	var lockedBy, lastError sql.NullString
+	var finishedAt sql.NullTime
+	err := row.Scan(&job.ID, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt,
+		&lockedBy, &lastError, &job.CreatedAt, &job.UpdatedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	job.LockedBy = lockedBy.String
+	job.LastError = lastError.String
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
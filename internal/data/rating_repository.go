@@ -0,0 +1,138 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// RatingRepository stores the ratings of users. The rating count and sum of the movie are updated in the
+// transaction of every change, so that the aggregate is read along with the movie.
+type RatingRepository struct {
+	DB *sql.DB
+}
+
+const ratingColumns = "movie_id, username, rating, created_at, updated_at"
+
+// ListForUser returns the ratings of the user, most recent first. Only the ratings of the given movies are returned,
+// unless movieIds is nil.
+func (r *RatingRepository) ListForUser(ctx context.Context, username string, movieIds []int) ([]*model.Rating, error) {
+	var ids []string
+	if movieIds != nil {
+		ids = make([]string, len(movieIds))
+		for i, movieId := range movieIds {
+			ids[i] = strconv.Itoa(movieId)
+		}
+	}
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+ratingColumns+` FROM movie_ratings
+		WHERE username = $1 AND ($2::varchar[] IS NULL OR movie_id = ANY($2::varchar[])) ORDER BY updated_at DESC;`,
+		username, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []*model.Rating{}
+	for rows.Next() {
+		rating, err := scanRating(rows)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, rating)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// Create adds the rating of a user. ErrRecordExists is returned if the user already rated the movie.
+func (r *RatingRepository) Create(ctx context.Context, rating *model.Rating) (*model.Rating, error) {
+	var created *model.Rating
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		var err error
+		created, err = scanRating(tx.QueryRowContext(ctx,
+			"INSERT INTO movie_ratings(movie_id, username, rating) VALUES($1, $2, $3) RETURNING "+ratingColumns+";",
+			strconv.Itoa(rating.MovieId), rating.Username, rating.Rating))
+		if err != nil {
+			return err
+		}
+		return updateRatingAggregate(ctx, tx, rating.MovieId, 1, rating.Rating)
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return nil, ErrRecordExists
+			case "23503":
+				return nil, ErrRecordNotFound
+			}
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// Update changes the rating of a user. ErrRecordNotFound is returned if the user did not rate the movie.
+func (r *RatingRepository) Update(ctx context.Context, rating *model.Rating) (*model.Rating, error) {
+	var updated *model.Rating
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		var previous int
+		err := tx.QueryRowContext(ctx, "SELECT rating FROM movie_ratings WHERE movie_id = $1 AND username = $2 FOR UPDATE;",
+			strconv.Itoa(rating.MovieId), rating.Username).Scan(&previous)
+		if err != nil {
+			return err
+		}
+		updated, err = scanRating(tx.QueryRowContext(ctx,
+			"UPDATE movie_ratings SET rating = $3, updated_at = now() WHERE movie_id = $1 AND username = $2 RETURNING "+ratingColumns+";",
+			strconv.Itoa(rating.MovieId), rating.Username, rating.Rating))
+		if err != nil {
+			return err
+		}
+		return updateRatingAggregate(ctx, tx, rating.MovieId, 0, rating.Rating-previous)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *RatingRepository) Delete(ctx context.Context, movieId int, username string) error {
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		var previous int
+		err := tx.QueryRowContext(ctx, "DELETE FROM movie_ratings WHERE movie_id = $1 AND username = $2 RETURNING rating;",
+			strconv.Itoa(movieId), username).Scan(&previous)
+		if err != nil {
+			return err
+		}
+		return updateRatingAggregate(ctx, tx, movieId, -1, -previous)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+// updateRatingAggregate adds the deltas to the rating count and sum of the movie.
+func updateRatingAggregate(ctx context.Context, tx *sql.Tx, movieId, countDelta, sumDelta int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE movies SET rating_count = rating_count + $2, rating_sum = rating_sum + $3 WHERE movieID = $1;",
+		strconv.Itoa(movieId), countDelta, sumDelta)
+	return err
+}
+
+func scanRating(row scanner) (*model.Rating, error) {
+	rating := &model.Rating{}
+	err := row.Scan(&rating.MovieId, &rating.Username, &rating.Rating, &rating.CreatedAt, &rating.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return rating, nil
+}
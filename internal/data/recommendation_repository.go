@@ -0,0 +1,162 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"rest_api/internal/api/model"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// RecommendationRepository reads the history of users that recommendations are based on, and stores the
+// precomputed item-item similarity matrix.
+type RecommendationRepository struct {
+	DB *sql.DB
+}
+
+// ListRatings returns the ratings of all users for the movies outside the trash.
+func (r *RecommendationRepository) ListRatings(ctx context.Context) ([]*model.Rating, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT r.movie_id, r.username, r.rating, r.created_at, r.updated_at
+		FROM movie_ratings r JOIN movies m ON m.movieID = r.movie_id WHERE m.deleted_at IS NULL;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ratings []*model.Rating
+	for rows.Next() {
+		rating, err := scanRating(rows)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, rating)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// ReplaceNeighbors replaces the similarity matrix. Entries of movies deleted in the meantime are left out.
+func (r *RecommendationRepository) ReplaceNeighbors(ctx context.Context, neighbors []model.MovieNeighbor) error {
+	movieIds := make([]string, len(neighbors))
+	neighborIds := make([]string, len(neighbors))
+	similarities := make([]float64, len(neighbors))
+	for i, n := range neighbors {
+		movieIds[i] = strconv.Itoa(n.MovieId)
+		neighborIds[i] = strconv.Itoa(n.Neighbor.MovieId)
+		similarities[i] = n.Similarity
+	}
+	return withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM movie_neighbors;"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO movie_neighbors(movie_id, neighbor_id, similarity)
+			SELECT n.movie_id, n.neighbor_id, n.similarity
+			FROM unnest($1::varchar[], $2::varchar[], $3::real[]) AS n(movie_id, neighbor_id, similarity)
+			WHERE EXISTS (SELECT 1 FROM movies WHERE movieID = n.movie_id)
+				AND EXISTS (SELECT 1 FROM movies WHERE movieID = n.neighbor_id);`,
+			pq.Array(movieIds), pq.Array(neighborIds), pq.Array(similarities))
+		return err
+	})
+}
+
+// Neighbors returns the entries of the similarity matrix for the movies, leaving out the neighbors in the trash.
+func (r *RecommendationRepository) Neighbors(ctx context.Context, movieIds []int) ([]model.MovieNeighbor, error) {
+	ids := make([]string, len(movieIds))
+	for i, movieId := range movieIds {
+		ids[i] = strconv.Itoa(movieId)
+	}
+	rows, err := r.DB.QueryContext(ctx, `SELECT n.movie_id, m.movieID, m.movieName, m.runtime, to_char(m.release_date, 'YYYY-MM-DD'),
+			n.similarity
+		FROM movie_neighbors n JOIN movies m ON m.movieID = n.neighbor_id
+		WHERE n.movie_id = ANY($1::varchar[]) AND m.deleted_at IS NULL;`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var neighbors []model.MovieNeighbor
+	for rows.Next() {
+		var n model.MovieNeighbor
+		var runtime sql.NullInt64
+		var releaseDate sql.NullString
+		err = rows.Scan(&n.MovieId, &n.Neighbor.MovieId, &n.Neighbor.MovieName, &runtime, &releaseDate, &n.Similarity)
+		if err != nil {
+			return nil, err
+		}
+		n.Neighbor.Runtime = int(runtime.Int64)
+		n.Neighbor.ReleaseDate = releaseDate.String
+		neighbors = append(neighbors, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return neighbors, nil
+}
+
+// History returns the ratings of the user, the movies they watched, and their genre affinity counting the movies
+// they rated at least likedRating or put on a watchlist.
+func (r *RecommendationRepository) History(ctx context.Context, username string, likedRating int) (*model.UserHistory, error) {
+	history := &model.UserHistory{Ratings: map[int]int{}, GenreAffinity: map[int]int{}}
+
+	err := queryPairs(ctx, r.DB, "SELECT movie_id, rating FROM movie_ratings WHERE username = $1;", history.Ratings, username)
+	if err != nil {
+		return nil, err
+	}
+	err = queryPairs(ctx, r.DB, `SELECT mg.genre_id, count(*) FROM movie_genres mg
+		WHERE mg.movie_id IN (SELECT movie_id FROM movie_ratings WHERE username = $1 AND rating >= $2
+			UNION SELECT wi.movie_id FROM watchlist_items wi JOIN watchlists w ON w.id = wi.watchlist_id WHERE w.username = $1)
+		GROUP BY mg.genre_id;`, history.GenreAffinity, username, likedRating)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `SELECT DISTINCT wi.movie_id FROM watchlist_items wi
+		JOIN watchlists w ON w.id = wi.watchlist_id WHERE w.username = $1 AND wi.watched_at IS NOT NULL;`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var movieId int
+		if err = rows.Scan(&movieId); err != nil {
+			return nil, err
+		}
+		history.Watched = append(history.Watched, movieId)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Popular returns the movies outside the trash rated by the most users, then by TMDB vote average.
+func (r *RecommendationRepository) Popular(ctx context.Context, limit int) ([]*model.Movie, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+movieColumns+` FROM movies WHERE deleted_at IS NULL
+		ORDER BY rating_count DESC, tmdb_vote_average DESC NULLS LAST, movieID LIMIT $1;`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMovies(rows)
+}
+
+// queryPairs reads the rows of two integer columns into the map.
+func queryPairs(ctx context.Context, db *sql.DB, query string, pairs map[int]int, args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value int
+		if err = rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		pairs[key] = value
+	}
+	return rows.Err()
+}
@@ -0,0 +1,43 @@
+package data
+
+import (
+	"database/sql"
+	"rest_api/internal/api/model"
+)
+
+type RefreshRunRepository struct {
+	DB *sql.DB
+}
+
+func (r *RefreshRunRepository) Create(run *model.RefreshRun) (*model.RefreshRun, error) {
+	err := r.DB.QueryRow(
+		"INSERT INTO metadata_refresh_runs(started_at, finished_at, candidates, refreshed, unchanged, failed) VALUES($1, $2, $3, $4, $5, $6) returning id;",
+		run.StartedAt, run.FinishedAt, run.Candidates, run.Refreshed, run.Unchanged, run.Failed).Scan(&run.ID)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *RefreshRunRepository) GetRecent(limit int) ([]*model.RefreshRun, error) {
+	rows, err := r.DB.Query(
+		"SELECT id, started_at, finished_at, candidates, refreshed, unchanged, failed FROM metadata_refresh_runs ORDER BY id DESC LIMIT $1;", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*model.RefreshRun{}
+	for rows.Next() {
+		run := &model.RefreshRun{}
+		err := rows.Scan(&run.ID, &run.StartedAt, &run.FinishedAt, &run.Candidates, &run.Refreshed, &run.Unchanged, &run.Failed)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -1,9 +1,50 @@
 package data
 
+import (
+	"context"
+	"database/sql"
+	"rest_api/internal/api/model"
+	"time"
+)
+
+// Repository is the generic store of an entity. The mutating methods take the context of the change, which
+// also carries the actor recorded in the audit log.
 type Repository[T any] interface {
 	GetAll() ([]T, error)
 	Get(int) (T, error)
-	Create(T) (T, error)
-	Update(T) (T, error)
-	Delete(int) error
+	Create(context.Context, T) (T, error)
+	CreateBatch(context.Context, []T) ([]T, error)
+	Update(context.Context, T) (T, error)
+	Delete(context.Context, int) error
+}
+
+// MovieStore is the movie Repository with the movie specific queries.
+type MovieStore interface {
+	Repository[*model.Movie]
+	List(filter model.MovieFilter) ([]*model.Movie, error)
+	GetMany(ctx context.Context, movieIds []int) ([]*model.Movie, error)
+	LastModified(ctx context.Context) (time.Time, error)
+	Stream(ctx context.Context, filter model.MovieFilter, fn func(*model.Movie) error) error
+	GetTrash() ([]*model.Movie, error)
+	Restore(ctx context.Context, movieId int) (*model.Movie, error)
+	Purge(ctx context.Context, deletedBefore time.Time) ([]int, error)
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// withTx runs fn in a transaction, committed if fn succeeds and rolled back otherwise.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
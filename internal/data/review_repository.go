@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// ReviewRepository stores the reviews of users, one per user and movie.
+type ReviewRepository struct {
+	DB *sql.DB
+}
+
+const reviewColumns = "id, movie_id, username, body, spoiler, hidden_at, hidden_by, created_at, updated_at"
+
+// List returns the reviews of the movie, most recent first. Hidden reviews are only included if requested.
+func (r *ReviewRepository) List(ctx context.Context, movieId int, includeHidden bool) ([]*model.Review, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+reviewColumns+` FROM movie_reviews
+		WHERE movie_id = $1 AND ($2 OR hidden_at IS NULL) ORDER BY created_at DESC, id DESC;`, strconv.Itoa(movieId), includeHidden)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []*model.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// Create adds the review of a user. ErrRecordExists is returned if the user already reviewed the movie.
+func (r *ReviewRepository) Create(ctx context.Context, review *model.Review) (*model.Review, error) {
+	created, err := scanReview(r.DB.QueryRowContext(ctx,
+		"INSERT INTO movie_reviews(movie_id, username, body, spoiler) VALUES($1, $2, $3, $4) RETURNING "+reviewColumns+";",
+		strconv.Itoa(review.MovieId), review.Username, review.Text, review.Spoiler))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return nil, ErrRecordExists
+			case "23503":
+				return nil, ErrRecordNotFound
+			}
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// Update changes the text and spoiler flag of the review of a user.
+func (r *ReviewRepository) Update(ctx context.Context, review *model.Review) (*model.Review, error) {
+	return r.single(r.DB.QueryRowContext(ctx, `UPDATE movie_reviews SET body = $3, spoiler = $4, updated_at = now()
+		WHERE movie_id = $1 AND username = $2 RETURNING `+reviewColumns+";",
+		strconv.Itoa(review.MovieId), review.Username, review.Text, review.Spoiler))
+}
+
+func (r *ReviewRepository) Delete(ctx context.Context, movieId int, username string) error {
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM movie_reviews WHERE movie_id = $1 AND username = $2;", strconv.Itoa(movieId), username)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// SetHidden hides the review on behalf of the admin, or shows it again if hiddenBy is empty.
+func (r *ReviewRepository) SetHidden(ctx context.Context, reviewId int, hiddenBy string) (*model.Review, error) {
+	return r.single(r.DB.QueryRowContext(ctx, `UPDATE movie_reviews SET
+			hidden_at = CASE WHEN $2::text = '' THEN NULL ELSE COALESCE(hidden_at, now()) END, hidden_by = NULLIF($2::text, '')
+		WHERE id = $1 RETURNING `+reviewColumns+";", reviewId, hiddenBy))
+}
+
+func (r *ReviewRepository) single(row *sql.Row) (*model.Review, error) {
+	review, err := scanReview(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return review, nil
+}
+
+func scanReview(row scanner) (*model.Review, error) {
+	review := &model.Review{}
+	var hiddenAt sql.NullTime
+	var hiddenBy sql.NullString
+	err := row.Scan(&review.ID, &review.MovieId, &review.Username, &review.Text, &review.Spoiler, &hiddenAt, &hiddenBy,
+		&review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if hiddenAt.Valid {
+		review.HiddenAt = &hiddenAt.Time
+	}
+	review.HiddenBy = hiddenBy.String
+	return review, nil
+}
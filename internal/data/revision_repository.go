@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"rest_api/internal/api/model"
+	"rest_api/internal/api/reqctx"
+	"strconv"
+)
+
+// RevisionRepository reads the movie revisions, which are written by MovieRepository.Update.
+type RevisionRepository struct {
+	DB *sql.DB
+}
+
+const revisionColumns = "revision, movie, actor, request_id, created_at"
+
+// List returns the revisions of the movie, most recent first.
+func (r *RevisionRepository) List(ctx context.Context, movieId int) ([]*model.MovieRevision, error) {
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+revisionColumns+" FROM movie_revisions WHERE movie_id = $1 ORDER BY revision DESC;", movieId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*model.MovieRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *RevisionRepository) Get(ctx context.Context, movieId, revision int) (*model.MovieRevision, error) {
+	rev, err := scanRevision(r.DB.QueryRowContext(ctx,
+		"SELECT "+revisionColumns+" FROM movie_revisions WHERE movie_id = $1 AND revision = $2;", movieId, revision))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return rev, nil
+}
+
+// insertRevision records the updated state of a movie as its next revision, in the transaction of the update.
+// The state before the first update of a movie is recorded first, so that it can be reverted to. The movie row
+// must be locked by the transaction.
+func insertRevision(ctx context.Context, tx *sql.Tx, before, after *model.Movie) error {
+	beforeJSON, err := json.Marshal(revisionSnapshot(before))
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(revisionSnapshot(after))
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO movie_revisions(movie_id, revision, movie)
+		SELECT $1::varchar, 1, $2::jsonb WHERE NOT EXISTS (SELECT 1 FROM movie_revisions WHERE movie_id = $1::varchar);`,
+		strconv.Itoa(after.MovieId), string(beforeJSON))
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO movie_revisions(movie_id, revision, movie, actor, request_id)
+		SELECT $1::varchar, MAX(revision) + 1, $2::jsonb, $3, NULLIF($4, '') FROM movie_revisions WHERE movie_id = $1::varchar;`,
+		strconv.Itoa(after.MovieId), string(afterJSON), reqctx.Actor(ctx), reqctx.RequestID(ctx))
+	return err
+}
+
+// revisionSnapshot returns the editable state of the movie, without the rating aggregate and the tags of its
+// users.
+func revisionSnapshot(movie *model.Movie) *model.Movie {
+	snapshot := *movie
+	snapshot.RatingAverage = 0
+	snapshot.RatingCount = 0
+	snapshot.Tags = nil
+	return &snapshot
+}
+
+func scanRevision(row scanner) (*model.MovieRevision, error) {
+	revision := &model.MovieRevision{}
+	var movie []byte
+	var actor, requestId sql.NullString
+	if err := row.Scan(&revision.Revision, &movie, &actor, &requestId, &revision.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(movie, &revision.Movie); err != nil {
+		return nil, err
+	}
+	revision.Actor = actor.String
+	revision.RequestID = requestId.String
+	return revision, nil
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// TagRepository stores the tags curated by users and the movies they are given to. Tag names are unique
+// regardless of case.
+type TagRepository struct {
+	DB *sql.DB
+}
+
+// List returns the tags by name, with the number of movies outside the trash they are given to.
+func (r *TagRepository) List(ctx context.Context) ([]*model.TagCount, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT t.id, t.name, count(m.movieID) FROM tags t
+		LEFT JOIN movie_tags mt ON mt.tag_id = t.id
+		LEFT JOIN movies m ON m.movieID = mt.movie_id AND m.deleted_at IS NULL
+		GROUP BY t.id ORDER BY lower(t.name);`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*model.TagCount{}
+	for rows.Next() {
+		tag := &model.TagCount{}
+		if err = rows.Scan(&tag.ID, &tag.Name, &tag.MovieCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Create adds a tag. ErrRecordExists is returned if a tag with the same name exists.
+func (r *TagRepository) Create(ctx context.Context, name, createdBy string) (*model.Tag, error) {
+	tag := &model.Tag{}
+	err := r.DB.QueryRowContext(ctx, "INSERT INTO tags(name, created_by) VALUES($1, $2) RETURNING id, name;", name, createdBy).
+		Scan(&tag.ID, &tag.Name)
+	if err != nil {
+		return nil, tagError(err)
+	}
+	return tag, nil
+}
+
+// Update renames the tag.
+func (r *TagRepository) Update(ctx context.Context, tag *model.Tag) (*model.Tag, error) {
+	updated := &model.Tag{}
+	err := r.DB.QueryRowContext(ctx, "UPDATE tags SET name = $2 WHERE id = $1 RETURNING id, name;", tag.ID, tag.Name).
+		Scan(&updated.ID, &updated.Name)
+	if err != nil {
+		return nil, tagError(err)
+	}
+	return updated, nil
+}
+
+// Delete removes the tag from every movie and deletes it.
+func (r *TagRepository) Delete(ctx context.Context, tagId int) error {
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM tags WHERE id = $1;", tagId)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// AddToMovie gives the tag to the movie. It does nothing if the movie already has the tag.
+func (r *TagRepository) AddToMovie(ctx context.Context, movieId, tagId int) error {
+	_, err := r.DB.ExecContext(ctx, "INSERT INTO movie_tags(movie_id, tag_id) VALUES($1, $2) ON CONFLICT DO NOTHING;",
+		strconv.Itoa(movieId), tagId)
+	if err != nil {
+		return tagError(err)
+	}
+	return nil
+}
+
+func (r *TagRepository) RemoveFromMovie(ctx context.Context, movieId, tagId int) error {
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM movie_tags WHERE movie_id = $1 AND tag_id = $2;", strconv.Itoa(movieId), tagId)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// tagError maps the constraint violations of tag statements.
+func tagError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrRecordExists
+		case "23503":
+			return ErrRecordNotFound
+		}
+	}
+	return err
+}
@@ -1,18 +1,31 @@
 package data
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"rest_api/internal/api/model"
+
+	"github.com/lib/pq"
 )
 
 type UserRepository struct {
 	DB *sql.DB
 }
 
+// auditedUser is the state of a user recorded in the audit log. Passwords are never recorded, only the fact
+// that one was changed.
+type auditedUser struct {
+	Username        string `json:"username"`
+	Admin           bool   `json:"admin"`
+	Curator         bool   `json:"curator"`
+	PasswordChanged bool   `json:"passwordChanged,omitempty"`
+}
+
 func (r *UserRepository) GetUser(username string) (*model.User, error) {
 	user := model.User{}
-	err := r.DB.QueryRow("SELECT username, password FROM users WHERE username = $1;", username).
-		Scan(user.Username, user.Password)
+	err := r.DB.QueryRow("SELECT username, password, is_admin, is_curator FROM users WHERE username = $1;", username).
+		Scan(&user.Username, &user.Password, &user.Admin, &user.Curator)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -22,4 +35,111 @@ func (r *UserRepository) GetUser(username string) (*model.User, error) {
 	}
 
 	return &user, nil
-}
\ No newline at end of file
+}
+
+// List returns the users ordered by username, without their password.
+func (r *UserRepository) List(ctx context.Context) ([]*model.User, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT username, is_admin, is_curator FROM users ORDER BY username;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*model.User{}
+	for rows.Next() {
+		user := &model.User{}
+		if err = rows.Scan(&user.Username, &user.Admin, &user.Curator); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO users(username, password, is_admin, is_curator) VALUES($1, $2, $3, $4);",
+			user.Username, user.Password, user.Admin, user.Curator)
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, model.AuditCreate, model.AuditEntityUser, user.Username, nil,
+			&auditedUser{Username: user.Username, Admin: user.Admin, Curator: user.Curator})
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrRecordExists
+		}
+		return nil, err
+	}
+	return &model.User{Username: user.Username, Admin: user.Admin, Curator: user.Curator}, nil
+}
+
+// Update changes the roles of the user, and its password unless it is empty.
+func (r *UserRepository) Update(ctx context.Context, user *model.User) (*model.User, error) {
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		before := &auditedUser{}
+		err := tx.QueryRowContext(ctx, "SELECT username, is_admin, is_curator FROM users WHERE username = $1 FOR UPDATE;", user.Username).
+			Scan(&before.Username, &before.Admin, &before.Curator)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE users SET password = COALESCE(NULLIF($2, ''), password), is_admin = $3, is_curator = $4 WHERE username = $1;",
+			user.Username, user.Password, user.Admin, user.Curator)
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, model.AuditUpdate, model.AuditEntityUser, user.Username, before,
+			&auditedUser{Username: user.Username, Admin: user.Admin, Curator: user.Curator, PasswordChanged: user.Password != ""})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &model.User{Username: user.Username, Admin: user.Admin, Curator: user.Curator}, nil
+}
+
+// Delete removes the user along with their ratings, which are first subtracted from the rating aggregates of their
+// movies, and their reviews. It returns the ids of the movies whose aggregates changed.
+func (r *UserRepository) Delete(ctx context.Context, username string) ([]int, error) {
+	movieIds := []int{}
+	err := withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `UPDATE movies SET rating_count = rating_count - 1, rating_sum = rating_sum - movie_ratings.rating
+			FROM movie_ratings WHERE movie_ratings.movie_id = movies.movieID AND movie_ratings.username = $1
+			RETURNING movies.movieID::int;`, username)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var movieId int
+			if err = rows.Scan(&movieId); err != nil {
+				return err
+			}
+			movieIds = append(movieIds, movieId)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		deleted := &auditedUser{}
+		err = tx.QueryRowContext(ctx, "DELETE FROM users WHERE username = $1 RETURNING username, is_admin, is_curator;", username).
+			Scan(&deleted.Username, &deleted.Admin, &deleted.Curator)
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, model.AuditDelete, model.AuditEntityUser, username, deleted, nil)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return movieIds, nil
+}
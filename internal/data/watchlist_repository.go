@@ -0,0 +1,257 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"rest_api/internal/api/model"
+	"slices"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// WatchlistRepository stores the watchlists of users. Items of movies in the trash are kept but hidden, and
+// removed along with the movie when it is purged.
+type WatchlistRepository struct {
+	DB *sql.DB
+}
+
+const watchlistColumns = "id, name, is_default, created_at"
+
+// List returns the watchlists of the user with their items, the default one first. The default watchlist is
+// created if the user has none yet.
+func (r *WatchlistRepository) List(ctx context.Context, username string) ([]*model.Watchlist, error) {
+	if _, err := r.GetDefault(ctx, username); err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+watchlistColumns+" FROM watchlists WHERE username = $1 ORDER BY is_default DESC, name;", username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	watchlists := []*model.Watchlist{}
+	for rows.Next() {
+		watchlist, err := scanWatchlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, watchlist := range watchlists {
+		if watchlist.Items, err = r.items(ctx, watchlist.ID); err != nil {
+			return nil, err
+		}
+	}
+	return watchlists, nil
+}
+
+// Get returns a watchlist of the user with its items. ErrRecordNotFound is returned if the watchlist does not
+// exist or belongs to another user.
+func (r *WatchlistRepository) Get(ctx context.Context, username string, watchlistId int) (*model.Watchlist, error) {
+	watchlist, err := scanWatchlist(r.DB.QueryRowContext(ctx,
+		"SELECT "+watchlistColumns+" FROM watchlists WHERE id = $1 AND username = $2;", watchlistId, username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if watchlist.Items, err = r.items(ctx, watchlist.ID); err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// GetDefault returns the default watchlist of the user with its items, creating it if needed.
+func (r *WatchlistRepository) GetDefault(ctx context.Context, username string) (*model.Watchlist, error) {
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO watchlists(username, name, is_default) VALUES($1, $2, true)
+		ON CONFLICT (username) WHERE is_default DO NOTHING;`, username, model.DefaultWatchlistName)
+	if err != nil {
+		return nil, err
+	}
+	watchlist, err := scanWatchlist(r.DB.QueryRowContext(ctx,
+		"SELECT "+watchlistColumns+" FROM watchlists WHERE username = $1 AND is_default;", username))
+	if err != nil {
+		return nil, err
+	}
+	if watchlist.Items, err = r.items(ctx, watchlist.ID); err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+func (r *WatchlistRepository) Create(ctx context.Context, username, name string) (*model.Watchlist, error) {
+	watchlist, err := scanWatchlist(r.DB.QueryRowContext(ctx,
+		"INSERT INTO watchlists(username, name) VALUES($1, $2) RETURNING "+watchlistColumns+";", username, name))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrRecordExists
+		}
+		return nil, err
+	}
+	watchlist.Items = []*model.WatchlistItem{}
+	return watchlist, nil
+}
+
+// Delete removes a named watchlist of the user. ErrInvalidState is returned for the default watchlist.
+func (r *WatchlistRepository) Delete(ctx context.Context, username string, watchlistId int) error {
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM watchlists WHERE id = $1 AND username = $2 AND NOT is_default;", watchlistId, username)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err = r.Get(ctx, username, watchlistId); err != nil {
+			return err
+		}
+		return ErrInvalidState
+	}
+	return nil
+}
+
+// AddMovie appends the movie to the watchlist. ErrRecordExists is returned if it is already in it.
+func (r *WatchlistRepository) AddMovie(ctx context.Context, watchlistId, movieId int) error {
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO watchlist_items(watchlist_id, movie_id, position)
+		SELECT $1::integer, $2::varchar, COALESCE(MAX(position), 0) + 1 FROM watchlist_items WHERE watchlist_id = $1::integer;`,
+		watchlistId, strconv.Itoa(movieId))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return ErrRecordExists
+			case "23503":
+				return ErrRecordNotFound
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *WatchlistRepository) RemoveMovie(ctx context.Context, watchlistId, movieId int) error {
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM watchlist_items WHERE watchlist_id = $1 AND movie_id = $2;", watchlistId, movieId)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// SetWatched marks the movie of the watchlist as watched now, or as not watched.
+func (r *WatchlistRepository) SetWatched(ctx context.Context, watchlistId, movieId int, watched bool) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE watchlist_items SET watched_at = CASE WHEN $3 THEN COALESCE(watched_at, now()) END
+		WHERE watchlist_id = $1 AND movie_id = $2;`, watchlistId, movieId, watched)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// Reorder sets the order of the watchlist to the given movie ids, which must be exactly the visible movies of
+// the watchlist. Hidden items of movies in the trash are moved after them. ErrInvalidState is returned if the
+// ids do not match the movies of the watchlist.
+func (r *WatchlistRepository) Reorder(ctx context.Context, watchlistId int, movieIds []int) error {
+	return withTx(ctx, r.DB, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT i.movie_id FROM watchlist_items i JOIN movies m ON m.movieID = i.movie_id
+			WHERE i.watchlist_id = $1 AND m.deleted_at IS NULL FOR UPDATE OF i;`, watchlistId)
+		if err != nil {
+			return err
+		}
+		var current []int
+		for rows.Next() {
+			var movieId int
+			if err = rows.Scan(&movieId); err != nil {
+				rows.Close()
+				return err
+			}
+			current = append(current, movieId)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		ordered := slices.Clone(movieIds)
+		slices.Sort(ordered)
+		slices.Sort(current)
+		if !slices.Equal(ordered, current) {
+			return ErrInvalidState
+		}
+
+		ids := make([]string, len(movieIds))
+		for i, movieId := range movieIds {
+			ids[i] = strconv.Itoa(movieId)
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE watchlist_items SET position = $2 + position
+			WHERE watchlist_id = $1 AND movie_id <> ALL($3);`, watchlistId, len(ids), pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE watchlist_items i SET position = o.position
+			FROM unnest($2::varchar[]) WITH ORDINALITY AS o(movie_id, position)
+			WHERE i.watchlist_id = $1 AND i.movie_id = o.movie_id;`, watchlistId, pq.Array(ids))
+		return err
+	})
+}
+
+// items returns the items of the watchlist in order, leaving out the movies in the trash.
+func (r *WatchlistRepository) items(ctx context.Context, watchlistId int) ([]*model.WatchlistItem, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT m.movieID, m.movieName, m.runtime, i.position, i.added_at, i.watched_at
+		FROM watchlist_items i JOIN movies m ON m.movieID = i.movie_id
+		WHERE i.watchlist_id = $1 AND m.deleted_at IS NULL ORDER BY i.position;`, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*model.WatchlistItem{}
+	for rows.Next() {
+		item := &model.WatchlistItem{Movie: &model.MovieSummary{}}
+		var runtime sql.NullInt64
+		var watchedAt sql.NullTime
+		err = rows.Scan(&item.Movie.MovieId, &item.Movie.MovieName, &runtime, &item.Position, &item.AddedAt, &watchedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.Movie.Runtime = int(runtime.Int64)
+		if watchedAt.Valid {
+			item.Watched = true
+			item.WatchedAt = &watchedAt.Time
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func scanWatchlist(row scanner) (*model.Watchlist, error) {
+	watchlist := &model.Watchlist{}
+	if err := row.Scan(&watchlist.ID, &watchlist.Name, &watchlist.Default, &watchlist.CreatedAt); err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// expectOneRow returns ErrRecordNotFound if the statement did not affect any row.
+func expectOneRow(res sql.Result) error {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package queue
+
+import "errors"
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that retrying will not fix. The job is moved to the dead state right away.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var pErr permanentError
+	return errors.As(err, &pErr)
+}
@@ -0,0 +1,298 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultTimeout     = 10 * time.Minute
+	// lockMargin is added to the longest job timeout to get the lock duration of a claimed job. A job is only
+	// claimed again by another worker once its lock expired.
+	lockMargin    = time.Minute
+	finishTimeout = 5 * time.Second
+)
+
+var (
+	ErrKindExists  = errors.New("job kind already registered")
+	ErrUnknownKind = errors.New("unknown job kind")
+)
+
+// Store persists the jobs. It is implemented by data.QueueRepository.
+type Store interface {
+	Enqueue(ctx context.Context, job *model.QueueJob) (*model.QueueJob, error)
+	Claim(ctx context.Context, kinds []string, worker string, lock time.Duration) (*model.QueueJob, error)
+	Finish(ctx context.Context, job *model.QueueJob) error
+	Release(ctx context.Context, job *model.QueueJob) error
+	Get(ctx context.Context, id int64) (*model.QueueJob, error)
+	List(ctx context.Context, filter model.QueueJobFilter) ([]*model.QueueJob, error)
+	Retry(ctx context.Context, id int64) (*model.QueueJob, error)
+	Cancel(ctx context.Context, id int64) (*model.QueueJob, error)
+}
+
+// Options configure the jobs of a kind. Zero values fall back to DefaultMaxAttempts and DefaultTimeout.
+type Options struct {
+	MaxAttempts int
+	Timeout     time.Duration
+}
+
+type handler struct {
+	options Options
+	run     func(ctx context.Context, payload json.RawMessage) error
+}
+
+// Queue runs the jobs stored in a Store with a pool of workers. Any number of replicas can run a Queue on the
+// same Store; each job is claimed by a single worker at a time.
+type Queue struct {
+	store        Store
+	worker       string
+	workers      int
+	pollInterval time.Duration
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]handler
+	wake     chan struct{}
+}
+
+func NewQueue(store Store, worker string, workers int, pollInterval, backoffBase, backoffMax time.Duration) *Queue {
+	return &Queue{
+		store:        store,
+		worker:       worker,
+		workers:      workers,
+		pollInterval: pollInterval,
+		backoffBase:  backoffBase,
+		backoffMax:   backoffMax,
+		handlers:     map[string]handler{},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Handle registers the handler of a job kind. The payload of the jobs is decoded from JSON into T. Handlers
+// should be registered before Run.
+func Handle[T any](q *Queue, kind string, options Options, fn func(ctx context.Context, payload T) error) error {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.handlers[kind]; ok {
+		return fmt.Errorf("%w: %s", ErrKindExists, kind)
+	}
+	q.handlers[kind] = handler{
+		options: options,
+		run: func(ctx context.Context, raw json.RawMessage) error {
+			var payload T
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return Permanent(fmt.Errorf("decoding payload: %w", err))
+			}
+			return fn(ctx, payload)
+		},
+	}
+	return nil
+}
+
+// Enqueue stores a job of a registered kind, to be run as soon as a worker is free.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any) (*model.QueueJob, error) {
+	h, ok := q.handler(kind)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := q.store.Enqueue(ctx, &model.QueueJob{Kind: kind, Payload: raw, MaxAttempts: h.options.MaxAttempts})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Enqueued job", "jobId", job.ID, "kind", kind)
+	q.notify()
+	return job, nil
+}
+
+func (q *Queue) Get(ctx context.Context, id int64) (*model.QueueJob, error) {
+	return q.store.Get(ctx, id)
+}
+
+func (q *Queue) List(ctx context.Context, filter model.QueueJobFilter) ([]*model.QueueJob, error) {
+	return q.store.List(ctx, filter)
+}
+
+// Retry makes a dead or cancelled job due again. data.ErrInvalidState is returned for jobs in other states.
+func (q *Queue) Retry(ctx context.Context, id int64) (*model.QueueJob, error) {
+	job, err := q.store.Retry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	q.notify()
+	return job, nil
+}
+
+// Cancel prevents a pending job from running. data.ErrInvalidState is returned for jobs in other states.
+func (q *Queue) Cancel(ctx context.Context, id int64) (*model.QueueJob, error) {
+	return q.store.Cancel(ctx, id)
+}
+
+// Run starts the workers and blocks until the context is cancelled and the running jobs have stopped. Jobs
+// interrupted by the cancellation are put back in the queue.
+func (q *Queue) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for i := range q.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, fmt.Sprintf("%s/%d", q.worker, i))
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context, worker string) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+
+		job, err := q.claim(ctx, worker)
+		switch {
+		case err == nil:
+			q.execute(ctx, job)
+			// look for the next job right away
+			timer.Reset(0)
+			continue
+		case errors.Is(err, data.ErrRecordNotFound), ctx.Err() != nil:
+		default:
+			slog.Error("Could not claim job", "error", err)
+		}
+		timer.Reset(q.pollInterval)
+	}
+}
+
+func (q *Queue) claim(ctx context.Context, worker string) (*model.QueueJob, error) {
+	q.mu.RLock()
+	kinds := make([]string, 0, len(q.handlers))
+	lock := time.Duration(0)
+	for kind, h := range q.handlers {
+		kinds = append(kinds, kind)
+		lock = max(lock, h.options.Timeout)
+	}
+	q.mu.RUnlock()
+	if len(kinds) == 0 {
+		return nil, data.ErrRecordNotFound
+	}
+	slices.Sort(kinds)
+	return q.store.Claim(ctx, kinds, worker, lock+lockMargin)
+}
+
+func (q *Queue) execute(ctx context.Context, job *model.QueueJob) {
+	h, _ := q.handler(job.Kind)
+	slog.Info("Running job", "jobId", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+
+	jobCtx, cancel := context.WithTimeout(withJob(ctx, job), h.options.Timeout)
+	err := run(jobCtx, h, job.Payload)
+	cancel()
+
+	finishCtx, cancelFinish := context.WithTimeout(context.WithoutCancel(ctx), finishTimeout)
+	defer cancelFinish()
+	if err != nil && ctx.Err() != nil {
+		slog.Info("Releasing job interrupted by shutdown", "jobId", job.ID, "kind", job.Kind)
+		if err = q.store.Release(finishCtx, job); err != nil {
+			slog.Error("Could not release job", "jobId", job.ID, "error", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		job.Status = model.QueueJobCompleted
+		job.FinishedAt = &now
+		job.LastError = ""
+		slog.Info("Job completed", "jobId", job.ID, "kind", job.Kind)
+	case job.Attempts >= job.MaxAttempts || IsPermanent(err):
+		job.Status = model.QueueJobDead
+		job.FinishedAt = &now
+		job.LastError = err.Error()
+		slog.Error("Job failed permanently", "jobId", job.ID, "kind", job.Kind, "attempts", job.Attempts, "error", err)
+	default:
+		job.Status = model.QueueJobPending
+		job.RunAt = now.Add(q.backoff(job.Attempts))
+		job.LastError = err.Error()
+		slog.Warn("Job failed, retrying", "jobId", job.ID, "kind", job.Kind, "attempts", job.Attempts, "runAt", job.RunAt, "error", err)
+	}
+	if err = q.store.Finish(finishCtx, job); err != nil {
+		slog.Error("Could not store job result", "jobId", job.ID, "error", err)
+	}
+}
+
+// run calls the handler and turns a panic into an error, so that a bad job cannot stop the worker.
+func run(ctx context.Context, h handler, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Job panicked", "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.run(ctx, payload)
+}
+
+// backoff returns the delay before the next attempt: exponential in the number of attempts, capped at
+// backoffMax, with up to 20% jitter so failing jobs do not retry in lockstep.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.backoffMax
+	if attempts < 32 {
+		delay = min(q.backoffBase<<(attempts-1), q.backoffMax)
+	}
+	return delay + time.Duration(rand.Int64N(int64(delay)/5+1))
+}
+
+func (q *Queue) handler(kind string) (handler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	h, ok := q.handlers[kind]
+	return h, ok
+}
+
+// notify wakes up an idle worker of this replica.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+type jobKey struct{}
+
+func withJob(ctx context.Context, job *model.QueueJob) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+// IsLastAttempt reports whether the job run by the handler is on its last attempt, so that handlers can record
+// a final failure themselves.
+func IsLastAttempt(ctx context.Context) bool {
+	job, ok := ctx.Value(jobKey{}).(*model.QueueJob)
+	return ok && job.Attempts >= job.MaxAttempts
+}
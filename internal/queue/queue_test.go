@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"rest_api/internal/api/model"
+	"rest_api/internal/data"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs []*model.QueueJob
+}
+
+func (m *memoryStore) Enqueue(_ context.Context, job *model.QueueJob) (*model.QueueJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.ID = int64(len(m.jobs) + 1)
+	job.Status = model.QueueJobPending
+	job.RunAt = time.Now()
+	m.jobs = append(m.jobs, job)
+	copied := *job
+	return &copied, nil
+}
+
+func (m *memoryStore) Claim(_ context.Context, kinds []string, worker string, _ time.Duration) (*model.QueueJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.Status == model.QueueJobPending && !job.RunAt.After(time.Now()) && slices.Contains(kinds, job.Kind) {
+			job.Status = model.QueueJobRunning
+			job.Attempts++
+			job.LockedBy = worker
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+func (m *memoryStore) Finish(_ context.Context, job *model.QueueJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.jobs[job.ID-1]
+	stored.Status, stored.RunAt, stored.LastError, stored.FinishedAt = job.Status, job.RunAt, job.LastError, job.FinishedAt
+	stored.LockedBy = ""
+	return nil
+}
+
+func (m *memoryStore) Release(_ context.Context, job *model.QueueJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.jobs[job.ID-1]
+	stored.Status = model.QueueJobPending
+	stored.Attempts--
+	stored.LockedBy = ""
+	return nil
+}
+
+func (m *memoryStore) Get(_ context.Context, id int64) (*model.QueueJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id < 1 || int(id) > len(m.jobs) {
+		return nil, data.ErrRecordNotFound
+	}
+	copied := *m.jobs[id-1]
+	return &copied, nil
+}
+
+func (m *memoryStore) List(context.Context, model.QueueJobFilter) ([]*model.QueueJob, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) Retry(context.Context, int64) (*model.QueueJob, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) Cancel(context.Context, int64) (*model.QueueJob, error) {
+	return nil, nil
+}
+
+type payload struct {
+	Value string `json:"value"`
+}
+
+func runQueue(t *testing.T, q *Queue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+}
+
+func waitForStatus(t *testing.T, store *memoryStore, id int64, status string) *model.QueueJob {
+	var job *model.QueueJob
+	require.Eventually(t, func() bool {
+		job, _ = store.Get(context.Background(), id)
+		return job.Status == status
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+func TestQueue_RunsTypedHandlers(t *testing.T) {
+	store := &memoryStore{}
+	q := NewQueue(store, "replica", 2, 10*time.Millisecond, time.Millisecond, time.Millisecond)
+	received := make(chan string, 1)
+	require.NoError(t, Handle(q, "echo", Options{}, func(ctx context.Context, p payload) error {
+		received <- p.Value
+		return nil
+	}))
+	assert.ErrorIs(t, Handle(q, "echo", Options{}, func(context.Context, payload) error { return nil }), ErrKindExists)
+	runQueue(t, q)
+
+	_, err := q.Enqueue(context.Background(), "missing", payload{})
+	assert.ErrorIs(t, err, ErrUnknownKind)
+	job, err := q.Enqueue(context.Background(), "echo", payload{Value: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMaxAttempts, job.MaxAttempts)
+
+	assert.Equal(t, "hello", <-received)
+	job = waitForStatus(t, store, job.ID, model.QueueJobCompleted)
+	assert.Equal(t, 1, job.Attempts)
+	assert.NotNil(t, job.FinishedAt)
+}
+
+func TestQueue_RetriesUntilDead(t *testing.T) {
+	store := &memoryStore{}
+	q := NewQueue(store, "replica", 1, time.Millisecond, time.Millisecond, 2*time.Millisecond)
+	var attempts, lastAttempts atomic.Int32
+	require.NoError(t, Handle(q, "flaky", Options{MaxAttempts: 3}, func(ctx context.Context, _ payload) error {
+		attempts.Add(1)
+		if IsLastAttempt(ctx) {
+			lastAttempts.Add(1)
+		}
+		return errors.New("unavailable")
+	}))
+	require.NoError(t, Handle(q, "broken", Options{}, func(ctx context.Context, _ payload) error {
+		return Permanent(errors.New("invalid"))
+	}))
+	require.NoError(t, Handle(q, "panics", Options{MaxAttempts: 1}, func(ctx context.Context, _ payload) error {
+		panic("boom")
+	}))
+	runQueue(t, q)
+
+	flaky, err := q.Enqueue(context.Background(), "flaky", payload{})
+	require.NoError(t, err)
+	broken, err := q.Enqueue(context.Background(), "broken", payload{})
+	require.NoError(t, err)
+	panics, err := q.Enqueue(context.Background(), "panics", payload{})
+	require.NoError(t, err)
+
+	job := waitForStatus(t, store, flaky.ID, model.QueueJobDead)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Equal(t, "unavailable", job.LastError)
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, int32(1), lastAttempts.Load())
+
+	job = waitForStatus(t, store, broken.ID, model.QueueJobDead)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "invalid", job.LastError)
+
+	job = waitForStatus(t, store, panics.ID, model.QueueJobDead)
+	assert.Equal(t, "panic: boom", job.LastError)
+}
+
+func TestQueue_ReleasesJobsOnShutdown(t *testing.T) {
+	store := &memoryStore{}
+	q := NewQueue(store, "replica", 1, time.Millisecond, time.Millisecond, time.Millisecond)
+	started := make(chan struct{})
+	require.NoError(t, Handle(q, "slow", Options{}, func(ctx context.Context, _ payload) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	job, err := q.Enqueue(context.Background(), "slow", payload{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(stopped)
+	}()
+	<-started
+	cancel()
+	<-stopped
+
+	job, err = store.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.QueueJobPending, job.Status)
+	assert.Zero(t, job.Attempts)
+}
+
+func TestQueue_Backoff(t *testing.T) {
+	q := NewQueue(nil, "replica", 1, time.Second, time.Second, time.Minute)
+	for attempts, expected := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 10: time.Minute, 100: time.Minute} {
+		delay := q.backoff(attempts)
+		assert.GreaterOrEqual(t, delay, expected)
+		assert.LessOrEqual(t, delay, expected+expected/5)
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"rest_api/internal/api/model"
+	"sync/atomic"
+	"time"
+)
+
+type LeaseStore interface {
+	TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, holder string) error
+	Get(ctx context.Context, name string) (*model.Lease, error)
+}
+
+// Elector elects a single leader among the replicas sharing a lease store. The leader renews its lease
+// every third of the TTL; if it dies, another replica takes over once the lease expires.
+type Elector struct {
+	store  LeaseStore
+	name   string
+	id     string
+	ttl    time.Duration
+	leader atomic.Bool
+}
+
+func NewElector(store LeaseStore, name, id string, ttl time.Duration) *Elector {
+	return &Elector{
+		store: store,
+		name:  name,
+		id:    id,
+		ttl:   ttl,
+	}
+}
+
+// Run takes part in the election until ctx is cancelled, releasing the lease on the way out.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		e.campaign(ctx)
+		select {
+		case <-ctx.Done():
+			if e.leader.Swap(false) {
+				releaseCtx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+				if err := e.store.Release(releaseCtx, e.name, e.id); err != nil {
+					slog.Error("Could not release leadership", "lease", e.name, "error", err)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) campaign(ctx context.Context) {
+	acquireCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+	defer cancel()
+	acquired, err := e.store.TryAcquire(acquireCtx, e.name, e.id, e.ttl)
+	if err != nil {
+		// without a renewal the lease may expire at any moment, so leadership cannot be assumed
+		acquired = false
+		slog.Error("Could not acquire leadership", "lease", e.name, "error", err)
+	}
+	if was := e.leader.Swap(acquired); was != acquired {
+		slog.Info("Leadership changed", "lease", e.name, "replica", e.id, "leader", acquired)
+	}
+}
+
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// Current returns the lease of the current leader, as stored.
+func (e *Elector) Current(ctx context.Context) (*model.Lease, error) {
+	return e.store.Get(ctx, e.name)
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"rest_api/internal/api/model"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]*model.Lease
+}
+
+func (m *memoryLeaseStore) TryAcquire(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	lease, ok := m.leases[name]
+	if ok && lease.Holder != holder && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	m.leases[name] = &model.Lease{Name: name, Holder: holder, RenewedAt: now, ExpiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *memoryLeaseStore) Release(_ context.Context, name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lease, ok := m.leases[name]; ok && lease.Holder == holder {
+		delete(m.leases, name)
+	}
+	return nil
+}
+
+func (m *memoryLeaseStore) Get(_ context.Context, name string) (*model.Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lease, ok := m.leases[name]; ok {
+		return lease, nil
+	}
+	return nil, errors.New("no lease")
+}
+
+func TestElector_FailsOver(t *testing.T) {
+	store := &memoryLeaseStore{leases: map[string]*model.Lease{}}
+	first := NewElector(store, "scheduler", "first", 30*time.Millisecond)
+	second := NewElector(store, "scheduler", "second", 30*time.Millisecond)
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		first.Run(firstCtx)
+		close(firstDone)
+	}()
+	require.Eventually(t, first.IsLeader, time.Second, time.Millisecond)
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+	go second.Run(secondCtx)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, second.IsLeader())
+
+	lease, err := second.Current(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "first", lease.Holder)
+
+	stopFirst()
+	<-firstDone
+	assert.False(t, first.IsLeader())
+	require.Eventually(t, second.IsLeader, time.Second, time.Millisecond)
+}
+
+type staticLeadership bool
+
+func (l staticLeadership) IsLeader() bool {
+	return bool(l)
+}
+
+type busyLocker struct{}
+
+func (busyLocker) TryLock(context.Context, string) (func(), bool, error) {
+	return nil, false, nil
+}
+
+func TestScheduler_OnlyLeaderRunsScheduledJobs(t *testing.T) {
+	var runs atomic.Int32
+	job := Job{
+		Name:     "count",
+		Schedule: Every(5 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	}
+	s := NewScheduler(staticLeadership(false), nil)
+	require.NoError(t, s.Register(job))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.Zero(t, runs.Load())
+}
+
+func TestScheduler_SkipsRunsLockedByOtherReplica(t *testing.T) {
+	var runs atomic.Int32
+	s := NewScheduler(staticLeadership(true), busyLocker{})
+	require.NoError(t, s.Register(Job{
+		Name:     "count",
+		Schedule: MustParseCron("@yearly"),
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	}))
+	require.NoError(t, s.Trigger("count"))
+	require.Eventually(t, func() bool { return s.Jobs()[0].Skipped == 1 }, time.Second, time.Millisecond)
+
+	status := s.Jobs()[0]
+	assert.False(t, status.Running)
+	assert.Nil(t, status.LastRun)
+	assert.Zero(t, runs.Load())
+}
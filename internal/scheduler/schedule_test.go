@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	// a wednesday
+	from := time.Date(2026, time.March, 4, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2026, time.March, 4, 10, 18, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", time.Date(2026, time.March, 4, 10, 30, 0, 0, time.UTC)},
+		{"list", "5,20 * * * *", time.Date(2026, time.March, 4, 10, 20, 0, 0, time.UTC)},
+		{"range", "0 2-4 * * *", time.Date(2026, time.March, 5, 2, 0, 0, 0, time.UTC)},
+		{"day of week name", "30 9 * * fri", time.Date(2026, time.March, 6, 9, 30, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2026, time.March, 8, 0, 0, 0, 0, time.UTC)},
+		{"month rollover", "0 0 1 * *", time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"day of month or week", "0 0 10 * mon", time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC)},
+		{"hourly", "@hourly", time.Date(2026, time.March, 4, 11, 0, 0, 0, time.UTC)},
+		{"yearly", "@yearly", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"every", "@every 1m30s", time.Date(2026, time.March, 4, 10, 19, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(from))
+			assert.Equal(t, tt.expr, schedule.String())
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "@every -1m", "@every soon", "0 0 * foo *"} {
+		_, err := ParseCron(expr)
+		assert.Errorf(t, err, "expected error for %q", expr)
+	}
+}
+
+func TestParseCron_NeverFires(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
@@ -1,37 +1,288 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"runtime/debug"
+	"sort"
 	"sync"
 	"time"
 )
 
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrJobExists   = errors.New("job already registered")
+)
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Job is a named unit of background work. Run receives a context that is cancelled when the job
+// times out or the scheduler shuts down.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Timeout bounds a single run. Zero means no timeout.
+	Timeout time.Duration
+	// Jitter delays every scheduled run by a random duration in [0, Jitter).
+	Jitter time.Duration
+	// Local jobs maintain state of the replica itself, so they run on every replica instead of the leader only.
+	Local bool
+	Run   func(ctx context.Context) error
+}
+
+type RunResult struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Duration   string    `json:"duration"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	Timeout   string     `json:"timeout,omitempty"`
+	Local     bool       `json:"local,omitempty"`
+	Running   bool       `json:"running"`
+	NextRun   time.Time  `json:"nextRun"`
+	LastRun   *RunResult `json:"lastRun,omitempty"`
+	Skipped   int        `json:"skippedRuns"`
+	TotalRuns int        `json:"totalRuns"`
+}
+
+type entry struct {
+	job     Job
+	running bool
+	next    time.Time
+	last    *RunResult
+	skipped int
+	total   int
+}
+
+// Leadership tells whether this replica is the one that should run scheduled jobs.
+type Leadership interface {
+	IsLeader() bool
+}
+
+// Locker guards single job runs, so that a job never runs on two replicas at the same time.
+type Locker interface {
+	TryLock(ctx context.Context, key string) (unlock func(), ok bool, err error)
+}
+
 type Scheduler struct {
-	interval time.Duration
-	done     <-chan bool
-	wg       *sync.WaitGroup
+	mu         sync.Mutex
+	jobs       map[string]*entry
+	ctx        context.Context
+	wg         sync.WaitGroup
+	started    bool
+	leadership Leadership
+	locker     Locker
 }
 
-func NewScheduler(interval time.Duration, done <-chan bool, wg *sync.WaitGroup) *Scheduler {
+// NewScheduler creates a scheduler. When running several replicas, leadership restricts scheduled runs to
+// the leader and locker prevents a manual run from overlapping a run on another replica. Both may be nil
+// for a single replica.
+func NewScheduler(leadership Leadership, locker Locker) *Scheduler {
 	return &Scheduler{
-		interval: interval,
-		done:     done,
-		wg:       wg,
+		jobs:       map[string]*entry{},
+		ctx:        context.Background(),
+		leadership: leadership,
+		locker:     locker,
+	}
+}
+
+// Register adds a job to the registry. Jobs have to be registered before Run is called.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return fmt.Errorf("job %q should have a name, a schedule and a run function", job.Name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("cannot register job %q: scheduler already running", job.Name)
+	}
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+	s.jobs[job.Name] = &entry{job: job}
+	return nil
+}
+
+// Run starts all registered jobs and blocks until ctx is cancelled. Running jobs get their context
+// cancelled and Run waits for them to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.started = true
+	for _, e := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+	s.mu.Unlock()
+
+	slog.Info("Scheduler started", "jobs", len(s.jobs))
+	<-ctx.Done()
+	slog.Info("Shutting down scheduler, waiting for running jobs")
+	s.wg.Wait()
+	slog.Info("Scheduler stopped")
+}
+
+// Trigger runs a job immediately, outside its schedule.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if e.running {
+		return ErrJobRunning
 	}
+	if s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+	e.running = true
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(ctx, e, TriggerManual)
+	}()
+	return nil
+}
+
+// Jobs returns the status of all registered jobs, ordered by name.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		status := JobStatus{
+			Name:      e.job.Name,
+			Schedule:  e.job.Schedule.String(),
+			Local:     e.job.Local,
+			Running:   e.running,
+			NextRun:   e.next,
+			Skipped:   e.skipped,
+			TotalRuns: e.total,
+		}
+		if e.job.Timeout > 0 {
+			status.Timeout = e.job.Timeout.String()
+		}
+		if e.last != nil {
+			last := *e.last
+			status.LastRun = &last
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
 }
 
-func (s *Scheduler) Run() {
-	ticker := time.NewTicker(time.Second * s.interval)
+func (s *Scheduler) loop(e *entry) {
+	defer s.wg.Done()
 	for {
+		next := e.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			slog.Warn("Job schedule never fires again", "job", e.job.Name)
+			return
+		}
+		if e.job.Jitter > 0 {
+			next = next.Add(rand.N(e.job.Jitter))
+		}
+		s.mu.Lock()
+		e.next = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
 		select {
-		case <-ticker.C:
-			fmt.Println("Running background process")
-		case <-s.done:
-			fmt.Println("Shutting down background process")
-			time.Sleep(10 * time.Second)
-			ticker.Stop()
-			s.wg.Done()
+		case <-s.ctx.Done():
+			timer.Stop()
 			return
+		case <-timer.C:
+		}
+		if !e.job.Local && s.leadership != nil && !s.leadership.IsLeader() {
+			slog.Debug("Not the leader, leaving job run to another replica", "job", e.job.Name)
+			continue
 		}
+
+		s.mu.Lock()
+		if e.running {
+			e.skipped++
+			s.mu.Unlock()
+			slog.Warn("Skipping job run, previous run still in progress", "job", e.job.Name)
+			continue
+		}
+		e.running = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.execute(s.ctx, e, TriggerSchedule)
+		}()
 	}
 }
+
+func (s *Scheduler) execute(ctx context.Context, e *entry, trigger string) {
+	if s.locker != nil && !e.job.Local {
+		unlock, ok, err := s.locker.TryLock(ctx, "job:"+e.job.Name)
+		if err != nil || !ok {
+			if err != nil {
+				slog.Error("Could not lock job", "job", e.job.Name, "error", err)
+			} else {
+				slog.Warn("Skipping job run, job is running on another replica", "job", e.job.Name)
+			}
+			s.mu.Lock()
+			e.running = false
+			e.skipped++
+			s.mu.Unlock()
+			return
+		}
+		defer unlock()
+	}
+
+	result := &RunResult{Trigger: trigger, StartedAt: time.Now()}
+	slog.Info("Running job", "job", e.job.Name, "trigger", trigger)
+
+	err := runJob(ctx, e.job)
+
+	result.FinishedAt = time.Now()
+	result.Duration = result.FinishedAt.Sub(result.StartedAt).String()
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+		slog.Error("Job failed", "job", e.job.Name, "duration", result.Duration, "error", err)
+	} else {
+		slog.Info("Job finished", "job", e.job.Name, "duration", result.Duration)
+	}
+
+	s.mu.Lock()
+	e.running = false
+	e.last = result
+	e.total++
+	s.mu.Unlock()
+}
+
+func runJob(ctx context.Context, job Job) (err error) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Job panicked", "job", job.Name, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
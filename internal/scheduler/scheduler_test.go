@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_RunsJobsOnSchedule(t *testing.T) {
+	var runs atomic.Int32
+	s := NewScheduler(nil, nil)
+	require.NoError(t, s.Register(Job{
+		Name:     "count",
+		Schedule: Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	s.Run(ctx)
+
+	assert.GreaterOrEqual(t, runs.Load(), int32(3))
+	status := s.Jobs()[0]
+	assert.Equal(t, "count", status.Name)
+	assert.Equal(t, "@every 10ms", status.Schedule)
+	require.NotNil(t, status.LastRun)
+	assert.True(t, status.LastRun.Success)
+	assert.Equal(t, TriggerSchedule, status.LastRun.Trigger)
+}
+
+func TestScheduler_PreventsOverlap(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	s := NewScheduler(nil, nil)
+	require.NoError(t, s.Register(Job{
+		Name:     "slow",
+		Schedule: Every(5 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			current := running.Add(1)
+			if current > maxRunning.Load() {
+				maxRunning.Store(current)
+			}
+			time.Sleep(40 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		},
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.Equal(t, int32(1), maxRunning.Load())
+	assert.Positive(t, s.Jobs()[0].Skipped)
+}
+
+func TestScheduler_TriggerRecoversPanicsAndTimesOut(t *testing.T) {
+	s := NewScheduler(nil, nil)
+	require.NoError(t, s.Register(Job{
+		Name:     "panics",
+		Schedule: MustParseCron("@yearly"),
+		Run: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}))
+	require.NoError(t, s.Register(Job{
+		Name:     "hangs",
+		Schedule: MustParseCron("@yearly"),
+		Timeout:  10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+	assert.ErrorIs(t, s.Register(Job{Name: "hangs", Schedule: Every(time.Second), Run: func(context.Context) error { return nil }}), ErrJobExists)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(stopped)
+	}()
+
+	assert.ErrorIs(t, s.Trigger("missing"), ErrJobNotFound)
+	require.Eventually(t, func() bool { return s.Trigger("panics") == nil }, time.Second, time.Millisecond)
+	require.NoError(t, s.Trigger("hangs"))
+	assert.ErrorIs(t, s.Trigger("hangs"), ErrJobRunning)
+
+	require.Eventually(t, func() bool {
+		for _, status := range s.Jobs() {
+			if status.LastRun == nil {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+
+	statuses := s.Jobs()
+	assert.Equal(t, "hangs", statuses[0].Name)
+	assert.Equal(t, context.DeadlineExceeded.Error(), statuses[0].LastRun.Error)
+	assert.Equal(t, "panics", statuses[1].Name)
+	assert.Equal(t, "panic: boom", statuses[1].LastRun.Error)
+	assert.Equal(t, TriggerManual, statuses[1].LastRun.Trigger)
+
+	cancel()
+	<-stopped
+	assert.True(t, errors.Is(s.Trigger("panics"), context.Canceled))
+}
+
+type follower struct{}
+
+func (follower) IsLeader() bool { return false }
+
+func TestScheduler_RunsLocalJobsOnFollowers(t *testing.T) {
+	var shared, local atomic.Int32
+	s := NewScheduler(follower{}, nil)
+	require.NoError(t, s.Register(Job{
+		Name:     "shared",
+		Schedule: Every(10 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			shared.Add(1)
+			return nil
+		},
+	}))
+	require.NoError(t, s.Register(Job{
+		Name:     "local",
+		Schedule: Every(10 * time.Millisecond),
+		Local:    true,
+		Run: func(ctx context.Context) error {
+			local.Add(1)
+			return nil
+		},
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.Zero(t, shared.Load())
+	assert.Positive(t, local.Load())
+	assert.True(t, s.Jobs()[0].Local)
+}
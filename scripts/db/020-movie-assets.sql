@@ -0,0 +1,14 @@
+CREATE TABLE movie_assets (
+                        id SERIAL,
+                        movieID varchar(50) NOT NULL REFERENCES movies (movieID) ON DELETE CASCADE,
+                        asset_type varchar(20) NOT NULL,
+                        object_key varchar(255) NOT NULL UNIQUE,
+                        size bigint NOT NULL,
+                        checksum varchar(128),
+                        content_type varchar(100),
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (id)
+);
+CREATE INDEX movie_assets_movie_idx ON movie_assets (movieID);
+GRANT ALL ON movie_assets TO "user";
+GRANT ALL ON SEQUENCE movie_assets_id_seq TO "user";
@@ -0,0 +1,16 @@
+ALTER TABLE movies ADD COLUMN tmdb_id integer;
+ALTER TABLE movies ADD COLUMN enriched_at timestamptz;
+CREATE INDEX movies_enriched_at_idx ON movies (enriched_at) WHERE tmdb_id IS NOT NULL;
+
+CREATE TABLE metadata_refresh_runs (
+                        id SERIAL,
+                        started_at timestamptz NOT NULL,
+                        finished_at timestamptz NOT NULL,
+                        candidates integer NOT NULL,
+                        refreshed integer NOT NULL,
+                        unchanged integer NOT NULL,
+                        failed integer NOT NULL,
+                        PRIMARY KEY (id)
+);
+GRANT ALL ON metadata_refresh_runs TO "user";
+GRANT ALL ON SEQUENCE metadata_refresh_runs_id_seq TO "user";
@@ -0,0 +1,9 @@
+CREATE TABLE scheduler_leases (
+                        name varchar(100) NOT NULL,
+                        holder varchar(255) NOT NULL,
+                        acquired_at timestamptz NOT NULL,
+                        renewed_at timestamptz NOT NULL,
+                        expires_at timestamptz NOT NULL,
+                        PRIMARY KEY (name)
+);
+GRANT ALL ON scheduler_leases TO "user";
@@ -0,0 +1,25 @@
+CREATE TABLE movie_imports (
+                        id SERIAL,
+                        format varchar(10) NOT NULL,
+                        status varchar(20) NOT NULL,
+                        total_rows integer NOT NULL DEFAULT 0,
+                        processed_rows integer NOT NULL DEFAULT 0,
+                        imported_rows integer NOT NULL DEFAULT 0,
+                        failed_rows integer NOT NULL DEFAULT 0,
+                        error text,
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        finished_at timestamptz,
+                        PRIMARY KEY (id)
+);
+GRANT ALL ON movie_imports TO "user";
+GRANT ALL ON SEQUENCE movie_imports_id_seq TO "user";
+
+CREATE TABLE movie_import_errors (
+                        import_id integer NOT NULL REFERENCES movie_imports (id) ON DELETE CASCADE,
+                        line integer NOT NULL,
+                        movie_id integer,
+                        title varchar(255),
+                        message text NOT NULL
+);
+CREATE INDEX movie_import_errors_import_idx ON movie_import_errors (import_id, line);
+GRANT ALL ON movie_import_errors TO "user";
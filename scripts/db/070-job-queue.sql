@@ -0,0 +1,21 @@
+CREATE TABLE queue_jobs (
+                        id BIGSERIAL,
+                        kind varchar(100) NOT NULL,
+                        payload jsonb NOT NULL,
+                        status varchar(20) NOT NULL DEFAULT 'pending',
+                        attempts integer NOT NULL DEFAULT 0,
+                        max_attempts integer NOT NULL,
+                        run_at timestamptz NOT NULL DEFAULT now(),
+                        locked_by varchar(255),
+                        locked_until timestamptz,
+                        last_error text,
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        updated_at timestamptz NOT NULL DEFAULT now(),
+                        finished_at timestamptz,
+                        PRIMARY KEY (id)
+);
+CREATE INDEX queue_jobs_pending_idx ON queue_jobs (run_at) WHERE status = 'pending';
+CREATE INDEX queue_jobs_running_idx ON queue_jobs (locked_until) WHERE status = 'running';
+CREATE INDEX queue_jobs_status_idx ON queue_jobs (status, created_at);
+GRANT ALL ON queue_jobs TO "user";
+GRANT ALL ON SEQUENCE queue_jobs_id_seq TO "user";
@@ -0,0 +1,25 @@
+CREATE TABLE audit_log (
+                        id BIGSERIAL,
+                        occurred_at timestamptz NOT NULL DEFAULT now(),
+                        actor varchar(50) NOT NULL,
+                        request_id varchar(64),
+                        action varchar(20) NOT NULL,
+                        entity varchar(20) NOT NULL,
+                        entity_id varchar(50) NOT NULL,
+                        before jsonb,
+                        after jsonb,
+                        PRIMARY KEY (id)
+);
+CREATE INDEX audit_log_occurred_at_idx ON audit_log (occurred_at);
+CREATE INDEX audit_log_entity_idx ON audit_log (entity, entity_id, occurred_at);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor, occurred_at);
+-- the log is append-only: the application can only insert and read entries
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+GRANT SELECT, INSERT ON audit_log TO "user";
+GRANT USAGE ON SEQUENCE audit_log_id_seq TO "user";
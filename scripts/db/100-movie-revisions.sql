@@ -0,0 +1,13 @@
+CREATE TABLE movie_revisions (
+                        id BIGSERIAL,
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        revision integer NOT NULL,
+                        movie jsonb NOT NULL,
+                        actor varchar(50),
+                        request_id varchar(64),
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (id),
+                        UNIQUE (movie_id, revision)
+);
+GRANT ALL ON movie_revisions TO "user";
+GRANT ALL ON SEQUENCE movie_revisions_id_seq TO "user";
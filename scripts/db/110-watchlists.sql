@@ -0,0 +1,22 @@
+CREATE TABLE watchlists (
+                        id SERIAL,
+                        username varchar(50) NOT NULL REFERENCES users (username) ON UPDATE CASCADE ON DELETE CASCADE,
+                        name varchar(100) NOT NULL,
+                        is_default boolean NOT NULL DEFAULT false,
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (id),
+                        UNIQUE (username, name)
+);
+CREATE UNIQUE INDEX watchlists_default_idx ON watchlists (username) WHERE is_default;
+CREATE TABLE watchlist_items (
+                        watchlist_id integer NOT NULL REFERENCES watchlists (id) ON DELETE CASCADE,
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        position integer NOT NULL,
+                        added_at timestamptz NOT NULL DEFAULT now(),
+                        watched_at timestamptz,
+                        PRIMARY KEY (watchlist_id, movie_id)
+);
+CREATE INDEX watchlist_items_movie_idx ON watchlist_items (movie_id);
+GRANT ALL ON watchlists TO "user";
+GRANT ALL ON SEQUENCE watchlists_id_seq TO "user";
+GRANT ALL ON watchlist_items TO "user";
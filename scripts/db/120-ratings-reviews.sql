@@ -0,0 +1,29 @@
+ALTER TABLE movies ADD COLUMN tmdb_vote_average numeric(3, 1);
+-- aggregate of movie_ratings, maintained by every rating change
+ALTER TABLE movies ADD COLUMN rating_count integer NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN rating_sum integer NOT NULL DEFAULT 0;
+CREATE TABLE movie_ratings (
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        username varchar(50) NOT NULL REFERENCES users (username) ON UPDATE CASCADE ON DELETE CASCADE,
+                        rating smallint NOT NULL CHECK (rating BETWEEN 1 AND 10),
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        updated_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (movie_id, username)
+);
+CREATE INDEX movie_ratings_username_idx ON movie_ratings (username);
+CREATE TABLE movie_reviews (
+                        id SERIAL,
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        username varchar(50) NOT NULL REFERENCES users (username) ON UPDATE CASCADE ON DELETE CASCADE,
+                        body text NOT NULL,
+                        spoiler boolean NOT NULL DEFAULT false,
+                        hidden_at timestamptz,
+                        hidden_by varchar(50),
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        updated_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (id),
+                        UNIQUE (movie_id, username)
+);
+GRANT ALL ON movie_ratings TO "user";
+GRANT ALL ON movie_reviews TO "user";
+GRANT ALL ON SEQUENCE movie_reviews_id_seq TO "user";
@@ -0,0 +1,36 @@
+-- genres use the ids of TMDB, so that the genre ids of its responses are stored as is
+CREATE TABLE genres (
+                        id integer,
+                        name varchar(50) NOT NULL UNIQUE,
+                        PRIMARY KEY (id)
+);
+INSERT INTO genres(id, name) VALUES
+    (28, 'Action'), (12, 'Adventure'), (16, 'Animation'), (35, 'Comedy'), (80, 'Crime'), (99, 'Documentary'),
+    (18, 'Drama'), (10751, 'Family'), (14, 'Fantasy'), (36, 'History'), (27, 'Horror'), (10402, 'Music'),
+    (9648, 'Mystery'), (10749, 'Romance'), (878, 'Science Fiction'), (10770, 'TV Movie'), (53, 'Thriller'),
+    (10752, 'War'), (37, 'Western');
+CREATE TABLE movie_genres (
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        genre_id integer NOT NULL REFERENCES genres (id) ON DELETE CASCADE,
+                        PRIMARY KEY (movie_id, genre_id)
+);
+CREATE INDEX movie_genres_genre_idx ON movie_genres (genre_id);
+CREATE TABLE tags (
+                        id SERIAL,
+                        name varchar(50) NOT NULL,
+                        created_by varchar(50),
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX tags_name_idx ON tags (lower(name));
+CREATE TABLE movie_tags (
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        tag_id integer NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+                        PRIMARY KEY (movie_id, tag_id)
+);
+CREATE INDEX movie_tags_tag_idx ON movie_tags (tag_id);
+GRANT SELECT ON genres TO "user";
+GRANT ALL ON movie_genres TO "user";
+GRANT ALL ON tags TO "user";
+GRANT ALL ON SEQUENCE tags_id_seq TO "user";
+GRANT ALL ON movie_tags TO "user";
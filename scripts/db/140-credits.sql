@@ -0,0 +1,25 @@
+-- people use the person ids of TMDB, so that the same person is stored once across movies
+CREATE TABLE people (
+                        id integer,
+                        name varchar(200) NOT NULL,
+                        profile_path varchar(200),
+                        known_for_department varchar(50),
+                        updated_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (id)
+);
+CREATE TABLE movie_credits (
+                        id SERIAL,
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        person_id integer NOT NULL REFERENCES people (id),
+                        role varchar(10) NOT NULL CHECK (role IN ('cast', 'crew')),
+                        character_name text,
+                        job varchar(100),
+                        department varchar(50),
+                        credit_order integer NOT NULL,
+                        PRIMARY KEY (id)
+);
+CREATE INDEX movie_credits_movie_idx ON movie_credits (movie_id, role, credit_order);
+CREATE INDEX movie_credits_person_idx ON movie_credits (person_id);
+GRANT ALL ON people TO "user";
+GRANT ALL ON movie_credits TO "user";
+GRANT ALL ON SEQUENCE movie_credits_id_seq TO "user";
@@ -0,0 +1,25 @@
+ALTER TABLE movies ADD COLUMN release_date date;
+ALTER TABLE users ADD COLUMN is_curator boolean NOT NULL DEFAULT false;
+
+-- collections are either a TMDB collection, linked when its movies are enriched, or a manual one created by a
+-- curator, whose movies are ordered by position
+CREATE TABLE collections (
+                        id SERIAL,
+                        name varchar(200) NOT NULL,
+                        description text,
+                        tmdb_id integer UNIQUE,
+                        created_by varchar(50),
+                        created_at timestamptz NOT NULL DEFAULT now(),
+                        updated_at timestamptz NOT NULL DEFAULT now(),
+                        PRIMARY KEY (id)
+);
+CREATE TABLE collection_movies (
+                        collection_id integer NOT NULL REFERENCES collections (id) ON DELETE CASCADE,
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        position integer,
+                        PRIMARY KEY (collection_id, movie_id)
+);
+CREATE INDEX collection_movies_movie_idx ON collection_movies (movie_id);
+GRANT ALL ON collections TO "user";
+GRANT ALL ON SEQUENCE collections_id_seq TO "user";
+GRANT ALL ON collection_movies TO "user";
@@ -0,0 +1,8 @@
+-- item-item similarity matrix of the recommendations, replaced by every run of the recommendation job
+CREATE TABLE movie_neighbors (
+                        movie_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        neighbor_id varchar(50) NOT NULL REFERENCES movies (movieID) ON UPDATE CASCADE ON DELETE CASCADE,
+                        similarity real NOT NULL,
+                        PRIMARY KEY (movie_id, neighbor_id)
+);
+GRANT ALL ON movie_neighbors TO "user";
@@ -0,0 +1,38 @@
+-- when the representation of the movie last changed, for the Last-Modified header of the movie endpoints. It is
+-- maintained by triggers, so that every writer of movies, of their genres and of their tags keeps it up to date.
+ALTER TABLE movies ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now();
+CREATE INDEX movies_updated_at_idx ON movies (updated_at);
+CREATE FUNCTION movies_touch() RETURNS trigger AS $$
+BEGIN
+    -- recording the enrichment does not change the movie
+    IF to_jsonb(NEW) - 'enriched_at' - 'updated_at' IS DISTINCT FROM to_jsonb(OLD) - 'enriched_at' - 'updated_at' THEN
+        NEW.updated_at = now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER movies_touch BEFORE UPDATE ON movies
+    FOR EACH ROW EXECUTE FUNCTION movies_touch();
+CREATE FUNCTION movie_labels_touch() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        UPDATE movies SET updated_at = now() WHERE movieID = OLD.movie_id;
+    ELSE
+        UPDATE movies SET updated_at = now() WHERE movieID = NEW.movie_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER movie_genres_touch AFTER INSERT OR DELETE ON movie_genres
+    FOR EACH ROW EXECUTE FUNCTION movie_labels_touch();
+CREATE TRIGGER movie_tags_touch AFTER INSERT OR DELETE ON movie_tags
+    FOR EACH ROW EXECUTE FUNCTION movie_labels_touch();
+-- renaming a tag changes the movies it is given to
+CREATE FUNCTION tags_touch_movies() RETURNS trigger AS $$
+BEGIN
+    UPDATE movies SET updated_at = now() WHERE movieID IN (SELECT movie_id FROM movie_tags WHERE tag_id = NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER tags_touch_movies AFTER UPDATE OF name ON tags
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE FUNCTION tags_touch_movies();
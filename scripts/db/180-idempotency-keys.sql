@@ -0,0 +1,15 @@
+-- keys sent with unsafe requests in the Idempotency-Key header, along with the response to replay on their retries.
+-- Keys are scoped to the user who sent them. status is null while the first request is being processed.
+CREATE TABLE idempotency_keys (
+                        actor varchar(50) NOT NULL,
+                        key varchar(255) NOT NULL,
+                        fingerprint char(64) NOT NULL,
+                        status smallint,
+                        headers jsonb,
+                        body bytea,
+                        created_at timestamptz NOT NULL,
+                        expires_at timestamptz NOT NULL,
+                        PRIMARY KEY (actor, key)
+);
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+GRANT ALL ON idempotency_keys TO "user";
@@ -0,0 +1,15 @@
+-- when the metadata refresh last tried to fetch the movie from TMDB, successfully or not, so that movies failing
+-- every time do not keep the other stale movies out of the batches
+ALTER TABLE movies ADD COLUMN enrich_attempted_at timestamptz;
+UPDATE movies SET enrich_attempted_at = enriched_at;
+CREATE INDEX movies_enrich_attempted_at_idx ON movies (enrich_attempted_at) WHERE tmdb_id IS NOT NULL;
+CREATE OR REPLACE FUNCTION movies_touch() RETURNS trigger AS $$
+BEGIN
+    -- recording the enrichment does not change the movie
+    IF to_jsonb(NEW) - 'enriched_at' - 'enrich_attempted_at' - 'updated_at'
+        IS DISTINCT FROM to_jsonb(OLD) - 'enriched_at' - 'enrich_attempted_at' - 'updated_at' THEN
+        NEW.updated_at = now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,30 @@
+-- when the catalog last changed, for the Last-Modified header of the movie list. Unlike the last updated_at of the
+-- movies, it never goes back, in particular when movies are purged from the trash. It is maintained by triggers on
+-- movies, whose updated_at already follows their genres and tags.
+CREATE TABLE catalog_modified (
+                        id boolean PRIMARY KEY DEFAULT true CHECK (id),
+                        modified_at timestamptz NOT NULL
+);
+INSERT INTO catalog_modified(modified_at) SELECT COALESCE(max(updated_at), now()) FROM movies;
+GRANT ALL ON catalog_modified TO "user";
+CREATE FUNCTION catalog_touch() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        -- recording the enrichment does not change the catalog
+        IF NOT EXISTS (SELECT 1 FROM changed JOIN previous USING (id) WHERE changed.updated_at IS DISTINCT FROM previous.updated_at) THEN
+            RETURN NULL;
+        END IF;
+    ELSIF NOT EXISTS (SELECT 1 FROM changed) THEN
+        RETURN NULL;
+    END IF;
+    -- the lock of the row orders the concurrent changes, and the clock is not trusted to never go back
+    UPDATE catalog_modified SET modified_at = greatest(modified_at, clock_timestamp());
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER movies_insert_catalog_touch AFTER INSERT ON movies
+    REFERENCING NEW TABLE AS changed FOR EACH STATEMENT EXECUTE FUNCTION catalog_touch();
+CREATE TRIGGER movies_update_catalog_touch AFTER UPDATE ON movies
+    REFERENCING OLD TABLE AS previous NEW TABLE AS changed FOR EACH STATEMENT EXECUTE FUNCTION catalog_touch();
+CREATE TRIGGER movies_delete_catalog_touch AFTER DELETE ON movies
+    REFERENCING OLD TABLE AS changed FOR EACH STATEMENT EXECUTE FUNCTION catalog_touch();